@@ -0,0 +1,179 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/sidalsoft/wallet/pkg/scenario"
+	"github.com/sidalsoft/wallet/pkg/types"
+	"github.com/sidalsoft/wallet/pkg/wallet"
+)
+
+const usage = `usage: walletctl [-dir path] <command> [args]
+
+commands:
+  register <phone>                     register new account
+  deposit  <accountID> <amount>        deposit amount to account
+  pay      <accountID> <amount> <cat>  make payment from account
+  history  <accountID>                 print payments of account
+  export   <dir>                       export state to dir
+  import   <dir>                       import state from dir
+  load     <scenario.yaml>             apply scenario to state
+  verify   <scenario.yaml>             check balances expected by scenario
+`
+
+func main() {
+	dir := flag.String("dir", "data", "dump directory to load and save state")
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := run(*dir, flag.Arg(0), flag.Args()[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "walletctl:", err)
+		os.Exit(1)
+	}
+}
+
+func run(dir string, cmd string, args []string) error {
+	svc := &wallet.Service{}
+	err := svc.Import(dir)
+	if err != nil {
+		return err
+	}
+
+	switch cmd {
+	case "register":
+		if len(args) != 1 {
+			return fmt.Errorf("register: expected <phone>")
+		}
+		account, err := svc.RegisterAccount(types.Phone(args[0]))
+		if err != nil {
+			return err
+		}
+		printAccount(account)
+	case "deposit":
+		if len(args) != 2 {
+			return fmt.Errorf("deposit: expected <accountID> <amount>")
+		}
+		accountID, err := parseAccountID(args[0])
+		if err != nil {
+			return err
+		}
+		amount, err := parseMoney(svc, accountID, args[1])
+		if err != nil {
+			return err
+		}
+		err = svc.Deposit(accountID, amount)
+		if err != nil {
+			return err
+		}
+		account, err := svc.FindAccountByID(accountID)
+		if err != nil {
+			return err
+		}
+		printAccount(account)
+	case "pay":
+		if len(args) != 3 {
+			return fmt.Errorf("pay: expected <accountID> <amount> <category>")
+		}
+		accountID, err := parseAccountID(args[0])
+		if err != nil {
+			return err
+		}
+		amount, err := parseMoney(svc, accountID, args[1])
+		if err != nil {
+			return err
+		}
+		payment, err := svc.Pay(accountID, amount, types.PaymentCategory(args[2]))
+		if err != nil {
+			return err
+		}
+		printPayment(*payment)
+	case "history":
+		if len(args) != 1 {
+			return fmt.Errorf("history: expected <accountID>")
+		}
+		accountID, err := parseAccountID(args[0])
+		if err != nil {
+			return err
+		}
+		payments, err := svc.ExportAccountHistory(accountID)
+		if err != nil {
+			return err
+		}
+		for _, payment := range payments {
+			printPayment(payment)
+		}
+		return nil
+	case "export":
+		if len(args) != 1 {
+			return fmt.Errorf("export: expected <dir>")
+		}
+		return svc.Export(args[0])
+	case "import":
+		if len(args) != 1 {
+			return fmt.Errorf("import: expected <dir>")
+		}
+		err = svc.Import(args[0])
+		if err != nil {
+			return err
+		}
+	case "load", "verify":
+		if len(args) != 1 {
+			return fmt.Errorf("%s: expected <scenario.yaml>", cmd)
+		}
+		sc, err := scenario.LoadFile(args[0])
+		if err != nil {
+			return err
+		}
+		if cmd == "verify" {
+			return sc.Verify(svc)
+		}
+		err = sc.Load(svc)
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown command %q", cmd)
+	}
+
+	return svc.Export(dir)
+}
+
+func parseAccountID(s string) (int64, error) {
+	id, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid account id %q", s)
+	}
+	return id, nil
+}
+
+// parseMoney разбирает сумму вида "105.50" в минимальные единицы валюты счета
+func parseMoney(svc *wallet.Service, accountID int64, s string) (types.Money, error) {
+	account, err := svc.FindAccountByID(accountID)
+	if err != nil {
+		return 0, err
+	}
+	amount, err := account.Currency.Parse(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	return amount, nil
+}
+
+func printAccount(account *types.Account) {
+	fmt.Printf("account %d\tphone %s\tbalance %s %s\n", account.ID, account.Phone, account.Currency.Format(account.Balance), account.Currency)
+}
+
+func printPayment(payment types.Payment) {
+	fmt.Printf("%s\taccount %d\t%s %s\t%s\t%s\n", payment.ID, payment.AccountID, payment.Currency.Format(payment.Amount), payment.Currency, payment.Category, payment.Status)
+}
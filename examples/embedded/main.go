@@ -0,0 +1,56 @@
+// Пример встраивания кошелька в приложение как библиотеки
+package main
+
+import (
+	"log"
+	"os"
+
+	"github.com/sidalsoft/wallet/pkg/wallet"
+)
+
+func main() {
+	svc := &wallet.Service{}
+
+	account, err := svc.RegisterAccount("+992928885522")
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = svc.Deposit(account.ID, 1_000_00)
+	if err != nil {
+		log.Fatal(err)
+	}
+	payment, err := svc.Pay(account.ID, 150_00, "internet")
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = svc.Confirm(payment.ID)
+	if err != nil {
+		log.Fatal(err)
+	}
+	account, err = svc.FindAccountByID(account.ID)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("payment %s: %d, balance %d", payment.ID, payment.Amount, account.Balance)
+
+	dir, err := os.MkdirTemp("", "wallet")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	err = svc.Export(dir)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	restored := &wallet.Service{}
+	err = restored.Import(dir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	history, err := restored.ExportAccountHistory(account.ID)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("restored %d payments from %s", len(history), dir)
+}
@@ -0,0 +1,78 @@
+// Пример пакетных выплат из CSV-файла вида "phone,amount,category".
+// Счета, которых еще нет, регистрируются, выплаты проводятся со счета -from.
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"io"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/sidalsoft/wallet/pkg/types"
+	"github.com/sidalsoft/wallet/pkg/wallet"
+)
+
+func main() {
+	dir := flag.String("dir", "data", "dump directory")
+	from := flag.Int64("from", 1, "account to pay from")
+	flag.Parse()
+	if flag.NArg() != 1 {
+		log.Fatal("usage: payouts [-dir path] [-from id] payouts.csv")
+	}
+
+	svc := &wallet.Service{}
+	err := svc.Import(*dir)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	file, err := os.Open(flag.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = 3
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		amount, err := strconv.ParseInt(record[1], 10, 64)
+		if err != nil {
+			log.Printf("line %d: invalid amount %q", line, record[1])
+			continue
+		}
+		payee, err := svc.FindAccountByPhone(types.Phone(record[0]))
+		if err != nil {
+			payee, err = svc.RegisterAccount(types.Phone(record[0]))
+			if err != nil {
+				log.Printf("line %d: %v", line, err)
+				continue
+			}
+		}
+		payment, err := svc.Pay(*from, types.Money(amount), types.PaymentCategory(record[2]))
+		if err != nil {
+			log.Printf("line %d: %v", line, err)
+			continue
+		}
+		err = svc.Deposit(payee.ID, types.Money(amount))
+		if err != nil {
+			log.Printf("line %d: %v", line, err)
+			_ = svc.Reject(payment.ID)
+			continue
+		}
+		_ = svc.Confirm(payment.ID)
+	}
+
+	err = svc.Export(*dir)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
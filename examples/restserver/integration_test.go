@@ -0,0 +1,90 @@
+//go:build integration
+// +build integration
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/sidalsoft/wallet/pkg/types"
+	"github.com/sidalsoft/wallet/pkg/wallet"
+)
+
+// Сквозной тест REST-сервера: go test -tags integration ./examples/restserver
+func TestServer_lifecycle(t *testing.T) {
+	srv := &server{svc: &wallet.Service{}}
+	ts := httptest.NewServer(newMux(srv))
+	defer ts.Close()
+
+	call := func(method string, path string, params url.Values, v interface{}) {
+		t.Helper()
+		req, err := http.NewRequest(method, ts.URL+path+"?"+params.Encode(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := ts.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("%s %s: status = %v", method, path, resp.Status)
+		}
+		err = json.NewDecoder(resp.Body).Decode(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var account types.Account
+	call(http.MethodPost, "/accounts", url.Values{"phone": {"+992000000001"}}, &account)
+	call(http.MethodPost, "/deposit", url.Values{"account": {"1"}, "amount": {"1000000"}}, &account)
+	var payments []types.Payment
+	for _, category := range []string{"auto", "food", "auto"} {
+		var payment types.Payment
+		call(http.MethodPost, "/pay", url.Values{"account": {"1"}, "amount": {"100000"}, "category": {category}}, &payment)
+		payments = append(payments, payment)
+	}
+	err := srv.svc.Reject(payments[1].ID)
+	if err != nil {
+		t.Fatalf("Reject(): error = %v", err)
+	}
+	var history []types.Payment
+	call(http.MethodGet, "/history", url.Values{"account": {"1"}}, &history)
+	if len(history) != 3 {
+		t.Fatalf("history = %v, want 3 payments", history)
+	}
+
+	dir := t.TempDir()
+	err = srv.svc.Export(dir)
+	if err != nil {
+		t.Fatalf("Export(): error = %v", err)
+	}
+	imported := &wallet.Service{}
+	err = imported.Import(dir)
+	if err != nil {
+		t.Fatalf("Import(): error = %v", err)
+	}
+
+	// инвариант: баланс равен внесенной сумме минус неотклоненные платежи
+	for _, svc := range []*wallet.Service{srv.svc, imported} {
+		got, err := svc.FindAccountByID(account.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		spent := types.Money(0)
+		svc.ForEachPayment(func(payment types.Payment) bool {
+			if payment.AccountID == got.ID && payment.Status != types.PaymentStatusFail {
+				spent += payment.Amount + payment.Fee
+			}
+			return true
+		})
+		if got.Balance != 1_000_000-spent || got.Balance != 800_000 {
+			t.Errorf("account = %v, spent = %v", got, spent)
+		}
+	}
+}
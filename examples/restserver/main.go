@@ -0,0 +1,141 @@
+// Пример REST-сервера поверх кошелька:
+//
+//	POST /accounts?phone=...
+//	POST /deposit?account=...&amount=...
+//	POST /pay?account=...&amount=...&category=...
+//	GET  /history?account=...
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/sidalsoft/wallet/pkg/types"
+	"github.com/sidalsoft/wallet/pkg/wallet"
+)
+
+type server struct {
+	svc *wallet.Service
+}
+
+func main() {
+	addr := flag.String("addr", ":8080", "listen address")
+	flag.Parse()
+
+	srv := &server{svc: &wallet.Service{}}
+	log.Fatal(http.ListenAndServe(*addr, newMux(srv)))
+}
+
+// newMux возвращает маршруты сервера, общие для main и тестов
+func newMux(srv *server) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/accounts", srv.post(srv.register))
+	mux.HandleFunc("/deposit", srv.post(srv.deposit))
+	mux.HandleFunc("/pay", srv.post(srv.pay))
+	mux.HandleFunc("/history", srv.history)
+	return mux
+}
+
+func (s *server) post(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		handler(w, r)
+	}
+}
+
+func (s *server) register(w http.ResponseWriter, r *http.Request) {
+	account, err := s.svc.RegisterAccount(types.Phone(r.FormValue("phone")))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, account)
+}
+
+func (s *server) deposit(w http.ResponseWriter, r *http.Request) {
+	accountID, amount, ok := parseAccountAmount(w, r)
+	if !ok {
+		return
+	}
+	err := s.svc.Deposit(accountID, amount)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	account, err := s.svc.FindAccountByID(accountID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, account)
+}
+
+func (s *server) pay(w http.ResponseWriter, r *http.Request) {
+	accountID, amount, ok := parseAccountAmount(w, r)
+	if !ok {
+		return
+	}
+	payment, err := s.svc.Pay(accountID, amount, types.PaymentCategory(r.FormValue("category")))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, payment)
+}
+
+func (s *server) history(w http.ResponseWriter, r *http.Request) {
+	accountID, err := strconv.ParseInt(r.FormValue("account"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid account", http.StatusBadRequest)
+		return
+	}
+	payments, err := s.svc.ExportAccountHistory(accountID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, payments)
+}
+
+func parseAccountAmount(w http.ResponseWriter, r *http.Request) (int64, types.Money, bool) {
+	accountID, err := strconv.ParseInt(r.FormValue("account"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid account", http.StatusBadRequest)
+		return 0, 0, false
+	}
+	amount, err := strconv.ParseInt(r.FormValue("amount"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid amount", http.StatusBadRequest)
+		return 0, 0, false
+	}
+	return accountID, types.Money(amount), true
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch wallet.Code(err) {
+	case wallet.CodeInvalid:
+		status = http.StatusBadRequest
+	case wallet.CodeNotFound:
+		status = http.StatusNotFound
+	case wallet.CodeConflict:
+		status = http.StatusConflict
+	case wallet.CodeRejected:
+		status = http.StatusUnprocessableEntity
+	}
+	http.Error(w, err.Error(), status)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		log.Print(err)
+	}
+}
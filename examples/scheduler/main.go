@@ -0,0 +1,42 @@
+// Пример ежедневного платежа, проводимого планировщиком в фоне
+package main
+
+import (
+	"log"
+	"time"
+
+	"github.com/sidalsoft/wallet/pkg/wallet"
+)
+
+func main() {
+	svc := &wallet.Service{}
+	account, err := svc.RegisterAccount("+992928885522")
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = svc.Deposit(account.ID, 1_000_00)
+	if err != nil {
+		log.Fatal(err)
+	}
+	_, err = svc.SchedulePayment(account.ID, 10_00, "internet", "@every 1s")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	scheduler, err := wallet.NewScheduler(svc, 500*time.Millisecond)
+	if err != nil {
+		log.Fatal(err)
+	}
+	scheduler.OnError = func(err error) {
+		log.Print(err)
+	}
+	scheduler.Start()
+	time.Sleep(3 * time.Second)
+	scheduler.Stop()
+
+	history, err := svc.ExportAccountHistory(account.ID)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("scheduler made %d payments", len(history))
+}
@@ -2,4 +2,7 @@ module github.com/sidalsoft/wallet
 
 go 1.16
 
-require github.com/google/uuid v1.2.0
+require (
+	github.com/google/uuid v1.2.0
+	gopkg.in/yaml.v3 v3.0.1
+)
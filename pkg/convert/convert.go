@@ -0,0 +1,202 @@
+package convert
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/sidalsoft/wallet/pkg/types"
+	"github.com/sidalsoft/wallet/pkg/wallet"
+)
+
+var ErrInvalidAmount = errors.New("invalid amount")
+
+//Transaction представляет операцию из выгрузки стороннего кошелька.
+//Положительная сумма - поступление на счет, отрицательная - платеж
+type Transaction struct {
+	Phone    types.Phone
+	Amount   types.Money
+	Category types.PaymentCategory
+	Failed   bool
+}
+
+//CSVMapping задает номера колонок CSV-файла, начиная с 0. Category и Status
+//необязательны, отсутствующая колонка обозначается -1. Status считается
+//неуспешным, если совпадает с одним из FailedStatuses
+type CSVMapping struct {
+	Phone          int
+	Amount         int
+	Category       int
+	Status         int
+	Comma          rune
+	SkipHeader     bool
+	MinorUnits     bool
+	FailedStatuses []string
+}
+
+//JSONMapping задает имена полей объектов JSON-массива. Пустое имя означает отсутствие поля
+type JSONMapping struct {
+	Phone          string
+	Amount         string
+	Category       string
+	Status         string
+	MinorUnits     bool
+	FailedStatuses []string
+}
+
+func ReadCSV(r io.Reader, mapping CSVMapping) ([]Transaction, error) {
+	reader := csv.NewReader(r)
+	if mapping.Comma != 0 {
+		reader.Comma = mapping.Comma
+	}
+	reader.FieldsPerRecord = -1
+
+	var transactions []Transaction
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if line == 1 && mapping.SkipHeader {
+			continue
+		}
+		field := func(column int) string {
+			if column < 0 || column >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[column])
+		}
+		amount, err := parseAmount(field(mapping.Amount), mapping.MinorUnits)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		transactions = append(transactions, Transaction{
+			Phone:    types.Phone(field(mapping.Phone)),
+			Amount:   amount,
+			Category: types.PaymentCategory(field(mapping.Category)),
+			Failed:   contains(mapping.FailedStatuses, field(mapping.Status)),
+		})
+	}
+	return transactions, nil
+}
+
+func ReadJSON(r io.Reader, mapping JSONMapping) ([]Transaction, error) {
+	var records []map[string]interface{}
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	err := decoder.Decode(&records)
+	if err != nil {
+		return nil, err
+	}
+
+	var transactions []Transaction
+	for i, record := range records {
+		field := func(name string) string {
+			if name == "" || record[name] == nil {
+				return ""
+			}
+			return strings.TrimSpace(fmt.Sprint(record[name]))
+		}
+		amount, err := parseAmount(field(mapping.Amount), mapping.MinorUnits)
+		if err != nil {
+			return nil, fmt.Errorf("transaction %d: %w", i, err)
+		}
+		transactions = append(transactions, Transaction{
+			Phone:    types.Phone(field(mapping.Phone)),
+			Amount:   amount,
+			Category: types.PaymentCategory(field(mapping.Category)),
+			Failed:   contains(mapping.FailedStatuses, field(mapping.Status)),
+		})
+	}
+	return transactions, nil
+}
+
+//Apply переносит операции в svc по порядку: регистрирует недостающие счета,
+//поступления проводит как пополнения, платежи - как подтвержденные
+//или отклоненные платежи. Неуспешные и нулевые поступления пропускаются
+func Apply(svc *wallet.Service, transactions []Transaction) error {
+	for i, transaction := range transactions {
+		account, err := svc.FindAccountByPhone(transaction.Phone)
+		if errors.Is(err, wallet.ErrAccountNotFound) {
+			account, err = svc.RegisterAccount(transaction.Phone)
+		}
+		if err != nil {
+			return fmt.Errorf("transaction %d: %w", i, err)
+		}
+
+		if transaction.Amount >= 0 {
+			if transaction.Failed || transaction.Amount == 0 {
+				continue
+			}
+			err = svc.Deposit(account.ID, transaction.Amount)
+			if err != nil {
+				return fmt.Errorf("transaction %d: %w", i, err)
+			}
+			continue
+		}
+
+		payment, err := svc.Pay(account.ID, -transaction.Amount, transaction.Category)
+		if err != nil {
+			return fmt.Errorf("transaction %d: %w", i, err)
+		}
+		if transaction.Failed {
+			err = svc.Reject(payment.ID)
+		} else {
+			err = svc.Confirm(payment.ID)
+		}
+		if err != nil {
+			return fmt.Errorf("transaction %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// parseAmount разбирает сумму в минимальных единицах или вида "-105.50"
+func parseAmount(s string, minorUnits bool) (types.Money, error) {
+	if minorUnits {
+		amount, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return 0, ErrInvalidAmount
+		}
+		return types.Money(amount), nil
+	}
+
+	sign := int64(1)
+	if strings.HasPrefix(s, "-") {
+		sign = -1
+		s = s[1:]
+	}
+	whole, frac := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		whole, frac = s[:i], s[i+1:]
+	}
+	if whole == "" || len(frac) > 2 || strings.Trim(whole+frac, "0123456789") != "" {
+		return 0, ErrInvalidAmount
+	}
+	frac += strings.Repeat("0", 2-len(frac))
+	units, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil {
+		return 0, ErrInvalidAmount
+	}
+	cents, err := strconv.ParseInt(frac, 10, 64)
+	if err != nil {
+		return 0, ErrInvalidAmount
+	}
+	return types.Money(sign * (units*100 + cents)), nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,83 @@
+package convert
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sidalsoft/wallet/pkg/types"
+	"github.com/sidalsoft/wallet/pkg/wallet"
+)
+
+const testCSV = `date;status;amount;phone;description
+2021-01-01;done;500.00;+992928885522;top up
+2021-01-02;done;-120.5;+992928885522;taxi
+2021-01-03;declined;-50;+992928885522;cinema
+`
+
+func TestReadCSV_success(t *testing.T) {
+	transactions, err := ReadCSV(strings.NewReader(testCSV), CSVMapping{
+		Phone:          3,
+		Amount:         2,
+		Category:       4,
+		Status:         1,
+		Comma:          ';',
+		SkipHeader:     true,
+		FailedStatuses: []string{"declined"},
+	})
+	if err != nil {
+		t.Errorf("ReadCSV(): error = %v", err)
+		return
+	}
+	if len(transactions) != 3 || transactions[1].Amount != -120_50 || transactions[1].Category != "taxi" || !transactions[2].Failed {
+		t.Errorf("ReadCSV(): wrong transactions = %v", transactions)
+		return
+	}
+
+	svc := &wallet.Service{}
+	err = Apply(svc, transactions)
+	if err != nil {
+		t.Errorf("Apply(): error = %v", err)
+		return
+	}
+	account, err := svc.FindAccountByPhone("+992928885522")
+	if err != nil || account.Balance != 379_50 {
+		t.Errorf("Apply(): wrong account = %v, error = %v", account, err)
+		return
+	}
+	history, _ := svc.ExportAccountHistory(account.ID)
+	if len(history) != 2 || history[0].Status != types.PaymentStatusOk || history[1].Status != types.PaymentStatusFail {
+		t.Errorf("Apply(): wrong history = %v", history)
+		return
+	}
+}
+
+func TestReadCSV_fail(t *testing.T) {
+	_, err := ReadCSV(strings.NewReader("+992928885522,1.234\n"), CSVMapping{Phone: 0, Amount: 1, Category: -1, Status: -1})
+	if err == nil || !strings.Contains(err.Error(), "line 1") {
+		t.Errorf("ReadCSV(): must return error with line, returned = %v", err)
+		return
+	}
+}
+
+func TestReadJSON_success(t *testing.T) {
+	transactions, err := ReadJSON(strings.NewReader(`[
+		{"msisdn": "+992928885522", "sum": 10000, "type": "deposit"},
+		{"msisdn": "+992928885522", "sum": -2500, "type": "food", "state": "ok"}
+	]`), JSONMapping{Phone: "msisdn", Amount: "sum", Category: "type", Status: "state", MinorUnits: true})
+	if err != nil {
+		t.Errorf("ReadJSON(): error = %v", err)
+		return
+	}
+	if len(transactions) != 2 || transactions[0].Amount != 100_00 || transactions[1].Category != "food" {
+		t.Errorf("ReadJSON(): wrong transactions = %v", transactions)
+		return
+	}
+}
+
+func TestReadJSON_fail(t *testing.T) {
+	_, err := ReadJSON(strings.NewReader(`[{"sum": "ten"}]`), JSONMapping{Amount: "sum"})
+	if err == nil {
+		t.Error("ReadJSON(): must return error, returned nil")
+		return
+	}
+}
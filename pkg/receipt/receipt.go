@@ -0,0 +1,101 @@
+package receipt
+
+import (
+	"html/template"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/sidalsoft/wallet/pkg/types"
+)
+
+//Receipt представляет данные квитанции об одном платеже.
+//Phone маскирован, Amount записан десятичной строкой в валюте платежа
+type Receipt struct {
+	Number   string
+	Phone    string
+	Amount   string
+	Fee      string
+	Currency types.Currency
+	Category types.PaymentCategory
+	Status   types.PaymentStatus
+	Created  time.Time
+}
+
+//New собирает квитанцию о платеже payment со счета account
+func New(account types.Account, payment types.Payment) Receipt {
+	return Receipt{
+		Number:   Number(payment),
+		Phone:    MaskPhone(account.Phone),
+		Amount:   payment.Amount.Format(payment.Currency),
+		Fee:      payment.Fee.Format(payment.Currency),
+		Currency: payment.Currency,
+		Category: payment.Category,
+		Status:   payment.Status,
+		Created:  payment.Created,
+	}
+}
+
+//Number возвращает номер квитанции: дату платежа и начало его ID,
+//например "20240101-6C1F2B4E"
+func Number(payment types.Payment) string {
+	id := strings.ReplaceAll(payment.ID, "-", "")
+	if len(id) > 8 {
+		id = id[:8]
+	}
+	return payment.Created.UTC().Format("20060102") + "-" + strings.ToUpper(id)
+}
+
+//MaskPhone оставляет от номера код страны и последние 4 цифры: "+992*****5522"
+func MaskPhone(phone types.Phone) string {
+	s := string(phone)
+	if len(s) <= 8 {
+		return strings.Repeat("*", len(s))
+	}
+	return s[:4] + strings.Repeat("*", len(s)-8) + s[len(s)-4:]
+}
+
+//DefaultTemplate - шаблон HTML-квитанции по умолчанию
+const DefaultTemplate = `<!DOCTYPE html>
+<html lang="ru">
+<head><meta charset="utf-8"><title>Квитанция {{.Number}}</title></head>
+<body>
+<h1>Квитанция № {{.Number}}</h1>
+<table>
+<tr><th>Дата</th><td>{{.Created.Format "02.01.2006 15:04"}}</td></tr>
+<tr><th>Счет</th><td>{{.Phone}}</td></tr>
+<tr><th>Сумма</th><td>{{.Amount}} {{.Currency}}</td></tr>
+<tr><th>Комиссия</th><td>{{.Fee}} {{.Currency}}</td></tr>
+<tr><th>Категория</th><td>{{.Category}}</td></tr>
+<tr><th>Статус</th><td>{{.Status}}</td></tr>
+</table>
+</body>
+</html>
+`
+
+//Renderer записывает квитанции по HTML-шаблону. Значения экранируются
+//html/template, поэтому категория платежа не может внедрить разметку
+type Renderer struct {
+	tmpl *template.Template
+}
+
+//NewRenderer разбирает шаблон text с полями Receipt
+func NewRenderer(text string) (*Renderer, error) {
+	tmpl, err := template.New("receipt").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	return &Renderer{tmpl: tmpl}, nil
+}
+
+//RenderHTML записывает квитанцию receipt в w
+func (r *Renderer) RenderHTML(w io.Writer, receipt Receipt) error {
+	return r.tmpl.Execute(w, receipt)
+}
+
+var defaultRenderer = template.Must(template.New("receipt").Parse(DefaultTemplate))
+
+//RenderHTML записывает квитанцию receipt в w по DefaultTemplate
+func RenderHTML(w io.Writer, receipt Receipt) error {
+	return defaultRenderer.Execute(w, receipt)
+}
@@ -0,0 +1,68 @@
+package receipt
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sidalsoft/wallet/pkg/types"
+)
+
+func TestRenderHTML(t *testing.T) {
+	account := types.Account{ID: 1, Phone: "+992928885522", Currency: "TJS"}
+	payment := types.Payment{
+		ID:       "6c1f2b4e-3d5a-4f8e-9b7c-1a2b3c4d5e6f",
+		Amount:   105_50,
+		Fee:      1_05,
+		Currency: "TJS",
+		Category: "<b>auto</b>",
+		Status:   types.PaymentStatusOk,
+		Created:  time.Date(2024, 1, 2, 15, 4, 0, 0, time.UTC),
+	}
+	receipt := New(account, payment)
+	if receipt.Number != "20240102-6C1F2B4E" || receipt.Phone != "+992*****5522" {
+		t.Errorf("New() = %+v", receipt)
+		return
+	}
+
+	buf := &bytes.Buffer{}
+	err := RenderHTML(buf, receipt)
+	if err != nil {
+		t.Errorf("RenderHTML(): error = %v", err)
+		return
+	}
+	html := buf.String()
+	for _, want := range []string{"20240102-6C1F2B4E", "&#43;992*****5522", "105.50 TJS", "1.05 TJS", "&lt;b&gt;auto&lt;/b&gt;", "02.01.2024 15:04", "OK"} {
+		if !strings.Contains(html, want) {
+			t.Errorf("RenderHTML(): %q not found in %s", want, html)
+			return
+		}
+	}
+	if strings.Contains(html, "928885522") {
+		t.Errorf("RenderHTML(): phone must be masked")
+	}
+}
+
+func TestNewRenderer(t *testing.T) {
+	r, err := NewRenderer("{{.Number}}: {{.Amount}}")
+	if err != nil {
+		t.Errorf("NewRenderer(): error = %v", err)
+		return
+	}
+	buf := &bytes.Buffer{}
+	err = r.RenderHTML(buf, Receipt{Number: "1", Amount: "2.00"})
+	if err != nil || buf.String() != "1: 2.00" {
+		t.Errorf("RenderHTML() = %q, error = %v", buf.String(), err)
+		return
+	}
+	_, err = NewRenderer("{{.Number")
+	if err == nil {
+		t.Errorf("NewRenderer(): must fail on broken template")
+	}
+	r, _ = NewRenderer("{{.Owner}}")
+	err = r.RenderHTML(buf, Receipt{})
+	if err == nil {
+		t.Errorf("RenderHTML(): must fail on unknown field")
+	}
+}
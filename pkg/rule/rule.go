@@ -0,0 +1,471 @@
+package rule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/sidalsoft/wallet/pkg/types"
+)
+
+//MaxLength и MaxDepth ограничивают размер правила, чтобы проверка платежа оставалась дешевой
+const (
+	MaxLength = 1024
+	MaxDepth  = 32
+)
+
+//Env - значения переменных правила для одного платежа:
+//amount, balance, accountID и hour (час создания платежа, 0-23) - целые,
+//category и currency - строки
+type Env struct {
+	Amount    types.Money
+	Balance   types.Money
+	AccountID int64
+	Hour      int
+	Category  types.PaymentCategory
+	Currency  types.Currency
+}
+
+//Rule - скомпилированное логическое выражение вида
+//"amount > 1000_00 && category == 'transfer'". Правило не вызывает функций
+//и не обращается к чему-либо, кроме Env, поэтому безопасно для пользовательских настроек
+type Rule struct {
+	source string
+	match  func(env *Env) bool
+}
+
+//SyntaxError возвращается Compile для неверного правила. Pos - смещение в байтах
+type SyntaxError struct {
+	Pos int
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("rule: position %d: %s", e.Pos, e.Msg)
+}
+
+//Compile разбирает правило и проверяет типы. Сравнения допустимы между значениями
+//одного типа, арифметика - только для целых. Деление на ноль дает 0
+func Compile(source string) (*Rule, error) {
+	if len(source) > MaxLength {
+		return nil, &SyntaxError{Pos: MaxLength, Msg: "rule too long"}
+	}
+	p := &parser{lexer: lexer{src: source}}
+	p.next()
+	n, err := p.parseOr(0)
+	if err != nil {
+		return nil, err
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	if p.tok.kind != tokenEOF {
+		return nil, p.errorf("unexpected %q", p.tok.text)
+	}
+	if n.typ != typeBool {
+		return nil, &SyntaxError{Pos: 0, Msg: "rule must be a boolean expression, got " + n.typ.String()}
+	}
+	return &Rule{source: source, match: n.b}, nil
+}
+
+//MustCompile как Compile, но паникует при ошибке. Для правил, заданных в коде
+func MustCompile(source string) *Rule {
+	r, err := Compile(source)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+//Match вычисляет правило для env
+func (r *Rule) Match(env *Env) bool {
+	return r.match(env)
+}
+
+func (r *Rule) String() string {
+	return r.source
+}
+
+type valueType int
+
+const (
+	typeInt valueType = iota
+	typeString
+	typeBool
+)
+
+func (t valueType) String() string {
+	switch t {
+	case typeInt:
+		return "int"
+	case typeString:
+		return "string"
+	}
+	return "bool"
+}
+
+//node - скомпилированное подвыражение. Заполнена функция, соответствующая typ
+type node struct {
+	typ valueType
+	i   func(env *Env) int64
+	s   func(env *Env) string
+	b   func(env *Env) bool
+}
+
+var variables = map[string]node{
+	"amount":    {typ: typeInt, i: func(env *Env) int64 { return int64(env.Amount) }},
+	"balance":   {typ: typeInt, i: func(env *Env) int64 { return int64(env.Balance) }},
+	"accountID": {typ: typeInt, i: func(env *Env) int64 { return env.AccountID }},
+	"hour":      {typ: typeInt, i: func(env *Env) int64 { return int64(env.Hour) }},
+	"category":  {typ: typeString, s: func(env *Env) string { return string(env.Category) }},
+	"currency":  {typ: typeString, s: func(env *Env) string { return string(env.Currency) }},
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenInt
+	tokenString
+	tokenIdent
+	tokenOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+type lexer struct {
+	src string
+	pos int
+}
+
+var operators = []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!", "+", "-", "*", "/", "%", "(", ")"}
+
+func (l *lexer) next() (token, error) {
+	for l.pos < len(l.src) && strings.ContainsRune(" \t\r\n", rune(l.src[l.pos])) {
+		l.pos++
+	}
+	start := l.pos
+	if l.pos >= len(l.src) {
+		return token{kind: tokenEOF, pos: start}, nil
+	}
+	c := l.src[l.pos]
+	switch {
+	case isDigit(c):
+		for l.pos < len(l.src) && (isDigit(l.src[l.pos]) || l.src[l.pos] == '_') {
+			l.pos++
+		}
+		return token{kind: tokenInt, text: l.src[start:l.pos], pos: start}, nil
+	case isLetter(c):
+		for l.pos < len(l.src) && (isLetter(l.src[l.pos]) || isDigit(l.src[l.pos])) {
+			l.pos++
+		}
+		return token{kind: tokenIdent, text: l.src[start:l.pos], pos: start}, nil
+	case c == '\'' || c == '"':
+		end := strings.IndexByte(l.src[l.pos+1:], c)
+		if end < 0 {
+			return token{}, &SyntaxError{Pos: start, Msg: "unterminated string"}
+		}
+		l.pos += end + 2
+		return token{kind: tokenString, text: l.src[start+1 : l.pos-1], pos: start}, nil
+	}
+	for _, op := range operators {
+		if strings.HasPrefix(l.src[l.pos:], op) {
+			l.pos += len(op)
+			return token{kind: tokenOp, text: op, pos: start}, nil
+		}
+	}
+	return token{}, &SyntaxError{Pos: start, Msg: fmt.Sprintf("unexpected character %q", c)}
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_'
+}
+
+type parser struct {
+	lexer lexer
+	tok   token
+	err   error
+}
+
+func (p *parser) next() {
+	if p.err != nil {
+		return
+	}
+	p.tok, p.err = p.lexer.next()
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	if p.err != nil {
+		return p.err
+	}
+	return &SyntaxError{Pos: p.tok.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) isOp(ops ...string) bool {
+	if p.err != nil || p.tok.kind != tokenOp {
+		return false
+	}
+	for _, op := range ops {
+		if p.tok.text == op {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) parseOr(depth int) (node, error) {
+	left, err := p.parseAnd(depth)
+	for err == nil && p.isOp("||") {
+		pos := p.tok.pos
+		p.next()
+		var right node
+		right, err = p.parseAnd(depth)
+		if err == nil {
+			left, err = logical("||", pos, left, right)
+		}
+	}
+	return left, err
+}
+
+func (p *parser) parseAnd(depth int) (node, error) {
+	left, err := p.parseNot(depth)
+	for err == nil && p.isOp("&&") {
+		pos := p.tok.pos
+		p.next()
+		var right node
+		right, err = p.parseNot(depth)
+		if err == nil {
+			left, err = logical("&&", pos, left, right)
+		}
+	}
+	return left, err
+}
+
+func (p *parser) parseNot(depth int) (node, error) {
+	if !p.isOp("!") {
+		return p.parseComparison(depth)
+	}
+	pos := p.tok.pos
+	p.next()
+	operand, err := p.parseNot(depth + 1)
+	if err != nil {
+		return node{}, err
+	}
+	if operand.typ != typeBool {
+		return node{}, &SyntaxError{Pos: pos, Msg: "operator ! requires bool, got " + operand.typ.String()}
+	}
+	b := operand.b
+	return node{typ: typeBool, b: func(env *Env) bool { return !b(env) }}, nil
+}
+
+func (p *parser) parseComparison(depth int) (node, error) {
+	left, err := p.parseSum(depth)
+	if err != nil || !p.isOp("==", "!=", "<", "<=", ">", ">=") {
+		return left, err
+	}
+	op, pos := p.tok.text, p.tok.pos
+	p.next()
+	right, err := p.parseSum(depth)
+	if err != nil {
+		return node{}, err
+	}
+	return compare(op, pos, left, right)
+}
+
+func (p *parser) parseSum(depth int) (node, error) {
+	left, err := p.parseTerm(depth)
+	for err == nil && p.isOp("+", "-") {
+		op, pos := p.tok.text, p.tok.pos
+		p.next()
+		var right node
+		right, err = p.parseTerm(depth)
+		if err == nil {
+			left, err = arithmetic(op, pos, left, right)
+		}
+	}
+	return left, err
+}
+
+func (p *parser) parseTerm(depth int) (node, error) {
+	left, err := p.parseUnary(depth)
+	for err == nil && p.isOp("*", "/", "%") {
+		op, pos := p.tok.text, p.tok.pos
+		p.next()
+		var right node
+		right, err = p.parseUnary(depth)
+		if err == nil {
+			left, err = arithmetic(op, pos, left, right)
+		}
+	}
+	return left, err
+}
+
+func (p *parser) parseUnary(depth int) (node, error) {
+	if !p.isOp("-") {
+		return p.parsePrimary(depth)
+	}
+	pos := p.tok.pos
+	p.next()
+	operand, err := p.parseUnary(depth + 1)
+	if err != nil {
+		return node{}, err
+	}
+	if operand.typ != typeInt {
+		return node{}, &SyntaxError{Pos: pos, Msg: "operator - requires int, got " + operand.typ.String()}
+	}
+	i := operand.i
+	return node{typ: typeInt, i: func(env *Env) int64 { return -i(env) }}, nil
+}
+
+func (p *parser) parsePrimary(depth int) (node, error) {
+	if depth > MaxDepth {
+		return node{}, p.errorf("rule nested too deeply")
+	}
+	tok := p.tok
+	if p.err != nil {
+		return node{}, p.err
+	}
+	switch tok.kind {
+	case tokenInt:
+		p.next()
+		value, err := strconv.ParseInt(strings.ReplaceAll(tok.text, "_", ""), 10, 64)
+		if err != nil {
+			return node{}, &SyntaxError{Pos: tok.pos, Msg: "invalid number " + tok.text}
+		}
+		return node{typ: typeInt, i: func(*Env) int64 { return value }}, nil
+	case tokenString:
+		p.next()
+		value := tok.text
+		return node{typ: typeString, s: func(*Env) string { return value }}, nil
+	case tokenIdent:
+		p.next()
+		switch tok.text {
+		case "true", "false":
+			value := tok.text == "true"
+			return node{typ: typeBool, b: func(*Env) bool { return value }}, nil
+		}
+		variable, ok := variables[tok.text]
+		if !ok {
+			return node{}, &SyntaxError{Pos: tok.pos, Msg: "unknown variable " + tok.text}
+		}
+		return variable, nil
+	}
+	if p.isOp("(") {
+		p.next()
+		inner, err := p.parseOr(depth + 1)
+		if err != nil {
+			return node{}, err
+		}
+		if !p.isOp(")") {
+			return node{}, p.errorf("expected )")
+		}
+		p.next()
+		return inner, nil
+	}
+	if tok.kind == tokenEOF {
+		return node{}, p.errorf("unexpected end of rule")
+	}
+	return node{}, p.errorf("unexpected %q", tok.text)
+}
+
+func logical(op string, pos int, left, right node) (node, error) {
+	if left.typ != typeBool || right.typ != typeBool {
+		return node{}, &SyntaxError{Pos: pos, Msg: fmt.Sprintf("operator %s requires bool, got %s and %s", op, left.typ, right.typ)}
+	}
+	l, r := left.b, right.b
+	if op == "&&" {
+		return node{typ: typeBool, b: func(env *Env) bool { return l(env) && r(env) }}, nil
+	}
+	return node{typ: typeBool, b: func(env *Env) bool { return l(env) || r(env) }}, nil
+}
+
+func arithmetic(op string, pos int, left, right node) (node, error) {
+	if left.typ != typeInt || right.typ != typeInt {
+		return node{}, &SyntaxError{Pos: pos, Msg: fmt.Sprintf("operator %s requires int, got %s and %s", op, left.typ, right.typ)}
+	}
+	l, r := left.i, right.i
+	var f func(env *Env) int64
+	switch op {
+	case "+":
+		f = func(env *Env) int64 { return l(env) + r(env) }
+	case "-":
+		f = func(env *Env) int64 { return l(env) - r(env) }
+	case "*":
+		f = func(env *Env) int64 { return l(env) * r(env) }
+	case "/":
+		f = func(env *Env) int64 {
+			d := r(env)
+			if d == 0 {
+				return 0
+			}
+			return l(env) / d
+		}
+	case "%":
+		f = func(env *Env) int64 {
+			d := r(env)
+			if d == 0 {
+				return 0
+			}
+			return l(env) % d
+		}
+	}
+	return node{typ: typeInt, i: f}, nil
+}
+
+func compare(op string, pos int, left, right node) (node, error) {
+	if left.typ != right.typ {
+		return node{}, &SyntaxError{Pos: pos, Msg: fmt.Sprintf("can't compare %s and %s", left.typ, right.typ)}
+	}
+	switch left.typ {
+	case typeInt:
+		l, r := left.i, right.i
+		return node{typ: typeBool, b: compareFunc(op, func(env *Env) int { return compareInts(l(env), r(env)) })}, nil
+	case typeString:
+		l, r := left.s, right.s
+		return node{typ: typeBool, b: compareFunc(op, func(env *Env) int { return strings.Compare(l(env), r(env)) })}, nil
+	}
+	if op != "==" && op != "!=" {
+		return node{}, &SyntaxError{Pos: pos, Msg: "operator " + op + " requires int or string, got bool"}
+	}
+	l, r := left.b, right.b
+	return node{typ: typeBool, b: compareFunc(op, func(env *Env) int {
+		if l(env) == r(env) {
+			return 0
+		}
+		return 1
+	})}, nil
+}
+
+func compareInts(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func compareFunc(op string, cmp func(env *Env) int) func(env *Env) bool {
+	switch op {
+	case "==":
+		return func(env *Env) bool { return cmp(env) == 0 }
+	case "!=":
+		return func(env *Env) bool { return cmp(env) != 0 }
+	case "<":
+		return func(env *Env) bool { return cmp(env) < 0 }
+	case "<=":
+		return func(env *Env) bool { return cmp(env) <= 0 }
+	case ">":
+		return func(env *Env) bool { return cmp(env) > 0 }
+	}
+	return func(env *Env) bool { return cmp(env) >= 0 }
+}
@@ -0,0 +1,89 @@
+package rule
+
+import (
+	"strings"
+	"testing"
+)
+
+var testEnv = Env{
+	Amount:    1500_00,
+	Balance:   10_000_00,
+	AccountID: 7,
+	Hour:      23,
+	Category:  "transfer",
+	Currency:  "TJS",
+}
+
+func TestRule_Match(t *testing.T) {
+	tests := []struct {
+		source string
+		want   bool
+	}{
+		{"amount > 1000_00 && category == 'transfer'", true},
+		{"amount > 1000_00 && category == \"auto\"", false},
+		{"category != 'transfer' || currency == 'TJS'", true},
+		{"!(hour >= 6 && hour < 22)", true},
+		{"amount * 100 / balance >= 15", true},
+		{"amount - -100 == 150100", true},
+		{"amount / 0 == 0 && amount % 0 == 0", true},
+		{"(accountID % 2 == 1) == true", true},
+		{"category < 'z' && 'a' <= category", true},
+		{"false", false},
+	}
+	for _, tt := range tests {
+		r, err := Compile(tt.source)
+		if err != nil {
+			t.Errorf("Compile(%q): error = %v", tt.source, err)
+			continue
+		}
+		if got := r.Match(&testEnv); got != tt.want {
+			t.Errorf("Match(%q) = %v, want %v", tt.source, got, tt.want)
+		}
+	}
+}
+
+func TestCompile_fail(t *testing.T) {
+	tests := []struct {
+		source string
+		pos    int
+	}{
+		{"amount", 0},
+		{"amount > 'big'", 7},
+		{"category + 1 > 0", 9},
+		{"limit > 0", 0},
+		{"amount > ", 9},
+		{"(amount > 0", 11},
+		{"category == 'auto", 12},
+		{"amount > 0 &", 11},
+		{"!amount", 0},
+		{"true < false", 5},
+		{"amount > 0 )", 11},
+		{strings.Repeat("(", MaxDepth+2) + "true" + strings.Repeat(")", MaxDepth+2), MaxDepth + 1},
+		{"amount > " + strings.Repeat("1", MaxLength), MaxLength},
+	}
+	for _, tt := range tests {
+		_, err := Compile(tt.source)
+		syntaxErr, ok := err.(*SyntaxError)
+		if !ok {
+			t.Errorf("Compile(%q): error = %v, want *SyntaxError", tt.source, err)
+			continue
+		}
+		if syntaxErr.Pos != tt.pos {
+			t.Errorf("Compile(%q): error = %v, want position %v", tt.source, err, tt.pos)
+		}
+	}
+}
+
+func BenchmarkRule_Match(b *testing.B) {
+	r := MustCompile("amount > 1000_00 && category == 'transfer' || !(hour >= 6 && hour < 22) && amount * 100 / balance >= 15")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		r.Match(&testEnv)
+	}
+}
+
+func BenchmarkCompile(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		_, _ = Compile("amount > 1000_00 && category == 'transfer' || !(hour >= 6 && hour < 22)")
+	}
+}
@@ -0,0 +1,110 @@
+package scenario
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/sidalsoft/wallet/pkg/types"
+	"github.com/sidalsoft/wallet/pkg/wallet"
+	"gopkg.in/yaml.v3"
+)
+
+//Scenario описывает заранее известное состояние кошелька
+type Scenario struct {
+	Accounts []Account `yaml:"accounts"`
+}
+
+//Account описывает счет сценария. Balance, если задан, - ожидаемый баланс после загрузки
+type Account struct {
+	Phone    types.Phone    `yaml:"phone"`
+	Currency types.Currency `yaml:"currency"`
+	Deposits []types.Money  `yaml:"deposits"`
+	Payments []Payment      `yaml:"payments"`
+	Balance  *types.Money   `yaml:"balance"`
+}
+
+//Payment описывает платеж сценария
+type Payment struct {
+	Amount   types.Money           `yaml:"amount"`
+	Category types.PaymentCategory `yaml:"category"`
+}
+
+//MismatchError перечисляет расхождения состояния с ожиданиями сценария
+type MismatchError struct {
+	Mismatches []string
+}
+
+func (e *MismatchError) Error() string {
+	return "scenario mismatch: " + strings.Join(e.Mismatches, "; ")
+}
+
+func Parse(r io.Reader) (*Scenario, error) {
+	sc := &Scenario{}
+	decoder := yaml.NewDecoder(r)
+	decoder.KnownFields(true)
+	err := decoder.Decode(sc)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	return sc, nil
+}
+
+func LoadFile(path string) (*Scenario, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return Parse(file)
+}
+
+//Load регистрирует счета, пополняет их и проводит платежи сценария
+func (sc *Scenario) Load(svc *wallet.Service) error {
+	for _, acc := range sc.Accounts {
+		currency := acc.Currency
+		if currency == "" {
+			currency = types.DefaultCurrency
+		}
+		account, err := svc.RegisterAccountWithCurrency(acc.Phone, currency)
+		if err != nil {
+			return fmt.Errorf("account %s: %w", acc.Phone, err)
+		}
+		for _, amount := range acc.Deposits {
+			err = svc.Deposit(account.ID, amount)
+			if err != nil {
+				return fmt.Errorf("account %s: deposit %d: %w", acc.Phone, amount, err)
+			}
+		}
+		for _, payment := range acc.Payments {
+			_, err = svc.Pay(account.ID, payment.Amount, payment.Category)
+			if err != nil {
+				return fmt.Errorf("account %s: pay %d: %w", acc.Phone, payment.Amount, err)
+			}
+		}
+	}
+	return nil
+}
+
+//Verify сравнивает балансы счетов svc с ожидаемыми и возвращает *MismatchError
+func (sc *Scenario) Verify(svc *wallet.Service) error {
+	var mismatches []string
+	for _, acc := range sc.Accounts {
+		if acc.Balance == nil {
+			continue
+		}
+		account, err := svc.FindAccountByPhone(acc.Phone)
+		if err != nil {
+			mismatches = append(mismatches, fmt.Sprintf("account %s: %v", acc.Phone, err))
+			continue
+		}
+		if account.Balance != *acc.Balance {
+			mismatches = append(mismatches, fmt.Sprintf("account %s: balance %d, want %d", acc.Phone, account.Balance, *acc.Balance))
+		}
+	}
+	if len(mismatches) > 0 {
+		return &MismatchError{Mismatches: mismatches}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package scenario
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sidalsoft/wallet/pkg/wallet"
+)
+
+func TestScenario_Load_success(t *testing.T) {
+	sc, err := LoadFile("testdata/basic.yaml")
+	if err != nil {
+		t.Errorf("LoadFile(): error = %v", err)
+		return
+	}
+	svc := &wallet.Service{}
+	err = sc.Load(svc)
+	if err != nil {
+		t.Errorf("Load(): error = %v", err)
+		return
+	}
+	err = sc.Verify(svc)
+	if err != nil {
+		t.Errorf("Verify(): error = %v", err)
+		return
+	}
+	account, err := svc.FindAccountByPhone("+992928000000")
+	if err != nil || account.Currency != "USD" {
+		t.Errorf("Load(): wrong account = %v, error = %v", account, err)
+		return
+	}
+}
+
+func TestScenario_Verify_fail(t *testing.T) {
+	sc, err := Parse(strings.NewReader(`
+accounts:
+  - phone: "+992928885522"
+    deposits: [1000]
+    balance: 500
+`))
+	if err != nil {
+		t.Errorf("Parse(): error = %v", err)
+		return
+	}
+	svc := &wallet.Service{}
+	err = sc.Load(svc)
+	if err != nil {
+		t.Errorf("Load(): error = %v", err)
+		return
+	}
+	err = sc.Verify(svc)
+	mismatch, ok := err.(*MismatchError)
+	if !ok || len(mismatch.Mismatches) != 1 {
+		t.Errorf("Verify(): must return one mismatch, returned = %v", err)
+		return
+	}
+}
+
+func TestScenario_Parse_fail(t *testing.T) {
+	_, err := Parse(strings.NewReader("accounts:\n  - phon: 1\n"))
+	if err == nil {
+		t.Error("Parse(): must return error for unknown field, returned nil")
+		return
+	}
+}
@@ -0,0 +1,42 @@
+package types
+
+import "strings"
+
+//CategorySeparator разделяет уровни иерархической категории: "transport/taxi" -
+//подкатегория taxi категории transport
+const CategorySeparator = "/"
+
+//Parent возвращает родительскую категорию, для категории верхнего уровня - пустую
+func (c PaymentCategory) Parent() PaymentCategory {
+	i := strings.LastIndex(string(c), CategorySeparator)
+	if i < 0 {
+		return ""
+	}
+	return c[:i]
+}
+
+//Depth возвращает число уровней категории, для пустой - 0
+func (c PaymentCategory) Depth() int {
+	if c == "" {
+		return 0
+	}
+	return strings.Count(string(c), CategorySeparator) + 1
+}
+
+//Truncate возвращает предка категории с depth уровнями.
+//Если depth не меньше числа уровней или не положителен, возвращается сама категория
+func (c PaymentCategory) Truncate(depth int) PaymentCategory {
+	if depth <= 0 {
+		return c
+	}
+	levels := strings.SplitN(string(c), CategorySeparator, depth+1)
+	if len(levels) <= depth {
+		return c
+	}
+	return PaymentCategory(strings.Join(levels[:depth], CategorySeparator))
+}
+
+//Within сообщает, совпадает ли категория с ancestor или вложена в нее
+func (c PaymentCategory) Within(ancestor PaymentCategory) bool {
+	return c == ancestor || strings.HasPrefix(string(c), string(ancestor)+CategorySeparator)
+}
@@ -0,0 +1,31 @@
+package types
+
+import "testing"
+
+func TestPaymentCategory_hierarchy(t *testing.T) {
+	tests := []struct {
+		category  PaymentCategory
+		parent    PaymentCategory
+		depth     int
+		truncated PaymentCategory
+	}{
+		{"transport/taxi/night", "transport/taxi", 3, "transport"},
+		{"transport/fuel", "transport", 2, "transport"},
+		{"food", "", 1, "food"},
+		{"", "", 0, ""},
+	}
+	for _, tt := range tests {
+		if got := tt.category.Parent(); got != tt.parent {
+			t.Errorf("Parent(%q) = %q, want %q", tt.category, got, tt.parent)
+		}
+		if got := tt.category.Depth(); got != tt.depth {
+			t.Errorf("Depth(%q) = %v, want %v", tt.category, got, tt.depth)
+		}
+		if got := tt.category.Truncate(1); got != tt.truncated {
+			t.Errorf("Truncate(%q, 1) = %q, want %q", tt.category, got, tt.truncated)
+		}
+	}
+	if !PaymentCategory("transport/taxi").Within("transport") || PaymentCategory("transportation").Within("transport") {
+		t.Errorf("Within(): wrong ancestor check")
+	}
+}
@@ -0,0 +1,57 @@
+package types
+
+import (
+	"errors"
+	"sync"
+)
+
+//maxDecimals - наибольшее число знаков минимальной единицы в ISO 4217
+const maxDecimals = 4
+
+//ErrInvalidDecimals возвращается RegisterCurrency для числа знаков вне 0..4
+var ErrInvalidDecimals = errors.New("invalid currency decimals")
+
+//currencies - реестр кодов валют ISO 4217 и числа знаков их минимальной единицы
+var currencies = struct {
+	sync.RWMutex
+	decimals map[Currency]int
+}{decimals: map[Currency]int{
+	"AED": 2, "AMD": 2, "AUD": 2, "AZN": 2, "BGN": 2, "BHD": 3, "BRL": 2, "BYN": 2,
+	"CAD": 2, "CHF": 2, "CLP": 0, "CNY": 2, "CZK": 2, "DKK": 2, "EUR": 2, "GBP": 2,
+	"GEL": 2, "HKD": 2, "HUF": 2, "IDR": 2, "ILS": 2, "INR": 2, "IQD": 3, "IRR": 2,
+	"ISK": 0, "JOD": 3, "JPY": 0, "KGS": 2, "KRW": 0, "KWD": 3, "KZT": 2, "LYD": 3,
+	"MDL": 2, "MXN": 2, "NOK": 2, "NZD": 2, "OMR": 3, "PKR": 2, "PLN": 2, "RON": 2,
+	"RUB": 2, "SAR": 2, "SEK": 2, "SGD": 2, "THB": 2, "TJS": 2, "TMT": 2, "TND": 3,
+	"TRY": 2, "UAH": 2, "USD": 2, "UZS": 2, "VND": 0, "ZAR": 2,
+}}
+
+//RegisterCurrency добавляет в реестр валюту или меняет число знаков ее минимальной единицы.
+//Число знаков должно быть от 0 до 4, иначе возвращается ErrInvalidDecimals
+func RegisterCurrency(c Currency, decimals int) error {
+	if decimals < 0 || decimals > maxDecimals {
+		return ErrInvalidDecimals
+	}
+	currencies.Lock()
+	defer currencies.Unlock()
+	currencies.decimals[c] = decimals
+	return nil
+}
+
+//Known сообщает, есть ли валюта в реестре
+func (c Currency) Known() bool {
+	currencies.RLock()
+	defer currencies.RUnlock()
+	_, ok := currencies.decimals[c]
+	return ok
+}
+
+//Decimals возвращает число знаков после запятой в суммах валюты.
+//Для валют не из реестра - 2
+func (c Currency) Decimals() int {
+	currencies.RLock()
+	defer currencies.RUnlock()
+	if decimals, ok := currencies.decimals[c]; ok {
+		return decimals
+	}
+	return 2
+}
@@ -0,0 +1,38 @@
+package types
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCurrency_Decimals(t *testing.T) {
+	tests := []struct {
+		currency Currency
+		known    bool
+		want     int
+	}{
+		{"TJS", true, 2},
+		{"USD", true, 2},
+		{"EUR", true, 2},
+		{"JPY", true, 0},
+		{"KWD", true, 3},
+		{"XYZ", false, 2},
+	}
+	for _, tt := range tests {
+		if got := tt.currency.Decimals(); got != tt.want || tt.currency.Known() != tt.known {
+			t.Errorf("Decimals(%v) = %v, known %v, want %v, known %v", tt.currency, got, tt.currency.Known(), tt.want, tt.known)
+		}
+	}
+
+	if err := RegisterCurrency("XTS", 4); err != nil {
+		t.Errorf("RegisterCurrency(XTS, 4) = %v", err)
+	}
+	for _, decimals := range []int{-1, 5, 100} {
+		if err := RegisterCurrency("XTS", decimals); !errors.Is(err, ErrInvalidDecimals) {
+			t.Errorf("RegisterCurrency(XTS, %v) = %v, want %v", decimals, err, ErrInvalidDecimals)
+		}
+	}
+	if got := Money(1_2345).Format("XTS"); got != "1.2345" {
+		t.Errorf("Format(XTS) = %v, want 1.2345", got)
+	}
+}
@@ -0,0 +1,88 @@
+package types
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+//ErrInvalidMoney возвращается Parse для строки, не являющейся суммой в валюте
+var ErrInvalidMoney = errors.New("invalid money")
+
+//Format записывает сумму в минимальных единицах в виде "-105.50" с числом знаков валюты
+func (c Currency) Format(m Money) string {
+	sign := ""
+	if m < 0 {
+		sign = "-"
+		m = -m
+	}
+	decimals := c.Decimals()
+	if decimals == 0 {
+		return sign + strconv.FormatInt(int64(m), 10)
+	}
+	unit := pow10(decimals)
+	frac := strconv.FormatInt(int64(m)%unit, 10)
+	return sign + strconv.FormatInt(int64(m)/unit, 10) + "." + strings.Repeat("0", decimals-len(frac)) + frac
+}
+
+//ParseMoney разбирает сумму вида "-105.50" с двумя знаками после запятой,
+//как у DefaultCurrency. Для сумм в других валютах используйте Currency.Parse
+func ParseMoney(s string) (Money, error) {
+	return DefaultCurrency.Parse(s)
+}
+
+//String записывает сумму в виде "-105.50" с двумя знаками после запятой, как у
+//DefaultCurrency. ВНИМАНИЕ: валюта суммы String неизвестна, поэтому суммы в валютах
+//с другим числом знаков (JPY, BHD и т.п.) печатаются неверно - для них и везде,
+//где валюта известна, используйте Format(currency)
+func (m Money) String() string {
+	return DefaultCurrency.Format(m)
+}
+
+//Format записывает сумму в виде "-105.50" с числом знаков валюты currency
+func (m Money) Format(currency Currency) string {
+	return currency.Format(m)
+}
+
+//Parse разбирает сумму вида "-105.50" в минимальные единицы валюты.
+//Знаков после запятой может быть меньше, чем у валюты, но не больше
+func (c Currency) Parse(s string) (Money, error) {
+	sign := int64(1)
+	if strings.HasPrefix(s, "-") {
+		sign = -1
+		s = s[1:]
+	}
+	whole, frac := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		whole, frac = s[:i], s[i+1:]
+	}
+	decimals := c.Decimals()
+	if whole == "" || len(frac) > decimals || strings.Trim(whole+frac, "0123456789") != "" {
+		return 0, ErrInvalidMoney
+	}
+	units, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil {
+		return 0, ErrInvalidMoney
+	}
+	minor := int64(0)
+	if frac != "" {
+		minor, err = strconv.ParseInt(frac+strings.Repeat("0", decimals-len(frac)), 10, 64)
+		if err != nil {
+			return 0, ErrInvalidMoney
+		}
+	}
+	unit := pow10(decimals)
+	if units > (math.MaxInt64-minor)/unit {
+		return 0, ErrInvalidMoney
+	}
+	return Money(sign * (units*unit + minor)), nil
+}
+
+func pow10(n int) int64 {
+	result := int64(1)
+	for i := 0; i < n; i++ {
+		result *= 10
+	}
+	return result
+}
@@ -0,0 +1,83 @@
+package types
+
+import "testing"
+
+func TestCurrency_Format(t *testing.T) {
+	tests := []struct {
+		currency Currency
+		money    Money
+		want     string
+	}{
+		{"TJS", 105_50, "105.50"},
+		{"USD", -5, "-0.05"},
+		{"JPY", 1500, "1500"},
+		{"BHD", 1_005, "1.005"},
+		{"BHD", -20, "-0.020"},
+	}
+	for _, tt := range tests {
+		if got := tt.currency.Format(tt.money); got != tt.want {
+			t.Errorf("Format(%v, %v) = %v, want %v", tt.currency, tt.money, got, tt.want)
+		}
+	}
+}
+
+func TestCurrency_Parse(t *testing.T) {
+	tests := []struct {
+		currency Currency
+		s        string
+		want     Money
+		wantErr  bool
+	}{
+		{"TJS", "105.5", 105_50, false},
+		{"USD", "-0.05", -5, false},
+		{"JPY", "1500", 1500, false},
+		{"JPY", "1500.5", 0, true},
+		{"BHD", "1.005", 1_005, false},
+		{"BHD", "1.0051", 0, true},
+		{"TJS", "1,5", 0, true},
+		{"TJS", ".5", 0, true},
+		{"TJS", "92233720368547758.07", 9223372036854775807, false},
+		{"TJS", "92233720368547758.08", 0, true},
+		{"TJS", "-92233720368547758.08", 0, true},
+		{"JPY", "9223372036854775808", 0, true},
+		{"BHD", "9223372036854775.808", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := tt.currency.Parse(tt.s)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("Parse(%v, %q) = %v, %v, want %v, error %v", tt.currency, tt.s, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    Money
+		wantErr bool
+	}{
+		{"105.50", 105_50, false},
+		{"-0.5", -50, false},
+		{"7", 7_00, false},
+		{"0.005", 0, true},
+		{"", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseMoney(tt.s)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseMoney(%q) = %v, %v, want %v, error %v", tt.s, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestMoney_String(t *testing.T) {
+	if got := Money(-105_50).String(); got != "-105.50" {
+		t.Errorf("String() = %v, want -105.50", got)
+	}
+	if got := Money(1_005).Format("BHD"); got != "1.005" {
+		t.Errorf("Format(BHD) = %v, want 1.005", got)
+	}
+	if got := JoinFields(Money(105_50)); got != "10550" {
+		t.Errorf("JoinFields() = %v, want minor units 10550", got)
+	}
+}
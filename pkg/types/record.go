@@ -0,0 +1,91 @@
+package types
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+//recordEscaper экранирует символы, которые разделяют поля и записи в дампах
+var recordEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, "|", `\|`, "\n", `\n`, "\r", `\r`)
+
+//JoinFields записывает значения через ";", экранируя "\", ";", "|" и переводы строк.
+//Суммы Money записываются в минимальных единицах
+func JoinFields(values ...interface{}) string {
+	fields := make([]string, len(values))
+	for i, value := range values {
+		if m, ok := value.(Money); ok {
+			value = int64(m)
+		}
+		fields[i] = recordEscaper.Replace(fmt.Sprint(value))
+	}
+	return strings.Join(fields, ";")
+}
+
+//SplitFields разбирает строку JoinFields на поля и снимает экранирование
+func SplitFields(line string) []string {
+	var fields []string
+	field := strings.Builder{}
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case c == '\\' && i+1 < len(line):
+			i++
+			switch line[i] {
+			case 'n':
+				field.WriteByte('\n')
+			case 'r':
+				field.WriteByte('\r')
+			default:
+				field.WriteByte(line[i])
+			}
+		case c == ';':
+			fields = append(fields, field.String())
+			field.Reset()
+		default:
+			field.WriteByte(c)
+		}
+	}
+	return append(fields, field.String())
+}
+
+//SplitRecords разделяет s по неэкранированным sep, не снимая экранирование
+func SplitRecords(s string, sep byte) []string {
+	var records []string
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case sep:
+			records = append(records, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(records, s[start:])
+}
+
+//JoinMetadata записывает пары ключ-значение одним полем в виде "key=value&key=value"
+//с сортировкой по ключам и экранированием, как в URL
+func JoinMetadata(metadata map[string]string) string {
+	values := make(url.Values, len(metadata))
+	for key, value := range metadata {
+		values.Set(key, value)
+	}
+	return values.Encode()
+}
+
+//SplitMetadata разбирает поле JoinMetadata. Для пустого поля возвращается nil
+func SplitMetadata(s string) (map[string]string, error) {
+	if s == "" {
+		return nil, nil
+	}
+	values, err := url.ParseQuery(s)
+	if err != nil {
+		return nil, err
+	}
+	metadata := make(map[string]string, len(values))
+	for key := range values {
+		metadata[key] = values.Get(key)
+	}
+	return metadata, nil
+}
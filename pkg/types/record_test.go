@@ -0,0 +1,61 @@
+package types
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestJoinFields(t *testing.T) {
+	tests := []struct {
+		values []interface{}
+		want   string
+	}{
+		{[]interface{}{1, "auto", Money(100)}, "1;auto;100"},
+		{[]interface{}{"a;b", "c|d", "e\nf", `g\h`, ""}, `a\;b;c\|d;e\nf;g\\h;`},
+	}
+	for _, tt := range tests {
+		got := JoinFields(tt.values...)
+		if got != tt.want {
+			t.Errorf("JoinFields(%q) = %q, want %q", tt.values, got, tt.want)
+			continue
+		}
+		fields := SplitFields(got)
+		if len(fields) != len(tt.values) {
+			t.Errorf("SplitFields(%q) = %q", got, fields)
+		}
+	}
+}
+
+func TestSplitFields(t *testing.T) {
+	got := SplitFields(`a\;b;c\|d;e\nf\r;g\\h;`)
+	want := []string{"a;b", "c|d", "e\nf\r", `g\h`, ""}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SplitFields() = %q, want %q", got, want)
+	}
+}
+
+func TestSplitRecords(t *testing.T) {
+	got := SplitRecords(`1;a\|b|2;c\\|3`, '|')
+	want := []string{`1;a\|b`, `2;c\\`, "3"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SplitRecords() = %q, want %q", got, want)
+	}
+}
+
+func TestJoinMetadata(t *testing.T) {
+	metadata := map[string]string{"order": "A;1|2", "note": "x=y&z", "empty": ""}
+	got := JoinMetadata(metadata)
+	if got != "empty=&note=x%3Dy%26z&order=A%3B1%7C2" {
+		t.Errorf("JoinMetadata() = %q", got)
+		return
+	}
+	split, err := SplitMetadata(got)
+	if err != nil || !reflect.DeepEqual(split, metadata) {
+		t.Errorf("SplitMetadata(%q) = %v, %v", got, split, err)
+		return
+	}
+	split, err = SplitMetadata("")
+	if err != nil || split != nil {
+		t.Errorf("SplitMetadata(\"\") = %v, %v, want nil", split, err)
+	}
+}
@@ -1,10 +1,19 @@
 package types
 
-import "fmt"
+import (
+	"strings"
+	"time"
+)
 
 //Money представляет собой денежную сумму в мин единицах
 type Money int64
 
+//Currency представляет собой код валюты
+type Currency string
+
+//DefaultCurrency валюта счетов, зарегистрированных без указания валюты
+const DefaultCurrency Currency = "TJS"
+
 //PaymentCategory представляет собой категорию. в которой был совершен платеж
 type PaymentCategory string
 
@@ -18,42 +27,196 @@ const (
 	PaymentStatusInProgress PaymentStatus = "INPROGRESS"
 )
 
-//Payment  представляет информацию о платеже
+//Payment  представляет информацию о платеже.
+//InferredCategory - категория, подобранная сервисом, если она не была указана.
+//OriginalCategory - категория до первой массовой смены категорий.
+//Comment и Metadata - данные интегратора, например номер заказа.
+//Tags - метки пользователя, независимые от категории.
+//MerchantID - получатель платежа PayToMerchant, пустой у остальных платежей
 type Payment struct {
+	ID               string
+	AccountID        int64
+	Amount           Money
+	Currency         Currency
+	Category         PaymentCategory
+	Status           PaymentStatus
+	Fee              Money
+	Created          time.Time
+	InferredCategory PaymentCategory
+	OriginalCategory PaymentCategory
+	Comment          string
+	Metadata         map[string]string
+	Tags             []string
+	MerchantID       string
+}
+
+func (ac *Payment) ToString() string {
+	return JoinFields(ac.ID, ac.AccountID, ac.Amount, ac.Category, ac.Status, ac.Currency, ac.Fee, ac.Created.Unix(), ac.InferredCategory, ac.OriginalCategory, ac.Comment, JoinMetadata(ac.Metadata), strings.Join(ac.Tags, ","), ac.MerchantID)
+}
+
+//Merchant представляет информацию о получателе платежей: магазине или поставщике услуг.
+//Платежи получателю проводятся с категорией Category и зачисляются на счет AccountID
+type Merchant struct {
 	ID        string
-	AccountID int64
-	Amount    Money
+	Name      string
 	Category  PaymentCategory
-	Status    PaymentStatus
+	AccountID int64
 }
 
-func (ac *Payment) ToString() string {
-	return fmt.Sprint(ac.ID, ";", ac.AccountID, ";", ac.Amount, ";", ac.Category, ";", ac.Status)
+func (ac *Merchant) ToString() string {
+	return JoinFields(ac.ID, ac.Name, ac.Category, ac.AccountID)
 }
 
 type Phone string
 
+//AccountStatus представляет собой статус счета
+type AccountStatus string
+
+//Предопределенные статусы счетов
+const (
+	AccountStatusActive AccountStatus = "ACTIVE"
+	AccountStatusFrozen AccountStatus = "FROZEN"
+	AccountStatusClosed AccountStatus = "CLOSED"
+)
+
+//AccountType представляет собой тип счета, от которого зависят доступные операции
+type AccountType string
+
+//Предопределенные типы счетов: счета пользователей, получателей платежей
+//и служебные счета сервиса, например счет комиссий
+const (
+	AccountTypePersonal AccountType = "PERSONAL"
+	AccountTypeMerchant AccountType = "MERCHANT"
+	AccountTypeSystem   AccountType = "SYSTEM"
+)
+
 //Account предаствялет информацию о счете пользоватлея
 type Account struct {
-	ID      int64
+	ID       int64
+	Phone    Phone
+	Balance  Money
+	Currency Currency
+	Status   AccountStatus
+	//CreditLimit - на сколько баланс может уйти в минус
+	CreditLimit Money
+	//OverdrawnSince - время, с которого баланс отрицателен
+	OverdrawnSince time.Time
+	//Registered - время регистрации счета
+	Registered time.Time
+	//Points - баллы лояльности, начисленные за платежи
+	Points int64
+	//Type - тип счета
+	Type AccountType
+	//Wallet - имя кошелька владельца телефона Phone, пустое у основного кошелька
+	Wallet string
+}
+
+//Owner представляет владельца телефона Phone и его открытые кошельки,
+//первым идет основной
+type Owner struct {
 	Phone   Phone
-	Balance Money
+	Wallets []Account
 }
 
 func (ac *Account) ToString() string {
-	return fmt.Sprint(ac.ID, ";", ac.Phone, ";", ac.Balance)
+	return JoinFields(ac.ID, ac.Phone, ac.Balance, ac.Currency, ac.Status, ac.CreditLimit, ac.OverdrawnSince.Unix(), ac.Registered.Unix(), ac.Points, ac.Type, ac.Wallet)
 }
 
+//FavoriteGroup представляет собой папку, в которую пользователь сложил избранное
+type FavoriteGroup string
+
 type Favorite struct {
 	ID        string
 	AccountID int64
 	Name      string
 	Amount    Money
 	Category  PaymentCategory
+	//Deleted - время удаления, у действующего избранного нулевое
+	Deleted time.Time
+	//Group - папка избранного, пустая - если избранное не разложено по папкам
+	Group FavoriteGroup
 }
 
 func (ac *Favorite) ToString() string {
-	return fmt.Sprint(ac.ID, ";", ac.AccountID, ";", ac.Name, ";", ac.Amount, ";", ac.Category)
+	return JoinFields(ac.ID, ac.AccountID, ac.Name, ac.Amount, ac.Category, ac.Deleted.Unix(), ac.Group)
+}
+
+//Schedule представляет собой расписание повторяющегося платежа:
+//"@every <интервал>", "@hourly", "@daily", "@weekly" или "@monthly"
+type Schedule string
+
+//ScheduledPayment представляет информацию о запланированном платеже
+type ScheduledPayment struct {
+	ID        string
+	AccountID int64
+	Amount    Money
+	Category  PaymentCategory
+	Schedule  Schedule
+	NextRun   time.Time
+	//Deleted - время отмены, у действующего платежа нулевое
+	Deleted time.Time
+}
+
+func (ac *ScheduledPayment) ToString() string {
+	return JoinFields(ac.ID, ac.AccountID, ac.Amount, ac.Category, ac.Schedule, ac.NextRun.Unix(), ac.Deleted.Unix())
+}
+
+//CorrectionKind представляет собой вид исправления данных
+type CorrectionKind string
+
+//Предопределенные виды исправлений
+const (
+	CorrectionBalance  CorrectionKind = "BALANCE"
+	CorrectionCategory CorrectionKind = "CATEGORY"
+	CorrectionPhone    CorrectionKind = "PHONE"
+)
+
+//CorrectionStatus представляет собой статус исправления
+type CorrectionStatus string
+
+//Предопределенные статусы исправлений
+const (
+	CorrectionStatusPending  CorrectionStatus = "PENDING"
+	CorrectionStatusApplied  CorrectionStatus = "APPLIED"
+	CorrectionStatusDeclined CorrectionStatus = "DECLINED"
+)
+
+//Correction представляет информацию об исправлении, предложенном поддержкой.
+//Amount для CorrectionBalance - изменение баланса со знаком
+type Correction struct {
+	ID         string
+	Kind       CorrectionKind
+	AccountID  int64
+	PaymentID  string
+	Amount     Money
+	Category   PaymentCategory
+	Phone      Phone
+	Reason     string
+	ProposedBy string
+	ReviewedBy string
+	Status     CorrectionStatus
+	PaymentIDs []string
+}
+
+//Operation представляет информацию о пользовательской операции, проведенной
+//через зарегистрированный обработчик. Payload хранится как есть
+type Operation struct {
+	ID        string
+	Kind      string
+	AccountID int64
+	PaymentID string
+	Payload   []byte
+}
+
+//AuditEntry представляет запись журнала аудита об изменяющем вызове.
+//Error пуст, если вызов завершился успешно
+type AuditEntry struct {
+	Seq       int64
+	Time      time.Time
+	Op        string
+	AccountID int64             `json:",omitempty"`
+	Args      map[string]string `json:",omitempty"`
+	Error     string            `json:",omitempty"`
 }
 
 type Progress struct {
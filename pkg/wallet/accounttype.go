@@ -0,0 +1,56 @@
+package wallet
+
+import (
+	"github.com/sidalsoft/wallet/pkg/types"
+)
+
+// restrictedOperations - операции, запрещенные счетам типа. Счета получателей
+// принимают платежи и не пользуются избранным и баллами, служебные счета
+// к тому же не платят получателям
+var restrictedOperations = map[types.AccountType]map[string]bool{
+	types.AccountTypeMerchant: {
+		"FavoritePayment": true,
+		"PayFromFavorite": true,
+		"RedeemPoints":    true,
+	},
+	types.AccountTypeSystem: {
+		"FavoritePayment": true,
+		"PayFromFavorite": true,
+		"RedeemPoints":    true,
+		"PayToMerchant":   true,
+	},
+}
+
+// SetAccountType меняет тип счета
+func (s *Service) SetAccountType(accountID int64, accountType types.AccountType) (err error) {
+	defer s.audit("SetAccountType", &err, "accountID", accountID, "type", accountType)
+	switch accountType {
+	case types.AccountTypePersonal, types.AccountTypeMerchant, types.AccountTypeSystem:
+	default:
+		return ErrInvalidAccountType
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	account, err := s.findAccountByID(accountID)
+	if err != nil {
+		return err
+	}
+	account.Type = accountType
+	s.markAccount(account.ID)
+	return nil
+}
+
+// checkAccountType возвращает ErrAccountTypeRestricted, если операция op запрещена типу счета
+func checkAccountType(account *types.Account, op string) error {
+	if restrictedOperations[account.Type][op] {
+		return ErrAccountTypeRestricted
+	}
+	return nil
+}
+
+// canDebit сообщает, можно ли списать amount со счета с учетом кредитного лимита
+// и удержаний Hold. Служебные счета, например счет комиссий, могут уходить в минус
+// без ограничений
+func (s *Service) canDebit(account *types.Account, amount types.Money) bool {
+	return account.Type == types.AccountTypeSystem || account.Balance+account.CreditLimit-s.held(account.ID) >= amount
+}
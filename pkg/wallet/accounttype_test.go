@@ -0,0 +1,82 @@
+package wallet
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/sidalsoft/wallet/pkg/types"
+)
+
+func TestService_SetAccountType_success(t *testing.T) {
+	s := newTestService()
+	account, err := s.RegisterAccount("+992000000001")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if account.Type != types.AccountTypePersonal {
+		t.Errorf("RegisterAccount(): type = %v, want %v", account.Type, types.AccountTypePersonal)
+		return
+	}
+	_, err = s.Pay(account.ID, 100_00, "fee")
+	if !errors.Is(err, ErrNotEnoughBalance) {
+		t.Errorf("Pay(): error = %v, want %v", err, ErrNotEnoughBalance)
+		return
+	}
+
+	err = s.SetAccountType(account.ID, types.AccountTypeSystem)
+	if err != nil {
+		t.Errorf("SetAccountType(): error = %v", err)
+		return
+	}
+	_, err = s.Pay(account.ID, 100_00, "fee")
+	if err != nil {
+		t.Errorf("Pay(): system account must go negative, error = %v", err)
+		return
+	}
+	got, err := s.FindAccountByID(account.ID)
+	if err != nil || got.Balance != -100_00 || got.Type != types.AccountTypeSystem {
+		t.Errorf("Pay(): account = %v, error = %v", got, err)
+		return
+	}
+}
+
+func TestService_SetAccountType_fail(t *testing.T) {
+	s := newTestService()
+	account, payments, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	favorite, err := s.FavoritePayment(payments[0].ID, "car")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = s.SetAccountType(account.ID, "VIP")
+	if !errors.Is(err, ErrInvalidAccountType) {
+		t.Errorf("SetAccountType(): error = %v, want %v", err, ErrInvalidAccountType)
+		return
+	}
+	err = s.SetAccountType(account.ID+1, types.AccountTypeMerchant)
+	if !errors.Is(err, ErrAccountNotFound) {
+		t.Errorf("SetAccountType(): error = %v, want %v", err, ErrAccountNotFound)
+		return
+	}
+
+	err = s.SetAccountType(account.ID, types.AccountTypeMerchant)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, err = s.PayFromFavorite(favorite.ID)
+	if !errors.Is(err, ErrAccountTypeRestricted) {
+		t.Errorf("PayFromFavorite(): error = %v, want %v", err, ErrAccountTypeRestricted)
+		return
+	}
+	_, err = s.FavoritePayment(payments[0].ID, "bike")
+	if !errors.Is(err, ErrAccountTypeRestricted) {
+		t.Errorf("FavoritePayment(): error = %v, want %v", err, ErrAccountTypeRestricted)
+		return
+	}
+}
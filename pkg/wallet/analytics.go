@@ -0,0 +1,394 @@
+package wallet
+
+import (
+	"encoding/csv"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sidalsoft/wallet/pkg/types"
+)
+
+// Cohort описывает счета, зарегистрированные в одном периоде.
+// Retained[i] - число счетов с платежом на Days[i]-й день после регистрации или позже
+type Cohort struct {
+	Start    time.Time
+	Accounts int
+	Days     []int
+	Retained []int
+}
+
+// Activity описывает платежи за период. Активный счет - счет хотя бы с одним платежом
+type Activity struct {
+	Start          time.Time
+	ActiveAccounts int
+	Payments       int
+}
+
+// PaymentsPerAccount возвращает среднее число платежей на активный счет
+func (a Activity) PaymentsPerAccount() float64 {
+	if a.ActiveAccounts == 0 {
+		return 0
+	}
+	return float64(a.Payments) / float64(a.ActiveAccounts)
+}
+
+// Cohorts группирует счета по периодам регистрации длиной period и считает
+// удержание на дни days. Отклоненные платежи активностью не считаются
+func (s *Service) Cohorts(period time.Duration, days ...int) ([]Cohort, error) {
+	if period <= 0 {
+		return nil, ErrInvalidPeriod
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	last := make(map[int64]time.Time)
+	for _, payment := range s.payments {
+		if payment.Status == types.PaymentStatusFail {
+			continue
+		}
+		if payment.Created.After(last[payment.AccountID]) {
+			last[payment.AccountID] = payment.Created
+		}
+	}
+
+	cohorts := make(map[time.Time]*Cohort)
+	for _, account := range s.accounts {
+		start := account.Registered.Truncate(period)
+		cohort, ok := cohorts[start]
+		if !ok {
+			cohort = &Cohort{Start: start, Days: days, Retained: make([]int, len(days))}
+			cohorts[start] = cohort
+		}
+		cohort.Accounts++
+		for i, day := range days {
+			if !last[account.ID].Before(account.Registered.AddDate(0, 0, day)) {
+				cohort.Retained[i]++
+			}
+		}
+	}
+
+	result := make([]Cohort, 0, len(cohorts))
+	for _, cohort := range cohorts {
+		result = append(result, *cohort)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Start.Before(result[j].Start)
+	})
+	return result, nil
+}
+
+// Activity считает активные счета и платежи по периодам длиной period
+func (s *Service) Activity(period time.Duration) ([]Activity, error) {
+	if period <= 0 {
+		return nil, ErrInvalidPeriod
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	type bucket struct {
+		activity Activity
+		accounts map[int64]bool
+	}
+	buckets := make(map[time.Time]*bucket)
+	for _, payment := range s.payments {
+		if payment.Status == types.PaymentStatusFail {
+			continue
+		}
+		start := payment.Created.Truncate(period)
+		b, ok := buckets[start]
+		if !ok {
+			b = &bucket{activity: Activity{Start: start}, accounts: make(map[int64]bool)}
+			buckets[start] = b
+		}
+		b.activity.Payments++
+		b.accounts[payment.AccountID] = true
+	}
+
+	result := make([]Activity, 0, len(buckets))
+	for _, b := range buckets {
+		b.activity.ActiveAccounts = len(b.accounts)
+		result = append(result, b.activity)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Start.Before(result[j].Start)
+	})
+	return result, nil
+}
+
+// WriteCohortsCSV записывает когорты в CSV: начало периода, число счетов
+// и доля удержанных счетов на каждый день
+func WriteCohortsCSV(w io.Writer, cohorts []Cohort) error {
+	writer := csv.NewWriter(w)
+	if len(cohorts) > 0 {
+		header := []string{"start", "accounts"}
+		for _, day := range cohorts[0].Days {
+			header = append(header, "day"+strconv.Itoa(day))
+		}
+		err := writer.Write(header)
+		if err != nil {
+			return err
+		}
+	}
+	for _, cohort := range cohorts {
+		record := []string{cohort.Start.Format(time.RFC3339), strconv.Itoa(cohort.Accounts)}
+		for _, retained := range cohort.Retained {
+			rate := float64(retained) / float64(cohort.Accounts)
+			record = append(record, strconv.FormatFloat(rate, 'f', 4, 64))
+		}
+		err := writer.Write(record)
+		if err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteActivityCSV записывает активность в CSV: начало периода, активные счета,
+// платежи и среднее число платежей на активный счет
+func WriteActivityCSV(w io.Writer, activity []Activity) error {
+	writer := csv.NewWriter(w)
+	err := writer.Write([]string{"start", "active_accounts", "payments", "payments_per_account"})
+	if err != nil {
+		return err
+	}
+	for _, a := range activity {
+		err = writer.Write([]string{
+			a.Start.Format(time.RFC3339),
+			strconv.Itoa(a.ActiveAccounts),
+			strconv.Itoa(a.Payments),
+			strconv.FormatFloat(a.PaymentsPerAccount(), 'f', 2, 64),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// SumByCategory суммирует неотклоненные платежи счета по категориям, сворачивая
+// подкатегории до depth уровней: с depth 1 платежи "transport/taxi" и "transport/fuel"
+// попадают в "transport". depth 0 оставляет категории как есть
+func (s *Service) SumByCategory(accountID int64, depth int) (map[types.PaymentCategory]types.Money, error) {
+	if depth < 0 {
+		return nil, ErrInvalidCategory
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, err := s.findAccountByID(accountID)
+	if err != nil {
+		return nil, err
+	}
+	sums := make(map[types.PaymentCategory]types.Money)
+	for _, payment := range s.payments {
+		if payment.AccountID != accountID || payment.Status == types.PaymentStatusFail || !isSpending(payment) {
+			continue
+		}
+		sums[payment.Category.Truncate(depth)] += payment.Amount
+	}
+	return sums, nil
+}
+
+// CategoryShare - сумма платежей категории и ее доля в процентах от всех платежей
+type CategoryShare struct {
+	Category types.PaymentCategory
+	Amount   types.Money
+	Percent  float64
+}
+
+// CategorySummary суммирует неотклоненные платежи счета, созданные в [from, to), по категориям
+func (s *Service) CategorySummary(accountID int64, from, to time.Time) (map[types.PaymentCategory]types.Money, error) {
+	if to.Before(from) {
+		return nil, ErrInvalidPeriod
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, err := s.findAccountByID(accountID)
+	if err != nil {
+		return nil, err
+	}
+	return s.categorySums(accountID, from, to, 1), nil
+}
+
+// CategoryBreakdown возвращает доли категорий в неотклоненных платежах счета,
+// созданных в [from, to), по убыванию суммы. Платежи делятся между goroutines
+// горутинами, каждая считает свои суммы за один проход
+func (s *Service) CategoryBreakdown(accountID int64, from, to time.Time, goroutines int) ([]CategoryShare, error) {
+	if to.Before(from) {
+		return nil, ErrInvalidPeriod
+	}
+	if goroutines < 1 {
+		goroutines = 1
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, err := s.findAccountByID(accountID)
+	if err != nil {
+		return nil, err
+	}
+	sums := s.categorySums(accountID, from, to, goroutines)
+	total := types.Money(0)
+	for _, amount := range sums {
+		total += amount
+	}
+	shares := make([]CategoryShare, 0, len(sums))
+	for category, amount := range sums {
+		shares = append(shares, CategoryShare{
+			Category: category,
+			Amount:   amount,
+			Percent:  float64(amount) * 100 / float64(total),
+		})
+	}
+	sort.Slice(shares, func(i, j int) bool {
+		if shares[i].Amount != shares[j].Amount {
+			return shares[i].Amount > shares[j].Amount
+		}
+		return shares[i].Category < shares[j].Category
+	})
+	return shares, nil
+}
+
+func (s *Service) categorySums(accountID int64, from, to time.Time, goroutines int) map[types.PaymentCategory]types.Money {
+	sum := func(payments []*types.Payment) map[types.PaymentCategory]types.Money {
+		sums := make(map[types.PaymentCategory]types.Money)
+		for _, payment := range payments {
+			if payment.AccountID != accountID || payment.Status == types.PaymentStatusFail ||
+				payment.Created.Before(from) || !payment.Created.Before(to) || !isSpending(payment) {
+				continue
+			}
+			sums[payment.Category] += payment.Amount
+		}
+		return sums
+	}
+	if goroutines <= 1 || len(s.payments) < goroutines {
+		return sum(s.payments)
+	}
+
+	parts := make([]map[types.PaymentCategory]types.Money, goroutines)
+	size := (len(s.payments) + goroutines - 1) / goroutines
+	wg := sync.WaitGroup{}
+	for i := range parts {
+		start, end := i*size, (i+1)*size
+		if start > len(s.payments) {
+			start = len(s.payments)
+		}
+		if end > len(s.payments) {
+			end = len(s.payments)
+		}
+		wg.Add(1)
+		go func(i int, payments []*types.Payment) {
+			defer wg.Done()
+			parts[i] = sum(payments)
+		}(i, s.payments[start:end])
+	}
+	wg.Wait()
+
+	sums := make(map[types.PaymentCategory]types.Money)
+	for _, part := range parts {
+		for category, amount := range part {
+			sums[category] += amount
+		}
+	}
+	return sums
+}
+
+// RankedTotal - сумма и число неотклоненных платежей, приходящихся на Key
+type RankedTotal struct {
+	Key      string
+	Amount   types.Money
+	Payments int
+}
+
+// TopCategories возвращает до n категорий с наибольшей суммой платежей счета
+func (s *Service) TopCategories(accountID int64, n int) ([]RankedTotal, error) {
+	if n <= 0 {
+		return nil, ErrInvalidPage
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, err := s.findAccountByID(accountID)
+	if err != nil {
+		return nil, err
+	}
+	totals := make(map[string]*RankedTotal)
+	for _, payment := range s.payments {
+		if payment.AccountID != accountID || payment.Status == types.PaymentStatusFail {
+			continue
+		}
+		addRankedTotal(totals, string(payment.Category), payment.Amount)
+	}
+	return topRankedTotals(totals, n), nil
+}
+
+// TopPayees возвращает до n получателей с наибольшей суммой платежей счета. Получатель
+// платежа PayToMerchant - имя получателя, перевода PayToPhone - телефон счета зачисления.
+// Остальные платежи получателя не имеют и не учитываются
+func (s *Service) TopPayees(accountID int64, n int) ([]RankedTotal, error) {
+	if n <= 0 {
+		return nil, ErrInvalidPage
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, err := s.findAccountByID(accountID)
+	if err != nil {
+		return nil, err
+	}
+	recipients := make(map[string]int64)
+	for _, operation := range s.operations {
+		if operation.Kind == P2POperation {
+			recipients[operation.PaymentID] = operation.AccountID
+		}
+	}
+	totals := make(map[string]*RankedTotal)
+	for _, payment := range s.payments {
+		if payment.AccountID != accountID || payment.Status == types.PaymentStatusFail {
+			continue
+		}
+		if payment.MerchantID != "" {
+			merchant, err := s.findMerchantByID(payment.MerchantID)
+			if err == nil {
+				addRankedTotal(totals, merchant.Name, payment.Amount)
+			}
+			continue
+		}
+		if recipientID, ok := recipients[payment.ID]; ok {
+			recipient, err := s.findAccountByID(recipientID)
+			if err == nil {
+				addRankedTotal(totals, string(recipient.Phone), payment.Amount)
+			}
+		}
+	}
+	return topRankedTotals(totals, n), nil
+}
+
+func addRankedTotal(totals map[string]*RankedTotal, key string, amount types.Money) {
+	total, ok := totals[key]
+	if !ok {
+		total = &RankedTotal{Key: key}
+		totals[key] = total
+	}
+	total.Amount += amount
+	total.Payments++
+}
+
+// topRankedTotals упорядочивает суммы по убыванию, при равенстве - по Key, и оставляет первые n
+func topRankedTotals(totals map[string]*RankedTotal, n int) []RankedTotal {
+	result := make([]RankedTotal, 0, len(totals))
+	for _, total := range totals {
+		result = append(result, *total)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Amount != result[j].Amount {
+			return result[i].Amount > result[j].Amount
+		}
+		return result[i].Key < result[j].Key
+	})
+	if len(result) > n {
+		result = result[:n]
+	}
+	return result
+}
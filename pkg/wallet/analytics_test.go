@@ -0,0 +1,255 @@
+package wallet
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sidalsoft/wallet/pkg/types"
+)
+
+func TestService_Cohorts_success(t *testing.T) {
+	s := newTestService()
+	now := time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+
+	active, err := s.addAccountWithBalance("+992928885522", 100_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, err = s.addAccountWithBalance("+992928885523", 100_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	now = now.AddDate(0, 0, 1)
+	_, err = s.addAccountWithBalance("+992928885524", 100_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	now = now.AddDate(0, 0, 7)
+	for i := 0; i < 2; i++ {
+		_, err = s.Pay(active.ID, 10_00, "auto")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	cohorts, err := s.Cohorts(24*time.Hour, 1, 30)
+	if err != nil {
+		t.Errorf("Cohorts(): error = %v", err)
+		return
+	}
+	if len(cohorts) != 2 || cohorts[0].Accounts != 2 || cohorts[1].Accounts != 1 {
+		t.Errorf("Cohorts(): wrong cohorts = %+v", cohorts)
+		return
+	}
+	if cohorts[0].Retained[0] != 1 || cohorts[0].Retained[1] != 0 || cohorts[1].Retained[0] != 0 {
+		t.Errorf("Cohorts(): wrong retention = %+v", cohorts)
+		return
+	}
+
+	activity, err := s.Activity(24 * time.Hour)
+	if err != nil {
+		t.Errorf("Activity(): error = %v", err)
+		return
+	}
+	if len(activity) != 1 || activity[0].ActiveAccounts != 1 || activity[0].PaymentsPerAccount() != 2 {
+		t.Errorf("Activity(): wrong activity = %+v", activity)
+		return
+	}
+
+	var buf bytes.Buffer
+	err = WriteCohortsCSV(&buf, cohorts)
+	if err != nil {
+		t.Errorf("WriteCohortsCSV(): error = %v", err)
+		return
+	}
+	want := "start,accounts,day1,day30\n" +
+		"2021-03-01T00:00:00Z,2,0.5000,0.0000\n" +
+		"2021-03-02T00:00:00Z,1,0.0000,0.0000\n"
+	if buf.String() != want {
+		t.Errorf("WriteCohortsCSV(): csv = %q, want = %q", buf.String(), want)
+		return
+	}
+}
+
+func TestService_Cohorts_fail(t *testing.T) {
+	s := newTestService()
+	_, err := s.Cohorts(0, 1)
+	if !errors.Is(err, ErrInvalidPeriod) {
+		t.Errorf("Cohorts(): must return ErrInvalidPeriod, returned = %v", err)
+		return
+	}
+}
+
+func TestService_SumByCategory(t *testing.T) {
+	s := newTestService()
+	account, _, err := s.addAccount(testAccount{
+		phone:   "+992928885522",
+		balance: 10_000_00,
+		payments: []struct {
+			amount   types.Money
+			category types.PaymentCategory
+		}{
+			{amount: 100_00, category: "transport/taxi"},
+			{amount: 200_00, category: "transport/fuel"},
+			{amount: 50_00, category: "transport"},
+			{amount: 300_00, category: "food"},
+		},
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	sums, err := s.SumByCategory(account.ID, 1)
+	want := map[types.PaymentCategory]types.Money{"transport": 350_00, "food": 300_00}
+	if err != nil || !reflect.DeepEqual(sums, want) {
+		t.Errorf("SumByCategory(): sums = %v, want %v, error = %v", sums, want, err)
+		return
+	}
+	sums, err = s.SumByCategory(account.ID, 0)
+	if err != nil || len(sums) != 4 || sums["transport/fuel"] != 200_00 {
+		t.Errorf("SumByCategory(): sums = %v, error = %v", sums, err)
+		return
+	}
+}
+
+func TestService_CategoryBreakdown_success(t *testing.T) {
+	s := newTestService()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	account, err := s.addAccountWithBalance("+992928885522", 10_000_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	payments := []struct {
+		amount   types.Money
+		category types.PaymentCategory
+	}{
+		{300_00, "food"}, {100_00, "auto"}, {100_00, "food"}, {500_00, "fun"},
+	}
+	for _, p := range payments {
+		_, err = s.Pay(account.ID, p.amount, p.category)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		now = now.Add(time.Hour)
+	}
+
+	sums, err := s.CategorySummary(account.ID, now.Add(-4*time.Hour), now.Add(-time.Hour))
+	want := map[types.PaymentCategory]types.Money{"food": 400_00, "auto": 100_00}
+	if err != nil || !reflect.DeepEqual(sums, want) {
+		t.Errorf("CategorySummary(): sums = %v, want %v, error = %v", sums, want, err)
+		return
+	}
+
+	for _, goroutines := range []int{1, 3} {
+		shares, err := s.CategoryBreakdown(account.ID, time.Time{}, now, goroutines)
+		wantShares := []CategoryShare{
+			{Category: "fun", Amount: 500_00, Percent: 50},
+			{Category: "food", Amount: 400_00, Percent: 40},
+			{Category: "auto", Amount: 100_00, Percent: 10},
+		}
+		if err != nil || !reflect.DeepEqual(shares, wantShares) {
+			t.Errorf("CategoryBreakdown(%d): shares = %v, error = %v", goroutines, shares, err)
+			return
+		}
+	}
+}
+
+func TestService_CategoryBreakdown_fail(t *testing.T) {
+	s := newTestService()
+	now := time.Now()
+	_, err := s.CategoryBreakdown(1, now, now.Add(-time.Hour), 1)
+	if !errors.Is(err, ErrInvalidPeriod) {
+		t.Errorf("CategoryBreakdown(): error = %v, want %v", err, ErrInvalidPeriod)
+		return
+	}
+	_, err = s.CategorySummary(1, now.Add(-time.Hour), now)
+	if !errors.Is(err, ErrAccountNotFound) {
+		t.Errorf("CategorySummary(): error = %v, want %v", err, ErrAccountNotFound)
+		return
+	}
+}
+
+func TestService_TopPayees_success(t *testing.T) {
+	s := newTestService()
+	account, err := s.addAccountWithBalance("+992900000001", 10_000_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	shop, err := s.RegisterAccount("+992900000002")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	friend, err := s.RegisterAccount("+992900000003")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	merchant, err := s.RegisterMerchant("Магазин", "food", shop.ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	for i := 0; i < 2; i++ {
+		_, err = s.PayToMerchant(account.ID, merchant.ID, 300_00)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+	}
+	_, err = s.PayToPhone(account.ID, friend.Phone, 200_00, "gift")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, err = s.Pay(account.ID, 1_000_00, "auto")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	payees, err := s.TopPayees(account.ID, 5)
+	want := []RankedTotal{
+		{Key: "Магазин", Amount: 600_00, Payments: 2},
+		{Key: string(friend.Phone), Amount: 200_00, Payments: 1},
+	}
+	if err != nil || !reflect.DeepEqual(payees, want) {
+		t.Errorf("TopPayees(): payees = %v, want %v, error = %v", payees, want, err)
+		return
+	}
+	categories, err := s.TopCategories(account.ID, 2)
+	want = []RankedTotal{
+		{Key: "auto", Amount: 1_000_00, Payments: 1},
+		{Key: "food", Amount: 600_00, Payments: 2},
+	}
+	if err != nil || !reflect.DeepEqual(categories, want) {
+		t.Errorf("TopCategories(): categories = %v, want %v, error = %v", categories, want, err)
+		return
+	}
+}
+
+func TestService_TopCategories_fail(t *testing.T) {
+	s := newTestService()
+	_, err := s.TopCategories(1, 0)
+	if !errors.Is(err, ErrInvalidPage) {
+		t.Errorf("TopCategories(): error = %v, want %v", err, ErrInvalidPage)
+		return
+	}
+	_, err = s.TopPayees(1, 3)
+	if !errors.Is(err, ErrAccountNotFound) {
+		t.Errorf("TopPayees(): error = %v, want %v", err, ErrAccountNotFound)
+		return
+	}
+}
@@ -0,0 +1,122 @@
+package wallet
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/sidalsoft/wallet/pkg/types"
+)
+
+// AuditQuery отбирает записи журнала аудита. Пустые поля не ограничивают выборку
+type AuditQuery struct {
+	Op        string
+	AccountID int64
+	From      time.Time
+	To        time.Time
+	Failed    bool
+}
+
+type auditLog struct {
+	mu      sync.RWMutex
+	seq     int64
+	entries []types.AuditEntry
+}
+
+// audit добавляет в журнал запись о вызове изменяющего метода и передает ее в Logger.
+// Вызывается через defer с указателем на возвращаемую ошибку и парами "имя, значение" аргументов
+func (s *Service) audit(op string, err *error, args ...interface{}) {
+	entry := types.AuditEntry{
+		Time: s.clock(),
+		Op:   op,
+		Args: make(map[string]string, len(args)/2),
+	}
+	var amount types.Money
+	for i := 0; i+1 < len(args); i += 2 {
+		name := fmt.Sprint(args[i])
+		value := args[i+1]
+		switch name {
+		case "accountID":
+			entry.AccountID, _ = value.(int64)
+		case "amount":
+			amount, _ = value.(types.Money)
+		}
+		// суммы в журнале - в минимальных единицах, как в дампах
+		if m, ok := value.(types.Money); ok {
+			value = int64(m)
+		}
+		entry.Args[name] = fmt.Sprint(value)
+	}
+	var callErr error
+	if err != nil && *err != nil {
+		callErr = *err
+		entry.Error = callErr.Error()
+		*err = wrapError(op, entry.AccountID, callErr)
+	}
+	s.log(LogEntry{
+		Time:      entry.Time,
+		Operation: op,
+		AccountID: entry.AccountID,
+		Amount:    amount,
+		Args:      entry.Args,
+		Err:       callErr,
+	})
+
+	s.auditLog.mu.Lock()
+	s.auditLog.seq++
+	entry.Seq = s.auditLog.seq
+	s.auditLog.entries = append(s.auditLog.entries, entry)
+	s.auditLog.mu.Unlock()
+
+	walErr := s.syncWAL()
+	if walErr != nil {
+		s.log(LogEntry{Time: s.clock(), Operation: "WAL", Err: walErr})
+		// изменение не стало надежным, вызывающий не должен считать его сохраненным
+		if err != nil && *err == nil {
+			*err = wrapError(op, entry.AccountID, walErr)
+		}
+	}
+}
+
+func (s *Service) AuditLog(query AuditQuery) []types.AuditEntry {
+	s.auditLog.mu.RLock()
+	defer s.auditLog.mu.RUnlock()
+
+	var entries []types.AuditEntry
+	for _, entry := range s.auditLog.entries {
+		if query.Op != "" && entry.Op != query.Op {
+			continue
+		}
+		if query.AccountID != 0 && entry.AccountID != query.AccountID {
+			continue
+		}
+		if !query.From.IsZero() && entry.Time.Before(query.From) {
+			continue
+		}
+		if !query.To.IsZero() && !entry.Time.Before(query.To) {
+			continue
+		}
+		if query.Failed && entry.Error == "" {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// ExportAudit записывает журнал аудита в w в формате JSON Lines
+func (s *Service) ExportAudit(w io.Writer) error {
+	s.auditLog.mu.RLock()
+	defer s.auditLog.mu.RUnlock()
+
+	encoder := json.NewEncoder(w)
+	for _, entry := range s.auditLog.entries {
+		err := encoder.Encode(entry)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package wallet
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sidalsoft/wallet/pkg/types"
+)
+
+func TestService_AuditLog_success(t *testing.T) {
+	s := newTestService()
+	now := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+
+	account, err := s.RegisterAccount("+992928885522")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = s.Deposit(account.ID, 100_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	now = now.Add(time.Hour)
+	_, err = s.Pay(account.ID, 200_00, "auto")
+	if !errors.Is(err, ErrNotEnoughBalance) {
+		t.Errorf("Pay(): must return ErrNotEnoughBalance, returned = %v", err)
+		return
+	}
+	_, _ = s.FindAccountByID(account.ID)
+
+	entries := s.AuditLog(AuditQuery{})
+	if len(entries) != 3 {
+		t.Errorf("AuditLog(): must record 3 mutations, entries = %+v", entries)
+		return
+	}
+	for i, entry := range entries {
+		if entry.Seq != int64(i+1) {
+			t.Errorf("AuditLog(): wrong sequence, entries = %+v", entries)
+			return
+		}
+	}
+	if entries[1].Op != "Deposit" || entries[1].AccountID != account.ID || entries[1].Args["amount"] != "10000" {
+		t.Errorf("AuditLog(): wrong deposit entry = %+v", entries[1])
+		return
+	}
+
+	failed := s.AuditLog(AuditQuery{Failed: true})
+	if len(failed) != 1 || failed[0].Op != "Pay" || failed[0].Error != ErrNotEnoughBalance.Error() {
+		t.Errorf("AuditLog(): wrong failed entries = %+v", failed)
+		return
+	}
+	later := s.AuditLog(AuditQuery{From: now})
+	if len(later) != 1 || !later[0].Time.Equal(now) {
+		t.Errorf("AuditLog(): wrong entries from %v = %+v", now, later)
+		return
+	}
+	deposits := s.AuditLog(AuditQuery{Op: "Deposit", AccountID: account.ID})
+	if len(deposits) != 1 {
+		t.Errorf("AuditLog(): wrong deposit entries = %+v", deposits)
+		return
+	}
+}
+
+func TestService_ExportAudit_success(t *testing.T) {
+	s := newTestService()
+	_, err := s.addAccountWithBalance("+992928885522", 100_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	var buf bytes.Buffer
+	err = s.ExportAudit(&buf)
+	if err != nil {
+		t.Errorf("ExportAudit(): error = %v", err)
+		return
+	}
+	decoder := json.NewDecoder(&buf)
+	var exported []types.AuditEntry
+	for decoder.More() {
+		var entry types.AuditEntry
+		err = decoder.Decode(&entry)
+		if err != nil {
+			t.Errorf("ExportAudit(): invalid line, error = %v", err)
+			return
+		}
+		exported = append(exported, entry)
+	}
+	entries := s.AuditLog(AuditQuery{})
+	if len(exported) != len(entries) || exported[1].Op != entries[1].Op || exported[1].Args["amount"] != entries[1].Args["amount"] {
+		t.Errorf("ExportAudit(): exported = %+v, want %+v", exported, entries)
+		return
+	}
+}
@@ -0,0 +1,62 @@
+package wallet
+
+import (
+	"time"
+)
+
+// autoSave периодически сохраняет состояние сервиса в каталог dir
+type autoSave struct {
+	dir      string
+	interval time.Duration
+	loop     *backgroundLoop
+}
+
+// WithAutoSave сохраняет состояние в каталог dir каждые interval и при Close.
+// Сохраняются только изменения, как в ExportIncremental. Ошибки сохранения
+// по таймеру передаются Logger с операцией "AutoSave". Неположительный interval
+// отключает автосохранение, в том числе при Close
+func WithAutoSave(dir string, interval time.Duration) Option {
+	return func(s *Service) {
+		if interval <= 0 {
+			s.autoSave = nil
+			return
+		}
+		s.autoSave = &autoSave{dir: dir, interval: interval}
+	}
+}
+
+func (s *Service) startAutoSave() {
+	a := s.autoSave
+	a.loop, _ = newBackgroundLoop(a.interval, func(time.Time) {
+		err := s.ExportIncremental(a.dir)
+		if err != nil {
+			s.log(LogEntry{Time: s.clock(), Operation: "AutoSave", Err: err})
+		}
+	})
+	a.loop.start()
+}
+
+// Close останавливает автосохранение, сохраняет состояние в последний раз
+// и закрывает журнал упреждающей записи. Изменения после Close не сохраняются
+func (s *Service) Close() error {
+	var err error
+	if a := s.autoSave; a != nil {
+		a.loop.halt()
+		err = s.ExportIncremental(a.dir)
+	}
+	if w := s.wal; w != nil {
+		walErr := s.syncWAL()
+		w.mu.Lock()
+		if w.file != nil {
+			if closeErr := w.file.Close(); walErr == nil {
+				walErr = closeErr
+			}
+			w.file = nil
+		}
+		w.mu.Unlock()
+		if err == nil {
+			err = walErr
+		}
+	}
+	return err
+}
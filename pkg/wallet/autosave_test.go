@@ -0,0 +1,112 @@
+package wallet
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestService_WithAutoSave_success(t *testing.T) {
+	dir := t.TempDir()
+	s := NewService(WithAutoSave(dir, 10*time.Millisecond))
+	account, err := s.RegisterAccount("+992000000001")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		imported := &Service{}
+		err = imported.Import(dir)
+		if err == nil {
+			if _, err = imported.FindAccountByID(account.ID); err == nil {
+				break
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Errorf("WithAutoSave(): account must be saved, error = %v", err)
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	err = s.Deposit(account.ID, 100)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = s.Close()
+	if err != nil {
+		t.Errorf("Close(): error = %v", err)
+		return
+	}
+	imported := &Service{}
+	err = imported.Import(dir)
+	if err != nil {
+		t.Errorf("Import(): error = %v", err)
+		return
+	}
+	got, err := imported.FindAccountByID(account.ID)
+	if err != nil || got.Balance != 100 {
+		t.Errorf("Close(): saved account = %v, error = %v", got, err)
+		return
+	}
+}
+
+func TestService_WithAutoSave_disabled(t *testing.T) {
+	dir := t.TempDir()
+	s := NewService(WithAutoSave(dir, 0))
+	_, err := s.RegisterAccount("+992000000001")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = s.Close()
+	if err != nil {
+		t.Errorf("Close(): error = %v", err)
+		return
+	}
+	_, err = os.Stat(dir + "/" + manifestName)
+	if !os.IsNotExist(err) {
+		t.Errorf("Close(): disabled autosave must not export, error = %v", err)
+		return
+	}
+}
+
+func TestService_Close_wal(t *testing.T) {
+	dir := t.TempDir()
+	s := NewService(WithWAL(dir, 0))
+	err := s.Recover()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, err = s.RegisterAccount("+992000000001")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = s.Close()
+	if err != nil {
+		t.Errorf("Close(): error = %v", err)
+		return
+	}
+	err = newTestService().Close()
+	if err != nil {
+		t.Errorf("Close(): error = %v", err)
+		return
+	}
+
+	recovered := NewService(WithWAL(dir, 0))
+	err = recovered.Recover()
+	if err != nil {
+		t.Errorf("Recover(): error = %v", err)
+		return
+	}
+	_, err = recovered.FindAccountByPhone("+992000000001")
+	if err != nil {
+		t.Errorf("Recover(): error = %v", err)
+		return
+	}
+}
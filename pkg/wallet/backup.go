@@ -0,0 +1,146 @@
+package wallet
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// backupLayout - имя каталога резервной копии: время создания в UTC,
+// лексикографический порядок имен совпадает с порядком по времени
+const backupLayout = "20060102T150405.000000000Z"
+
+// BackupManager создает полные резервные копии состояния сервиса в подкаталогах dir
+// и хранит последние Keep копий
+type BackupManager struct {
+	svc *Service
+	dir string
+	// Keep - число хранимых копий, 0 - хранить все
+	Keep int
+}
+
+func NewBackupManager(svc *Service, dir string, keep int) *BackupManager {
+	return &BackupManager{svc: svc, dir: dir, Keep: keep}
+}
+
+// Backup экспортирует состояние в новый каталог и удаляет копии сверх Keep.
+// Возвращает время созданной копии
+func (m *BackupManager) Backup() (time.Time, error) {
+	created := m.svc.clock()
+	dir := filepath.Join(m.dir, created.Format(backupLayout))
+	err := m.svc.Export(dir)
+	if err != nil {
+		os.RemoveAll(dir)
+		return time.Time{}, err
+	}
+	backups, err := m.Backups()
+	if err != nil {
+		return created, err
+	}
+	for m.Keep > 0 && len(backups) > m.Keep {
+		err = os.RemoveAll(filepath.Join(m.dir, backups[0].Format(backupLayout)))
+		if err != nil {
+			return created, err
+		}
+		backups = backups[1:]
+	}
+	return created, nil
+}
+
+// Backups возвращает времена имеющихся копий по возрастанию
+func (m *BackupManager) Backups() ([]time.Time, error) {
+	entries, err := ioutil.ReadDir(m.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var backups []time.Time
+	for _, entry := range entries {
+		created, err := time.Parse(backupLayout, entry.Name())
+		if err != nil || !entry.IsDir() {
+			continue
+		}
+		backups = append(backups, created)
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].Before(backups[j])
+	})
+	return backups, nil
+}
+
+// RestoreLatest заменяет состояние сервиса последней копией.
+// Возвращает время восстановленной копии
+func (m *BackupManager) RestoreLatest() (time.Time, error) {
+	backups, err := m.Backups()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return m.restore(backups, len(backups))
+}
+
+// RestoreAt заменяет состояние сервиса последней копией, созданной не позже at.
+// Возвращает время восстановленной копии
+func (m *BackupManager) RestoreAt(at time.Time) (time.Time, error) {
+	backups, err := m.Backups()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return m.restore(backups, sort.Search(len(backups), func(i int) bool {
+		return backups[i].After(at)
+	}))
+}
+
+// restore восстанавливает копию, предшествующую backups[i]
+func (m *BackupManager) restore(backups []time.Time, i int) (time.Time, error) {
+	if i == 0 {
+		return time.Time{}, ErrBackupNotFound
+	}
+	created := backups[i-1]
+	return created, m.svc.Restore(filepath.Join(m.dir, created.Format(backupLayout)))
+}
+
+// Restore заменяет счета, платежи, избранное, запланированные платежи и операции
+// содержимым каталога dir, созданного Export. В отличие от Import, записи, которых
+// нет в дампах, удаляются. При ошибке состояние не меняется
+func (s *Service) Restore(dir string) (err error) {
+	defer s.audit("Restore", &err, "dir", dir)
+	defer s.observe("Restore", time.Now())
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	err = verifyManifest(dir)
+	if err != nil {
+		return err
+	}
+	dumps := make(map[string]string)
+	for _, name := range dumpNames {
+		dumps[name], err = s.readDump(dir, name)
+		if err != nil {
+			return err
+		}
+	}
+	parsed, err := parseDumps(dumps)
+	if err != nil {
+		return err
+	}
+	s.accounts = nil
+	s.phones.reset()
+	s.payments = nil
+	s.paymentTimes.reset()
+	s.favorites = nil
+	s.scheduled = nil
+	s.operations = nil
+	s.merchants = nil
+	s.balances = nil
+	s.nextAccountID = 0
+	s.markFull()
+	s.importDumps(parsed, MergeOverwrite)
+	return nil
+}
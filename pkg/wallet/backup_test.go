@@ -0,0 +1,92 @@
+package wallet
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBackupManager_RestoreAt_success(t *testing.T) {
+	s := newTestService()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	m := NewBackupManager(s.Service, t.TempDir(), 2)
+
+	account, err := s.addAccountWithBalance("+992928885522", 1_000_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	var backups []time.Time
+	for i := 0; i < 3; i++ {
+		_, err = s.Pay(account.ID, 100_00, "auto")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		created, err := m.Backup()
+		if err != nil {
+			t.Errorf("Backup(): error = %v", err)
+			return
+		}
+		backups = append(backups, created)
+		now = now.Add(time.Hour)
+	}
+	kept, err := m.Backups()
+	if err != nil || len(kept) != 2 || !kept[0].Equal(backups[1]) {
+		t.Errorf("Backups(): backups = %v, error = %v", kept, err)
+		return
+	}
+
+	other, err := s.RegisterAccount("+992000000001")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	restored, err := m.RestoreAt(backups[1].Add(time.Minute))
+	if err != nil || !restored.Equal(backups[1]) {
+		t.Errorf("RestoreAt(): restored = %v, error = %v", restored, err)
+		return
+	}
+	got, err := s.FindAccountByID(account.ID)
+	if err != nil || got.Balance != 800_00 {
+		t.Errorf("RestoreAt(): account = %v, error = %v", got, err)
+		return
+	}
+	_, err = s.FindAccountByID(other.ID)
+	if !errors.Is(err, ErrAccountNotFound) {
+		t.Errorf("RestoreAt(): accounts registered after backup must be removed, error = %v", err)
+		return
+	}
+
+	restored, err = m.RestoreLatest()
+	if err != nil || !restored.Equal(backups[2]) {
+		t.Errorf("RestoreLatest(): restored = %v, error = %v", restored, err)
+		return
+	}
+	got, err = s.FindAccountByID(account.ID)
+	if err != nil || got.Balance != 700_00 {
+		t.Errorf("RestoreLatest(): account = %v, error = %v", got, err)
+		return
+	}
+}
+
+func TestBackupManager_RestoreAt_fail(t *testing.T) {
+	s := newTestService()
+	m := NewBackupManager(s.Service, t.TempDir(), 0)
+	_, err := m.RestoreLatest()
+	if !errors.Is(err, ErrBackupNotFound) {
+		t.Errorf("RestoreLatest(): error = %v, want %v", err, ErrBackupNotFound)
+		return
+	}
+	created, err := m.Backup()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, err = m.RestoreAt(created.Add(-time.Second))
+	if !errors.Is(err, ErrBackupNotFound) {
+		t.Errorf("RestoreAt(): error = %v, want %v", err, ErrBackupNotFound)
+		return
+	}
+}
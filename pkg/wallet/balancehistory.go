@@ -0,0 +1,44 @@
+package wallet
+
+import (
+	"time"
+
+	"github.com/sidalsoft/wallet/pkg/types"
+)
+
+// maxBalancePoints ограничивает число точек одного запроса BalanceHistory
+const maxBalancePoints = 10_000
+
+// BalancePoint - баланс счета на момент Time
+type BalancePoint struct {
+	Time    time.Time
+	Balance types.Money
+}
+
+// BalanceHistory возвращает баланс счета на моменты from, from+interval, ... не позже to
+// для графика баланса. Баланс известен с регистрации счета в сервисе или с его импорта,
+// более ранние моменты пропускаются
+func (s *Service) BalanceHistory(accountID int64, from, to time.Time, interval time.Duration) ([]BalancePoint, error) {
+	if interval <= 0 || to.Before(from) || to.Sub(from)/interval >= maxBalancePoints {
+		return nil, ErrInvalidPeriod
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, err := s.findAccountByID(accountID)
+	if err != nil {
+		return nil, err
+	}
+	entries := s.balances[accountID]
+	var points []BalancePoint
+	i := 0
+	for at := from; !at.After(to); at = at.Add(interval) {
+		for i < len(entries) && !entries[i].at.After(at) {
+			i++
+		}
+		if i == 0 {
+			continue
+		}
+		points = append(points, BalancePoint{Time: at, Balance: entries[i-1].balance})
+	}
+	return points, nil
+}
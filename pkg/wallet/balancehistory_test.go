@@ -0,0 +1,70 @@
+package wallet
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestService_BalanceHistory_success(t *testing.T) {
+	s := newTestService()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := start
+	s.now = func() time.Time { return now }
+	account, err := s.addAccountWithBalance("+992928885522", 1_000_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	now = now.Add(90 * time.Minute)
+	_, err = s.Pay(account.ID, 300_00, "auto")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	now = now.Add(2 * time.Hour)
+	err = s.Deposit(account.ID, 50_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	points, err := s.BalanceHistory(account.ID, start.Add(-time.Hour), start.Add(4*time.Hour), time.Hour)
+	want := []BalancePoint{
+		{Time: start, Balance: 1_000_00},
+		{Time: start.Add(time.Hour), Balance: 1_000_00},
+		{Time: start.Add(2 * time.Hour), Balance: 700_00},
+		{Time: start.Add(3 * time.Hour), Balance: 700_00},
+		{Time: start.Add(4 * time.Hour), Balance: 750_00},
+	}
+	if err != nil || !reflect.DeepEqual(points, want) {
+		t.Errorf("BalanceHistory(): points = %v, want %v, error = %v", points, want, err)
+		return
+	}
+}
+
+func TestService_BalanceHistory_fail(t *testing.T) {
+	s := newTestService()
+	now := time.Now()
+	tests := []struct {
+		from, to time.Time
+		interval time.Duration
+	}{
+		{now, now.Add(time.Hour), 0},
+		{now, now.Add(-time.Hour), time.Minute},
+		{now, now.Add(time.Hour), time.Millisecond},
+	}
+	for _, tt := range tests {
+		_, err := s.BalanceHistory(1, tt.from, tt.to, tt.interval)
+		if !errors.Is(err, ErrInvalidPeriod) {
+			t.Errorf("BalanceHistory(%v): error = %v, want %v", tt.interval, err, ErrInvalidPeriod)
+			return
+		}
+	}
+	_, err := s.BalanceHistory(1, now, now.Add(time.Hour), time.Minute)
+	if !errors.Is(err, ErrAccountNotFound) {
+		t.Errorf("BalanceHistory(): error = %v, want %v", err, ErrAccountNotFound)
+		return
+	}
+}
@@ -0,0 +1,217 @@
+package wallet
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sidalsoft/wallet/pkg/types"
+)
+
+// Batch накапливает операции одного счета, которые Commit применяет атомарно:
+// либо все, либо ни одной. Batch не безопасен для одновременного использования
+type Batch struct {
+	svc       *Service
+	accountID int64
+	steps     []batchStep
+	committed bool
+}
+
+type batchStep struct {
+	op       string
+	amount   types.Money
+	category types.PaymentCategory
+	name     string
+}
+
+// BatchResult - записи, созданные Commit, в порядке операций пакета
+type BatchResult struct {
+	Payments  []*types.Payment
+	Favorites []*types.Favorite
+}
+
+// BatchError возвращается Commit, если операция пакета с номером Index не прошла.
+// Ни одна операция пакета при этом не применяется
+type BatchError struct {
+	Index int
+	Op    string
+	Err   error
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("batch operation %d (%s): %v", e.Index, e.Op, e.Err)
+}
+
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
+// BeginBatch начинает пакет операций счета accountID
+func (s *Service) BeginBatch(accountID int64) *Batch {
+	return &Batch{svc: s, accountID: accountID}
+}
+
+// Deposit добавляет в пакет пополнение счета
+func (b *Batch) Deposit(amount types.Money) *Batch {
+	b.steps = append(b.steps, batchStep{op: "Deposit", amount: amount})
+	return b
+}
+
+// Pay добавляет в пакет платеж
+func (b *Batch) Pay(amount types.Money, category types.PaymentCategory) *Batch {
+	b.steps = append(b.steps, batchStep{op: "Pay", amount: amount, category: category})
+	return b
+}
+
+// Favorite добавляет в пакет избранное из последнего платежа пакета
+func (b *Batch) Favorite(name string) *Batch {
+	b.steps = append(b.steps, batchStep{op: "Favorite", name: name})
+	return b
+}
+
+// Commit проверяет и применяет операции пакета под одной блокировкой с одной записью
+// в журнале аудита. События операций доставляются подписчикам OnEvent только после
+// успешного применения всего пакета. Если операция не прошла, изменения предыдущих
+// откатываются и возвращается *BatchError. Побочные эффекты обработчиков
+// RegisterProcessor не откатываются. Пакет применяется не больше одного раза
+func (b *Batch) Commit() (_ BatchResult, err error) {
+	s := b.svc
+	defer s.audit("Batch", &err, "accountID", b.accountID, "operations", len(b.steps))
+	defer s.observe("Batch", time.Now())
+	if b.committed {
+		return BatchResult{}, ErrBatchCommitted
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.commitBatch(b)
+}
+
+func (s *Service) commitBatch(b *Batch) (_ BatchResult, err error) {
+	account, err := s.findAccountByID(b.accountID)
+	if err != nil {
+		return BatchResult{}, err
+	}
+
+	state := s.saveBatchState(account)
+	s.batchEvents = []Event{}
+	defer func() {
+		events := s.batchEvents
+		s.batchEvents = nil
+		if err != nil {
+			state.restore(s)
+			return
+		}
+		for _, event := range events {
+			for _, handler := range s.eventHandlers {
+				handler(event)
+			}
+		}
+	}()
+
+	result := BatchResult{}
+	for i, step := range b.steps {
+		var err error
+		switch step.op {
+		case "Deposit":
+			err = s.deposit(b.accountID, step.amount)
+		case "Pay":
+			var payment *types.Payment
+			payment, err = s.pay(b.accountID, step.amount, step.category)
+			if err == nil {
+				result.Payments = append(result.Payments, payment)
+			}
+		case "Favorite":
+			if len(result.Payments) == 0 {
+				err = ErrPaymentNotFound
+				break
+			}
+			var favorite *types.Favorite
+			favorite, err = s.favoritePayment(result.Payments[len(result.Payments)-1].ID, step.name)
+			if err == nil {
+				result.Favorites = append(result.Favorites, favorite)
+			}
+		}
+		if err != nil {
+			return BatchResult{}, &BatchError{Index: i, Op: step.op, Err: err}
+		}
+	}
+	b.committed = true
+	for i, payment := range result.Payments {
+		result.Payments[i], _ = copyPayment(payment, nil)
+	}
+	for i, favorite := range result.Favorites {
+		result.Favorites[i], _ = copyFavorite(favorite, nil)
+	}
+	return result, nil
+}
+
+// PaymentRequest - платеж пакета PayBatch
+type PaymentRequest struct {
+	Amount   types.Money
+	Category types.PaymentCategory
+}
+
+// PayBatch проводит платежи items со счета accountID атомарно: либо все, либо ни одного.
+// До проведения сумма платежей сверяется с балансом с учетом кредитного лимита,
+// комиссии проверяются при проведении. При ошибке платежа возвращается *BatchError
+func (s *Service) PayBatch(accountID int64, items []PaymentRequest) (_ []*types.Payment, err error) {
+	defer s.audit("PayBatch", &err, "accountID", accountID, "payments", len(items))
+	defer s.observe("PayBatch", time.Now())
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	account, err := s.findAccountByID(accountID)
+	if err != nil {
+		return nil, err
+	}
+	b := s.BeginBatch(accountID)
+	total := types.Money(0)
+	for _, item := range items {
+		if item.Amount <= 0 {
+			return nil, ErrAmountMustBePositive
+		}
+		total += item.Amount
+		b.Pay(item.Amount, item.Category)
+	}
+	if !s.canDebit(account, total) {
+		return nil, ErrNotEnoughBalance
+	}
+	result, err := s.commitBatch(b)
+	if err != nil {
+		return nil, err
+	}
+	return result.Payments, nil
+}
+
+// batchState - состояние, которое меняют операции пакета, для отката
+type batchState struct {
+	accounts  map[*types.Account]types.Account
+	balances  map[int64][]balanceEntry
+	payments  int
+	favorites int
+}
+
+func (s *Service) saveBatchState(account *types.Account) *batchState {
+	state := &batchState{
+		accounts:  map[*types.Account]types.Account{account: *account},
+		balances:  map[int64][]balanceEntry{account.ID: s.balances[account.ID]},
+		payments:  len(s.payments),
+		favorites: len(s.favorites),
+	}
+	feeAccount, err := s.findAccountByID(s.feeAccountID)
+	if err == nil {
+		state.accounts[feeAccount] = *feeAccount
+		state.balances[feeAccount.ID] = s.balances[feeAccount.ID]
+	}
+	return state
+}
+
+func (state *batchState) restore(s *Service) {
+	for account, saved := range state.accounts {
+		*account = saved
+	}
+	for accountID, entries := range state.balances {
+		s.balances[accountID] = entries
+	}
+	s.payments = s.payments[:state.payments]
+	s.paymentTimes.reset()
+	s.favorites = s.favorites[:state.favorites]
+}
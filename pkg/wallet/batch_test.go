@@ -0,0 +1,152 @@
+package wallet
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestBatch_Commit_success(t *testing.T) {
+	s := newTestService()
+	account, err := s.RegisterAccount("+992928885522")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	var events []Event
+	s.OnEvent(func(event Event) {
+		events = append(events, event)
+	})
+
+	batch := s.BeginBatch(account.ID).
+		Deposit(1_000_00).
+		Pay(100_00, "auto").
+		Favorite("Бензин").
+		Pay(200_00, "food")
+	result, err := batch.Commit()
+	if err != nil {
+		t.Errorf("Commit(): error = %v", err)
+		return
+	}
+	if len(result.Payments) != 2 || len(result.Favorites) != 1 || result.Favorites[0].Amount != 100_00 {
+		t.Errorf("Commit(): result = %v", result)
+		return
+	}
+	account, err = s.FindAccountByID(account.ID)
+	if err != nil || account.Balance != 700_00 {
+		t.Errorf("Commit(): account = %v, error = %v", account, err)
+		return
+	}
+	if len(events) == 0 {
+		t.Errorf("Commit(): events must be delivered after commit")
+		return
+	}
+
+	_, err = batch.Commit()
+	if !errors.Is(err, ErrBatchCommitted) {
+		t.Errorf("Commit(): error = %v, want %v", err, ErrBatchCommitted)
+		return
+	}
+}
+
+func TestBatch_Commit_fail(t *testing.T) {
+	s := newTestService()
+	account, err := s.addAccountWithBalance("+992928885522", 1_000_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	var events []Event
+	s.OnEvent(func(event Event) {
+		events = append(events, event)
+	})
+
+	_, err = s.BeginBatch(account.ID).
+		Pay(600_00, "auto").
+		Favorite("Бензин").
+		Pay(600_00, "auto").
+		Commit()
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) || batchErr.Index != 2 || !errors.Is(err, ErrNotEnoughBalance) {
+		t.Errorf("Commit(): error = %v, want *BatchError at 2 with %v", err, ErrNotEnoughBalance)
+		return
+	}
+	account, err = s.FindAccountByID(account.ID)
+	if err != nil || account.Balance != 1_000_00 {
+		t.Errorf("Commit(): balance must be restored, account = %v, error = %v", account, err)
+		return
+	}
+	payments, err := s.ExportAccountHistory(account.ID)
+	if len(payments) != 0 || len(events) != 0 {
+		t.Errorf("Commit(): payments = %v, events = %v, error = %v", payments, events, err)
+		return
+	}
+
+	_, err = s.BeginBatch(account.ID).Favorite("Бензин").Commit()
+	if !errors.Is(err, ErrPaymentNotFound) {
+		t.Errorf("Commit(): error = %v, want %v", err, ErrPaymentNotFound)
+		return
+	}
+	_, err = s.BeginBatch(404).Pay(1, "auto").Commit()
+	if !errors.Is(err, ErrAccountNotFound) {
+		t.Errorf("Commit(): error = %v, want %v", err, ErrAccountNotFound)
+		return
+	}
+}
+
+func TestService_PayBatch_success(t *testing.T) {
+	s := newTestService()
+	account, err := s.addAccountWithBalance("+992928885522", 1_000_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	payments, err := s.PayBatch(account.ID, []PaymentRequest{
+		{Amount: 300_00, Category: "salary"},
+		{Amount: 700_00, Category: "vendor"},
+	})
+	if err != nil || len(payments) != 2 || payments[1].Category != "vendor" {
+		t.Errorf("PayBatch(): payments = %v, error = %v", payments, err)
+		return
+	}
+	account, err = s.FindAccountByID(account.ID)
+	if err != nil || account.Balance != 0 {
+		t.Errorf("PayBatch(): account = %v, error = %v", account, err)
+		return
+	}
+}
+
+func TestService_PayBatch_fail(t *testing.T) {
+	s := newTestService()
+	account, err := s.addAccountWithBalance("+992928885522", 1_000_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, err = s.PayBatch(account.ID, []PaymentRequest{
+		{Amount: 600_00, Category: "salary"},
+		{Amount: 600_00, Category: "salary"},
+	})
+	if !errors.Is(err, ErrNotEnoughBalance) {
+		t.Errorf("PayBatch(): must return ErrNotEnoughBalance, returned = %v", err)
+		return
+	}
+	err = s.AddBlockRule("category == 'casino'")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, err = s.PayBatch(account.ID, []PaymentRequest{
+		{Amount: 100_00, Category: "salary"},
+		{Amount: 100_00, Category: "casino"},
+	})
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) || batchErr.Index != 1 || !errors.Is(err, ErrPaymentBlocked) {
+		t.Errorf("PayBatch(): error = %v, want *BatchError at 1 with %v", err, ErrPaymentBlocked)
+		return
+	}
+	account, err = s.FindAccountByID(account.ID)
+	if err != nil || account.Balance != 1_000_00 {
+		t.Errorf("PayBatch(): balance must be restored, account = %v, error = %v", account, err)
+		return
+	}
+}
@@ -0,0 +1,118 @@
+package wallet
+
+import (
+	"sort"
+	"time"
+
+	"github.com/sidalsoft/wallet/pkg/types"
+)
+
+// BudgetMode задает, что делает Pay с платежом, превышающим бюджет
+type BudgetMode int
+
+// Режимы бюджетов, по умолчанию BudgetWarn
+const (
+	// BudgetWarn проводит платеж и сообщает о превышении событием EventBudgetExceeded
+	BudgetWarn BudgetMode = iota
+	// BudgetEnforce отклоняет платеж с ErrBudgetExceeded
+	BudgetEnforce
+)
+
+// WithBudgetMode задает режим бюджетов
+func WithBudgetMode(mode BudgetMode) Option {
+	return func(s *Service) {
+		s.budgetMode = mode
+	}
+}
+
+// Budget - бюджет счета на платежи категории и ее подкатегорий за скользящий период
+type Budget struct {
+	Category types.PaymentCategory
+	Period   LimitPeriod
+	Amount   types.Money
+}
+
+// BudgetUsage - бюджет и неотклоненные платежи по нему за период, заканчивающийся сейчас.
+// Remaining отрицателен, если бюджет превышен
+type BudgetUsage struct {
+	Budget
+	Spent     types.Money
+	Remaining types.Money
+}
+
+// SetBudget задает бюджет счета на категорию. У категории один бюджет, новый заменяет
+// прежний. Нулевая сумма снимает бюджет
+func (s *Service) SetBudget(accountID int64, category types.PaymentCategory, period LimitPeriod, amount types.Money) (err error) {
+	defer s.audit("SetBudget", &err, "accountID", accountID, "category", category, "period", period, "amount", amount)
+	if category == "" || amount < 0 || (period != LimitDaily && period != LimitMonthly) {
+		return ErrInvalidBudget
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.findAccountByID(accountID)
+	if err != nil {
+		return err
+	}
+	if amount == 0 {
+		delete(s.budgets[accountID], category)
+		return nil
+	}
+	if s.budgets == nil {
+		s.budgets = make(map[int64]map[types.PaymentCategory]Budget)
+	}
+	if s.budgets[accountID] == nil {
+		s.budgets[accountID] = make(map[types.PaymentCategory]Budget)
+	}
+	s.budgets[accountID][category] = Budget{Category: category, Period: period, Amount: amount}
+	return nil
+}
+
+// BudgetStatus возвращает бюджеты счета, упорядоченные по категории
+func (s *Service) BudgetStatus(accountID int64) ([]BudgetUsage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, err := s.findAccountByID(accountID)
+	if err != nil {
+		return nil, err
+	}
+	now := s.clock()
+	var usages []BudgetUsage
+	for _, budget := range s.budgets[accountID] {
+		spent := s.spentOnCategory(accountID, budget.Category, periodStart(budget.Period, now))
+		usages = append(usages, BudgetUsage{Budget: budget, Spent: spent, Remaining: budget.Amount - spent})
+	}
+	sort.Slice(usages, func(i, j int) bool {
+		return usages[i].Category < usages[j].Category
+	})
+	return usages, nil
+}
+
+// checkBudgets сообщает, превысит ли платеж один из бюджетов счета. В режиме
+// BudgetEnforce превышение возвращается ошибкой ErrBudgetExceeded
+func (s *Service) checkBudgets(payment *types.Payment) (bool, error) {
+	for _, budget := range s.budgets[payment.AccountID] {
+		if !payment.Category.Within(budget.Category) {
+			continue
+		}
+		spent := s.spentOnCategory(payment.AccountID, budget.Category, periodStart(budget.Period, payment.Created))
+		if spent+payment.Amount <= budget.Amount {
+			continue
+		}
+		if s.budgetMode == BudgetEnforce {
+			return true, ErrBudgetExceeded
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+func (s *Service) spentOnCategory(accountID int64, category types.PaymentCategory, from time.Time) types.Money {
+	sum := types.Money(0)
+	for _, payment := range s.payments {
+		if payment.AccountID == accountID && payment.Status != types.PaymentStatusFail &&
+			payment.Category.Within(category) && payment.Created.After(from) && isSpending(payment) {
+			sum += payment.Amount
+		}
+	}
+	return sum
+}
@@ -0,0 +1,83 @@
+package wallet
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestService_SetBudget_success(t *testing.T) {
+	s := newTestService()
+	account, err := s.addAccountWithBalance("+992928885522", 1_000_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	var exceeded []Event
+	s.OnEvent(func(event Event) {
+		if event.Kind == EventBudgetExceeded {
+			exceeded = append(exceeded, event)
+		}
+	})
+	err = s.SetBudget(account.ID, "food", LimitMonthly, 100_00)
+	if err != nil {
+		t.Errorf("SetBudget(): error = %v", err)
+		return
+	}
+
+	_, err = s.Pay(account.ID, 60_00, "food/cafe")
+	if err != nil || len(exceeded) != 0 {
+		t.Errorf("Pay(): events = %v, error = %v", exceeded, err)
+		return
+	}
+	payment, err := s.Pay(account.ID, 50_00, "food")
+	if err != nil || len(exceeded) != 1 || exceeded[0].Payment.ID != payment.ID {
+		t.Errorf("Pay(): budget warning not emitted, events = %v, error = %v", exceeded, err)
+		return
+	}
+	_, err = s.Pay(account.ID, 50_00, "auto")
+	if err != nil || len(exceeded) != 1 {
+		t.Errorf("Pay(): other category must not count, events = %v, error = %v", exceeded, err)
+		return
+	}
+
+	usages, err := s.BudgetStatus(account.ID)
+	if err != nil || len(usages) != 1 || usages[0].Spent != 110_00 || usages[0].Remaining != -10_00 {
+		t.Errorf("BudgetStatus(): usages = %v, error = %v", usages, err)
+		return
+	}
+}
+
+func TestService_SetBudget_fail(t *testing.T) {
+	s := newTestService()
+	s.budgetMode = BudgetEnforce
+	account, err := s.addAccountWithBalance("+992928885522", 1_000_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = s.SetBudget(account.ID, "food", "YEARLY", 100_00)
+	if !errors.Is(err, ErrInvalidBudget) {
+		t.Errorf("SetBudget(): error = %v, want %v", err, ErrInvalidBudget)
+		return
+	}
+	err = s.SetBudget(account.ID+1, "food", LimitDaily, 100_00)
+	if !errors.Is(err, ErrAccountNotFound) {
+		t.Errorf("SetBudget(): error = %v, want %v", err, ErrAccountNotFound)
+		return
+	}
+	err = s.SetBudget(account.ID, "food", LimitDaily, 100_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, err = s.Pay(account.ID, 150_00, "food/cafe")
+	if !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("Pay(): error = %v, want %v", err, ErrBudgetExceeded)
+		return
+	}
+	got, _ := s.FindAccountByID(account.ID)
+	if got.Balance != 1_000_00 {
+		t.Errorf("Pay(): rejected payment changed balance, account = %v", got)
+		return
+	}
+}
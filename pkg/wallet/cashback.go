@@ -0,0 +1,108 @@
+package wallet
+
+import (
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/sidalsoft/wallet/pkg/types"
+)
+
+// CashbackOperation - вид операции, которой записывается кешбэк за платеж.
+// Payload операции - сумма кешбэка в минимальных единицах
+const CashbackOperation = "cashback"
+
+// Cashback задает кешбэк за платежи категории в базисных пунктах от суммы платежа (100 = 1%)
+// и его предел за календарный месяц для счета. Нулевой MonthlyCap - без предела
+type Cashback struct {
+	BasisPoints int64
+	MonthlyCap  types.Money
+}
+
+// SetCashback назначает кешбэк категории, он действует и для ее подкатегорий, если для них
+// не задан свой. Нулевой кешбэк снимает его
+func (s *Service) SetCashback(category types.PaymentCategory, cashback Cashback) (err error) {
+	defer s.audit("SetCashback", &err, "category", category, "basisPoints", cashback.BasisPoints, "monthlyCap", cashback.MonthlyCap)
+	if category == "" || cashback.BasisPoints < 0 || cashback.MonthlyCap < 0 {
+		return ErrInvalidCashback
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cashback == (Cashback{}) {
+		delete(s.cashback, category)
+		return nil
+	}
+	if s.cashback == nil {
+		s.cashback = make(map[types.PaymentCategory]Cashback)
+	}
+	s.cashback[category] = cashback
+	return nil
+}
+
+// creditCashback зачисляет плательщику кешбэк за завершенный платеж по правилу его категории
+// или ближайшей родительской и записывает его операцией вида CashbackOperation со ссылкой
+// на платеж. Кешбэк уменьшается до остатка месячного предела
+func (s *Service) creditCashback(account *types.Account, payment *types.Payment) {
+	category := payment.Category
+	cashback, ok := s.cashback[category]
+	for !ok && category != "" {
+		category = category.Parent()
+		cashback, ok = s.cashback[category]
+	}
+	if !ok || cashback.BasisPoints == 0 {
+		return
+	}
+	amount := types.Money(round(int64(payment.Amount)*cashback.BasisPoints, 10_000, RoundDown))
+	if cashback.MonthlyCap > 0 {
+		left := cashback.MonthlyCap - s.monthCashback(account.ID, category, payment)
+		if amount > left {
+			amount = left
+		}
+	}
+	if amount <= 0 {
+		return
+	}
+	s.changeBalance(account, amount)
+	operation := &types.Operation{
+		ID:        uuid.New().String(),
+		Kind:      CashbackOperation,
+		AccountID: account.ID,
+		PaymentID: payment.ID,
+		Payload:   []byte(strconv.FormatInt(int64(amount), 10)),
+	}
+	s.operations = append(s.operations, operation)
+	s.markOperation(operation.ID)
+}
+
+// monthCashback возвращает кешбэк счета за платежи категории category того же
+// календарного месяца, что и payment
+func (s *Service) monthCashback(accountID int64, category types.PaymentCategory, payment *types.Payment) types.Money {
+	year, month, _ := payment.Created.Date()
+	sum := types.Money(0)
+	for _, operation := range s.operations {
+		if operation.Kind != CashbackOperation || operation.AccountID != accountID {
+			continue
+		}
+		paid, err := s.findPaymentByID(operation.PaymentID)
+		if err != nil || !paid.Category.Within(category) {
+			continue
+		}
+		y, m, _ := paid.Created.Date()
+		if y == year && m == month {
+			amount, _ := strconv.ParseInt(string(operation.Payload), 10, 64)
+			sum += types.Money(amount)
+		}
+	}
+	return sum
+}
+
+// FindCashbackByPaymentID возвращает операцию кешбэка за платеж paymentID
+func (s *Service) FindCashbackByPaymentID(paymentID string) (*types.Operation, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, operation := range s.operations {
+		if operation.Kind == CashbackOperation && operation.PaymentID == paymentID {
+			return copyOperation(operation, nil)
+		}
+	}
+	return nil, ErrOperationNotFound
+}
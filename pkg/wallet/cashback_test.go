@@ -0,0 +1,119 @@
+package wallet
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sidalsoft/wallet/pkg/types"
+)
+
+func TestService_SetCashback_success(t *testing.T) {
+	s := newTestService()
+	now := time.Date(2024, 1, 20, 0, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	account, err := s.addAccountWithBalance("+992928885522", 10_000_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = s.SetCashback("food", Cashback{BasisPoints: 500, MonthlyCap: 30_00})
+	if err != nil {
+		t.Errorf("SetCashback(): error = %v", err)
+		return
+	}
+
+	payment, err := s.Pay(account.ID, 400_00, "food/cafe")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = s.Confirm(payment.ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	got, err := s.FindAccountByID(account.ID)
+	if err != nil || got.Balance != 9_620_00 {
+		t.Errorf("Confirm(): cashback not credited, account = %v, error = %v", got, err)
+		return
+	}
+	operation, err := s.FindCashbackByPaymentID(payment.ID)
+	if err != nil || operation.AccountID != account.ID || string(operation.Payload) != "2000" {
+		t.Errorf("FindCashbackByPaymentID(): operation = %v, error = %v", operation, err)
+		return
+	}
+
+	payment, err = s.Pay(account.ID, 400_00, "food")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = s.Confirm(payment.ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	got, err = s.FindAccountByID(account.ID)
+	if err != nil || got.Balance != 9_230_00 {
+		t.Errorf("Confirm(): cashback must be capped, account = %v, error = %v", got, err)
+		return
+	}
+
+	now = now.AddDate(0, 1, 0)
+	payment, err = s.Pay(account.ID, 100_00, "food")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = s.Confirm(payment.ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	got, err = s.FindAccountByID(account.ID)
+	if err != nil || got.Balance != 9_135_00 {
+		t.Errorf("Confirm(): cap must reset next month, account = %v, error = %v", got, err)
+		return
+	}
+
+	err = s.SetCashback("food", Cashback{})
+	if err != nil {
+		t.Errorf("SetCashback(): error = %v", err)
+		return
+	}
+	payment, err = s.Pay(account.ID, 100_00, "food")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = s.Confirm(payment.ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, err = s.FindCashbackByPaymentID(payment.ID)
+	if !errors.Is(err, ErrOperationNotFound) {
+		t.Errorf("FindCashbackByPaymentID(): removed cashback credited, error = %v", err)
+		return
+	}
+}
+
+func TestService_SetCashback_fail(t *testing.T) {
+	s := newTestService()
+	tests := []struct {
+		category string
+		cashback Cashback
+	}{
+		{"", Cashback{BasisPoints: 100}},
+		{"food", Cashback{BasisPoints: -1}},
+		{"food", Cashback{BasisPoints: 100, MonthlyCap: -1}},
+	}
+	for _, tt := range tests {
+		err := s.SetCashback(types.PaymentCategory(tt.category), tt.cashback)
+		if !errors.Is(err, ErrInvalidCashback) {
+			t.Errorf("SetCashback(%q, %v): error = %v, want %v", tt.category, tt.cashback, err, ErrInvalidCashback)
+			return
+		}
+	}
+}
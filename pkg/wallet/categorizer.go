@@ -0,0 +1,73 @@
+package wallet
+
+import "github.com/sidalsoft/wallet/pkg/types"
+
+// Categorizer предлагает категорию платежа, когда Pay вызван с пустой категорией.
+// history - подтвержденные платежи того же счета; у платежей, где InferredCategory
+// отличается от Category, пользователь исправил предложенную категорию
+type Categorizer interface {
+	Categorize(payment types.Payment, history []types.Payment) types.PaymentCategory
+}
+
+// CategorizerFunc позволяет использовать функцию как Categorizer
+type CategorizerFunc func(payment types.Payment, history []types.Payment) types.PaymentCategory
+
+func (f CategorizerFunc) Categorize(payment types.Payment, history []types.Payment) types.PaymentCategory {
+	return f(payment, history)
+}
+
+// SetCategorizer назначает Categorizer сервису. nil отключает подбор категорий
+func (s *Service) SetCategorizer(categorizer Categorizer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.categorizer = categorizer
+}
+
+// OverrideCategory заменяет подобранную категорию неподтвержденного платежа.
+// Предложенная категория остается в InferredCategory, комиссия пересчитывается
+func (s *Service) OverrideCategory(paymentID string, category types.PaymentCategory) (err error) {
+	defer s.audit("OverrideCategory", &err, "paymentID", paymentID, "category", category)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	payment, err := s.findPaymentByID(paymentID)
+	if err != nil {
+		return err
+	}
+	if payment.InferredCategory == "" {
+		return ErrCategoryNotInferred
+	}
+	if payment.Status != types.PaymentStatusInProgress {
+		return ErrInvalidPaymentStatus
+	}
+	account, err := s.findAccountByID(payment.AccountID)
+	if err != nil {
+		return err
+	}
+	env := ruleEnv(account, payment)
+	env.Category = category
+	fee := s.fee(&env)
+	if !s.canDebit(account, fee-payment.Fee) {
+		return ErrNotEnoughBalance
+	}
+	s.changeBalance(account, payment.Fee-fee)
+	s.creditFee(fee - payment.Fee)
+	payment.Category = category
+	payment.Fee = fee
+	s.markPayment(payment.ID)
+	return nil
+}
+
+// categorize подбирает категорию черновику платежа с пустой категорией
+func (s *Service) categorize(payment *types.Payment) {
+	if payment.Category != "" || s.categorizer == nil {
+		return
+	}
+	var history []types.Payment
+	for _, p := range s.payments {
+		if p.AccountID == payment.AccountID && p.Status == types.PaymentStatusOk {
+			history = append(history, *p)
+		}
+	}
+	payment.Category = s.categorizer.Categorize(*payment, history)
+	payment.InferredCategory = payment.Category
+}
@@ -0,0 +1,101 @@
+package wallet
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/sidalsoft/wallet/pkg/types"
+)
+
+// lastCategory предлагает категорию последнего подтвержденного платежа на ту же сумму
+var lastCategory = CategorizerFunc(func(payment types.Payment, history []types.Payment) types.PaymentCategory {
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].Amount == payment.Amount {
+			return history[i].Category
+		}
+	}
+	return "other"
+})
+
+func TestService_Pay_categorized(t *testing.T) {
+	s := newTestService()
+	s.SetCategorizer(lastCategory)
+	account, err := s.addAccountWithBalance("+992928885522", 100_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	first, err := s.Pay(account.ID, 10_00, "")
+	if err != nil {
+		t.Errorf("Pay(): error = %v", err)
+		return
+	}
+	if first.Category != "other" || first.InferredCategory != "other" {
+		t.Errorf("Pay(): wrong inferred category, payment = %+v", first)
+		return
+	}
+	err = s.OverrideCategory(first.ID, "food")
+	if err != nil {
+		t.Errorf("OverrideCategory(): error = %v", err)
+		return
+	}
+	err = s.Confirm(first.ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	second, err := s.Pay(account.ID, 10_00, "")
+	if err != nil {
+		t.Errorf("Pay(): error = %v", err)
+		return
+	}
+	if second.Category != "food" || second.InferredCategory != "food" {
+		t.Errorf("Pay(): must learn overridden category, payment = %+v", second)
+		return
+	}
+	explicit, err := s.Pay(account.ID, 10_00, "auto")
+	if err != nil {
+		t.Errorf("Pay(): error = %v", err)
+		return
+	}
+	if explicit.Category != "auto" || explicit.InferredCategory != "" {
+		t.Errorf("Pay(): must keep explicit category, payment = %+v", explicit)
+		return
+	}
+}
+
+func TestService_OverrideCategory_fail(t *testing.T) {
+	s := newTestService()
+	s.SetCategorizer(lastCategory)
+	account, err := s.addAccountWithBalance("+992928885522", 100_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	explicit, err := s.Pay(account.ID, 10_00, "auto")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = s.OverrideCategory(explicit.ID, "food")
+	if !errors.Is(err, ErrCategoryNotInferred) {
+		t.Errorf("OverrideCategory(): must return ErrCategoryNotInferred, returned = %v", err)
+		return
+	}
+	inferred, err := s.Pay(account.ID, 10_00, "")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = s.Confirm(inferred.ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = s.OverrideCategory(inferred.ID, "food")
+	if !errors.Is(err, ErrInvalidPaymentStatus) {
+		t.Errorf("OverrideCategory(): must return ErrInvalidPaymentStatus, returned = %v", err)
+		return
+	}
+}
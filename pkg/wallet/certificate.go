@@ -0,0 +1,123 @@
+package wallet
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/sidalsoft/wallet/pkg/types"
+)
+
+// BalanceCertificate подтверждает баланс счета на момент Date
+type BalanceCertificate struct {
+	AccountID int64          `json:"accountId"`
+	Phone     types.Phone    `json:"phone"`
+	Currency  types.Currency `json:"currency"`
+	Balance   types.Money    `json:"balance"`
+	Date      time.Time      `json:"date"`
+	Issued    time.Time      `json:"issued"`
+}
+
+type balanceEntry struct {
+	at      time.Time
+	balance types.Money
+}
+
+// defaultBalanceLimit - число хранимых изменений баланса одного счета по умолчанию
+const defaultBalanceLimit = 10_000
+
+// WithBalanceHistoryLimit задает, сколько последних изменений баланса каждого счета
+// хранится для справок о балансе и BalanceHistory, по умолчанию defaultBalanceLimit.
+// История не входит в дампы: после перезапуска и Import она начинается заново с момента импорта
+func WithBalanceHistoryLimit(limit int) Option {
+	return func(s *Service) {
+		s.balanceLimit = limit
+	}
+}
+
+// WithSigningKey задает ключ, которым подписываются справки о балансе
+func WithSigningKey(key ed25519.PrivateKey) Option {
+	return func(s *Service) {
+		s.signingKey = key
+	}
+}
+
+// GenerateBalanceCertificate возвращает справку о балансе счета на момент date в JSON
+// и отдельную подпись ed25519. Баланс известен с регистрации счета в сервисе
+// или с его импорта, для более ранних дат возвращается ErrBalanceUnknown
+func (s *Service) GenerateBalanceCertificate(accountID int64, date time.Time) (document []byte, signature []byte, err error) {
+	defer s.audit("GenerateBalanceCertificate", &err, "accountID", accountID, "date", date.Unix())
+	if s.signingKey == nil {
+		return nil, nil, ErrNoSigningKey
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	account, err := s.findAccountByID(accountID)
+	if err != nil {
+		return nil, nil, err
+	}
+	now := s.clock()
+	if date.After(now) {
+		return nil, nil, ErrBalanceUnknown
+	}
+	balance, err := s.balanceAt(accountID, date)
+	if err != nil {
+		return nil, nil, err
+	}
+	document, err = json.Marshal(BalanceCertificate{
+		AccountID: account.ID,
+		Phone:     account.Phone,
+		Currency:  account.Currency,
+		Balance:   balance,
+		Date:      date.UTC(),
+		Issued:    now,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return document, ed25519.Sign(s.signingKey, document), nil
+}
+
+// VerifyBalanceCertificate проверяет подпись справки открытым ключом сервиса
+// и возвращает ее содержимое
+func VerifyBalanceCertificate(publicKey ed25519.PublicKey, document []byte, signature []byte) (*BalanceCertificate, error) {
+	if len(publicKey) != ed25519.PublicKeySize || !ed25519.Verify(publicKey, document, signature) {
+		return nil, ErrInvalidSignature
+	}
+	certificate := &BalanceCertificate{}
+	err := json.Unmarshal(document, certificate)
+	if err != nil {
+		return nil, err
+	}
+	return certificate, nil
+}
+
+// recordBalance запоминает текущий баланс счета для справок о балансе. Когда записей
+// становится больше предела, самые старые отбрасываются в новый срез, чтобы не трогать
+// срезы, сохраненные для отката пакета
+func (s *Service) recordBalance(account *types.Account) {
+	if s.balances == nil {
+		s.balances = make(map[int64][]balanceEntry)
+	}
+	entries := append(s.balances[account.ID], balanceEntry{at: s.clock(), balance: account.Balance})
+	limit := s.balanceLimit
+	if limit <= 0 {
+		limit = defaultBalanceLimit
+	}
+	if len(entries) > limit {
+		entries = append([]balanceEntry(nil), entries[len(entries)-limit:]...)
+	}
+	s.balances[account.ID] = entries
+}
+
+func (s *Service) balanceAt(accountID int64, at time.Time) (types.Money, error) {
+	entries := s.balances[accountID]
+	i := sort.Search(len(entries), func(i int) bool {
+		return entries[i].at.After(at)
+	})
+	if i == 0 {
+		return 0, ErrBalanceUnknown
+	}
+	return entries[i-1].balance, nil
+}
@@ -0,0 +1,168 @@
+package wallet
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestService_GenerateBalanceCertificate_success(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewService(WithSigningKey(privateKey))
+	now := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+
+	account, err := s.RegisterAccount("+992928885522")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = s.Deposit(account.ID, 100_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	date := now.Add(time.Hour)
+	now = now.AddDate(0, 0, 1)
+	_, err = s.Pay(account.ID, 30_00, "auto")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	document, signature, err := s.GenerateBalanceCertificate(account.ID, date)
+	if err != nil {
+		t.Errorf("GenerateBalanceCertificate(): error = %v", err)
+		return
+	}
+	certificate, err := VerifyBalanceCertificate(publicKey, document, signature)
+	if err != nil {
+		t.Errorf("VerifyBalanceCertificate(): error = %v", err)
+		return
+	}
+	if certificate.AccountID != account.ID || certificate.Balance != 100_00 || !certificate.Date.Equal(date) {
+		t.Errorf("GenerateBalanceCertificate(): wrong certificate = %+v", certificate)
+		return
+	}
+
+	document[len(document)-2] ^= 1
+	_, err = VerifyBalanceCertificate(publicKey, document, signature)
+	if !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("VerifyBalanceCertificate(): must return ErrInvalidSignature, returned = %v", err)
+		return
+	}
+}
+
+func TestService_GenerateBalanceCertificate_fail(t *testing.T) {
+	s := newTestService()
+	account, err := s.addAccountWithBalance("+992928885522", 100_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, _, err = s.GenerateBalanceCertificate(account.ID, time.Now())
+	if !errors.Is(err, ErrNoSigningKey) {
+		t.Errorf("GenerateBalanceCertificate(): must return ErrNoSigningKey, returned = %v", err)
+		return
+	}
+
+	_, privateKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.signingKey = privateKey
+	_, _, err = s.GenerateBalanceCertificate(account.ID, account.Registered.Add(-time.Hour))
+	if !errors.Is(err, ErrBalanceUnknown) {
+		t.Errorf("GenerateBalanceCertificate(): must return ErrBalanceUnknown, returned = %v", err)
+		return
+	}
+}
+
+func TestService_recordBalance_limit(t *testing.T) {
+	s := NewService(WithBalanceHistoryLimit(3))
+	start := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	now := start
+	s.now = func() time.Time { return now }
+	account, err := s.RegisterAccount("+992928885522")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	for i := 0; i < 5; i++ {
+		now = now.Add(time.Hour)
+		err = s.Deposit(account.ID, 10_00)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+	}
+	if len(s.balances[account.ID]) != 3 {
+		t.Errorf("recordBalance(): entries = %v, want 3", s.balances[account.ID])
+		return
+	}
+	_, err = s.balanceAt(account.ID, start.Add(time.Hour))
+	if !errors.Is(err, ErrBalanceUnknown) {
+		t.Errorf("balanceAt(): error = %v, want %v", err, ErrBalanceUnknown)
+		return
+	}
+	balance, err := s.balanceAt(account.ID, now)
+	if err != nil || balance != 50_00 {
+		t.Errorf("balanceAt(): balance = %v, error = %v", balance, err)
+		return
+	}
+}
+
+func TestService_GenerateBalanceCertificate_afterImport(t *testing.T) {
+	_, privateKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewService(WithSigningKey(privateKey))
+	now := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	account, err := s.RegisterAccount("+992928885522")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = s.Deposit(account.ID, 100_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	dir := t.TempDir()
+	err = s.Export(dir)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	// история балансов не входит в дамп и начинается заново с момента импорта
+	imported := NewService(WithSigningKey(privateKey))
+	importedAt := now.AddDate(0, 0, 1)
+	imported.now = func() time.Time { return importedAt }
+	err = imported.Import(dir)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, _, err = imported.GenerateBalanceCertificate(account.ID, now.Add(time.Hour))
+	if !errors.Is(err, ErrBalanceUnknown) {
+		t.Errorf("GenerateBalanceCertificate(): error = %v, want %v", err, ErrBalanceUnknown)
+		return
+	}
+	document, _, err := imported.GenerateBalanceCertificate(account.ID, importedAt)
+	if err != nil {
+		t.Errorf("GenerateBalanceCertificate(): error = %v", err)
+		return
+	}
+	certificate, err := VerifyBalanceCertificate(privateKey.Public().(ed25519.PublicKey), document, ed25519.Sign(privateKey, document))
+	if err != nil || certificate.Balance != 100_00 {
+		t.Errorf("GenerateBalanceCertificate(): certificate = %v, error = %v", certificate, err)
+		return
+	}
+}
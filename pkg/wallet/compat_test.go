@@ -0,0 +1,72 @@
+package wallet
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/sidalsoft/wallet/pkg/types"
+)
+
+// Каждый каталог testdata/compat содержит дамп одного и того же состояния,
+// записанный очередной версией формата. При изменении формата добавляется
+// новый каталог, старые не меняются.
+var compatAccounts = []types.Account{
+	{ID: 1, Phone: "+992900000001", Balance: 900_00, Currency: "TJS", Status: types.AccountStatusActive, Type: types.AccountTypePersonal},
+	{ID: 2, Phone: "+992900000002", Balance: 0, Currency: "TJS", Status: types.AccountStatusActive, Type: types.AccountTypePersonal},
+}
+
+var compatPayments = []types.Payment{
+	{ID: "6c1f2b4e-3d5a-4f8e-9b7c-1a2b3c4d5e6f", AccountID: 1, Amount: 100_00, Currency: "TJS", Category: "auto", Status: types.PaymentStatusInProgress},
+}
+
+var compatFavorites = []types.Favorite{
+	{ID: "f0e1d2c3-b4a5-4968-8776-655443322110", AccountID: 1, Name: "car", Amount: 100_00, Category: "auto"},
+}
+
+func TestService_Import_compat(t *testing.T) {
+	dirs, err := ioutil.ReadDir("testdata/compat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, dir := range dirs {
+		path := filepath.Join("testdata/compat", dir.Name())
+		t.Run(dir.Name(), func(t *testing.T) {
+			s := &Service{}
+			err := s.Import(path)
+			if err != nil {
+				t.Errorf("Import(): error = %v", err)
+				return
+			}
+
+			var accounts []types.Account
+			for _, account := range s.accounts {
+				accounts = append(accounts, *account)
+			}
+			if !reflect.DeepEqual(accounts, compatAccounts) {
+				t.Errorf("Import(): accounts = %v, want = %v", accounts, compatAccounts)
+			}
+
+			var payments []types.Payment
+			for _, payment := range s.payments {
+				payments = append(payments, *payment)
+			}
+			if !reflect.DeepEqual(payments, compatPayments) {
+				t.Errorf("Import(): payments = %v, want = %v", payments, compatPayments)
+			}
+
+			var favorites []types.Favorite
+			for _, favorite := range s.favorites {
+				favorites = append(favorites, *favorite)
+			}
+			if !reflect.DeepEqual(favorites, compatFavorites) {
+				t.Errorf("Import(): favorites = %v, want = %v", favorites, compatFavorites)
+			}
+
+			if s.nextAccountID != 2 {
+				t.Errorf("Import(): nextAccountID = %v, want = 2", s.nextAccountID)
+			}
+		})
+	}
+}
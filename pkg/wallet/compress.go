@@ -0,0 +1,84 @@
+package wallet
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+// ExportCompressed работает как Export, но сжимает дампы gzip и пишет их в файлы .dump.gz.
+// Import распознает сжатые дампы сам
+func (s *Service) ExportCompressed(dir string, options ...ExportOption) error {
+	defer s.observe("Export", time.Now())
+	return s.export(dir, true, newExportConfig(options))
+}
+
+// writeDump записывает дамп name, сжимая и шифруя его при необходимости,
+// и удаляет дамп того же имени в другом формате, чтобы Import не прочел устаревший.
+// Возвращает имя записанного файла и его контрольную сумму для манифеста
+func (s *Service) writeDump(dir string, name string, data string, compressed bool) (string, string, error) {
+	data = addDumpHeader(data)
+	file, stale := name+".dump", name+".dump.gz"
+	if compressed {
+		file, stale = stale, file
+		var buf bytes.Buffer
+		writer := gzip.NewWriter(&buf)
+		_, err := writer.Write([]byte(data))
+		if err == nil {
+			err = writer.Close()
+		}
+		if err != nil {
+			return "", "", err
+		}
+		data = buf.String()
+	}
+	sealed, err := s.seal(name, data)
+	if err != nil {
+		return "", "", err
+	}
+	err = writeFileAtomic(dir+"/"+file, sealed)
+	if err != nil {
+		return "", "", err
+	}
+	err = os.Remove(dir + "/" + stale)
+	if err != nil && !os.IsNotExist(err) {
+		return "", "", err
+	}
+	return file, checksum([]byte(sealed)), nil
+}
+
+// readDump читает дамп name из .dump или .dump.gz. Отсутствующий дамп считается пустым,
+// остальные ошибки чтения возвращаются
+func (s *Service) readDump(dir string, name string) (string, error) {
+	compressed := false
+	data, err := ioutil.ReadFile(dir + "/" + name + ".dump")
+	if os.IsNotExist(err) {
+		compressed = true
+		data, err = ioutil.ReadFile(dir + "/" + name + ".dump.gz")
+	}
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	plain, err := s.open(name, data)
+	if err != nil {
+		return "", err
+	}
+	if compressed {
+		reader, err := gzip.NewReader(bytes.NewReader([]byte(plain)))
+		if err != nil {
+			return "", err
+		}
+		defer reader.Close()
+		unzipped, err := ioutil.ReadAll(reader)
+		if err != nil {
+			return "", err
+		}
+		plain = string(unzipped)
+	}
+	return migrateDump(name, plain)
+}
@@ -0,0 +1,77 @@
+package wallet
+
+import (
+	"os"
+	"testing"
+)
+
+func TestService_ExportCompressed_success(t *testing.T) {
+	s := newTestService()
+	account, payments, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	dir := t.TempDir()
+	err = s.Export(dir)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = s.ExportCompressed(dir)
+	if err != nil {
+		t.Errorf("ExportCompressed(): error = %v", err)
+		return
+	}
+	if _, err = os.Stat(dir + "/payments.dump.gz"); err != nil {
+		t.Errorf("ExportCompressed(): compressed dump must exist, error = %v", err)
+		return
+	}
+	if _, err = os.Stat(dir + "/payments.dump"); !os.IsNotExist(err) {
+		t.Errorf("ExportCompressed(): stale plain dump must be removed, error = %v", err)
+		return
+	}
+
+	imported := &Service{}
+	err = imported.Import(dir)
+	if err != nil {
+		t.Errorf("Import(): error = %v", err)
+		return
+	}
+	got, err := imported.FindAccountByID(account.ID)
+	if err != nil || got.Balance != account.Balance {
+		t.Errorf("Import(): account = %v, error = %v", got, err)
+		return
+	}
+	_, err = imported.FindPaymentByID(payments[0].ID)
+	if err != nil {
+		t.Errorf("Import(): payment must be imported, error = %v", err)
+		return
+	}
+}
+
+func TestService_ExportCompressed_encrypted(t *testing.T) {
+	s := NewService(WithEncryptionKey(testDumpKey))
+	account, err := s.RegisterAccount("+992928885522")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	dir := t.TempDir()
+	err = s.ExportCompressed(dir)
+	if err != nil {
+		t.Errorf("ExportCompressed(): error = %v", err)
+		return
+	}
+	imported := NewService(WithEncryptionKey(testDumpKey))
+	err = imported.Import(dir)
+	if err != nil {
+		t.Errorf("Import(): error = %v", err)
+		return
+	}
+	_, err = imported.FindAccountByID(account.ID)
+	if err != nil {
+		t.Errorf("Import(): account must be imported, error = %v", err)
+		return
+	}
+}
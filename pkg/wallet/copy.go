@@ -0,0 +1,73 @@
+package wallet
+
+import "github.com/sidalsoft/wallet/pkg/types"
+
+// Методы сервиса возвращают копии счетов, платежей и других записей: изменение
+// копии не меняет состояние сервиса. Изменения проводятся только методами сервиса,
+// чтобы соблюдались проверки, журнал аудита, события и сохранение.
+// Сигнатуры с указателями сохранены, чтобы вызывающий код продолжал собираться
+
+func copyAccount(account *types.Account, err error) (*types.Account, error) {
+	if err != nil {
+		return nil, err
+	}
+	copied := *account
+	return &copied, nil
+}
+
+func copyPayment(payment *types.Payment, err error) (*types.Payment, error) {
+	if err != nil {
+		return nil, err
+	}
+	copied := *payment
+	if payment.Metadata != nil {
+		copied.Metadata = make(map[string]string, len(payment.Metadata))
+		for key, value := range payment.Metadata {
+			copied.Metadata[key] = value
+		}
+	}
+	copied.Tags = append([]string(nil), payment.Tags...)
+	return &copied, nil
+}
+
+func copyFavorite(favorite *types.Favorite, err error) (*types.Favorite, error) {
+	if err != nil {
+		return nil, err
+	}
+	copied := *favorite
+	return &copied, nil
+}
+
+func copyScheduledPayment(scheduled *types.ScheduledPayment, err error) (*types.ScheduledPayment, error) {
+	if err != nil {
+		return nil, err
+	}
+	copied := *scheduled
+	return &copied, nil
+}
+
+func copyCorrection(correction *types.Correction, err error) (*types.Correction, error) {
+	if err != nil {
+		return nil, err
+	}
+	copied := *correction
+	copied.PaymentIDs = append([]string(nil), correction.PaymentIDs...)
+	return &copied, nil
+}
+
+func copyOperation(operation *types.Operation, err error) (*types.Operation, error) {
+	if err != nil {
+		return nil, err
+	}
+	copied := *operation
+	copied.Payload = append([]byte(nil), operation.Payload...)
+	return &copied, nil
+}
+
+func copyMerchant(merchant *types.Merchant, err error) (*types.Merchant, error) {
+	if err != nil {
+		return nil, err
+	}
+	copied := *merchant
+	return &copied, nil
+}
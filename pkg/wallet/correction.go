@@ -0,0 +1,201 @@
+package wallet
+
+import (
+	"github.com/google/uuid"
+	"github.com/sidalsoft/wallet/pkg/types"
+)
+
+// CorrectionCategory категория исправительных транзакций
+const CorrectionCategory types.PaymentCategory = "correction"
+
+// CorrectionMetadata - ключ метаданных исправительной транзакции, значение - ID исправления.
+// Исправительные транзакции не считаются тратами: они не входят в лимиты, бюджеты
+// и суммы платежей
+const CorrectionMetadata = "correction"
+
+// isSpending сообщает, считается ли платеж тратой счета: исправления
+// и начисления процентов тратами не являются
+func isSpending(payment *types.Payment) bool {
+	return payment.Metadata[CorrectionMetadata] == "" && payment.Metadata[InterestMetadata] == ""
+}
+
+// ProposeCorrection регистрирует исправление, которое вступит в силу
+// только после подтверждения другим сотрудником через ApproveCorrection
+func (s *Service) ProposeCorrection(correction types.Correction, proposedBy string) (_ *types.Correction, err error) {
+	defer s.audit("ProposeCorrection", &err, correctionArgs(correction, "proposedBy", proposedBy)...)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	account, err := s.findAccountByID(correction.AccountID)
+	if err != nil {
+		return nil, err
+	}
+	if account.Status == types.AccountStatusClosed {
+		return nil, ErrAccountClosed
+	}
+	switch correction.Kind {
+	case types.CorrectionBalance:
+		if correction.Amount == 0 {
+			return nil, ErrInvalidCorrection
+		}
+	case types.CorrectionCategory:
+		payment, err := s.findPaymentByID(correction.PaymentID)
+		if err != nil {
+			return nil, err
+		}
+		if payment.AccountID != account.ID || correction.Category == "" {
+			return nil, ErrInvalidCorrection
+		}
+	case types.CorrectionPhone:
+		if correction.Phone == "" {
+			return nil, ErrInvalidCorrection
+		}
+	default:
+		return nil, ErrInvalidCorrection
+	}
+
+	proposed := correction
+	proposed.ID = uuid.New().String()
+	proposed.ProposedBy = proposedBy
+	proposed.ReviewedBy = ""
+	proposed.Status = types.CorrectionStatusPending
+	proposed.PaymentIDs = nil
+	s.corrections = append(s.corrections, &proposed)
+	return copyCorrection(&proposed, nil)
+}
+
+// correctionArgs возвращает аргументы журнала аудита, по которым видно, что меняет исправление
+func correctionArgs(correction types.Correction, args ...interface{}) []interface{} {
+	return append(args,
+		"kind", correction.Kind,
+		"accountID", correction.AccountID,
+		"amount", correction.Amount,
+		"paymentID", correction.PaymentID,
+		"category", correction.Category,
+		"phone", correction.Phone,
+	)
+}
+
+// ApproveCorrection применяет исправление. Баланс и категории не редактируются,
+// а исправляются отдельными транзакциями с метаданными CorrectionMetadata: баланс -
+// транзакцией категории CorrectionCategory, категория - сторно и повтором платежа. Исправления
+// закрытых счетов отклоняются, смена телефона проходит те же проверки и хуки, что ChangePhone
+func (s *Service) ApproveCorrection(correctionID string, approvedBy string) (err error) {
+	var correction types.Correction
+	defer func() {
+		s.audit("ApproveCorrection", &err, correctionArgs(correction, "correctionID", correctionID, "approvedBy", approvedBy)...)
+	}()
+	after, err := s.approveCorrectionHooked(correctionID, approvedBy, &correction)
+	if err != nil {
+		return err
+	}
+	after()
+	return nil
+}
+
+// approveCorrectionHooked применяет исправление под блокировкой и копирует его в applied
+// для журнала аудита. Возвращает вызов хука AfterChangePhone для исполнения без блокировки
+func (s *Service) approveCorrectionHooked(correctionID string, approvedBy string, applied *types.Correction) (func(), error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	correction, err := s.reviewCorrection(correctionID, approvedBy)
+	if err != nil {
+		return nil, err
+	}
+	*applied = *correction
+	account, err := s.findAccountByID(correction.AccountID)
+	if err != nil {
+		return nil, err
+	}
+	if account.Status == types.AccountStatusClosed {
+		return nil, ErrAccountClosed
+	}
+	after := func() {}
+
+	switch correction.Kind {
+	case types.CorrectionBalance:
+		// как и у обычного платежа, положительная сумма списывается со счета
+		payment := s.addCorrectionPayment(account, -correction.Amount, CorrectionCategory, correction.ID)
+		correction.PaymentIDs = []string{payment.ID}
+	case types.CorrectionCategory:
+		original, err := s.findPaymentByID(correction.PaymentID)
+		if err != nil {
+			return nil, err
+		}
+		reversal := s.addCorrectionPayment(account, -original.Amount, original.Category, correction.ID)
+		repost := s.addCorrectionPayment(account, original.Amount, correction.Category, correction.ID)
+		correction.PaymentIDs = []string{reversal.ID, repost.ID}
+	case types.CorrectionPhone:
+		after, err = s.changePhoneWithHooks(account, correction.Phone)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	correction.ReviewedBy = approvedBy
+	correction.Status = types.CorrectionStatusApplied
+	return after, nil
+}
+
+func (s *Service) DeclineCorrection(correctionID string, reviewedBy string) (err error) {
+	defer s.audit("DeclineCorrection", &err, "correctionID", correctionID, "reviewedBy", reviewedBy)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	correction, err := s.reviewCorrection(correctionID, reviewedBy)
+	if err != nil {
+		return err
+	}
+	correction.ReviewedBy = reviewedBy
+	correction.Status = types.CorrectionStatusDeclined
+	return nil
+}
+
+func (s *Service) FindCorrectionByID(correctionID string) (*types.Correction, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return copyCorrection(s.findCorrectionByID(correctionID))
+}
+
+func (s *Service) findCorrectionByID(correctionID string) (*types.Correction, error) {
+	for _, correction := range s.corrections {
+		if correction.ID == correctionID {
+			return correction, nil
+		}
+	}
+	return nil, ErrCorrectionNotFound
+}
+
+func (s *Service) reviewCorrection(correctionID string, reviewer string) (*types.Correction, error) {
+	correction, err := s.findCorrectionByID(correctionID)
+	if err != nil {
+		return nil, err
+	}
+	if correction.Status != types.CorrectionStatusPending {
+		return nil, ErrCorrectionReviewed
+	}
+	if reviewer == "" || reviewer == correction.ProposedBy {
+		return nil, ErrSameReviewer
+	}
+	return correction, nil
+}
+
+func (s *Service) addCorrectionPayment(account *types.Account, amount types.Money, category types.PaymentCategory, correctionID string) *types.Payment {
+	s.changeBalance(account, -amount)
+	payment := &types.Payment{
+		ID:        uuid.New().String(),
+		AccountID: account.ID,
+		Amount:    amount,
+		Currency:  account.Currency,
+		Category:  category,
+		Status:    types.PaymentStatusOk,
+		Created:   s.clock(),
+		Metadata:  map[string]string{CorrectionMetadata: correctionID},
+	}
+	s.payments = append(s.payments, payment)
+	s.paymentTimes.add(payment)
+	s.markPayment(payment.ID)
+	s.emit(EventPaymentCreated, account, payment, 0)
+	return payment
+}
@@ -0,0 +1,248 @@
+package wallet
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/sidalsoft/wallet/pkg/types"
+)
+
+func TestService_ApproveCorrection_balance(t *testing.T) {
+	s := newTestService()
+	account, err := s.addAccountWithBalance("+992928885522", 100_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	correction, err := s.ProposeCorrection(types.Correction{
+		Kind:      types.CorrectionBalance,
+		AccountID: account.ID,
+		Amount:    25_00,
+		Reason:    "lost deposit",
+	}, "maker")
+	if err != nil {
+		t.Errorf("ProposeCorrection(): error = %v", err)
+		return
+	}
+	if account.Balance != 100_00 {
+		t.Errorf("ProposeCorrection(): balance changed before approval, account = %v", account)
+		return
+	}
+	err = s.ApproveCorrection(correction.ID, "maker")
+	if !errors.Is(err, ErrSameReviewer) {
+		t.Errorf("ApproveCorrection(): must return ErrSameReviewer, returned = %v", err)
+		return
+	}
+	err = s.ApproveCorrection(correction.ID, "checker")
+	if err != nil {
+		t.Errorf("ApproveCorrection(): error = %v", err)
+		return
+	}
+	account, _ = s.FindAccountByID(account.ID)
+	correction, _ = s.FindCorrectionByID(correction.ID)
+	if account.Balance != 125_00 || correction.Status != types.CorrectionStatusApplied || len(correction.PaymentIDs) != 1 {
+		t.Errorf("ApproveCorrection(): correction not applied, account = %v, correction = %v", account, correction)
+		return
+	}
+	err = s.ApproveCorrection(correction.ID, "checker")
+	if !errors.Is(err, ErrCorrectionReviewed) {
+		t.Errorf("ApproveCorrection(): must return ErrCorrectionReviewed, returned = %v", err)
+		return
+	}
+}
+
+func TestService_ApproveCorrection_category(t *testing.T) {
+	s := newTestService()
+	account, payments, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	balance := account.Balance
+	correction, err := s.ProposeCorrection(types.Correction{
+		Kind:      types.CorrectionCategory,
+		AccountID: account.ID,
+		PaymentID: payments[0].ID,
+		Category:  "fuel",
+	}, "maker")
+	if err != nil {
+		t.Errorf("ProposeCorrection(): error = %v", err)
+		return
+	}
+	err = s.ApproveCorrection(correction.ID, "checker")
+	if err != nil {
+		t.Errorf("ApproveCorrection(): error = %v", err)
+		return
+	}
+	payments[0], _ = s.FindPaymentByID(payments[0].ID)
+	account, _ = s.FindAccountByID(account.ID)
+	correction, _ = s.FindCorrectionByID(correction.ID)
+	if payments[0].Category != "auto" || account.Balance != balance {
+		t.Errorf("ApproveCorrection(): original payment or balance changed, payment = %v, account = %v", payments[0], account)
+		return
+	}
+	repost, err := s.FindPaymentByID(correction.PaymentIDs[1])
+	if err != nil || repost.Category != "fuel" || repost.Amount != payments[0].Amount {
+		t.Errorf("ApproveCorrection(): wrong correcting payment = %v, error = %v", repost, err)
+		return
+	}
+}
+
+func TestService_ProposeCorrection_fail(t *testing.T) {
+	s := newTestService()
+	account, err := s.addAccountWithBalance("+992928885522", 100_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, err = s.ProposeCorrection(types.Correction{Kind: types.CorrectionPhone, AccountID: account.ID}, "maker")
+	if !errors.Is(err, ErrInvalidCorrection) {
+		t.Errorf("ProposeCorrection(): must return ErrInvalidCorrection, returned = %v", err)
+		return
+	}
+}
+
+func TestService_ApproveCorrection_audit(t *testing.T) {
+	s := newTestService()
+	account, err := s.addAccountWithBalance("+992928885522", 100_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	correction, err := s.ProposeCorrection(types.Correction{
+		Kind:      types.CorrectionBalance,
+		AccountID: account.ID,
+		Amount:    25_00,
+	}, "maker")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = s.ApproveCorrection(correction.ID, "checker")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	for _, op := range []string{"ProposeCorrection", "ApproveCorrection"} {
+		entries := s.AuditLog(AuditQuery{Op: op})
+		if len(entries) != 1 || entries[0].AccountID != account.ID || entries[0].Args["amount"] != "2500" ||
+			entries[0].Args["kind"] != string(types.CorrectionBalance) {
+			t.Errorf("AuditLog(): %s must record correction details, entries = %+v", op, entries)
+			return
+		}
+	}
+}
+
+func TestService_ApproveCorrection_checks(t *testing.T) {
+	s := newTestService()
+	account, err := s.RegisterAccount("+992928885522")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	balance, err := s.ProposeCorrection(types.Correction{
+		Kind:      types.CorrectionBalance,
+		AccountID: account.ID,
+		Amount:    -25_00,
+	}, "maker")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	phone, err := s.ProposeCorrection(types.Correction{
+		Kind:      types.CorrectionPhone,
+		AccountID: account.ID,
+		Phone:     "+992000000001",
+	}, "maker")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	s.SetAccountHooks(AccountHooks{
+		BeforeChangePhone: func(types.Account, types.Phone) error {
+			return errors.New("phone change denied")
+		},
+	})
+	err = s.ApproveCorrection(phone.ID, "checker")
+	var hookErr *HookError
+	if !errors.As(err, &hookErr) {
+		t.Errorf("ApproveCorrection(): must run ChangePhone hooks, returned = %v", err)
+		return
+	}
+
+	err = s.CloseAccount(account.ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = s.ApproveCorrection(balance.ID, "checker")
+	if !errors.Is(err, ErrAccountClosed) {
+		t.Errorf("ApproveCorrection(): error = %v, want %v", err, ErrAccountClosed)
+		return
+	}
+	_, err = s.ProposeCorrection(types.Correction{
+		Kind:      types.CorrectionBalance,
+		AccountID: account.ID,
+		Amount:    10_00,
+	}, "maker")
+	if !errors.Is(err, ErrAccountClosed) {
+		t.Errorf("ProposeCorrection(): error = %v, want %v", err, ErrAccountClosed)
+		return
+	}
+}
+
+func TestService_ApproveCorrection_limits(t *testing.T) {
+	s := newTestService()
+	account, err := s.addAccountWithBalance("+992928885522", 1_000_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = s.SetLimit(account.ID, LimitDaily, 100_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = s.SetBudget(account.ID, "auto", LimitDaily, 100_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	payment, err := s.Pay(account.ID, 100_00, "auto")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	for _, correction := range []types.Correction{
+		{Kind: types.CorrectionBalance, AccountID: account.ID, Amount: 50_00},
+		{Kind: types.CorrectionCategory, AccountID: account.ID, PaymentID: payment.ID, Category: "fuel"},
+	} {
+		proposed, err := s.ProposeCorrection(correction, "maker")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		err = s.ApproveCorrection(proposed.ID, "checker")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	_, err = s.Pay(account.ID, 1, "food")
+	if !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("Pay(): correction must not raise the limit, error = %v", err)
+		return
+	}
+	usages, err := s.BudgetStatus(account.ID)
+	if err != nil || len(usages) != 1 || usages[0].Spent != 100_00 {
+		t.Errorf("BudgetStatus(): correction must not change spending, usages = %v, error = %v", usages, err)
+		return
+	}
+	sum := s.SumPayments(1)
+	if sum != 100_00 {
+		t.Errorf("SumPayments(): sum = %v, want %v", sum, 100_00)
+		return
+	}
+}
@@ -0,0 +1,78 @@
+package wallet
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+)
+
+// dumpNames перечисляет дампы, которые пишет Export, в порядке чтения Import
+var dumpNames = []string{"accounts", "payments", "favorites", "scheduled", "operations", "merchants"}
+
+// encryptedDumpMagic начинает зашифрованный дамп, за ним следуют nonce и шифротекст
+var encryptedDumpMagic = []byte("WALLETGCM1\n")
+
+// WithEncryptionKey включает шифрование дампов Export и Import алгоритмом AES-GCM.
+// Ключ должен быть длиной 16, 24 или 32 байта. Имя дампа аутентифицируется вместе
+// с содержимым, поэтому файлы нельзя переставить местами
+func WithEncryptionKey(key []byte) Option {
+	return func(s *Service) {
+		s.dumpKey = append([]byte(nil), key...)
+	}
+}
+
+func (s *Service) dumpCipher() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(s.dumpKey)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal шифрует дамп name, если задан ключ
+func (s *Service) seal(name string, data string) (string, error) {
+	if s.dumpKey == nil {
+		return data, nil
+	}
+	aead, err := s.dumpCipher()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return "", err
+	}
+	sealed := append(append([]byte(nil), encryptedDumpMagic...), nonce...)
+	sealed = aead.Seal(sealed, nonce, []byte(data), []byte(name))
+	return string(sealed), nil
+}
+
+// open расшифровывает дамп name. С ключом принимаются только зашифрованные дампы,
+// без ключа - только открытые
+func (s *Service) open(name string, data []byte) (string, error) {
+	encrypted := bytes.HasPrefix(data, encryptedDumpMagic)
+	if s.dumpKey == nil {
+		if encrypted {
+			return "", ErrDumpEncrypted
+		}
+		return string(data), nil
+	}
+	if !encrypted {
+		return "", ErrDumpNotEncrypted
+	}
+	aead, err := s.dumpCipher()
+	if err != nil {
+		return "", err
+	}
+	data = data[len(encryptedDumpMagic):]
+	if len(data) < aead.NonceSize() {
+		return "", ErrDumpDecrypt
+	}
+	plain, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], []byte(name))
+	if err != nil {
+		return "", ErrDumpDecrypt
+	}
+	return string(plain), nil
+}
@@ -0,0 +1,118 @@
+package wallet
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+var testDumpKey = bytes.Repeat([]byte{7}, 32)
+
+func TestService_Export_encrypted(t *testing.T) {
+	s := NewService(WithEncryptionKey(testDumpKey))
+	account, err := s.RegisterAccount("+992928885522")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = s.Deposit(account.ID, 100_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	dir := t.TempDir()
+	err = s.Export(dir)
+	if err != nil {
+		t.Errorf("Export(): error = %v", err)
+		return
+	}
+	data, err := ioutil.ReadFile(dir + "/accounts.dump")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if bytes.Contains(data, []byte(account.Phone)) {
+		t.Errorf("Export(): dump must not contain plaintext phone")
+		return
+	}
+
+	imported := NewService(WithEncryptionKey(testDumpKey))
+	err = imported.Import(dir)
+	if err != nil {
+		t.Errorf("Import(): error = %v", err)
+		return
+	}
+	got, err := imported.FindAccountByID(account.ID)
+	if err != nil || got.Phone != account.Phone || got.Balance != 100_00 {
+		t.Errorf("Import(): account = %v, error = %v", got, err)
+		return
+	}
+}
+
+func TestService_Import_encrypted_fail(t *testing.T) {
+	s := NewService(WithEncryptionKey(testDumpKey))
+	_, err := s.RegisterAccount("+992928885522")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	dir := t.TempDir()
+	err = s.Export(dir)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = (&Service{}).Import(dir)
+	if !errors.Is(err, ErrDumpEncrypted) {
+		t.Errorf("Import(): must return ErrDumpEncrypted, returned = %v", err)
+		return
+	}
+	err = NewService(WithEncryptionKey(bytes.Repeat([]byte{8}, 32))).Import(dir)
+	if !errors.Is(err, ErrDumpDecrypt) {
+		t.Errorf("Import(): must return ErrDumpDecrypt for wrong key, returned = %v", err)
+		return
+	}
+
+	// без манифеста перестановку должна обнаружить сама аутентификация дампа
+	err = os.Remove(dir + "/" + manifestName)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = os.Rename(dir+"/accounts.dump", dir+"/payments.dump")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = NewService(WithEncryptionKey(testDumpKey)).Import(dir)
+	if !errors.Is(err, ErrDumpDecrypt) {
+		t.Errorf("Import(): must return ErrDumpDecrypt for swapped dump, returned = %v", err)
+		return
+	}
+
+	plain := t.TempDir()
+	err = (&Service{}).Export(plain)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	// дамп вне манифеста отклоняется раньше, поэтому проверяется каталог без манифеста
+	err = os.Remove(plain + "/" + manifestName)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = ioutil.WriteFile(plain+"/accounts.dump", []byte("1;+992928885522;0\n"), 0666)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = NewService(WithEncryptionKey(testDumpKey)).Import(plain)
+	if !errors.Is(err, ErrDumpNotEncrypted) {
+		t.Errorf("Import(): must return ErrDumpNotEncrypted, returned = %v", err)
+		return
+	}
+}
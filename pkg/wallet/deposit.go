@@ -0,0 +1,50 @@
+package wallet
+
+import (
+	"time"
+
+	"github.com/sidalsoft/wallet/pkg/types"
+)
+
+// DepositRequest - пополнение пакета DepositBatch
+type DepositRequest struct {
+	AccountID int64
+	Amount    types.Money
+}
+
+// DepositResult - результат пополнения пакета DepositBatch: баланс счета
+// после пополнения или ошибка, если пополнение не проведено
+type DepositResult struct {
+	Balance types.Money
+	Err     error
+}
+
+// DepositBatch проводит пополнения items под одной блокировкой, например
+// из файла расчетов банка. Пополнения независимы: ошибка одного не отменяет
+// остальные. Результаты возвращаются в порядке items
+func (s *Service) DepositBatch(items []DepositRequest) []DepositResult {
+	var err error
+	defer s.audit("DepositBatch", &err, "deposits", len(items))
+	defer s.observe("DepositBatch", time.Now())
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	accounts := make(map[int64]*types.Account, len(s.accounts))
+	for _, account := range s.accounts {
+		accounts[account.ID] = account
+	}
+	results := make([]DepositResult, len(items))
+	for i, item := range items {
+		account, ok := accounts[item.AccountID]
+		switch {
+		case item.Amount <= 0:
+			results[i].Err = ErrAmountMustBePositive
+		case !ok:
+			results[i].Err = ErrAccountNotFound
+		default:
+			results[i].Err = s.depositTo(account, item.Amount)
+			results[i].Balance = account.Balance
+		}
+	}
+	return results
+}
@@ -0,0 +1,52 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/sidalsoft/wallet/pkg/types"
+)
+
+func TestService_DepositBatch(t *testing.T) {
+	s := newTestService()
+	account, err := s.RegisterAccount("+992928885522")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	results := s.DepositBatch([]DepositRequest{
+		{AccountID: account.ID, Amount: 100_00},
+		{AccountID: 404, Amount: 100_00},
+		{AccountID: account.ID, Amount: 0},
+		{AccountID: account.ID, Amount: 50_00},
+	})
+	want := []DepositResult{
+		{Balance: 100_00},
+		{Err: ErrAccountNotFound},
+		{Err: ErrAmountMustBePositive},
+		{Balance: 150_00},
+	}
+	for i, result := range results {
+		if result.Balance != want[i].Balance || !errors.Is(result.Err, want[i].Err) {
+			t.Errorf("DepositBatch(): result %d = %v, want %v", i, result, want[i])
+			return
+		}
+	}
+}
+
+func BenchmarkDepositBatch(b *testing.B) {
+	s := newTestService()
+	items := make([]DepositRequest, 10_000)
+	for i := range items {
+		account, err := s.RegisterAccount(types.Phone(fmt.Sprintf("+992%09d", i)))
+		if err != nil {
+			b.Fatal(err)
+		}
+		items[i] = DepositRequest{AccountID: account.ID, Amount: 1_00}
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.DepositBatch(items)
+	}
+}
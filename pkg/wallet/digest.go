@@ -0,0 +1,108 @@
+package wallet
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sidalsoft/wallet/pkg/types"
+)
+
+// Digest сводит события одного счета за период
+type Digest struct {
+	AccountID int64
+	From      time.Time
+	To        time.Time
+	Counts    map[EventKind]int
+	NetChange types.Money
+	// Notable - созданные платежи на сумму не меньше DigestCompactor.Threshold
+	Notable []types.Payment
+}
+
+// DigestCompactor накапливает события сервиса и периодически передает в Deliver
+// сводки по счетам вместо отдельных событий
+type DigestCompactor struct {
+	interval time.Duration
+	deliver  func([]Digest)
+	// Threshold - минимальная сумма платежа для Notable, 0 - не отбирать платежи
+	Threshold types.Money
+
+	mu      sync.Mutex
+	from    time.Time
+	digests map[int64]*Digest
+
+	loop *backgroundLoop
+}
+
+// NewDigestCompactor возвращает ErrInvalidInterval, если interval не положителен.
+// Сводки закрываются по часам сервиса, как и время событий
+func NewDigestCompactor(svc *Service, interval time.Duration, deliver func([]Digest)) (*DigestCompactor, error) {
+	d := &DigestCompactor{
+		deliver: deliver,
+		digests: make(map[int64]*Digest),
+	}
+	loop, err := newBackgroundLoop(interval, func(time.Time) {
+		digests := d.Flush(svc.clock())
+		if len(digests) > 0 {
+			d.deliver(digests)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	d.loop = loop
+	svc.OnEvent(d.add)
+	return d, nil
+}
+
+// add вызывается под блокировкой сервиса
+func (d *DigestCompactor) add(event Event) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.from.IsZero() {
+		d.from = event.Time
+	}
+	digest, ok := d.digests[event.Account.ID]
+	if !ok {
+		digest = &Digest{AccountID: event.Account.ID, Counts: make(map[EventKind]int)}
+		d.digests[event.Account.ID] = digest
+	}
+	digest.Counts[event.Kind]++
+	switch event.Kind {
+	case EventBalanceChanged:
+		digest.NetChange += event.Delta
+	case EventPaymentCreated:
+		if d.Threshold > 0 && event.Payment.Amount >= d.Threshold {
+			digest.Notable = append(digest.Notable, event.Payment)
+		}
+	}
+}
+
+// Flush возвращает сводки, накопленные с прошлого вызова, упорядоченные по счету
+func (d *DigestCompactor) Flush(now time.Time) []Digest {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	digests := make([]Digest, 0, len(d.digests))
+	for _, digest := range d.digests {
+		digest.From = d.from
+		digest.To = now
+		digests = append(digests, *digest)
+	}
+	sort.Slice(digests, func(i, j int) bool {
+		return digests[i].AccountID < digests[j].AccountID
+	})
+	d.digests = make(map[int64]*Digest)
+	d.from = now
+	return digests
+}
+
+// Start запускает компактор. Повторный вызов ничего не делает
+func (d *DigestCompactor) Start() {
+	d.loop.start()
+}
+
+// Stop останавливает запущенный Start компактор. Накопленные сводки можно забрать через Flush.
+// Без Start возвращается сразу
+func (d *DigestCompactor) Stop() {
+	d.loop.halt()
+}
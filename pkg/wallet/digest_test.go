@@ -0,0 +1,79 @@
+package wallet
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDigestCompactor_Flush_success(t *testing.T) {
+	s := newTestService()
+	d, err := NewDigestCompactor(s.Service, time.Hour, func([]Digest) {})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	d.Threshold = 50_00
+
+	account, err := s.addAccountWithBalance("+992928885522", 100_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, err = s.Pay(account.ID, 10_00, "auto")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	notable, err := s.Pay(account.ID, 60_00, "auto")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	digests := d.Flush(time.Now())
+	if len(digests) != 1 {
+		t.Errorf("Flush(): wrong digests = %+v", digests)
+		return
+	}
+	digest := digests[0]
+	if digest.AccountID != account.ID || digest.NetChange != 30_00 || digest.Counts[EventPaymentCreated] != 2 || digest.Counts[EventBalanceChanged] != 3 {
+		t.Errorf("Flush(): wrong digest = %+v", digest)
+		return
+	}
+	if len(digest.Notable) != 1 || digest.Notable[0].ID != notable.ID {
+		t.Errorf("Flush(): wrong notable payments = %+v", digest.Notable)
+		return
+	}
+	if digests = d.Flush(time.Now()); len(digests) != 0 {
+		t.Errorf("Flush(): must reset digests, digests = %+v", digests)
+		return
+	}
+}
+
+func TestDigestCompactor_Start_success(t *testing.T) {
+	s := newTestService()
+	delivered := make(chan []Digest, 1)
+	d, err := NewDigestCompactor(s.Service, 10*time.Millisecond, func(digests []Digest) {
+		delivered <- digests
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, err = s.addAccountWithBalance("+992928885522", 100_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	d.Start()
+	defer d.Stop()
+
+	select {
+	case digests := <-delivered:
+		if len(digests) != 1 || digests[0].NetChange != 100_00 {
+			t.Errorf("Start(): wrong digests = %+v", digests)
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("Start(): digests were not delivered")
+	}
+}
@@ -0,0 +1,57 @@
+package wallet
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sidalsoft/wallet/pkg/types"
+)
+
+// PossibleDuplicateError возвращается Pay, если за окно WithDuplicateWindow со счета
+// уже был платеж той же суммы и категории. Duplicate - копия этого платежа.
+// Чтобы все равно провести платеж, используется ForcePay.
+// errors.Is(err, ErrPossibleDuplicate) истинно
+type PossibleDuplicateError struct {
+	Duplicate types.Payment
+}
+
+func (e *PossibleDuplicateError) Error() string {
+	return fmt.Sprintf("%s: payment %s", ErrPossibleDuplicate, e.Duplicate.ID)
+}
+
+func (e *PossibleDuplicateError) Unwrap() error {
+	return ErrPossibleDuplicate
+}
+
+// WithDuplicateWindow включает проверку повторных платежей: Pay, PayInCurrency,
+// PayFromFavorite и PayFromFavoriteAmount отклоняют платеж, совпадающий по счету, сумме и категории с
+// неотклоненным платежом, созданным не раньше window назад. 0 отключает проверку
+func WithDuplicateWindow(window time.Duration) Option {
+	return func(s *Service) {
+		s.dedupWindow = window
+	}
+}
+
+// ForcePay проводит платеж как Pay, но без проверки повторных платежей
+func (s *Service) ForcePay(accountID int64, amount types.Money, category types.PaymentCategory) (_ *types.Payment, err error) {
+	defer s.audit("ForcePay", &err, "accountID", accountID, "amount", amount, "category", category)
+	defer s.observe("Pay", time.Now())
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return copyPayment(s.pay(accountID, amount, category))
+}
+
+func (s *Service) checkDuplicate(accountID int64, amount types.Money, category types.PaymentCategory) error {
+	if s.dedupWindow <= 0 {
+		return nil
+	}
+	from := s.clock().Add(-s.dedupWindow)
+	for i := len(s.payments) - 1; i >= 0; i-- {
+		payment := s.payments[i]
+		if payment.AccountID == accountID && payment.Amount == amount && payment.Category == category &&
+			payment.Status != types.PaymentStatusFail && !payment.Created.Before(from) {
+			return &PossibleDuplicateError{Duplicate: *payment}
+		}
+	}
+	return nil
+}
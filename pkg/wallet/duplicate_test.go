@@ -0,0 +1,53 @@
+package wallet
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestService_Pay_duplicate(t *testing.T) {
+	s := &testService{NewService(WithDuplicateWindow(10 * time.Second))}
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	account, err := s.addAccountWithBalance("+992928885522", 1_000_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	payment, err := s.Pay(account.ID, 100_00, "auto")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	now = now.Add(5 * time.Second)
+	_, err = s.Pay(account.ID, 100_00, "auto")
+	var duplicate *PossibleDuplicateError
+	if !errors.As(err, &duplicate) || !errors.Is(err, ErrPossibleDuplicate) || duplicate.Duplicate.ID != payment.ID {
+		t.Errorf("Pay(): error = %v, want %v", err, ErrPossibleDuplicate)
+		return
+	}
+	_, err = s.Pay(account.ID, 100_00, "food")
+	if err != nil {
+		t.Errorf("Pay(): other category is not a duplicate, error = %v", err)
+		return
+	}
+	_, err = s.ForcePay(account.ID, 100_00, "auto")
+	if err != nil {
+		t.Errorf("ForcePay(): error = %v", err)
+		return
+	}
+
+	now = now.Add(11 * time.Second)
+	_, err = s.Pay(account.ID, 100_00, "auto")
+	if err != nil {
+		t.Errorf("Pay(): payment outside the window is not a duplicate, error = %v", err)
+		return
+	}
+	account, _ = s.FindAccountByID(account.ID)
+	if account.Balance != 600_00 {
+		t.Errorf("Pay(): duplicate must not change balance, account = %v", account)
+		return
+	}
+}
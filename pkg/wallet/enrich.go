@@ -0,0 +1,195 @@
+package wallet
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/sidalsoft/wallet/pkg/types"
+)
+
+// EnrichmentMetadata - ключ метаданных с версией правил, которыми EnrichmentPipeline
+// обработал платеж
+const EnrichmentMetadata = "enrichment"
+
+// Enricher дополняет описание платежа, например нормализует имя получателя, подбирает
+// ссылку на логотип или место по данным провайдера из метаданных. Возвращает метаданные
+// для записи в платеж, пустое значение удаляет ключ. Вызывается без блокировки сервиса
+// и может обращаться к внешним источникам
+type Enricher interface {
+	Enrich(payment types.Payment) map[string]string
+}
+
+// EnricherFunc позволяет использовать функцию как Enricher
+type EnricherFunc func(payment types.Payment) map[string]string
+
+func (f EnricherFunc) Enrich(payment types.Payment) map[string]string {
+	return f(payment)
+}
+
+// UpdatePaymentMetadata записывает в метаданные платежа пары metadata, пустое значение
+// удаляет ключ. Ключи, которые записывает сам сервис, отклоняются с ErrInvalidMetadata
+func (s *Service) UpdatePaymentMetadata(paymentID string, metadata map[string]string) (err error) {
+	defer s.audit("UpdatePaymentMetadata", &err, "paymentID", paymentID, "metadata", types.JoinMetadata(metadata))
+	err = checkMetadata(metadata)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	payment, err := s.findPaymentByID(paymentID)
+	if err != nil {
+		return err
+	}
+	for key, value := range metadata {
+		if value == "" {
+			delete(payment.Metadata, key)
+			continue
+		}
+		if payment.Metadata == nil {
+			payment.Metadata = make(map[string]string, len(metadata))
+		}
+		payment.Metadata[key] = value
+	}
+	s.markPayment(payment.ID)
+	return nil
+}
+
+// EnrichmentPipeline асинхронно обогащает платежи: новые платежи ставятся в очередь
+// после создания, а обработчик, запущенный Start, пропускает их через Enricher и
+// сохраняет результат UpdatePaymentMetadata вместе с версией правил version
+type EnrichmentPipeline struct {
+	svc      *Service
+	enricher Enricher
+	version  string
+	// OnError, если задан, получает ошибки сохранения результата
+	OnError func(paymentID string, err error)
+
+	mu      sync.Mutex
+	queue   []string
+	pending chan struct{}
+	started bool
+	stopped bool
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// NewEnrichmentPipeline подписывает конвейер на новые платежи svc. При изменении правил
+// enricher меняется version, и Reprocess обрабатывает историю заново
+func NewEnrichmentPipeline(svc *Service, enricher Enricher, version string) *EnrichmentPipeline {
+	p := &EnrichmentPipeline{
+		svc:      svc,
+		enricher: enricher,
+		version:  version,
+		pending:  make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	svc.OnEvent(func(event Event) {
+		if event.Kind == EventPaymentCreated {
+			p.enqueue(event.Payment.ID)
+		}
+	})
+	return p
+}
+
+// Reprocess ставит в очередь платежи, обработанные другой версией правил или
+// не обработанные вовсе, и возвращает их число
+func (p *EnrichmentPipeline) Reprocess() int {
+	p.svc.mu.RLock()
+	var ids []string
+	for _, payment := range p.svc.payments {
+		if payment.Metadata[EnrichmentMetadata] != p.version {
+			ids = append(ids, payment.ID)
+		}
+	}
+	p.svc.mu.RUnlock()
+	p.enqueue(ids...)
+	return len(ids)
+}
+
+// enqueue вызывается и под блокировкой сервиса, поэтому только ставит платежи в очередь
+func (p *EnrichmentPipeline) enqueue(ids ...string) {
+	if len(ids) == 0 {
+		return
+	}
+	p.mu.Lock()
+	p.queue = append(p.queue, ids...)
+	p.mu.Unlock()
+	select {
+	case p.pending <- struct{}{}:
+	default:
+	}
+}
+
+// Start запускает обработку очереди. Повторный вызов и вызов после Stop ничего не делают
+func (p *EnrichmentPipeline) Start() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.started || p.stopped {
+		return
+	}
+	p.started = true
+	go func() {
+		defer close(p.done)
+		for {
+			select {
+			case <-p.stop:
+				return
+			case <-p.pending:
+			}
+			p.mu.Lock()
+			ids := p.queue
+			p.queue = nil
+			p.mu.Unlock()
+			for _, id := range ids {
+				select {
+				case <-p.stop:
+					return
+				default:
+				}
+				p.process(id)
+			}
+		}
+	}()
+}
+
+// Stop останавливает обработку и ждет завершения текущего платежа. Необработанные
+// платежи остаются без обогащения до Reprocess. Без Start возвращается сразу
+func (p *EnrichmentPipeline) Stop() {
+	p.mu.Lock()
+	started := p.started
+	if !p.stopped {
+		p.stopped = true
+		if started {
+			close(p.stop)
+		}
+	}
+	p.mu.Unlock()
+	if started {
+		<-p.done
+	}
+}
+
+func (p *EnrichmentPipeline) process(paymentID string) {
+	err := p.enrich(paymentID)
+	// платеж мог быть удален раньше, чем до него дошла очередь
+	if err != nil && !errors.Is(err, ErrPaymentNotFound) && p.OnError != nil {
+		p.OnError(paymentID, err)
+	}
+}
+
+func (p *EnrichmentPipeline) enrich(paymentID string) error {
+	payment, err := p.svc.FindPaymentByID(paymentID)
+	if err != nil {
+		return err
+	}
+	if payment.Metadata[EnrichmentMetadata] == p.version {
+		return nil
+	}
+	metadata := make(map[string]string)
+	for key, value := range p.enricher.Enrich(*payment) {
+		metadata[key] = value
+	}
+	metadata[EnrichmentMetadata] = p.version
+	return p.svc.UpdatePaymentMetadata(paymentID, metadata)
+}
@@ -0,0 +1,138 @@
+package wallet
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sidalsoft/wallet/pkg/types"
+)
+
+// merchantNames нормализует имя получателя из описания провайдера
+var merchantNames = EnricherFunc(func(payment types.Payment) map[string]string {
+	descriptor := payment.Metadata["descriptor"]
+	if descriptor == "" {
+		return nil
+	}
+	name := strings.TrimSpace(strings.SplitN(descriptor, "*", 2)[0])
+	return map[string]string{"merchantName": name}
+})
+
+// waitMetadata ждет, пока метаданные платежа не получат значение value по ключу key
+func waitMetadata(s *Service, paymentID string, key string, value string) (*types.Payment, bool) {
+	deadline := time.Now().Add(time.Second)
+	for {
+		payment, err := s.FindPaymentByID(paymentID)
+		if err == nil && payment.Metadata[key] == value {
+			return payment, true
+		}
+		if time.Now().After(deadline) {
+			return payment, false
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestEnrichmentPipeline_success(t *testing.T) {
+	s := newTestService()
+	account, err := s.addAccountWithBalance("+992928885522", 1_000_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	pipeline := NewEnrichmentPipeline(s.Service, merchantNames, "v1")
+	pipeline.Start()
+	defer pipeline.Stop()
+
+	payment, err := s.PayWithOptions(account.ID, 100_00, "food", WithMetadata(map[string]string{"descriptor": " COFFEE HOUSE*0042 "}))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	got, ok := waitMetadata(s.Service, payment.ID, EnrichmentMetadata, "v1")
+	if !ok || got.Metadata["merchantName"] != "COFFEE HOUSE" || got.Metadata["descriptor"] != " COFFEE HOUSE*0042 " {
+		t.Errorf("EnrichmentPipeline: payment = %v", got)
+		return
+	}
+}
+
+func TestEnrichmentPipeline_Reprocess(t *testing.T) {
+	s := newTestService()
+	account, err := s.addAccountWithBalance("+992928885522", 1_000_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	payment, err := s.PayWithOptions(account.ID, 100_00, "food", WithMetadata(map[string]string{"descriptor": "COFFEE HOUSE*0042"}))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	// правила изменились: к имени добавилась ссылка на логотип
+	logos := EnricherFunc(func(payment types.Payment) map[string]string {
+		metadata := merchantNames(payment)
+		metadata["logo"] = "logos/" + strings.ToLower(strings.ReplaceAll(metadata["merchantName"], " ", "-")) + ".png"
+		return metadata
+	})
+	pipeline := NewEnrichmentPipeline(s.Service, logos, "v2")
+	if count := pipeline.Reprocess(); count != 1 {
+		t.Errorf("Reprocess(): count = %v, want 1", count)
+		return
+	}
+	pipeline.Start()
+	defer pipeline.Stop()
+	got, ok := waitMetadata(s.Service, payment.ID, EnrichmentMetadata, "v2")
+	if !ok || got.Metadata["logo"] != "logos/coffee-house.png" {
+		t.Errorf("Reprocess(): payment = %v", got)
+		return
+	}
+	if count := pipeline.Reprocess(); count != 0 {
+		t.Errorf("Reprocess(): count = %v, want 0 after processing", count)
+		return
+	}
+}
+
+func TestEnrichmentPipeline_lifecycle(t *testing.T) {
+	p := NewEnrichmentPipeline(newTestService().Service, merchantNames, "v1")
+	// Stop без Start не должен зависать, повторные вызовы безопасны
+	p.Stop()
+	p.Start()
+	p.Stop()
+}
+
+func TestService_UpdatePaymentMetadata(t *testing.T) {
+	s := newTestService()
+	_, payments, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	payment := payments[0]
+	err = s.UpdatePaymentMetadata(payment.ID, map[string]string{"location": "Dushanbe"})
+	if err != nil {
+		t.Errorf("UpdatePaymentMetadata(): error = %v", err)
+		return
+	}
+	err = s.UpdatePaymentMetadata(payment.ID, map[string]string{"location": ""})
+	if err != nil {
+		t.Errorf("UpdatePaymentMetadata(): error = %v", err)
+		return
+	}
+	got, _ := s.FindPaymentByID(payment.ID)
+	if _, ok := got.Metadata["location"]; ok {
+		t.Errorf("UpdatePaymentMetadata(): empty value must delete the key, payment = %v", got)
+		return
+	}
+	err = s.UpdatePaymentMetadata(payment.ID, map[string]string{CorrectionMetadata: "forged"})
+	if !errors.Is(err, ErrInvalidMetadata) {
+		t.Errorf("UpdatePaymentMetadata(): error = %v, want %v", err, ErrInvalidMetadata)
+		return
+	}
+	err = s.UpdatePaymentMetadata("unknown", map[string]string{"location": "Dushanbe"})
+	if !errors.Is(err, ErrPaymentNotFound) {
+		t.Errorf("UpdatePaymentMetadata(): error = %v, want %v", err, ErrPaymentNotFound)
+		return
+	}
+}
@@ -0,0 +1,156 @@
+package wallet
+
+import (
+	"errors"
+	"strconv"
+)
+
+// ErrorCode - класс ошибки для внешних слоев, например для выбора кода ответа HTTP
+type ErrorCode string
+
+// Классы ошибок
+const (
+	// CodeInvalid - неверные аргументы или данные
+	CodeInvalid ErrorCode = "INVALID"
+	// CodeNotFound - запись не найдена
+	CodeNotFound ErrorCode = "NOT_FOUND"
+	// CodeConflict - запись уже существует или конфликтует с существующей
+	CodeConflict ErrorCode = "CONFLICT"
+	// CodeRejected - операция запрещена состоянием счета, лимитами или правилами
+	CodeRejected ErrorCode = "REJECTED"
+	// CodeInternal - прочие ошибки: ввод-вывод, шифрование, повреждение дампов
+	CodeInternal ErrorCode = "INTERNAL"
+)
+
+var errorCodes = []struct {
+	err  error
+	code ErrorCode
+}{
+	{ErrAccountNotFound, CodeNotFound},
+	{ErrPaymentNotFound, CodeNotFound},
+	{ErrFavoriteNotFound, CodeNotFound},
+	{ErrScheduledPaymentNotFound, CodeNotFound},
+	{ErrSLONotFound, CodeNotFound},
+	{ErrOperationNotFound, CodeNotFound},
+	{ErrCorrectionNotFound, CodeNotFound},
+	{ErrBackupNotFound, CodeNotFound},
+	{ErrTemplateNotFound, CodeNotFound},
+	{ErrUnknownOperation, CodeNotFound},
+	{ErrUnknownMessage, CodeNotFound},
+	{ErrMerchantNotFound, CodeNotFound},
+	{ErrHoldNotFound, CodeNotFound},
+	{ErrStandingOrderNotFound, CodeNotFound},
+
+	{ErrPhoneRegistered, CodeConflict},
+	{ErrFavoriteRegistered, CodeConflict},
+	{ErrOperationRegistered, CodeConflict},
+	{ErrCorrectionReviewed, CodeConflict},
+	{ErrImportConflict, CodeConflict},
+	{ErrPossibleDuplicate, CodeConflict},
+	{ErrBatchCommitted, CodeConflict},
+	{ErrWalletRegistered, CodeConflict},
+	{ErrHoldNotActive, CodeConflict},
+
+	{ErrNotEnoughBalance, CodeRejected},
+	{ErrAccountFrozen, CodeRejected},
+	{ErrAccountClosed, CodeRejected},
+	{ErrBalanceNotEmpty, CodeRejected},
+	{ErrInvalidPaymentStatus, CodeRejected},
+	{ErrQuotaExceeded, CodeRejected},
+	{ErrLimitExceeded, CodeRejected},
+	{ErrSameReviewer, CodeRejected},
+	{ErrCategoryNotInferred, CodeRejected},
+	{ErrPaymentBlocked, CodeRejected},
+	{ErrRefundExceeded, CodeRejected},
+	{ErrWALDisabled, CodeRejected},
+	{ErrNoSigningKey, CodeRejected},
+	{ErrNotEnoughPoints, CodeRejected},
+	{ErrPointsDisabled, CodeRejected},
+	{ErrAccountTypeRestricted, CodeRejected},
+	{ErrBudgetExceeded, CodeRejected},
+	{ErrHoldExceeded, CodeRejected},
+
+	{ErrAmountMustBePositive, CodeInvalid},
+	{ErrInvalidSchedule, CodeInvalid},
+	{ErrInvalidInterval, CodeInvalid},
+	{ErrCurrencyMismatch, CodeInvalid},
+	{ErrUnknownCurrency, CodeInvalid},
+	{ErrSelfTransfer, CodeInvalid},
+	{ErrInvalidTag, CodeInvalid},
+	{ErrInvalidMerchant, CodeInvalid},
+	{ErrInvalidSLO, CodeInvalid},
+	{ErrInvalidPage, CodeInvalid},
+	{ErrInvalidOperation, CodeInvalid},
+	{ErrInvalidFee, CodeInvalid},
+	{ErrInvalidInterestRate, CodeInvalid},
+	{ErrInvalidCashback, CodeInvalid},
+	{ErrInvalidPoints, CodeInvalid},
+	{ErrInvalidAccountType, CodeInvalid},
+	{ErrInvalidWallet, CodeInvalid},
+	{ErrNotSameOwner, CodeInvalid},
+	{ErrInvalidBudget, CodeInvalid},
+	{ErrInvalidStandingOrder, CodeInvalid},
+	{ErrInvalidQuota, CodeInvalid},
+	{ErrInvalidLimit, CodeInvalid},
+	{ErrInvalidCreditLimit, CodeInvalid},
+	{ErrInvalidCorrection, CodeInvalid},
+	{ErrInvalidPeriod, CodeInvalid},
+	{ErrInvalidCategory, CodeInvalid},
+	{ErrInvalidMetadata, CodeInvalid},
+	{ErrInvalidSearch, CodeInvalid},
+	{ErrTemplateVariable, CodeInvalid},
+	{ErrBalanceUnknown, CodeInvalid},
+	{ErrMalformedDump, CodeInvalid},
+	{ErrMalformedProto, CodeInvalid},
+	{ErrUnsupportedDumpVersion, CodeInvalid},
+}
+
+// Error - ошибка метода сервиса с контекстом. Методы, изменяющие состояние, возвращают
+// *Error, оборачивающую исходную ошибку, поэтому errors.Is(err, ErrAccountNotFound)
+// и errors.As для типов ошибок по-прежнему работают
+type Error struct {
+	Code ErrorCode
+	// Op - имя метода
+	Op string
+	// AccountID - счет, с которым работал метод, 0 - если метод не относится к счету
+	AccountID int64
+	Err       error
+}
+
+func (e *Error) Error() string {
+	if e.AccountID != 0 {
+		return e.Op + ": account " + strconv.FormatInt(e.AccountID, 10) + ": " + e.Err.Error()
+	}
+	return e.Op + ": " + e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Code возвращает класс ошибки err: из *Error или по известной ошибке пакета.
+// Для nil возвращается пустой класс
+func Code(err error) ErrorCode {
+	if err == nil {
+		return ""
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	for _, c := range errorCodes {
+		if errors.Is(err, c.err) {
+			return c.code
+		}
+	}
+	return CodeInternal
+}
+
+// wrapError оборачивает ошибку метода op в *Error, если она еще не обернута
+func wrapError(op string, accountID int64, err error) error {
+	var e *Error
+	if errors.As(err, &e) {
+		return err
+	}
+	return &Error{Code: Code(err), Op: op, AccountID: accountID, Err: err}
+}
@@ -0,0 +1,40 @@
+package wallet
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestError_success(t *testing.T) {
+	s := newTestService()
+	account, err := s.RegisterAccount("+992928885522")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, err = s.Pay(account.ID, 100_00, "auto")
+	var walletErr *Error
+	if !errors.As(err, &walletErr) || !errors.Is(err, ErrNotEnoughBalance) {
+		t.Errorf("Pay(): error = %v, want *Error wrapping %v", err, ErrNotEnoughBalance)
+		return
+	}
+	if walletErr.Code != CodeRejected || walletErr.Op != "Pay" || walletErr.AccountID != account.ID {
+		t.Errorf("Pay(): error = %#v", walletErr)
+		return
+	}
+
+	err = s.Deposit(404, 100)
+	if Code(err) != CodeNotFound || err.Error() != "Deposit: account 404: account not found" {
+		t.Errorf("Deposit(): error = %v, code = %v", err, Code(err))
+		return
+	}
+	_, err = s.FindPaymentByID("unknown")
+	if Code(err) != CodeNotFound {
+		t.Errorf("FindPaymentByID(): code = %v, want %v", Code(err), CodeNotFound)
+		return
+	}
+	if Code(nil) != "" || Code(errors.New("disk full")) != CodeInternal {
+		t.Errorf("Code(): wrong code for nil or unknown error")
+		return
+	}
+}
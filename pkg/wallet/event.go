@@ -0,0 +1,64 @@
+package wallet
+
+import (
+	"time"
+
+	"github.com/sidalsoft/wallet/pkg/types"
+)
+
+// EventKind вид события кошелька
+type EventKind string
+
+// Виды событий, о которых сообщает OnEvent
+const (
+	EventAccountRegistered EventKind = "AccountRegistered"
+	EventPaymentCreated    EventKind = "PaymentCreated"
+	EventPaymentRejected   EventKind = "PaymentRejected"
+	EventPaymentConfirmed  EventKind = "PaymentConfirmed"
+	EventBalanceChanged    EventKind = "BalanceChanged"
+	// EventBudgetExceeded - платеж превысил бюджет в режиме BudgetWarn
+	EventBudgetExceeded EventKind = "BudgetExceeded"
+	// EventStandingOrderFailed - исполнение постоянного поручения не прошло
+	EventStandingOrderFailed EventKind = "StandingOrderFailed"
+)
+
+// Event описывает изменение состояния. Account и Payment - копии на момент события,
+// Payment заполнен только для событий платежей, Delta - только для BalanceChanged,
+// Err - только для StandingOrderFailed
+type Event struct {
+	Kind    EventKind
+	Time    time.Time
+	Account types.Account
+	Payment types.Payment
+	Delta   types.Money
+	Err     error
+}
+
+// OnEvent подписывает handler на события сервиса. Обработчики вызываются по порядку
+// подписки под блокировкой сервиса, поэтому не должны вызывать его методы
+func (s *Service) OnEvent(handler func(Event)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.eventHandlers = append(s.eventHandlers, handler)
+}
+
+func (s *Service) emit(kind EventKind, account *types.Account, payment *types.Payment, delta types.Money) {
+	if len(s.eventHandlers) == 0 {
+		return
+	}
+	event := Event{Kind: kind, Time: s.clock(), Account: *account, Delta: delta}
+	if payment != nil {
+		event.Payment = *payment
+	}
+	s.dispatch(event)
+}
+
+func (s *Service) dispatch(event Event) {
+	if s.batchEvents != nil {
+		s.batchEvents = append(s.batchEvents, event)
+		return
+	}
+	for _, handler := range s.eventHandlers {
+		handler(event)
+	}
+}
@@ -0,0 +1,51 @@
+package wallet
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestService_OnEvent_success(t *testing.T) {
+	s := newTestService()
+	var kinds []EventKind
+	var deltas []int64
+	s.OnEvent(func(event Event) {
+		kinds = append(kinds, event.Kind)
+		if event.Kind == EventBalanceChanged {
+			deltas = append(deltas, int64(event.Delta))
+		}
+	})
+
+	account, err := s.addAccountWithBalance("+992928885522", 100_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	payment, err := s.Pay(account.ID, 30_00, "auto")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = s.Reject(payment.ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	want := []EventKind{
+		EventAccountRegistered,
+		EventBalanceChanged,
+		EventBalanceChanged,
+		EventPaymentCreated,
+		EventBalanceChanged,
+		EventPaymentRejected,
+	}
+	if !reflect.DeepEqual(kinds, want) {
+		t.Errorf("OnEvent(): events = %v, want = %v", kinds, want)
+		return
+	}
+	if !reflect.DeepEqual(deltas, []int64{100_00, -30_00, 30_00}) {
+		t.Errorf("OnEvent(): wrong balance deltas = %v", deltas)
+		return
+	}
+}
@@ -0,0 +1,43 @@
+package wallet_test
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sidalsoft/wallet/pkg/wallet"
+)
+
+func ExampleService_Pay() {
+	svc := &wallet.Service{}
+	account, _ := svc.RegisterAccount("+992928885522")
+	_ = svc.Deposit(account.ID, 1_000_00)
+
+	payment, err := svc.Pay(account.ID, 150_00, "internet")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	_ = svc.Confirm(payment.ID)
+	payment, _ = svc.FindPaymentByID(payment.ID)
+	account, _ = svc.FindAccountByID(account.ID)
+	fmt.Println(payment.Amount, payment.Status, account.Balance)
+
+	_, err = svc.Pay(account.ID, 10_000_00, "auto")
+	fmt.Println(err)
+	// Output:
+	// 150.00 OK 850.00
+	// Pay: account 1: not enough balance
+}
+
+func ExampleService_RunScheduled() {
+	svc := &wallet.Service{}
+	account, _ := svc.RegisterAccount("+992928885522")
+	_ = svc.Deposit(account.ID, 1_000_00)
+	scheduled, _ := svc.SchedulePayment(account.ID, 100_00, "internet", "@monthly")
+
+	payments, _ := svc.RunScheduled(scheduled.NextRun.Add(time.Second))
+	account, _ = svc.FindAccountByID(account.ID)
+	fmt.Println(len(payments), account.Balance)
+	// Output:
+	// 1 900.00
+}
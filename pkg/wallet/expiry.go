@@ -0,0 +1,77 @@
+package wallet
+
+import (
+	"time"
+
+	"github.com/sidalsoft/wallet/pkg/types"
+)
+
+// WithPaymentTTL задает срок, после которого платежи в статусе INPROGRESS считаются
+// брошенными и отклоняются ExpireStalePayments с возвратом средств. 0 отключает истечение
+func WithPaymentTTL(ttl time.Duration) Option {
+	return func(s *Service) {
+		s.paymentTTL = ttl
+	}
+}
+
+// ExpireStalePayments отклоняет платежи в статусе INPROGRESS, созданные раньше now
+// более чем на WithPaymentTTL, как Reject, и возвращает их
+func (s *Service) ExpireStalePayments(now time.Time) (_ []*types.Payment, err error) {
+	defer s.audit("ExpireStalePayments", &err, "now", now.Unix())
+	defer s.observe("ExpireStalePayments", time.Now())
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.paymentTTL <= 0 {
+		return nil, nil
+	}
+	deadline := now.Add(-s.paymentTTL)
+	var expired []*types.Payment
+	for _, payment := range s.payments {
+		if payment.Status != types.PaymentStatusInProgress || !payment.Created.Before(deadline) {
+			continue
+		}
+		err = s.reject(payment)
+		if err != nil {
+			return expired, err
+		}
+		payment, _ = copyPayment(payment, nil)
+		expired = append(expired, payment)
+	}
+	return expired, nil
+}
+
+// ExpiryJob периодически вызывает ExpireStalePayments в фоне
+type ExpiryJob struct {
+	svc  *Service
+	loop *backgroundLoop
+	// OnError, если задан, получает ошибки каждого запуска
+	OnError func(error)
+}
+
+// NewExpiryJob возвращает ErrInvalidInterval, если interval не положителен.
+// Возраст платежей отсчитывается по часам сервиса
+func NewExpiryJob(svc *Service, interval time.Duration) (*ExpiryJob, error) {
+	j := &ExpiryJob{svc: svc}
+	loop, err := newBackgroundLoop(interval, func(time.Time) {
+		_, err := j.svc.ExpireStalePayments(j.svc.clock())
+		if err != nil && j.OnError != nil {
+			j.OnError(err)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	j.loop = loop
+	return j, nil
+}
+
+// Start запускает задание. Повторный вызов ничего не делает
+func (j *ExpiryJob) Start() {
+	j.loop.start()
+}
+
+// Stop останавливает запущенное Start задание и ждет завершения текущего запуска.
+// Без Start возвращается сразу
+func (j *ExpiryJob) Stop() {
+	j.loop.halt()
+}
@@ -0,0 +1,126 @@
+package wallet
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sidalsoft/wallet/pkg/types"
+)
+
+func TestService_ExpireStalePayments_success(t *testing.T) {
+	s := newTestService()
+	s.paymentTTL = time.Hour
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	account, err := s.addAccountWithBalance("+992928885522", 1_000_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	stale, err := s.Pay(account.ID, 100_00, "auto")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	confirmed, err := s.Pay(account.ID, 100_00, "food")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = s.Confirm(confirmed.ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	now = now.Add(90 * time.Minute)
+	fresh, err := s.Pay(account.ID, 100_00, "auto")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	expired, err := s.ExpireStalePayments(now)
+	if err != nil || len(expired) != 1 || expired[0].ID != stale.ID || expired[0].Status != types.PaymentStatusFail {
+		t.Errorf("ExpireStalePayments(): expired = %v, error = %v", expired, err)
+		return
+	}
+	got, _ := s.FindPaymentByID(fresh.ID)
+	if got.Status != types.PaymentStatusInProgress {
+		t.Errorf("ExpireStalePayments(): fresh payment expired, payment = %v", got)
+		return
+	}
+	acct, _ := s.FindAccountByID(account.ID)
+	if acct.Balance != 800_00 {
+		t.Errorf("ExpireStalePayments(): balance = %v, want %v", acct.Balance, 800_00)
+		return
+	}
+}
+
+func TestService_ExpireStalePayments_disabled(t *testing.T) {
+	s := newTestService()
+	account, err := s.addAccountWithBalance("+992928885522", 1_000_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, err = s.Pay(account.ID, 100_00, "auto")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	expired, err := s.ExpireStalePayments(time.Now().Add(24 * time.Hour))
+	if err != nil || len(expired) != 0 {
+		t.Errorf("ExpireStalePayments(): expired = %v, error = %v", expired, err)
+		return
+	}
+}
+
+func TestExpiryJob_lifecycle(t *testing.T) {
+	_, err := NewExpiryJob(newTestService().Service, -time.Second)
+	if !errors.Is(err, ErrInvalidInterval) {
+		t.Errorf("NewExpiryJob(): error = %v, want %v", err, ErrInvalidInterval)
+		return
+	}
+	j, err := NewExpiryJob(newTestService().Service, time.Hour)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	// Stop без Start не должен зависать, повторные вызовы безопасны
+	j.Stop()
+	j.Start()
+	j.Stop()
+}
+
+func TestExpiryJob_clock(t *testing.T) {
+	s := newTestService()
+	s.paymentTTL = time.Hour
+	now := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	account, err := s.addAccountWithBalance("+992928885522", 1_000_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	payment, err := s.Pay(account.ID, 100_00, "auto")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	// по настоящему времени платеж давно брошен, но по часам сервиса он свежий
+	j, err := NewExpiryJob(s.Service, time.Millisecond)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	j.Start()
+	time.Sleep(20 * time.Millisecond)
+	j.Stop()
+	got, _ := s.FindPaymentByID(payment.ID)
+	if got.Status != types.PaymentStatusInProgress {
+		t.Errorf("ExpiryJob: payment expired before the service clock passed its TTL, payment = %v", got)
+		return
+	}
+}
@@ -0,0 +1,38 @@
+package wallet
+
+import (
+	"github.com/sidalsoft/wallet/pkg/types"
+)
+
+// MoveFavoriteToGroup перекладывает избранное в папку group.
+// Пустая group убирает избранное из папки
+func (s *Service) MoveFavoriteToGroup(favoriteID string, group types.FavoriteGroup) (err error) {
+	defer s.audit("MoveFavoriteToGroup", &err, "favoriteID", favoriteID, "group", group)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	favorite, err := s.findActiveFavoriteByID(favoriteID)
+	if err != nil {
+		return err
+	}
+	favorite.Group = group
+	s.markFavorite(favorite.ID)
+	return nil
+}
+
+// ListFavoritesByGroup возвращает действующее избранное счета из папки group
+// в порядке создания. Пустая group возвращает избранное вне папок
+func (s *Service) ListFavoritesByGroup(accountID int64, group types.FavoriteGroup) ([]types.Favorite, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, err := s.findAccountByID(accountID)
+	if err != nil {
+		return nil, err
+	}
+	var favorites []types.Favorite
+	for _, favorite := range s.favorites {
+		if favorite.AccountID == accountID && favorite.Group == group && favorite.Deleted.IsZero() {
+			favorites = append(favorites, *favorite)
+		}
+	}
+	return favorites, nil
+}
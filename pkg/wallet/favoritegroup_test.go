@@ -0,0 +1,73 @@
+package wallet
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestService_MoveFavoriteToGroup_success(t *testing.T) {
+	s := newTestService()
+	account, payments, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	car, err := s.FavoritePayment(payments[0].ID, "car")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, err = s.FavoritePayment(payments[0].ID, "home")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = s.MoveFavoriteToGroup(car.ID, "transport")
+	if err != nil {
+		t.Errorf("MoveFavoriteToGroup(): error = %v", err)
+		return
+	}
+
+	grouped, err := s.ListFavoritesByGroup(account.ID, "transport")
+	if err != nil || len(grouped) != 1 || grouped[0].ID != car.ID {
+		t.Errorf("ListFavoritesByGroup(): favorites = %v, error = %v", grouped, err)
+		return
+	}
+	ungrouped, err := s.ListFavoritesByGroup(account.ID, "")
+	if err != nil || len(ungrouped) != 1 || ungrouped[0].Name != "home" {
+		t.Errorf("ListFavoritesByGroup(): favorites = %v, error = %v", ungrouped, err)
+		return
+	}
+
+	dir := t.TempDir()
+	err = s.Export(dir)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	imported := newTestService()
+	err = imported.Import(dir)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	grouped, err = imported.ListFavoritesByGroup(account.ID, "transport")
+	if err != nil || len(grouped) != 1 || grouped[0].ID != car.ID {
+		t.Errorf("Import(): group not imported, favorites = %v, error = %v", grouped, err)
+		return
+	}
+}
+
+func TestService_MoveFavoriteToGroup_fail(t *testing.T) {
+	s := newTestService()
+	err := s.MoveFavoriteToGroup("unknown", "transport")
+	if !errors.Is(err, ErrFavoriteNotFound) {
+		t.Errorf("MoveFavoriteToGroup(): must return ErrFavoriteNotFound, returned = %v", err)
+		return
+	}
+	_, err = s.ListFavoritesByGroup(404, "transport")
+	if !errors.Is(err, ErrAccountNotFound) {
+		t.Errorf("ListFavoritesByGroup(): must return ErrAccountNotFound, returned = %v", err)
+		return
+	}
+}
@@ -0,0 +1,152 @@
+package wallet
+
+import (
+	"github.com/sidalsoft/wallet/pkg/rule"
+	"github.com/sidalsoft/wallet/pkg/types"
+)
+
+// Fee задает комиссию за платежи категории: фиксированную часть Flat
+// и процентную часть в базисных пунктах (100 = 1%, не больше 10_000), округляемую
+// до минимальной единицы. Flat задается в сотых долях, как суммы DefaultCurrency,
+// и пересчитывается в минимальные единицы валюты платежа: Flat 1_00 - это 1.00 TJS,
+// 1 JPY или 1.000 BHD
+type Fee struct {
+	Flat        types.Money
+	BasisPoints int64
+}
+
+// maxFeeBasisPoints - 100%, процентная часть комиссии не превышает суммы платежа
+const maxFeeBasisPoints = 10_000
+
+// flatDecimals - число знаков после запятой, в котором задается Fee.Flat
+const flatDecimals = 2
+
+// RoundingMode задает округление процентной части комиссии до минимальной единицы валюты
+type RoundingMode int
+
+// Режимы округления, по умолчанию RoundHalfUp
+const (
+	RoundHalfUp RoundingMode = iota
+	RoundHalfEven
+	RoundDown
+	RoundUp
+)
+
+// WithRounding задает режим округления комиссий
+func WithRounding(mode RoundingMode) Option {
+	return func(s *Service) {
+		s.rounding = mode
+	}
+}
+
+// SetFee назначает комиссию категории. Нулевая комиссия снимает ее
+func (s *Service) SetFee(category types.PaymentCategory, fee Fee) (err error) {
+	defer s.audit("SetFee", &err, "category", category, "flat", fee.Flat, "basisPoints", fee.BasisPoints)
+	err = validateFee(fee)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if fee == (Fee{}) {
+		delete(s.fees, category)
+		return nil
+	}
+	if s.fees == nil {
+		s.fees = make(map[types.PaymentCategory]Fee)
+	}
+	s.fees[category] = fee
+	return nil
+}
+
+// SetFeeAccount назначает счет, на который зачисляются комиссии.
+// 0 отключает зачисление
+func (s *Service) SetFeeAccount(accountID int64) (err error) {
+	defer s.audit("SetFeeAccount", &err, "accountID", accountID)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if accountID != 0 {
+		_, err := s.findAccountByID(accountID)
+		if err != nil {
+			return err
+		}
+	}
+	s.feeAccountID = accountID
+	return nil
+}
+
+func validateFee(fee Fee) error {
+	if fee.Flat < 0 || fee.BasisPoints < 0 || fee.BasisPoints > maxFeeBasisPoints {
+		return ErrInvalidFee
+	}
+	return nil
+}
+
+// fee вычисляет комиссию платежа по первому совпавшему правилу AddFeeRule
+// или по комиссии категории
+func (s *Service) fee(env *rule.Env) types.Money {
+	fee, ok := s.fees[env.Category]
+	for _, r := range s.feeRules {
+		if r.rule.Match(env) {
+			fee, ok = r.fee, true
+			break
+		}
+	}
+	if !ok {
+		return 0
+	}
+	return s.flatFee(fee.Flat, env.Currency) + types.Money(s.percentFee(int64(env.Amount), fee.BasisPoints))
+}
+
+// flatFee пересчитывает Fee.Flat в минимальные единицы валюты currency
+func (s *Service) flatFee(flat types.Money, currency types.Currency) types.Money {
+	decimals := currency.Decimals()
+	for ; decimals > flatDecimals; decimals-- {
+		flat *= 10
+	}
+	unit := int64(1)
+	for ; decimals < flatDecimals; decimals++ {
+		unit *= 10
+	}
+	return types.Money(round(int64(flat), unit, s.rounding))
+}
+
+// percentFee вычисляет amount*basisPoints/10_000 без переполнения: целые десятки тысяч
+// суммы умножаются отдельно, а округляется только часть от остатка
+func (s *Service) percentFee(amount int64, basisPoints int64) int64 {
+	return amount/10_000*basisPoints + round(amount%10_000*basisPoints, 10_000, s.rounding)
+}
+
+// round делит неотрицательное n на d с округлением mode
+func round(n, d int64, mode RoundingMode) int64 {
+	q, r := n/d, n%d
+	switch mode {
+	case RoundDown:
+		return q
+	case RoundUp:
+		if r > 0 {
+			q++
+		}
+	case RoundHalfEven:
+		if 2*r > d || (2*r == d && q%2 == 1) {
+			q++
+		}
+	default:
+		if 2*r >= d {
+			q++
+		}
+	}
+	return q
+}
+
+// creditFee зачисляет комиссию на счет комиссий, отрицательная сумма списывает ее при возврате
+func (s *Service) creditFee(fee types.Money) {
+	if fee == 0 || s.feeAccountID == 0 {
+		return
+	}
+	account, err := s.findAccountByID(s.feeAccountID)
+	if err != nil {
+		return
+	}
+	s.changeBalance(account, fee)
+}
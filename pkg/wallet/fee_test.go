@@ -0,0 +1,164 @@
+package wallet
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/sidalsoft/wallet/pkg/types"
+)
+
+func TestService_SetFee_success(t *testing.T) {
+	s := newTestService()
+	account, err := s.addAccountWithBalance("+992928885522", 1_000_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	feeAccount, err := s.RegisterAccount("+992000000000")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = s.SetFeeAccount(feeAccount.ID)
+	if err != nil {
+		t.Errorf("SetFeeAccount(): error = %v", err)
+		return
+	}
+	err = s.SetFee("transfer", Fee{Flat: 1_00, BasisPoints: 150})
+	if err != nil {
+		t.Errorf("SetFee(): error = %v", err)
+		return
+	}
+
+	payment, err := s.Pay(account.ID, 100_00, "transfer")
+	if err != nil {
+		t.Errorf("Pay(): error = %v", err)
+		return
+	}
+	account, _ = s.FindAccountByID(account.ID)
+	feeAccount, _ = s.FindAccountByID(feeAccount.ID)
+	if payment.Fee != 2_50 || account.Balance != 897_50 || feeAccount.Balance != 2_50 {
+		t.Errorf("Pay(): fee not applied, payment = %v, account = %v, fee account = %v", payment, account, feeAccount)
+		return
+	}
+	_, err = s.Pay(account.ID, 100_00, "auto")
+	account, _ = s.FindAccountByID(account.ID)
+	if err != nil || account.Balance != 797_50 {
+		t.Errorf("Pay(): fee applied to other category, account = %v, error = %v", account, err)
+		return
+	}
+
+	err = s.Reject(payment.ID)
+	if err != nil {
+		t.Errorf("Reject(): error = %v", err)
+		return
+	}
+	account, _ = s.FindAccountByID(account.ID)
+	feeAccount, _ = s.FindAccountByID(feeAccount.ID)
+	if account.Balance != 900_00 || feeAccount.Balance != 0 {
+		t.Errorf("Reject(): fee not returned, account = %v, fee account = %v", account, feeAccount)
+		return
+	}
+}
+
+func TestService_SetFee_fail(t *testing.T) {
+	s := newTestService()
+	account, err := s.addAccountWithBalance("+992928885522", 100_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = s.SetFee("transfer", Fee{Flat: -1})
+	if !errors.Is(err, ErrInvalidFee) {
+		t.Errorf("SetFee(): must return ErrInvalidFee, returned = %v", err)
+		return
+	}
+	err = s.SetFee("transfer", Fee{BasisPoints: 10_001})
+	if !errors.Is(err, ErrInvalidFee) {
+		t.Errorf("SetFee(): must return ErrInvalidFee for more than 100%%, returned = %v", err)
+		return
+	}
+	err = s.AddFeeRule("amount > 0", Fee{BasisPoints: 20_000})
+	if !errors.Is(err, ErrInvalidFee) {
+		t.Errorf("AddFeeRule(): must return ErrInvalidFee for more than 100%%, returned = %v", err)
+		return
+	}
+	_ = s.SetFee("transfer", Fee{Flat: 1})
+	_, err = s.Pay(account.ID, 100_00, "transfer")
+	if !errors.Is(err, ErrNotEnoughBalance) {
+		t.Errorf("Pay(): must return ErrNotEnoughBalance, returned = %v", err)
+		return
+	}
+}
+
+func TestService_fee_rounding(t *testing.T) {
+	tests := []struct {
+		currency types.Currency
+		amount   types.Money
+		mode     RoundingMode
+		want     types.Money
+	}{
+		{"TJS", 1_25, RoundHalfUp, 1},
+		{"TJS", 1_50, RoundHalfUp, 2},
+		{"USD", 2_50, RoundHalfEven, 2},
+		{"USD", 3_50, RoundHalfEven, 4},
+		{"JPY", 150, RoundDown, 1},
+		{"JPY", 101, RoundUp, 2},
+		{"BHD", 1_250, RoundHalfEven, 12},
+		{"BHD", 1_250, RoundHalfUp, 13},
+	}
+	for _, tt := range tests {
+		s := NewService(WithRounding(tt.mode))
+		err := s.SetFee("auto", Fee{BasisPoints: 100})
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		account, err := s.RegisterAccountWithCurrency("+992928885522", tt.currency)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		err = s.Deposit(account.ID, 100_000)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		payment, err := s.Pay(account.ID, tt.amount, "auto")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if payment.Fee != tt.want {
+			t.Errorf("Pay(): %v %v with mode %v: fee = %v, want = %v", tt.currency, tt.currency.Format(tt.amount), tt.mode, payment.Fee, tt.want)
+		}
+	}
+}
+
+func TestService_fee_currency(t *testing.T) {
+	tests := []struct {
+		currency types.Currency
+		flat     types.Money
+		want     types.Money
+	}{
+		{"TJS", 1_00, 1_00},
+		{"JPY", 1_00, 1},
+		{"JPY", 1_50, 2},
+		{"BHD", 1_00, 1_000},
+	}
+	s := newTestService()
+	for _, tt := range tests {
+		if got := s.flatFee(tt.flat, tt.currency); got != tt.want {
+			t.Errorf("flatFee(%v, %v) = %v, want %v", tt.flat, tt.currency, got, tt.want)
+		}
+	}
+	// сумма, умножение которой на базисные пункты переполнило бы int64
+	amount := int64(math.MaxInt64/10_000) * 100
+	if got, want := s.percentFee(amount, 10_000), amount; got != want {
+		t.Errorf("percentFee(%v, 10_000) = %v, want %v", amount, got, want)
+	}
+	if got, want := s.percentFee(amount, 100), amount/100; got != want {
+		t.Errorf("percentFee(%v, 100) = %v, want %v", amount, got, want)
+	}
+}
@@ -0,0 +1,98 @@
+package wallet
+
+import "github.com/sidalsoft/wallet/pkg/types"
+
+// Payments возвращает страницу платежей счета и общее количество его платежей
+func (s *Service) Payments(accountID int64, offset, limit int) ([]types.Payment, int, error) {
+	if offset < 0 || limit <= 0 {
+		return nil, 0, ErrInvalidPage
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, err := s.findAccountByID(accountID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var payments []types.Payment
+	total := 0
+	for _, v := range s.payments {
+		if v.AccountID != accountID {
+			continue
+		}
+		if total >= offset && len(payments) < limit {
+			payments = append(payments, *v)
+		}
+		total++
+	}
+	return payments, total, nil
+}
+
+// PaymentsAfter возвращает до limit платежей счета, следующих за платежом cursor
+// (с начала истории, если cursor пуст), и курсор следующей страницы.
+// Пустой курсор в ответе означает, что страниц больше нет
+func (s *Service) PaymentsAfter(accountID int64, cursor string, limit int) ([]types.Payment, string, error) {
+	if limit <= 0 {
+		return nil, "", ErrInvalidPage
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, err := s.findAccountByID(accountID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	found := cursor == ""
+	var payments []types.Payment
+	for _, v := range s.payments {
+		if v.AccountID != accountID {
+			continue
+		}
+		if !found {
+			found = v.ID == cursor
+			continue
+		}
+		if len(payments) == limit {
+			return payments, payments[limit-1].ID, nil
+		}
+		payments = append(payments, *v)
+	}
+	if !found {
+		return nil, "", ErrPaymentNotFound
+	}
+	return payments, "", nil
+}
+
+// ForEachPayment вызывает fn для копий всех платежей в порядке проведения, пока fn
+// возвращает true. Платежи копируются под блокировкой, поэтому fn видит согласованный
+// снимок и может вызывать методы сервиса
+func (s *Service) ForEachPayment(fn func(p types.Payment) bool) {
+	s.mu.RLock()
+	payments := make([]types.Payment, len(s.payments))
+	for i, payment := range s.payments {
+		payments[i] = *payment
+	}
+	s.mu.RUnlock()
+
+	for _, payment := range payments {
+		if !fn(payment) {
+			return
+		}
+	}
+}
+
+// ForEachAccount вызывает fn для копий всех счетов в порядке регистрации, как ForEachPayment
+func (s *Service) ForEachAccount(fn func(a types.Account) bool) {
+	s.mu.RLock()
+	accounts := make([]types.Account, len(s.accounts))
+	for i, account := range s.accounts {
+		accounts[i] = *account
+	}
+	s.mu.RUnlock()
+
+	for _, account := range accounts {
+		if !fn(account) {
+			return
+		}
+	}
+}
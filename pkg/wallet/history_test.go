@@ -0,0 +1,156 @@
+package wallet
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/sidalsoft/wallet/pkg/types"
+)
+
+func (s *testService) addPayments(count int) (int64, error) {
+	account, err := s.addAccountWithBalance("+992928885522", 1_000_00)
+	if err != nil {
+		return 0, err
+	}
+	for i := 0; i < count; i++ {
+		_, err = s.Pay(account.ID, 1_00, "auto")
+		if err != nil {
+			return 0, err
+		}
+	}
+	return account.ID, nil
+}
+
+func TestService_Payments_success(t *testing.T) {
+	s := newTestService()
+	accountID, err := s.addPayments(5)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	page, total, err := s.Payments(accountID, 3, 10)
+	if err != nil {
+		t.Errorf("Payments(): error = %v", err)
+		return
+	}
+	if total != 5 || len(page) != 2 || page[0].ID != s.payments[3].ID {
+		t.Errorf("Payments(): wrong page = %v, total = %v", page, total)
+		return
+	}
+}
+
+func TestService_Payments_fail(t *testing.T) {
+	s := newTestService()
+	_, _, err := s.Payments(1, 0, 10)
+	if !errors.Is(err, ErrAccountNotFound) {
+		t.Errorf("Payments(): must return ErrAccountNotFound, returned = %v", err)
+		return
+	}
+	_, _, err = s.Payments(1, -1, 10)
+	if !errors.Is(err, ErrInvalidPage) {
+		t.Errorf("Payments(): must return ErrInvalidPage, returned = %v", err)
+		return
+	}
+}
+
+func TestService_PaymentsAfter_success(t *testing.T) {
+	s := newTestService()
+	accountID, err := s.addPayments(5)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	cursor := ""
+	count := 0
+	for pages := 0; ; pages++ {
+		if pages > 3 {
+			t.Error("PaymentsAfter(): pagination doesn't end")
+			return
+		}
+		page, next, err := s.PaymentsAfter(accountID, cursor, 2)
+		if err != nil {
+			t.Errorf("PaymentsAfter(): error = %v", err)
+			return
+		}
+		for _, payment := range page {
+			if payment.ID != s.payments[count].ID {
+				t.Errorf("PaymentsAfter(): wrong payment = %v", payment)
+				return
+			}
+			count++
+		}
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+	if count != 5 {
+		t.Errorf("PaymentsAfter(): returned %v payments, want 5", count)
+		return
+	}
+}
+
+func TestService_PaymentsAfter_fail(t *testing.T) {
+	s := newTestService()
+	accountID, err := s.addPayments(1)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, _, err = s.PaymentsAfter(accountID, "unknown", 2)
+	if !errors.Is(err, ErrPaymentNotFound) {
+		t.Errorf("PaymentsAfter(): must return ErrPaymentNotFound, returned = %v", err)
+		return
+	}
+}
+
+func TestService_ForEachPayment_success(t *testing.T) {
+	s := newTestService()
+	accountID, err := s.addPayments(5)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	var ids []string
+	s.ForEachPayment(func(p types.Payment) bool {
+		ids = append(ids, p.ID)
+		// вызовы сервиса не блокируются и не меняют обходимый снимок
+		_, err := s.Pay(accountID, 1, "auto")
+		if err != nil {
+			t.Error(err)
+		}
+		return len(ids) < 3
+	})
+	if len(ids) != 3 || ids[0] != s.payments[0].ID {
+		t.Errorf("ForEachPayment(): ids = %v", ids)
+		return
+	}
+	count := 0
+	s.ForEachPayment(func(p types.Payment) bool {
+		count++
+		return true
+	})
+	if count != 8 {
+		t.Errorf("ForEachPayment(): count = %v, want 8", count)
+		return
+	}
+}
+
+func TestService_ForEachAccount_success(t *testing.T) {
+	s := newTestService()
+	_, err := s.addPayments(1)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	var phones []types.Phone
+	s.ForEachAccount(func(a types.Account) bool {
+		phones = append(phones, a.Phone)
+		a.Balance = 0
+		return true
+	})
+	if len(phones) != 1 || s.accounts[0].Balance == 0 {
+		t.Errorf("ForEachAccount(): phones = %v, account = %v", phones, s.accounts[0])
+		return
+	}
+}
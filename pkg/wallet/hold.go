@@ -0,0 +1,206 @@
+package wallet
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sidalsoft/wallet/pkg/types"
+)
+
+// HoldOperation - вид операции, которой записывается удержание средств Hold.
+// ID операции - идентификатор удержания, Payload - HoldState в JSON
+const HoldOperation = "hold"
+
+// HoldStatus - состояние удержания
+type HoldStatus string
+
+const (
+	HoldActive   HoldStatus = "ACTIVE"
+	HoldCaptured HoldStatus = "CAPTURED"
+	HoldReleased HoldStatus = "RELEASED"
+)
+
+// HoldState - удержанная сумма Amount и ее судьба. Category - категория платежа,
+// которым Capture спишет удержание. Captured - сумма, списанная Capture
+type HoldState struct {
+	Amount   types.Money           `json:"amount"`
+	Category types.PaymentCategory `json:"category,omitempty"`
+	Status   HoldStatus            `json:"status"`
+	Captured types.Money           `json:"captured,omitempty"`
+}
+
+// Hold резервирует amount на счете, не списывая его: баланс не меняется, но доступный
+// остаток AvailableBalance уменьшается на сумму активных удержаний. Capture спишет
+// удержание платежом категории category: с ее комиссиями, бюджетами и кешбэком
+func (s *Service) Hold(accountID int64, amount types.Money, category types.PaymentCategory) (_ *types.Operation, err error) {
+	defer s.audit("Hold", &err, "accountID", accountID, "amount", amount, "category", category)
+	defer s.observe("Hold", time.Now())
+	if amount <= 0 {
+		return nil, ErrAmountMustBePositive
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	account, err := s.findAccountByID(accountID)
+	if err != nil {
+		return nil, err
+	}
+	if account.Status == types.AccountStatusFrozen {
+		return nil, ErrAccountFrozen
+	}
+	if account.Status == types.AccountStatusClosed {
+		return nil, ErrAccountClosed
+	}
+	if !s.canDebit(account, amount) {
+		return nil, ErrNotEnoughBalance
+	}
+	payload, err := json.Marshal(HoldState{Amount: amount, Category: category, Status: HoldActive})
+	if err != nil {
+		return nil, err
+	}
+	operation := &types.Operation{
+		ID:        uuid.New().String(),
+		Kind:      HoldOperation,
+		AccountID: account.ID,
+		Payload:   payload,
+	}
+	s.operations = append(s.operations, operation)
+	s.markOperation(operation.ID)
+	if s.heldFunds == nil {
+		s.heldFunds = make(holdIndex)
+	}
+	s.heldFunds[account.ID] += amount
+	return copyOperation(operation, nil)
+}
+
+// Capture списывает по удержанию holdID сумму amount, не большую удержанной, обычным
+// платежом. Остаток удержания освобождается
+func (s *Service) Capture(holdID string, amount types.Money) (_ *types.Payment, err error) {
+	defer s.audit("Capture", &err, "holdID", holdID, "amount", amount)
+	defer s.observe("Capture", time.Now())
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	operation, hold, err := s.findActiveHold(holdID)
+	if err != nil {
+		return nil, err
+	}
+	if amount > hold.Amount {
+		return nil, ErrHoldExceeded
+	}
+	// удержание закрывается до платежа, чтобы не уменьшать доступный для него остаток
+	captured := hold
+	captured.Status = HoldCaptured
+	captured.Captured = amount
+	err = s.setHoldState(operation, captured)
+	if err != nil {
+		return nil, err
+	}
+	payment, err := s.pay(operation.AccountID, amount, hold.Category)
+	if err != nil {
+		_ = s.setHoldState(operation, hold)
+		return nil, err
+	}
+	operation.PaymentID = payment.ID
+	return copyPayment(payment, nil)
+}
+
+// ReleaseHold снимает удержание holdID без списания
+func (s *Service) ReleaseHold(holdID string) (err error) {
+	defer s.audit("ReleaseHold", &err, "holdID", holdID)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	operation, hold, err := s.findActiveHold(holdID)
+	if err != nil {
+		return err
+	}
+	hold.Status = HoldReleased
+	return s.setHoldState(operation, hold)
+}
+
+// FindHoldByID возвращает состояние удержания holdID
+func (s *Service) FindHoldByID(holdID string) (*HoldState, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, hold, err := s.findHold(holdID)
+	if err != nil {
+		return nil, err
+	}
+	return &hold, nil
+}
+
+// AvailableBalance возвращает остаток счета за вычетом активных удержаний
+func (s *Service) AvailableBalance(accountID int64) (types.Money, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	account, err := s.findAccountByID(accountID)
+	if err != nil {
+		return 0, err
+	}
+	return account.Balance - s.held(account.ID), nil
+}
+
+// held возвращает сумму активных удержаний счета по индексу heldFunds
+func (s *Service) held(accountID int64) types.Money {
+	return s.heldFunds[accountID]
+}
+
+// holdIndex - суммы активных удержаний по счетам. Меняется вместе с состоянием удержаний
+// и строится заново по операциям, когда их заменяет импорт
+type holdIndex map[int64]types.Money
+
+// rebuildHolds строит индекс удержаний по операциям
+func (s *Service) rebuildHolds() {
+	s.heldFunds = make(holdIndex)
+	for _, operation := range s.operations {
+		if operation.Kind != HoldOperation {
+			continue
+		}
+		var hold HoldState
+		if json.Unmarshal(operation.Payload, &hold) == nil && hold.Status == HoldActive {
+			s.heldFunds[operation.AccountID] += hold.Amount
+		}
+	}
+}
+
+func (s *Service) findHold(holdID string) (*types.Operation, HoldState, error) {
+	for _, operation := range s.operations {
+		if operation.ID != holdID || operation.Kind != HoldOperation {
+			continue
+		}
+		var hold HoldState
+		err := json.Unmarshal(operation.Payload, &hold)
+		if err != nil {
+			return nil, HoldState{}, err
+		}
+		return operation, hold, nil
+	}
+	return nil, HoldState{}, ErrHoldNotFound
+}
+
+func (s *Service) findActiveHold(holdID string) (*types.Operation, HoldState, error) {
+	operation, hold, err := s.findHold(holdID)
+	if err != nil {
+		return nil, HoldState{}, err
+	}
+	if hold.Status != HoldActive {
+		return nil, HoldState{}, ErrHoldNotActive
+	}
+	return operation, hold, nil
+}
+
+// setHoldState записывает новое состояние удержания и обновляет индекс heldFunds.
+// Вызывается только для активного удержания или для его возврата в активное
+func (s *Service) setHoldState(operation *types.Operation, hold HoldState) error {
+	payload, err := json.Marshal(hold)
+	if err != nil {
+		return err
+	}
+	operation.Payload = payload
+	s.markOperation(operation.ID)
+	if hold.Status == HoldActive {
+		s.heldFunds[operation.AccountID] += hold.Amount
+	} else {
+		s.heldFunds[operation.AccountID] -= hold.Amount
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package wallet
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestService_Hold_success(t *testing.T) {
+	s := newTestService()
+	account, err := s.addAccountWithBalance("+992928885522", 1_000_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	hold, err := s.Hold(account.ID, 600_00, "auto")
+	if err != nil {
+		t.Errorf("Hold(): error = %v", err)
+		return
+	}
+	available, err := s.AvailableBalance(account.ID)
+	if err != nil || available != 400_00 {
+		t.Errorf("AvailableBalance(): available = %v, error = %v", available, err)
+		return
+	}
+	_, err = s.Pay(account.ID, 500_00, "auto")
+	if !errors.Is(err, ErrNotEnoughBalance) {
+		t.Errorf("Pay(): held funds must not be spent, error = %v", err)
+		return
+	}
+
+	payment, err := s.Capture(hold.ID, 450_00)
+	if err != nil || payment.Amount != 450_00 {
+		t.Errorf("Capture(): payment = %v, error = %v", payment, err)
+		return
+	}
+	got, _ := s.FindAccountByID(account.ID)
+	available, _ = s.AvailableBalance(account.ID)
+	if got.Balance != 550_00 || available != 550_00 {
+		t.Errorf("Capture(): balance = %v, available = %v", got.Balance, available)
+		return
+	}
+	state, err := s.FindHoldByID(hold.ID)
+	if err != nil || state.Status != HoldCaptured || state.Captured != 450_00 {
+		t.Errorf("FindHoldByID(): hold = %v, error = %v", state, err)
+		return
+	}
+
+	hold, err = s.Hold(account.ID, 100_00, "auto")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = s.ReleaseHold(hold.ID)
+	if err != nil {
+		t.Errorf("ReleaseHold(): error = %v", err)
+		return
+	}
+	available, _ = s.AvailableBalance(account.ID)
+	if available != 550_00 {
+		t.Errorf("ReleaseHold(): available = %v, want %v", available, 550_00)
+		return
+	}
+}
+
+func TestService_Hold_fail(t *testing.T) {
+	s := newTestService()
+	account, err := s.addAccountWithBalance("+992928885522", 100_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, err = s.Hold(account.ID, 200_00, "auto")
+	if !errors.Is(err, ErrNotEnoughBalance) {
+		t.Errorf("Hold(): error = %v, want %v", err, ErrNotEnoughBalance)
+		return
+	}
+	hold, err := s.Hold(account.ID, 50_00, "auto")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, err = s.Capture(hold.ID, 60_00)
+	if !errors.Is(err, ErrHoldExceeded) {
+		t.Errorf("Capture(): error = %v, want %v", err, ErrHoldExceeded)
+		return
+	}
+	_, err = s.Capture("unknown", 10_00)
+	if !errors.Is(err, ErrHoldNotFound) {
+		t.Errorf("Capture(): error = %v, want %v", err, ErrHoldNotFound)
+		return
+	}
+	err = s.ReleaseHold(hold.ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, err = s.Capture(hold.ID, 10_00)
+	if !errors.Is(err, ErrHoldNotActive) {
+		t.Errorf("Capture(): error = %v, want %v", err, ErrHoldNotActive)
+		return
+	}
+}
+
+func TestService_Capture_category(t *testing.T) {
+	s := newTestService()
+	account, err := s.addAccountWithBalance("+992928885522", 1_000_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = s.SetFee("hotel", Fee{BasisPoints: 100})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	hold, err := s.Hold(account.ID, 500_00, "hotel")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	payment, err := s.Capture(hold.ID, 400_00)
+	if err != nil || payment.Category != "hotel" || payment.Fee != 4_00 {
+		t.Errorf("Capture(): payment = %v, error = %v", payment, err)
+		return
+	}
+}
+
+func TestService_Hold_import(t *testing.T) {
+	s := newTestService()
+	account, err := s.addAccountWithBalance("+992928885522", 1_000_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, err = s.Hold(account.ID, 300_00, "auto")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	dir := t.TempDir()
+	err = s.Export(dir)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	imported := &Service{}
+	err = imported.Import(dir)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	available, err := imported.AvailableBalance(account.ID)
+	if err != nil || available != 700_00 {
+		t.Errorf("AvailableBalance(): available = %v, error = %v, want %v", available, err, 700_00)
+		return
+	}
+}
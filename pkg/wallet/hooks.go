@@ -0,0 +1,36 @@
+package wallet
+
+import "github.com/sidalsoft/wallet/pkg/types"
+
+// AccountHooks вызываются вокруг операций жизненного цикла счета.
+// Before-хуки вызываются под блокировкой сервиса и могут отменить операцию,
+// вернув ошибку; After-хуки вызываются после снятия блокировки
+type AccountHooks struct {
+	BeforeRegister    func(phone types.Phone) error
+	AfterRegister     func(account types.Account)
+	BeforeClose       func(account types.Account) error
+	AfterClose        func(account types.Account)
+	BeforeChangePhone func(account types.Account, phone types.Phone) error
+	AfterChangePhone  func(account types.Account, oldPhone types.Phone)
+}
+
+// HookError возвращается, когда операцию отменил Before-хук.
+// Исходная ошибка хука доступна через errors.Is и errors.As
+type HookError struct {
+	Op  string
+	Err error
+}
+
+func (e *HookError) Error() string {
+	return e.Op + ": rejected by hook: " + e.Err.Error()
+}
+
+func (e *HookError) Unwrap() error {
+	return e.Err
+}
+
+func (s *Service) SetAccountHooks(hooks AccountHooks) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hooks = hooks
+}
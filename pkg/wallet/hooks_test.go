@@ -0,0 +1,76 @@
+package wallet
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/sidalsoft/wallet/pkg/types"
+)
+
+var errCRMUnavailable = errors.New("crm unavailable")
+
+func TestService_SetAccountHooks_success(t *testing.T) {
+	s := newTestService()
+	var registered, closed []int64
+	var changed []types.Phone
+	s.SetAccountHooks(AccountHooks{
+		AfterRegister: func(account types.Account) {
+			registered = append(registered, account.ID)
+		},
+		AfterClose: func(account types.Account) {
+			closed = append(closed, account.ID)
+		},
+		AfterChangePhone: func(account types.Account, oldPhone types.Phone) {
+			changed = append(changed, oldPhone, account.Phone)
+		},
+	})
+	account, err := s.RegisterAccount("+992928885522")
+	if err != nil {
+		t.Errorf("RegisterAccount(): error = %v", err)
+		return
+	}
+	err = s.ChangePhone(account.ID, "+992928000000")
+	if err != nil {
+		t.Errorf("ChangePhone(): error = %v", err)
+		return
+	}
+	err = s.CloseAccount(account.ID)
+	if err != nil {
+		t.Errorf("CloseAccount(): error = %v", err)
+		return
+	}
+	if len(registered) != 1 || len(closed) != 1 || len(changed) != 2 || changed[0] != "+992928885522" {
+		t.Errorf("hooks not called, registered = %v, closed = %v, changed = %v", registered, closed, changed)
+		return
+	}
+}
+
+func TestService_SetAccountHooks_fail(t *testing.T) {
+	s := newTestService()
+	s.SetAccountHooks(AccountHooks{
+		BeforeRegister: func(phone types.Phone) error {
+			return errCRMUnavailable
+		},
+	})
+	_, err := s.RegisterAccount("+992928885522")
+	var hookErr *HookError
+	if !errors.As(err, &hookErr) || !errors.Is(err, errCRMUnavailable) || hookErr.Op != "RegisterAccount" {
+		t.Errorf("RegisterAccount(): must return *HookError, returned = %v", err)
+		return
+	}
+	if len(s.accounts) != 0 {
+		t.Errorf("RegisterAccount(): account registered, accounts = %v", s.accounts)
+		return
+	}
+}
+
+func TestService_ChangePhone_fail(t *testing.T) {
+	s := newTestService()
+	first, _ := s.RegisterAccount("+992928885522")
+	_, _ = s.RegisterAccount("+992928000000")
+	err := s.ChangePhone(first.ID, "+992928000000")
+	if !errors.Is(err, ErrPhoneRegistered) {
+		t.Errorf("ChangePhone(): must return ErrPhoneRegistered, returned = %v", err)
+		return
+	}
+}
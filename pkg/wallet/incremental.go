@@ -0,0 +1,308 @@
+package wallet
+
+import (
+	"crypto/sha256"
+	"encoding"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// dirtySet отмечает счета, платежи и избранное, измененные после последнего Export.
+// Пока дамп в каталоге dir полон и актуален с учетом отмеченных изменений,
+// ExportIncremental может дописать к нему только измененные записи
+type dirtySet struct {
+	mu         sync.Mutex
+	dir        string
+	full       bool
+	accounts   map[int64]bool
+	payments   map[string]bool
+	favorites  map[string]bool
+	scheduled  map[string]bool
+	operations map[string]bool
+	merchants  map[string]bool
+	// hashes - состояние SHA-256 дописываемых дампов после последней записи, чтобы
+	// считать контрольную сумму только по дописанным данным
+	hashes map[string][]byte
+}
+
+func (d *dirtySet) markAccount(accountID int64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.accounts == nil {
+		d.accounts = make(map[int64]bool)
+	}
+	d.accounts[accountID] = true
+}
+
+func (d *dirtySet) markPayment(paymentID string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.payments == nil {
+		d.payments = make(map[string]bool)
+	}
+	d.payments[paymentID] = true
+}
+
+func (d *dirtySet) markFavorite(favoriteID string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.favorites == nil {
+		d.favorites = make(map[string]bool)
+	}
+	d.favorites[favoriteID] = true
+}
+
+func (d *dirtySet) markScheduled(scheduledID string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.scheduled == nil {
+		d.scheduled = make(map[string]bool)
+	}
+	d.scheduled[scheduledID] = true
+}
+
+func (d *dirtySet) markOperation(operationID string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.operations == nil {
+		d.operations = make(map[string]bool)
+	}
+	d.operations[operationID] = true
+}
+
+func (d *dirtySet) markMerchant(merchantID string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.merchants == nil {
+		d.merchants = make(map[string]bool)
+	}
+	d.merchants[merchantID] = true
+}
+
+// markFull отмечает изменения, которые нельзя выразить дописыванием: удаление и импорт
+func (d *dirtySet) markFull() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.full = true
+}
+
+// reset вызывается под d.mu после записи дампов в dir. К сжатым дампам дописывать нельзя
+func (d *dirtySet) reset(dir string, compressed bool) {
+	d.dir = dir
+	d.full = compressed
+	d.accounts = nil
+	d.payments = nil
+	d.favorites = nil
+	d.scheduled = nil
+	d.operations = nil
+	d.merchants = nil
+}
+
+// markAccount и остальные mark-методы отмечают изменение для ExportIncremental и журнала
+func (s *Service) markAccount(accountID int64) {
+	s.dirty.markAccount(accountID)
+	if s.wal != nil {
+		s.wal.pending.markAccount(accountID)
+	}
+}
+
+func (s *Service) markPayment(paymentID string) {
+	s.dirty.markPayment(paymentID)
+	if s.wal != nil {
+		s.wal.pending.markPayment(paymentID)
+	}
+}
+
+func (s *Service) markFavorite(favoriteID string) {
+	s.dirty.markFavorite(favoriteID)
+	if s.wal != nil {
+		s.wal.pending.markFavorite(favoriteID)
+	}
+}
+
+// markScheduled, markOperation и markMerchant нужны только журналу: ExportIncremental
+// записывает запланированные платежи, операции и получателей целиком
+func (s *Service) markScheduled(scheduledID string) {
+	if s.wal != nil {
+		s.wal.pending.markScheduled(scheduledID)
+	}
+}
+
+func (s *Service) markOperation(operationID string) {
+	if s.wal != nil {
+		s.wal.pending.markOperation(operationID)
+	}
+}
+
+func (s *Service) markMerchant(merchantID string) {
+	if s.wal != nil {
+		s.wal.pending.markMerchant(merchantID)
+	}
+}
+
+func (s *Service) markFull() {
+	s.dirty.markFull()
+	if s.wal != nil {
+		s.wal.pending.markFull()
+	}
+}
+
+// ExportIncremental дописывает в дампы каталога dir только счета, платежи и избранное,
+// измененные после предыдущего экспорта в этот каталог; Import применяет последние строки.
+// Запланированные платежи и операции записываются целиком. Если каталог экспортировался
+// не этим сервисом, сжатым, зашифрованным, с WithCanonicalOrder или после удаления данных,
+// выполняется полный Export в порядке добавления.
+//
+// Манифест записывается после дописывания дампов. Если процесс упадет между ними,
+// Import вернет ErrCorruptedDump для дописанных дампов, хотя их прежние строки целы.
+// Для восстановления удалите manifest.sha256 (каталог без манифеста импортируется без
+// проверки), выполните Import и сразу полный Export, который запишет новый манифест
+func (s *Service) ExportIncremental(dir string) error {
+	defer s.observe("Export", time.Now())
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	s.dirty.mu.Lock()
+	defer s.dirty.mu.Unlock()
+	if s.dirty.full || s.dirty.dir != dir || s.dumpKey != nil {
+		return s.exportLocked(dir, false, exportConfig{order: OrderInsertion})
+	}
+	sums, order, err := readManifest(dir)
+	if err != nil || order != OrderInsertion {
+		return s.exportLocked(dir, false, exportConfig{order: OrderInsertion})
+	}
+
+	exportErr := &ExportError{Failed: make(map[string]error)}
+	appendDump := func(data string, name string) {
+		file := name + ".dump"
+		sum, state, err := appendFile(dir+"/"+file, data, s.dirty.hashes[file])
+		if err != nil {
+			delete(s.dirty.hashes, file)
+			exportErr.Failed[name] = err
+			return
+		}
+		if s.dirty.hashes == nil {
+			s.dirty.hashes = make(map[string][]byte)
+		}
+		s.dirty.hashes[file] = state
+		sums[file] = sum
+	}
+	save := func(data string, name string) {
+		file, sum, err := s.writeDump(dir, name, data, false)
+		if err != nil {
+			exportErr.Failed[name] = err
+			return
+		}
+		sums[file] = sum
+	}
+
+	data := strings.Builder{}
+	for _, account := range s.accounts {
+		if s.dirty.accounts[account.ID] {
+			data.WriteString(account.ToString() + "\n")
+		}
+	}
+	if data.Len() > 0 {
+		appendDump(data.String(), "accounts")
+	}
+
+	data.Reset()
+	for _, favorite := range s.favorites {
+		if s.dirty.favorites[favorite.ID] {
+			data.WriteString(favorite.ToString() + "\n")
+		}
+	}
+	if data.Len() > 0 {
+		appendDump(data.String(), "favorites")
+	}
+
+	data.Reset()
+	for _, payment := range s.payments {
+		if s.dirty.payments[payment.ID] {
+			data.WriteString(payment.ToString() + "\n")
+		}
+	}
+	if data.Len() > 0 {
+		appendDump(data.String(), "payments")
+	}
+
+	if len(s.scheduled) > 0 {
+		data.Reset()
+		for _, scheduled := range s.scheduled {
+			data.WriteString(scheduled.ToString() + "\n")
+		}
+		save(data.String(), "scheduled")
+	}
+
+	if len(s.operations) > 0 {
+		data.Reset()
+		for _, operation := range s.operations {
+			data.WriteString(operationToString(operation) + "\n")
+		}
+		save(data.String(), "operations")
+	}
+
+	if len(s.merchants) > 0 {
+		data.Reset()
+		for _, merchant := range s.merchants {
+			data.WriteString(merchant.ToString() + "\n")
+		}
+		save(data.String(), "merchants")
+	}
+
+	err = writeManifest(dir, sums, OrderInsertion)
+	if err != nil {
+		exportErr.Failed[manifestName] = err
+	}
+	if len(exportErr.Failed) > 0 {
+		// дописанные части могли остаться, следующий экспорт перепишет дампы целиком
+		s.dirty.full = true
+		return exportErr
+	}
+	s.dirty.reset(dir, false)
+	return nil
+}
+
+// appendFile дописывает данные в конец дампа, создавая его с заголовком версии,
+// и возвращает контрольную сумму файла целиком и состояние хеша для следующего вызова.
+// Без состояния state файл хешируется целиком один раз
+func appendFile(path string, data string, state []byte) (string, []byte, error) {
+	h := sha256.New()
+	_, err := os.Stat(path)
+	switch {
+	case os.IsNotExist(err):
+		data = addDumpHeader(data)
+	case state != nil:
+		err = h.(encoding.BinaryUnmarshaler).UnmarshalBinary(state)
+	default:
+		var content []byte
+		content, err = ioutil.ReadFile(path)
+		h.Write(content)
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0666)
+	if err != nil {
+		return "", nil, err
+	}
+	_, err = f.WriteString(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	h.Write([]byte(data))
+	state, err = h.(encoding.BinaryMarshaler).MarshalBinary()
+	if err != nil {
+		return "", nil, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), state, nil
+}
@@ -0,0 +1,161 @@
+package wallet
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/sidalsoft/wallet/pkg/types"
+)
+
+func TestService_ExportIncremental_success(t *testing.T) {
+	s := newTestService()
+	account, payments, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	dir := t.TempDir()
+	err = s.ExportIncremental(dir)
+	if err != nil {
+		t.Errorf("ExportIncremental(): error = %v", err)
+		return
+	}
+	before, err := ioutil.ReadFile(dir + "/payments.dump")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = s.Reject(payments[0].ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = s.ExportIncremental(dir)
+	if err != nil {
+		t.Errorf("ExportIncremental(): error = %v", err)
+		return
+	}
+	after, err := ioutil.ReadFile(dir + "/payments.dump")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !strings.HasPrefix(string(after), string(before)) || strings.Count(string(after), "\n")-strings.Count(string(before), "\n") != 1 {
+		t.Errorf("ExportIncremental(): must append only the changed payment, dump = %q", after)
+		return
+	}
+
+	imported := &Service{}
+	err = imported.Import(dir)
+	if err != nil {
+		t.Errorf("Import(): error = %v", err)
+		return
+	}
+	payment, err := imported.FindPaymentByID(payments[0].ID)
+	if err != nil || payment.Status != types.PaymentStatusFail {
+		t.Errorf("Import(): payment = %v, error = %v", payment, err)
+		return
+	}
+	got, err := imported.FindAccountByID(account.ID)
+	if err != nil || got.Balance != s.accounts[0].Balance {
+		t.Errorf("Import(): account = %v, error = %v", got, err)
+		return
+	}
+	if len(imported.payments) != len(s.payments) {
+		t.Errorf("Import(): payments = %v, want %v", len(imported.payments), len(s.payments))
+		return
+	}
+}
+
+func TestService_ExportIncremental_runningHash(t *testing.T) {
+	s := newTestService()
+	account, _, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	dir := t.TempDir()
+	err = s.Export(dir)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	for i := 0; i < 3; i++ {
+		_, err = s.Pay(account.ID, 1_00, "auto")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		err = s.ExportIncremental(dir)
+		if err != nil {
+			t.Errorf("ExportIncremental(): error = %v", err)
+			return
+		}
+		err = verifyManifest(dir)
+		if err != nil {
+			t.Errorf("ExportIncremental(): manifest must match appended dump, error = %v", err)
+			return
+		}
+	}
+	if s.dirty.hashes["payments.dump"] == nil {
+		t.Errorf("ExportIncremental(): must keep running hash of appended dump")
+		return
+	}
+}
+
+func TestService_ExportIncremental_crashRecovery(t *testing.T) {
+	s := newTestService()
+	account, _, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	dir := t.TempDir()
+	err = s.Export(dir)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	// сбой после дописывания, но до записи манифеста
+	payment, err := s.Pay(account.ID, 1_00, "auto")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, _, err = appendFile(dir+"/payments.dump", payment.ToString()+"\n", nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = (&Service{}).Import(dir)
+	if !errors.Is(err, ErrCorruptedDump) {
+		t.Errorf("Import(): error = %v, want %v", err, ErrCorruptedDump)
+		return
+	}
+
+	err = os.Remove(dir + "/" + manifestName)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	recovered := &Service{}
+	err = recovered.Import(dir)
+	if err != nil {
+		t.Errorf("Import(): error = %v", err)
+		return
+	}
+	_, err = recovered.FindPaymentByID(payment.ID)
+	if err != nil {
+		t.Errorf("Import(): appended payment must be recovered, error = %v", err)
+		return
+	}
+	err = recovered.Export(dir)
+	if err != nil || verifyManifest(dir) != nil {
+		t.Errorf("Export(): must write new manifest, error = %v", err)
+		return
+	}
+}
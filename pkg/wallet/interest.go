@@ -0,0 +1,141 @@
+package wallet
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sidalsoft/wallet/pkg/types"
+)
+
+// InterestCategory - категория платежей, которыми AccrueInterest зачисляет проценты.
+// Как и исправления, зачисление - платеж с отрицательной суммой, он не считается тратой
+const InterestCategory types.PaymentCategory = "interest"
+
+// InterestMetadata - ключ метаданных платежа начисления процентов, значение - годовая
+// ставка в базисных пунктах. Начисление узнается по этому ключу, а не по категории,
+// которую может выбрать и обычный платеж
+const InterestMetadata = "interest"
+
+// SetInterestRate задает годовую ставку в базисных пунктах для счетов типа accountType.
+// 0 отключает начисление
+func (s *Service) SetInterestRate(accountType types.AccountType, basisPoints int64) (err error) {
+	defer s.audit("SetInterestRate", &err, "type", accountType, "basisPoints", basisPoints)
+	switch accountType {
+	case types.AccountTypePersonal, types.AccountTypeMerchant, types.AccountTypeSystem:
+	default:
+		return ErrInvalidAccountType
+	}
+	if basisPoints < 0 {
+		return ErrInvalidInterestRate
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if basisPoints == 0 {
+		delete(s.interestRates, accountType)
+		return nil
+	}
+	if s.interestRates == nil {
+		s.interestRates = make(map[types.AccountType]int64)
+	}
+	s.interestRates[accountType] = basisPoints
+	return nil
+}
+
+// AccrueInterest начисляет проценты на положительный баланс активных счетов, для типа
+// которых задана ставка, за целые дни с предыдущего начисления (или с регистрации счета)
+// по asOf. Проценты считаются от текущего баланса из расчета 365 дней в году, округляются
+// вниз и зачисляются платежом категории InterestCategory. Расчет - период, дни, баланс
+// и ставка - записывается в метаданные платежа и в журнал аудита отдельной записью
+// для каждого начисления. Возвращаются созданные платежи
+func (s *Service) AccrueInterest(asOf time.Time) (_ []*types.Payment, err error) {
+	defer s.audit("AccrueInterest", &err, "asOf", asOf.UTC().Format(time.RFC3339))
+	defer s.observe("AccrueInterest", time.Now())
+	if asOf.IsZero() {
+		return nil, ErrInvalidPeriod
+	}
+	s.mu.Lock()
+	payments, err := s.accrueInterest(asOf)
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	for _, payment := range payments {
+		s.audit("InterestAccrued", nil,
+			"accountID", payment.AccountID,
+			"paymentID", payment.ID,
+			"from", payment.Metadata["from"],
+			"to", payment.Metadata["to"],
+			"days", payment.Metadata["days"],
+			"balance", payment.Metadata["balance"],
+			"basisPoints", payment.Metadata[InterestMetadata],
+			"amount", -payment.Amount,
+		)
+	}
+	return payments, nil
+}
+
+func (s *Service) accrueInterest(asOf time.Time) ([]*types.Payment, error) {
+	accrued := make(map[int64]time.Time)
+	for _, payment := range s.payments {
+		if payment.Metadata[InterestMetadata] == "" {
+			continue
+		}
+		to, err := time.Parse(time.RFC3339Nano, payment.Metadata["to"])
+		if err != nil {
+			continue
+		}
+		if to.After(accrued[payment.AccountID]) {
+			accrued[payment.AccountID] = to
+		}
+	}
+
+	var payments []*types.Payment
+	for _, account := range s.accounts {
+		basisPoints, ok := s.interestRates[account.Type]
+		if !ok || account.Status != types.AccountStatusActive || account.Balance <= 0 {
+			continue
+		}
+		from, ok := accrued[account.ID]
+		if !ok {
+			from = account.Registered
+		}
+		if from.IsZero() {
+			continue
+		}
+		days := int64(asOf.Sub(from) / (24 * time.Hour))
+		if days < 1 {
+			continue
+		}
+		amount := types.Money(round(int64(account.Balance)*basisPoints*days, 10_000*365, RoundDown))
+		if amount <= 0 {
+			continue
+		}
+		to := from.Add(time.Duration(days) * 24 * time.Hour)
+		balance := account.Balance
+		s.changeBalance(account, amount)
+		payment := &types.Payment{
+			ID:        uuid.New().String(),
+			AccountID: account.ID,
+			Amount:    -amount,
+			Currency:  account.Currency,
+			Category:  InterestCategory,
+			Status:    types.PaymentStatusOk,
+			Created:   s.clock(),
+			Metadata: map[string]string{
+				"from":           from.UTC().Format(time.RFC3339Nano),
+				"to":             to.UTC().Format(time.RFC3339Nano),
+				"days":           strconv.FormatInt(days, 10),
+				"balance":        strconv.FormatInt(int64(balance), 10),
+				InterestMetadata: strconv.FormatInt(basisPoints, 10),
+			},
+		}
+		s.payments = append(s.payments, payment)
+		s.paymentTimes.add(payment)
+		s.markPayment(payment.ID)
+		s.emit(EventPaymentCreated, account, payment, 0)
+		copied, _ := copyPayment(payment, nil)
+		payments = append(payments, copied)
+	}
+	return payments, nil
+}
@@ -0,0 +1,134 @@
+package wallet
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sidalsoft/wallet/pkg/types"
+)
+
+func TestService_AccrueInterest_success(t *testing.T) {
+	s := newTestService()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	account, err := s.addAccountWithBalance("+992928885522", 100_000_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	empty, err := s.RegisterAccount("+992000000001")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	merchant, err := s.addAccountWithBalance("+992000000002", 100_000_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = s.SetAccountType(merchant.ID, types.AccountTypeMerchant)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = s.SetInterestRate(types.AccountTypePersonal, 1000)
+	if err != nil {
+		t.Errorf("SetInterestRate(): error = %v", err)
+		return
+	}
+
+	asOf := now.Add(30*24*time.Hour + 12*time.Hour)
+	payments, err := s.AccrueInterest(asOf)
+	if err != nil || len(payments) != 1 {
+		t.Errorf("AccrueInterest(): payments = %v, error = %v", payments, err)
+		return
+	}
+	payment := payments[0]
+	if payment.Category != InterestCategory || payment.AccountID != account.ID || payment.Amount != -821_91 ||
+		payment.Metadata["days"] != "30" || payment.Metadata["to"] != now.Add(30*24*time.Hour).Format(time.RFC3339Nano) {
+		t.Errorf("AccrueInterest(): payment = %v", payment)
+		return
+	}
+	got, err := s.FindAccountByID(account.ID)
+	if err != nil || got.Balance != 100_821_91 {
+		t.Errorf("AccrueInterest(): account = %v, error = %v", got, err)
+		return
+	}
+	got, err = s.FindAccountByID(empty.ID)
+	if err != nil || got.Balance != 0 {
+		t.Errorf("AccrueInterest(): empty account = %v, error = %v", got, err)
+		return
+	}
+	got, err = s.FindAccountByID(merchant.ID)
+	if err != nil || got.Balance != 100_000_00 {
+		t.Errorf("AccrueInterest(): merchant account = %v, error = %v", got, err)
+		return
+	}
+	entries := s.AuditLog(AuditQuery{Op: "InterestAccrued", AccountID: account.ID})
+	if len(entries) != 1 || entries[0].Args["amount"] != "82191" || entries[0].Args["days"] != "30" ||
+		entries[0].Args["balance"] != "10000000" || entries[0].Args["basisPoints"] != "1000" {
+		t.Errorf("AuditLog(): entries = %v", entries)
+		return
+	}
+
+	if sum := s.SumPayments(1); sum != 0 {
+		t.Errorf("SumPayments(): interest must not count as spending, sum = %v", sum)
+		return
+	}
+
+	payments, err = s.AccrueInterest(asOf)
+	if err != nil || len(payments) != 0 {
+		t.Errorf("AccrueInterest(): repeated accrual, payments = %v, error = %v", payments, err)
+		return
+	}
+	payments, err = s.AccrueInterest(asOf.Add(12 * time.Hour))
+	if err != nil || len(payments) != 1 {
+		t.Errorf("AccrueInterest(): next day, payments = %v, error = %v", payments, err)
+		return
+	}
+}
+
+func TestService_AccrueInterest_fail(t *testing.T) {
+	s := newTestService()
+	_, err := s.AccrueInterest(time.Time{})
+	if !errors.Is(err, ErrInvalidPeriod) {
+		t.Errorf("AccrueInterest(): error = %v, want %v", err, ErrInvalidPeriod)
+		return
+	}
+	err = s.SetInterestRate(types.AccountTypePersonal, -1)
+	if !errors.Is(err, ErrInvalidInterestRate) {
+		t.Errorf("SetInterestRate(): error = %v, want %v", err, ErrInvalidInterestRate)
+		return
+	}
+	err = s.SetInterestRate("SAVINGS", 100)
+	if !errors.Is(err, ErrInvalidAccountType) {
+		t.Errorf("SetInterestRate(): error = %v, want %v", err, ErrInvalidAccountType)
+		return
+	}
+}
+
+func TestService_Pay_interestCategory(t *testing.T) {
+	s := newTestService()
+	account, err := s.addAccountWithBalance("+992928885522", 1_000_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = s.SetLimit(account.ID, LimitDaily, 100_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	// обычный платеж в категории начислений остается тратой
+	_, err = s.Pay(account.ID, 100_00, InterestCategory)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, err = s.Pay(account.ID, 1, InterestCategory)
+	if !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("Pay(): error = %v, want %v", err, ErrLimitExceeded)
+		return
+	}
+}
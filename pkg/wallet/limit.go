@@ -0,0 +1,90 @@
+package wallet
+
+import (
+	"time"
+
+	"github.com/sidalsoft/wallet/pkg/types"
+)
+
+// LimitPeriod представляет собой скользящее окно, в котором действует лимит расходов
+type LimitPeriod string
+
+const (
+	LimitDaily   LimitPeriod = "DAILY"
+	LimitMonthly LimitPeriod = "MONTHLY"
+)
+
+// clock возвращает текущее время в UTC
+func (s *Service) clock() time.Time {
+	if s.now != nil {
+		return s.now().UTC()
+	}
+	return time.Now().UTC()
+}
+
+// SetLimit ограничивает сумму платежей счета за последние сутки или месяц.
+// Нулевая сумма снимает лимит
+func (s *Service) SetLimit(accountID int64, period LimitPeriod, amount types.Money) (err error) {
+	defer s.audit("SetLimit", &err, "accountID", accountID, "period", period, "amount", amount)
+	if amount < 0 || (period != LimitDaily && period != LimitMonthly) {
+		return ErrInvalidLimit
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.findAccountByID(accountID)
+	if err != nil {
+		return err
+	}
+	if amount == 0 {
+		delete(s.limits[accountID], period)
+		return nil
+	}
+	if s.limits == nil {
+		s.limits = make(map[int64]map[LimitPeriod]types.Money)
+	}
+	if s.limits[accountID] == nil {
+		s.limits[accountID] = make(map[LimitPeriod]types.Money)
+	}
+	s.limits[accountID][period] = amount
+	return nil
+}
+
+// Spent возвращает сумму неотклоненных платежей счета за период, заканчивающийся сейчас
+func (s *Service) Spent(accountID int64, period LimitPeriod) (types.Money, error) {
+	if period != LimitDaily && period != LimitMonthly {
+		return 0, ErrInvalidLimit
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, err := s.findAccountByID(accountID)
+	if err != nil {
+		return 0, err
+	}
+	return s.spent(accountID, periodStart(period, s.clock())), nil
+}
+
+func periodStart(period LimitPeriod, now time.Time) time.Time {
+	if period == LimitMonthly {
+		return now.AddDate(0, -1, 0)
+	}
+	return now.AddDate(0, 0, -1)
+}
+
+func (s *Service) spent(accountID int64, from time.Time) types.Money {
+	sum := types.Money(0)
+	for _, payment := range s.payments {
+		if payment.AccountID == accountID && payment.Status != types.PaymentStatusFail && payment.Created.After(from) && isSpending(payment) {
+			sum += payment.Amount
+		}
+	}
+	return sum
+}
+
+func (s *Service) checkLimits(accountID int64, amount types.Money, now time.Time) error {
+	for period, limit := range s.limits[accountID] {
+		if s.spent(accountID, periodStart(period, now))+amount > limit {
+			return ErrLimitExceeded
+		}
+	}
+	return nil
+}
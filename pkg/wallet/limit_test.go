@@ -0,0 +1,71 @@
+package wallet
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestService_SetLimit_success(t *testing.T) {
+	s := newTestService()
+	now := time.Date(2021, 3, 31, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	account, err := s.addAccountWithBalance("+992928885522", 1_000_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = s.SetLimit(account.ID, LimitDaily, 100_00)
+	if err != nil {
+		t.Errorf("SetLimit(): error = %v", err)
+		return
+	}
+	first, err := s.Pay(account.ID, 60_00, "auto")
+	if err != nil {
+		t.Errorf("Pay(): error = %v", err)
+		return
+	}
+	_, err = s.Pay(account.ID, 50_00, "auto")
+	if !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("Pay(): must return ErrLimitExceeded, returned = %v", err)
+		return
+	}
+
+	_ = s.Reject(first.ID)
+	_, err = s.Pay(account.ID, 50_00, "auto")
+	if err != nil {
+		t.Errorf("Pay(): rejected payment must not count, error = %v", err)
+		return
+	}
+
+	now = now.Add(25 * time.Hour)
+	_, err = s.Pay(account.ID, 100_00, "auto")
+	if err != nil {
+		t.Errorf("Pay(): limit must reset after a day, error = %v", err)
+		return
+	}
+	spent, err := s.Spent(account.ID, LimitMonthly)
+	if err != nil || spent != 150_00 {
+		t.Errorf("Spent(): spent = %v, error = %v", spent, err)
+		return
+	}
+}
+
+func TestService_SetLimit_fail(t *testing.T) {
+	s := newTestService()
+	account, err := s.addAccountWithBalance("+992928885522", 1_000_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = s.SetLimit(account.ID, "WEEKLY", 100_00)
+	if !errors.Is(err, ErrInvalidLimit) {
+		t.Errorf("SetLimit(): must return ErrInvalidLimit, returned = %v", err)
+		return
+	}
+	err = s.SetLimit(account.ID+1, LimitDaily, 100_00)
+	if !errors.Is(err, ErrAccountNotFound) {
+		t.Errorf("SetLimit(): must return ErrAccountNotFound, returned = %v", err)
+		return
+	}
+}
@@ -0,0 +1,57 @@
+package wallet
+
+import (
+	"time"
+
+	"github.com/sidalsoft/wallet/pkg/types"
+)
+
+// LogEntry описывает вызов изменяющего метода сервиса.
+// AccountID и Amount равны 0, если у метода нет таких аргументов
+type LogEntry struct {
+	Time      time.Time
+	Operation string
+	AccountID int64
+	Amount    types.Money
+	Args      map[string]string
+	Err       error
+}
+
+// Logger получает записи о вызовах изменяющих методов сервиса после снятия блокировки
+type Logger interface {
+	Log(entry LogEntry)
+}
+
+// LoggerFunc позволяет использовать функцию как Logger
+type LoggerFunc func(entry LogEntry)
+
+func (f LoggerFunc) Log(entry LogEntry) {
+	f(entry)
+}
+
+// Option настраивает сервис, созданный NewService
+type Option func(s *Service)
+
+func NewService(options ...Option) *Service {
+	s := &Service{}
+	for _, option := range options {
+		option(s)
+	}
+	if s.autoSave != nil {
+		s.startAutoSave()
+	}
+	return s
+}
+
+// WithLogger задает Logger сервиса. По умолчанию записи отбрасываются
+func WithLogger(logger Logger) Option {
+	return func(s *Service) {
+		s.logger = logger
+	}
+}
+
+func (s *Service) log(entry LogEntry) {
+	if s.logger != nil {
+		s.logger.Log(entry)
+	}
+}
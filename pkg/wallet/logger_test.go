@@ -0,0 +1,36 @@
+package wallet
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/sidalsoft/wallet/pkg/types"
+)
+
+func TestNewService_WithLogger(t *testing.T) {
+	var entries []LogEntry
+	s := NewService(WithLogger(LoggerFunc(func(entry LogEntry) {
+		entries = append(entries, entry)
+	})))
+
+	account, err := s.RegisterAccount("+992928885522")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, err = s.Pay(account.ID, 10_00, "auto")
+	if !errors.Is(err, ErrNotEnoughBalance) {
+		t.Errorf("Pay(): must return ErrNotEnoughBalance, returned = %v", err)
+		return
+	}
+
+	if len(entries) != 2 {
+		t.Errorf("Log(): wrong entries = %+v", entries)
+		return
+	}
+	pay := entries[1]
+	if pay.Operation != "Pay" || pay.AccountID != account.ID || pay.Amount != types.Money(10_00) || !errors.Is(pay.Err, ErrNotEnoughBalance) {
+		t.Errorf("Log(): wrong entry = %+v", pay)
+		return
+	}
+}
@@ -0,0 +1,70 @@
+package wallet
+
+import (
+	"sync"
+	"time"
+)
+
+// backgroundLoop вызывает run каждые interval в фоне между start и stop.
+// Повторный start и start после stop ничего не делают, stop без start сразу возвращается
+type backgroundLoop struct {
+	interval time.Duration
+	run      func(now time.Time)
+
+	mu      sync.Mutex
+	started bool
+	stopped bool
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// newBackgroundLoop возвращает ErrInvalidInterval, если interval не положителен
+func newBackgroundLoop(interval time.Duration, run func(now time.Time)) (*backgroundLoop, error) {
+	if interval <= 0 {
+		return nil, ErrInvalidInterval
+	}
+	return &backgroundLoop{
+		interval: interval,
+		run:      run,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}, nil
+}
+
+func (l *backgroundLoop) start() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.started || l.stopped {
+		return
+	}
+	l.started = true
+	go func() {
+		defer close(l.done)
+		ticker := time.NewTicker(l.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-l.stop:
+				return
+			case now := <-ticker.C:
+				l.run(now)
+			}
+		}
+	}()
+}
+
+// halt останавливает цикл и ждет завершения текущего запуска run
+func (l *backgroundLoop) halt() {
+	l.mu.Lock()
+	started := l.started
+	if !l.stopped {
+		l.stopped = true
+		if started {
+			close(l.stop)
+		}
+	}
+	l.mu.Unlock()
+	if started {
+		<-l.done
+	}
+}
@@ -0,0 +1,119 @@
+package wallet
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sidalsoft/wallet/pkg/types"
+)
+
+// PointsOperation - вид операции, которой записывается начисление баллов за платеж.
+// Payload операции - число баллов
+const PointsOperation = "points"
+
+// PointsRule задает начисление баллов за платежи категории: Points баллов
+// за каждые полные Per суммы платежа
+type PointsRule struct {
+	Points int64
+	Per    types.Money
+}
+
+// SetPointsRule назначает правило начисления баллов категории, оно действует и для ее
+// подкатегорий, если для них не задано свое. Нулевое правило снимает его
+func (s *Service) SetPointsRule(category types.PaymentCategory, rule PointsRule) (err error) {
+	defer s.audit("SetPointsRule", &err, "category", category, "points", rule.Points, "per", rule.Per)
+	if rule != (PointsRule{}) && (category == "" || rule.Points <= 0 || rule.Per <= 0) {
+		return ErrInvalidPoints
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if rule == (PointsRule{}) {
+		delete(s.pointsRules, category)
+		return nil
+	}
+	if s.pointsRules == nil {
+		s.pointsRules = make(map[types.PaymentCategory]PointsRule)
+	}
+	s.pointsRules[category] = rule
+	return nil
+}
+
+// SetPointValue задает стоимость одного балла в минимальных единицах валюты счета
+// для RedeemPoints. 0 запрещает обмен баллов
+func (s *Service) SetPointValue(value types.Money) (err error) {
+	defer s.audit("SetPointValue", &err, "value", value)
+	if value < 0 {
+		return ErrInvalidPoints
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pointValue = value
+	return nil
+}
+
+// RedeemPoints списывает points баллов счета и зачисляет на его баланс их стоимость
+// по SetPointValue. Возвращается зачисленная сумма
+func (s *Service) RedeemPoints(accountID int64, points int64) (_ types.Money, err error) {
+	defer s.audit("RedeemPoints", &err, "accountID", accountID, "points", points)
+	defer s.observe("RedeemPoints", time.Now())
+	if points <= 0 {
+		return 0, ErrInvalidPoints
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	account, err := s.findAccountByID(accountID)
+	if err != nil {
+		return 0, err
+	}
+	if account.Status == types.AccountStatusFrozen {
+		return 0, ErrAccountFrozen
+	}
+	if account.Status == types.AccountStatusClosed {
+		return 0, ErrAccountClosed
+	}
+	err = checkAccountType(account, "RedeemPoints")
+	if err != nil {
+		return 0, err
+	}
+	if s.pointValue == 0 {
+		return 0, ErrPointsDisabled
+	}
+	if account.Points < points {
+		return 0, ErrNotEnoughPoints
+	}
+	amount := types.Money(points) * s.pointValue
+	account.Points -= points
+	s.changeBalance(account, amount)
+	return amount, nil
+}
+
+// earnPoints начисляет баллы за завершенный платеж по правилу его категории
+// или ближайшей родительской и записывает их операцией вида PointsOperation
+// со ссылкой на платеж
+func (s *Service) earnPoints(account *types.Account, payment *types.Payment) {
+	category := payment.Category
+	rule, ok := s.pointsRules[category]
+	for !ok && category != "" {
+		category = category.Parent()
+		rule, ok = s.pointsRules[category]
+	}
+	if !ok {
+		return
+	}
+	points := int64(payment.Amount/rule.Per) * rule.Points
+	if points == 0 {
+		return
+	}
+	account.Points += points
+	s.markAccount(account.ID)
+	operation := &types.Operation{
+		ID:        uuid.New().String(),
+		Kind:      PointsOperation,
+		AccountID: account.ID,
+		PaymentID: payment.ID,
+		Payload:   []byte(strconv.FormatInt(points, 10)),
+	}
+	s.operations = append(s.operations, operation)
+	s.markOperation(operation.ID)
+}
@@ -0,0 +1,96 @@
+package wallet
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestService_RedeemPoints_success(t *testing.T) {
+	s := newTestService()
+	account, err := s.addAccountWithBalance("+992928885522", 10_000_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = s.SetPointsRule("food", PointsRule{Points: 2, Per: 10_00})
+	if err != nil {
+		t.Errorf("SetPointsRule(): error = %v", err)
+		return
+	}
+	err = s.SetPointValue(50)
+	if err != nil {
+		t.Errorf("SetPointValue(): error = %v", err)
+		return
+	}
+
+	payment, err := s.Pay(account.ID, 255_00, "food/cafe")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	got, _ := s.FindAccountByID(account.ID)
+	if got.Points != 0 {
+		t.Errorf("Pay(): points earned before confirmation, account = %v", got)
+		return
+	}
+	err = s.Confirm(payment.ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	got, _ = s.FindAccountByID(account.ID)
+	if got.Points != 50 {
+		t.Errorf("Confirm(): account = %v, want 50 points", got)
+		return
+	}
+
+	amount, err := s.RedeemPoints(account.ID, 40)
+	if err != nil || amount != 20_00 {
+		t.Errorf("RedeemPoints(): amount = %v, error = %v", amount, err)
+		return
+	}
+	got, _ = s.FindAccountByID(account.ID)
+	if got.Points != 10 || got.Balance != 9_765_00 {
+		t.Errorf("RedeemPoints(): account = %v", got)
+		return
+	}
+}
+
+func TestService_RedeemPoints_fail(t *testing.T) {
+	s := newTestService()
+	account, err := s.addAccountWithBalance("+992928885522", 10_000_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, err = s.RedeemPoints(account.ID, 1)
+	if !errors.Is(err, ErrPointsDisabled) {
+		t.Errorf("RedeemPoints(): error = %v, want %v", err, ErrPointsDisabled)
+		return
+	}
+	err = s.SetPointValue(50)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, err = s.RedeemPoints(account.ID, 1)
+	if !errors.Is(err, ErrNotEnoughPoints) {
+		t.Errorf("RedeemPoints(): error = %v, want %v", err, ErrNotEnoughPoints)
+		return
+	}
+	_, err = s.RedeemPoints(account.ID, 0)
+	if !errors.Is(err, ErrInvalidPoints) {
+		t.Errorf("RedeemPoints(): error = %v, want %v", err, ErrInvalidPoints)
+		return
+	}
+	_, err = s.RedeemPoints(account.ID+1, 1)
+	if !errors.Is(err, ErrAccountNotFound) {
+		t.Errorf("RedeemPoints(): error = %v, want %v", err, ErrAccountNotFound)
+		return
+	}
+	err = s.SetPointsRule("food", PointsRule{Points: 1})
+	if !errors.Is(err, ErrInvalidPoints) {
+		t.Errorf("SetPointsRule(): error = %v, want %v", err, ErrInvalidPoints)
+		return
+	}
+}
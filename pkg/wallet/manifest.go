@@ -0,0 +1,142 @@
+package wallet
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+)
+
+// manifestName - файл с контрольными суммами SHA-256 дампов в формате sha256sum.
+// Первая строка "#order ..." сообщает порядок записей в дампах
+const manifestName = "manifest.sha256"
+
+// CorruptedDumpError возвращается Import, если файлы дампа не совпадают с манифестом.
+// Failed содержит причину для каждого файла. errors.Is(err, ErrCorruptedDump) истинно
+type CorruptedDumpError struct {
+	Failed map[string]string
+}
+
+func (e *CorruptedDumpError) Error() string {
+	files := make([]string, 0, len(e.Failed))
+	for file := range e.Failed {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	messages := make([]string, len(files))
+	for i, file := range files {
+		messages[i] = file + ": " + e.Failed[file]
+	}
+	return ErrCorruptedDump.Error() + ": " + strings.Join(messages, "; ")
+}
+
+func (e *CorruptedDumpError) Unwrap() error {
+	return ErrCorruptedDump
+}
+
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func writeManifest(dir string, sums map[string]string, order ExportOrder) error {
+	files := make([]string, 0, len(sums))
+	for file := range sums {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	data := strings.Builder{}
+	data.WriteString(manifestOrder + string(order) + "\n")
+	for _, file := range files {
+		data.WriteString(sums[file] + "  " + file + "\n")
+	}
+	return writeFileAtomic(dir+"/"+manifestName, data.String())
+}
+
+// readManifest читает контрольные суммы файлов и порядок записей из манифеста каталога
+func readManifest(dir string) (map[string]string, ExportOrder, error) {
+	order := OrderInsertion
+	data, err := ioutil.ReadFile(dir + "/" + manifestName)
+	if err != nil {
+		return nil, order, err
+	}
+	sums := make(map[string]string)
+	for i, line := range strings.Split(string(data), "\n") {
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, manifestOrder) {
+			order = ExportOrder(strings.TrimPrefix(line, manifestOrder))
+			continue
+		}
+		fields := strings.SplitN(line, "  ", 2)
+		if len(fields) != 2 {
+			return nil, order, &CorruptedDumpError{Failed: map[string]string{manifestName: fmt.Sprintf("invalid line %d", i+1)}}
+		}
+		sums[fields[1]] = fields[0]
+	}
+	return sums, order, nil
+}
+
+// removeStaleDumps удаляет дампы, которые остались от прошлых экспортов, но не записаны
+// в этот: без них Import прочел бы устаревшие данные. Дампы, запись которых не удалась, остаются
+func removeStaleDumps(dir string, manifest map[string]string, failed map[string]error) error {
+	for _, name := range dumpNames {
+		_, plain := manifest[name+".dump"]
+		_, compressed := manifest[name+".dump.gz"]
+		if plain || compressed || failed[name] != nil {
+			continue
+		}
+		for _, file := range []string{name + ".dump", name + ".dump.gz"} {
+			err := os.Remove(dir + "/" + file)
+			if err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// verifyManifest сверяет файлы дампа с манифестом. Дампы, которых нет в манифесте,
+// считаются поврежденными. Дампы без манифеста, записанные до его появления, не проверяются
+func verifyManifest(dir string) error {
+	sums, _, err := readManifest(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	failed := make(map[string]string)
+	for file, want := range sums {
+		content, err := ioutil.ReadFile(dir + "/" + file)
+		if err != nil {
+			failed[file] = err.Error()
+			continue
+		}
+		if checksum(content) != want {
+			failed[file] = "checksum mismatch"
+		}
+	}
+	for _, name := range dumpNames {
+		for _, file := range []string{name + ".dump", name + ".dump.gz"} {
+			if _, ok := sums[file]; ok {
+				continue
+			}
+			_, err := os.Stat(dir + "/" + file)
+			if err == nil {
+				failed[file] = "not in manifest"
+			} else if !os.IsNotExist(err) {
+				failed[file] = err.Error()
+			}
+		}
+	}
+	if len(failed) > 0 {
+		return &CorruptedDumpError{Failed: failed}
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+package wallet
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestService_Import_manifest_fail(t *testing.T) {
+	s := newTestService()
+	_, _, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	dir := t.TempDir()
+	err = s.Export(dir)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = (&Service{}).Import(dir)
+	if err != nil {
+		t.Errorf("Import(): error = %v", err)
+		return
+	}
+
+	data, err := ioutil.ReadFile(dir + "/accounts.dump")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = ioutil.WriteFile(dir+"/accounts.dump", data[:len(data)/2], 0666)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = os.Remove(dir + "/payments.dump")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	imported := &Service{}
+	err = imported.Import(dir)
+	if !errors.Is(err, ErrCorruptedDump) {
+		t.Errorf("Import(): must return ErrCorruptedDump, returned = %v", err)
+		return
+	}
+	var corrupted *CorruptedDumpError
+	errors.As(err, &corrupted)
+	if len(corrupted.Failed) != 2 || corrupted.Failed["accounts.dump"] != "checksum mismatch" {
+		t.Errorf("Import(): wrong details = %v", corrupted.Failed)
+		return
+	}
+	if len(imported.accounts) != 0 {
+		t.Errorf("Import(): must not import corrupted dump, accounts = %v", imported.accounts)
+		return
+	}
+}
+
+func TestService_Export_removesStaleDumps(t *testing.T) {
+	s := newTestService()
+	account, _, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, err = s.SchedulePayment(account.ID, 10_00, "auto", "@daily")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	dir := t.TempDir()
+	err = s.Export(dir)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	// каталог перезаписывает сервис без запланированных платежей
+	other := newTestService()
+	_, _, err = other.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = other.Export(dir)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, err = os.Stat(dir + "/scheduled.dump")
+	if !os.IsNotExist(err) {
+		t.Errorf("Export(): stale scheduled.dump must be removed, error = %v", err)
+		return
+	}
+	imported := &Service{}
+	err = imported.Import(dir)
+	if err != nil || len(imported.scheduled) != 0 {
+		t.Errorf("Import(): scheduled = %v, error = %v", imported.scheduled, err)
+		return
+	}
+}
+
+func TestService_Import_unlistedDump_fail(t *testing.T) {
+	s := newTestService()
+	_, _, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	dir := t.TempDir()
+	err = s.Export(dir)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	data, err := ioutil.ReadFile(dir + "/payments.dump")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = ioutil.WriteFile(dir+"/scheduled.dump", data, 0666)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = (&Service{}).Import(dir)
+	var corrupted *CorruptedDumpError
+	if !errors.As(err, &corrupted) || corrupted.Failed["scheduled.dump"] != "not in manifest" {
+		t.Errorf("Import(): must reject dump missing from manifest, returned = %v", err)
+		return
+	}
+}
+
+func TestService_readDump_fail(t *testing.T) {
+	s := newTestService()
+	dir := t.TempDir()
+	// каталог вместо файла дает ошибку чтения, отличную от отсутствия файла
+	err := os.Mkdir(dir+"/accounts.dump", 0777)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, err = s.readDump(dir, "accounts")
+	if err == nil {
+		t.Errorf("readDump(): must return read error")
+		return
+	}
+	data, err := s.readDump(dir, "payments")
+	if err != nil || data != "" {
+		t.Errorf("readDump(): missing dump must be empty, data = %q, error = %v", data, err)
+		return
+	}
+}
+
+func TestService_Export_fileMode(t *testing.T) {
+	s := newTestService()
+	_, _, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	dir := t.TempDir()
+	err = s.Export(dir)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	info, err := os.Stat(dir + "/accounts.dump")
+	if err != nil || info.Mode().Perm() != 0644 {
+		t.Errorf("Export(): mode = %v, error = %v, want %v", info.Mode().Perm(), err, os.FileMode(0644))
+		return
+	}
+
+	// права существующего дампа сохраняются при перезаписи
+	err = os.Chmod(dir+"/accounts.dump", 0640)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = s.Export(dir)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	info, err = os.Stat(dir + "/accounts.dump")
+	if err != nil || info.Mode().Perm() != 0640 {
+		t.Errorf("Export(): mode = %v, error = %v, want %v", info.Mode().Perm(), err, os.FileMode(0640))
+		return
+	}
+}
@@ -0,0 +1,103 @@
+package wallet
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sidalsoft/wallet/pkg/types"
+)
+
+// RegisterMerchant регистрирует получателя платежей name с категорией category,
+// платежи которому зачисляются на счет accountID
+func (s *Service) RegisterMerchant(name string, category types.PaymentCategory, accountID int64) (_ *types.Merchant, err error) {
+	defer s.audit("RegisterMerchant", &err, "accountID", accountID, "name", name, "category", category)
+	if name == "" {
+		return nil, ErrInvalidMerchant
+	}
+	if category == "" {
+		return nil, ErrInvalidCategory
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	account, err := s.findAccountByID(accountID)
+	if err != nil {
+		return nil, err
+	}
+	if account.Status == types.AccountStatusClosed {
+		return nil, ErrAccountClosed
+	}
+	merchant := &types.Merchant{
+		ID:        uuid.New().String(),
+		Name:      name,
+		Category:  category,
+		AccountID: accountID,
+	}
+	s.merchants = append(s.merchants, merchant)
+	s.markMerchant(merchant.ID)
+	return copyMerchant(merchant, nil)
+}
+
+func (s *Service) FindMerchantByID(merchantID string) (*types.Merchant, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return copyMerchant(s.findMerchantByID(merchantID))
+}
+
+func (s *Service) findMerchantByID(merchantID string) (*types.Merchant, error) {
+	for _, merchant := range s.merchants {
+		if merchant.ID == merchantID {
+			return merchant, nil
+		}
+	}
+	return nil, ErrMerchantNotFound
+}
+
+// PayToMerchant проводит платеж со счета accountID получателю merchantID с категорией
+// получателя и сразу зачисляет сумму на его счет. Платеж завершен сразу, поэтому его
+// нельзя отклонить, а Refund списывает возврат со счета получателя
+func (s *Service) PayToMerchant(accountID int64, merchantID string, amount types.Money) (_ *types.Payment, err error) {
+	defer s.audit("PayToMerchant", &err, "accountID", accountID, "merchantID", merchantID, "amount", amount)
+	defer s.observe("Pay", time.Now())
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	from, err := s.findAccountByID(accountID)
+	if err != nil {
+		return nil, err
+	}
+	merchant, err := s.findMerchantByID(merchantID)
+	if err != nil {
+		return nil, err
+	}
+	to, err := s.findAccountByID(merchant.AccountID)
+	if err != nil {
+		return nil, err
+	}
+	if to.ID == from.ID {
+		return nil, ErrSelfTransfer
+	}
+	err = checkAccountType(from, "PayToMerchant")
+	if err != nil {
+		return nil, err
+	}
+	if to.Currency != from.Currency {
+		return nil, ErrCurrencyMismatch
+	}
+	if to.Status == types.AccountStatusClosed {
+		return nil, ErrAccountClosed
+	}
+	err = s.checkDuplicate(accountID, amount, merchant.Category)
+	if err != nil {
+		return nil, err
+	}
+	payment, err := s.pay(accountID, amount, merchant.Category, func(payment *types.Payment) {
+		payment.MerchantID = merchant.ID
+	})
+	if err != nil {
+		return nil, err
+	}
+	payment.Status = types.PaymentStatusOk
+	s.changeBalance(to, payment.Amount)
+	s.creditCashback(from, payment)
+	s.earnPoints(from, payment)
+	return copyPayment(payment, nil)
+}
@@ -0,0 +1,98 @@
+package wallet
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestService_PayToMerchant_success(t *testing.T) {
+	s := newTestService()
+	account, err := s.addAccountWithBalance("+992900000001", 1_000_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	shop, err := s.RegisterAccount("+992900000002")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	merchant, err := s.RegisterMerchant("Магазин", "food", shop.ID)
+	if err != nil {
+		t.Errorf("RegisterMerchant(): error = %v", err)
+		return
+	}
+	payment, err := s.PayToMerchant(account.ID, merchant.ID, 300_00)
+	if err != nil || payment.MerchantID != merchant.ID || payment.Category != "food" {
+		t.Errorf("PayToMerchant(): payment = %v, error = %v", payment, err)
+		return
+	}
+	_, err = s.Refund(payment.ID, 100_00)
+	if err != nil {
+		t.Errorf("Refund(): error = %v", err)
+		return
+	}
+	account, _ = s.FindAccountByID(account.ID)
+	shop, _ = s.FindAccountByID(shop.ID)
+	if account.Balance != 800_00 || shop.Balance != 200_00 {
+		t.Errorf("PayToMerchant(): account = %v, merchant account = %v", account, shop)
+		return
+	}
+
+	dir := t.TempDir()
+	err = s.Export(dir)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	imported := newTestService()
+	err = imported.Import(dir)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	got, err := imported.FindMerchantByID(merchant.ID)
+	if err != nil || *got != *merchant {
+		t.Errorf("Import(): merchant = %v, want %v, error = %v", got, merchant, err)
+		return
+	}
+	gotPayment, err := imported.FindPaymentByID(payment.ID)
+	if err != nil || gotPayment.MerchantID != merchant.ID {
+		t.Errorf("Import(): payment = %v, error = %v", gotPayment, err)
+		return
+	}
+}
+
+func TestService_PayToMerchant_fail(t *testing.T) {
+	s := newTestService()
+	account, err := s.addAccountWithBalance("+992900000001", 1_000_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, err = s.RegisterMerchant("", "food", account.ID)
+	if !errors.Is(err, ErrInvalidMerchant) {
+		t.Errorf("RegisterMerchant(): must return ErrInvalidMerchant, returned = %v", err)
+		return
+	}
+	_, err = s.RegisterMerchant("Магазин", "food", 404)
+	if !errors.Is(err, ErrAccountNotFound) {
+		t.Errorf("RegisterMerchant(): must return ErrAccountNotFound, returned = %v", err)
+		return
+	}
+	_, err = s.PayToMerchant(account.ID, "unknown", 100_00)
+	if !errors.Is(err, ErrMerchantNotFound) {
+		t.Errorf("PayToMerchant(): must return ErrMerchantNotFound, returned = %v", err)
+		return
+	}
+	merchant, err := s.RegisterMerchant("Магазин", "food", account.ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, err = s.PayToMerchant(account.ID, merchant.ID, 100_00)
+	if !errors.Is(err, ErrSelfTransfer) {
+		t.Errorf("PayToMerchant(): must return ErrSelfTransfer, returned = %v", err)
+		return
+	}
+}
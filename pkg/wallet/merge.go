@@ -0,0 +1,154 @@
+package wallet
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MergeStrategy определяет, что Import делает с записями, ID которых уже есть в сервисе
+type MergeStrategy int
+
+const (
+	// MergeOverwrite заменяет существующие записи записями дампа
+	MergeOverwrite MergeStrategy = iota
+	// MergeSkipExisting оставляет существующие записи без изменений
+	MergeSkipExisting
+	// MergeFailOnConflict отменяет импорт целиком, если хотя бы одна запись уже есть
+	MergeFailOnConflict
+)
+
+func (m MergeStrategy) String() string {
+	switch m {
+	case MergeOverwrite:
+		return "overwrite"
+	case MergeSkipExisting:
+		return "skip"
+	case MergeFailOnConflict:
+		return "fail"
+	}
+	return "MergeStrategy(" + strconv.Itoa(int(m)) + ")"
+}
+
+// ImportOption настраивает вызов Import
+type ImportOption func(config *importConfig)
+
+type importConfig struct {
+	merge MergeStrategy
+}
+
+// WithMergeStrategy задает стратегию слияния записей с совпадающими ID
+func WithMergeStrategy(merge MergeStrategy) ImportOption {
+	return func(config *importConfig) {
+		config.merge = merge
+	}
+}
+
+// ImportConflictError возвращается Import с MergeFailOnConflict. Conflicts содержит
+// ID уже существующих записей для каждого дампа. errors.Is(err, ErrImportConflict) истинно
+type ImportConflictError struct {
+	Conflicts map[string][]string
+}
+
+func (e *ImportConflictError) Error() string {
+	names := make([]string, 0, len(e.Conflicts))
+	for name := range e.Conflicts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	messages := make([]string, len(names))
+	for i, name := range names {
+		messages[i] = name + ": " + strings.Join(e.Conflicts[name], ", ")
+	}
+	return ErrImportConflict.Error() + ": " + strings.Join(messages, "; ")
+}
+
+func (e *ImportConflictError) Unwrap() error {
+	return ErrImportConflict
+}
+
+// importIndex - позиции записей сервиса по ID, чтобы импорт находил существующие записи
+// без перебора. Верен, пока записи сервиса меняет только импорт, который его ведет
+type importIndex struct {
+	accounts   map[int64]int
+	payments   map[string]int
+	favorites  map[string]int
+	scheduled  map[string]int
+	operations map[string]int
+	merchants  map[string]int
+}
+
+// newImportIndex строит индекс по текущим записям. Вызывается под блокировкой сервиса
+func (s *Service) newImportIndex() *importIndex {
+	index := &importIndex{
+		accounts:   make(map[int64]int, len(s.accounts)),
+		payments:   make(map[string]int, len(s.payments)),
+		favorites:  make(map[string]int, len(s.favorites)),
+		scheduled:  make(map[string]int, len(s.scheduled)),
+		operations: make(map[string]int, len(s.operations)),
+		merchants:  make(map[string]int, len(s.merchants)),
+	}
+	for i, account := range s.accounts {
+		index.accounts[account.ID] = i
+	}
+	for i, payment := range s.payments {
+		index.payments[payment.ID] = i
+	}
+	for i, favorite := range s.favorites {
+		index.favorites[favorite.ID] = i
+	}
+	for i, sp := range s.scheduled {
+		index.scheduled[sp.ID] = i
+	}
+	for i, operation := range s.operations {
+		index.operations[operation.ID] = i
+	}
+	for i, merchant := range s.merchants {
+		index.merchants[merchant.ID] = i
+	}
+	return index
+}
+
+// checkImportConflicts ищет в дампах записи с ID, которые уже есть в index
+func (s *Service) checkImportConflicts(parsed *parsedDumps, index *importIndex) error {
+	conflicts := make(map[string][]string)
+	conflict := func(name string, id string, exists bool) {
+		if !exists {
+			return
+		}
+		for _, seen := range conflicts[name] {
+			if seen == id {
+				return
+			}
+		}
+		conflicts[name] = append(conflicts[name], id)
+	}
+	for _, account := range parsed.accounts {
+		_, ok := index.accounts[account.ID]
+		conflict("accounts", strconv.FormatInt(account.ID, 10), ok)
+	}
+	for _, payment := range parsed.payments {
+		_, ok := index.payments[payment.ID]
+		conflict("payments", payment.ID, ok)
+	}
+	for _, favorite := range parsed.favorites {
+		_, ok := index.favorites[favorite.ID]
+		conflict("favorites", favorite.ID, ok)
+	}
+	for _, sp := range parsed.scheduled {
+		_, ok := index.scheduled[sp.ID]
+		conflict("scheduled", sp.ID, ok)
+	}
+	for _, operation := range parsed.operations {
+		_, ok := index.operations[operation.ID]
+		conflict("operations", operation.ID, ok)
+	}
+	for _, merchant := range parsed.merchants {
+		_, ok := index.merchants[merchant.ID]
+		conflict("merchants", merchant.ID, ok)
+	}
+	if len(conflicts) > 0 {
+		return &ImportConflictError{Conflicts: conflicts}
+	}
+	return nil
+}
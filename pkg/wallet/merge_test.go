@@ -0,0 +1,125 @@
+package wallet
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/sidalsoft/wallet/pkg/types"
+)
+
+func TestService_Import_mergeStrategies(t *testing.T) {
+	s := newTestService()
+	account, payments, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	favorite, err := s.FavoritePayment(payments[0].ID, "car")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	dir := t.TempDir()
+	err = s.Export(dir)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = s.Deposit(account.ID, 100)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = s.Import(dir, WithMergeStrategy(MergeSkipExisting))
+	if err != nil {
+		t.Errorf("Import(): error = %v", err)
+		return
+	}
+	got, err := s.FindAccountByID(account.ID)
+	if err != nil || got.Balance != 9_000_00+100 {
+		t.Errorf("Import(): skip must keep account, account = %v, error = %v", got, err)
+		return
+	}
+
+	err = s.Import(dir, WithMergeStrategy(MergeFailOnConflict))
+	var conflict *ImportConflictError
+	if !errors.As(err, &conflict) || !errors.Is(err, ErrImportConflict) {
+		t.Errorf("Import(): error = %v, want *ImportConflictError", err)
+		return
+	}
+	if !reflect.DeepEqual(conflict.Conflicts["favorites"], []string{favorite.ID}) {
+		t.Errorf("Import(): conflicts = %v", conflict.Conflicts)
+		return
+	}
+
+	err = s.Import(dir)
+	if err != nil {
+		t.Errorf("Import(): error = %v", err)
+		return
+	}
+	got, err = s.FindAccountByID(account.ID)
+	if err != nil || got.Balance != 9_000_00 {
+		t.Errorf("Import(): overwrite must replace account, account = %v, error = %v", got, err)
+		return
+	}
+	if len(s.favorites) != 1 {
+		t.Errorf("Import(): favorite IDs must be stable, favorites = %v", s.favorites)
+		return
+	}
+}
+
+func TestService_Import_failOnConflictEmpty(t *testing.T) {
+	s := newTestService()
+	_, _, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	dir := t.TempDir()
+	err = s.Export(dir)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	imported := newTestService()
+	err = imported.Import(dir, WithMergeStrategy(MergeFailOnConflict))
+	if err != nil || len(imported.accounts) != 1 || len(imported.payments) != 1 {
+		t.Errorf("Import(): accounts = %v, error = %v", imported.accounts, err)
+		return
+	}
+}
+
+func TestService_Import_mergeKeepsNextAccountID(t *testing.T) {
+	export := func(nextAccountID int64, phone types.Phone) string {
+		src := newTestService()
+		src.nextAccountID = nextAccountID
+		_, err := src.RegisterAccount(phone)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dir := t.TempDir()
+		err = src.Export(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return dir
+	}
+	high := export(4, "+992000000005")
+	low := export(1, "+992000000002")
+
+	s := newTestService()
+	for _, dir := range []string{high, low} {
+		err := s.Import(dir)
+		if err != nil {
+			t.Errorf("Import(): error = %v", err)
+			return
+		}
+	}
+	account, err := s.RegisterAccount("+992000000006")
+	if err != nil || account.ID != 6 {
+		t.Errorf("RegisterAccount(): account = %v, error = %v, want ID 6", account, err)
+		return
+	}
+}
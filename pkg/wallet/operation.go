@@ -0,0 +1,96 @@
+package wallet
+
+import (
+	"encoding/base64"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/sidalsoft/wallet/pkg/types"
+)
+
+// OperationHandler реализует пользовательский вид операции,
+// например покупку лотерейного билета
+type OperationHandler interface {
+	// Prepare проверяет данные операции и возвращает сумму списания со счета.
+	// Вызывается под блокировкой сервиса, поэтому не должен вызывать его методы
+	Prepare(account types.Account, payload []byte) (types.Money, error)
+}
+
+// reservedOperations - виды операций, которые записывает сам сервис
+var reservedOperations = map[string]bool{
+	P2POperation:           true,
+	RefundOperation:        true,
+	CashbackOperation:      true,
+	PointsOperation:        true,
+	MoveOperation:          true,
+	HoldOperation:          true,
+	StandingOrderOperation: true,
+}
+
+// RegisterOperation регистрирует обработчик операций вида kind.
+// Виды reservedOperations заняты сервисом
+func (s *Service) RegisterOperation(kind string, handler OperationHandler) error {
+	if kind == "" || strings.ContainsAny(kind, ";\n") {
+		return ErrInvalidOperation
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.handlers[kind]; ok || reservedOperations[kind] {
+		return ErrOperationRegistered
+	}
+	if s.handlers == nil {
+		s.handlers = make(map[string]OperationHandler)
+	}
+	s.handlers[kind] = handler
+	return nil
+}
+
+// ExecuteOperation проводит операцию вида kind как обычный платеж с категорией kind
+// и сохраняет ее данные вместе с остальным состоянием
+func (s *Service) ExecuteOperation(accountID int64, kind string, payload []byte) (_ *types.Operation, err error) {
+	defer s.audit("ExecuteOperation", &err, "accountID", accountID, "kind", kind)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	handler, ok := s.handlers[kind]
+	if !ok {
+		return nil, ErrUnknownOperation
+	}
+	account, err := s.findAccountByID(accountID)
+	if err != nil {
+		return nil, err
+	}
+	amount, err := handler.Prepare(*account, payload)
+	if err != nil {
+		return nil, err
+	}
+	payment, err := s.pay(accountID, amount, types.PaymentCategory(kind))
+	if err != nil {
+		return nil, err
+	}
+	operation := &types.Operation{
+		ID:        uuid.New().String(),
+		Kind:      kind,
+		AccountID: accountID,
+		PaymentID: payment.ID,
+		Payload:   append([]byte(nil), payload...),
+	}
+	s.operations = append(s.operations, operation)
+	s.markOperation(operation.ID)
+	return copyOperation(operation, nil)
+}
+
+func (s *Service) FindOperationByID(operationID string) (*types.Operation, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, operation := range s.operations {
+		if operation.ID == operationID {
+			return copyOperation(operation, nil)
+		}
+	}
+	return nil, ErrOperationNotFound
+}
+
+func operationToString(operation *types.Operation) string {
+	return types.JoinFields(operation.ID, operation.Kind, operation.AccountID, operation.PaymentID, base64.StdEncoding.EncodeToString(operation.Payload))
+}
@@ -0,0 +1,89 @@
+package wallet
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/sidalsoft/wallet/pkg/types"
+)
+
+var errSoldOut = errors.New("sold out")
+
+// lotteryHandler продает билеты по 10 сомони, номер билета передается в payload
+type lotteryHandler struct{}
+
+func (lotteryHandler) Prepare(account types.Account, payload []byte) (types.Money, error) {
+	ticket, err := strconv.Atoi(string(payload))
+	if err != nil || ticket > 100 {
+		return 0, errSoldOut
+	}
+	return 10_00, nil
+}
+
+func TestService_ExecuteOperation_success(t *testing.T) {
+	s := newTestService()
+	account, err := s.addAccountWithBalance("+992928885522", 100_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = s.RegisterOperation("lottery", lotteryHandler{})
+	if err != nil {
+		t.Errorf("RegisterOperation(): error = %v", err)
+		return
+	}
+	operation, err := s.ExecuteOperation(account.ID, "lottery", []byte("42"))
+	if err != nil {
+		t.Errorf("ExecuteOperation(): error = %v", err)
+		return
+	}
+	account, _ = s.FindAccountByID(account.ID)
+	if account.Balance != 90_00 {
+		t.Errorf("ExecuteOperation(): balance didn't changed, account = %v", account)
+		return
+	}
+
+	dir := t.TempDir()
+	err = s.Export(dir)
+	if err != nil {
+		t.Errorf("Export(): error = %v", err)
+		return
+	}
+	imported := &Service{}
+	err = imported.Import(dir)
+	if err != nil {
+		t.Errorf("Import(): error = %v", err)
+		return
+	}
+	got, err := imported.FindOperationByID(operation.ID)
+	if err != nil || !bytes.Equal(got.Payload, operation.Payload) || got.PaymentID != operation.PaymentID {
+		t.Errorf("Import(): wrong operation = %v, error = %v", got, err)
+		return
+	}
+}
+
+func TestService_ExecuteOperation_fail(t *testing.T) {
+	s := newTestService()
+	account, err := s.addAccountWithBalance("+992928885522", 100_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, err = s.ExecuteOperation(account.ID, "lottery", nil)
+	if !errors.Is(err, ErrUnknownOperation) {
+		t.Errorf("ExecuteOperation(): must return ErrUnknownOperation, returned = %v", err)
+		return
+	}
+	_ = s.RegisterOperation("lottery", lotteryHandler{})
+	_, err = s.ExecuteOperation(account.ID, "lottery", []byte("500"))
+	if !errors.Is(err, errSoldOut) {
+		t.Errorf("ExecuteOperation(): must return handler error, returned = %v", err)
+		return
+	}
+	if account.Balance != 100_00 || len(s.payments) != 0 {
+		t.Errorf("ExecuteOperation(): state changed, account = %v", account)
+		return
+	}
+}
@@ -0,0 +1,98 @@
+package wallet
+
+import (
+	"os"
+	"sort"
+
+	"github.com/sidalsoft/wallet/pkg/types"
+)
+
+// ExportOrder определяет порядок записей в дампах
+type ExportOrder string
+
+const (
+	// OrderInsertion - записи в порядке добавления в сервис
+	OrderInsertion ExportOrder = "insertion"
+	// OrderByID - записи упорядочены по ID, поэтому одинаковое состояние дает
+	// одинаковые дампы независимо от истории изменений
+	OrderByID ExportOrder = "id"
+)
+
+// manifestOrder - префикс строки манифеста с порядком записей
+const manifestOrder = "#order "
+
+// ExportOption настраивает вызов Export и других методов экспорта
+type ExportOption func(config *exportConfig)
+
+type exportConfig struct {
+	order ExportOrder
+}
+
+func newExportConfig(options []ExportOption) exportConfig {
+	config := exportConfig{order: OrderInsertion}
+	for _, option := range options {
+		option(&config)
+	}
+	return config
+}
+
+// WithCanonicalOrder упорядочивает записи дампов по ID
+func WithCanonicalOrder() ExportOption {
+	return func(config *exportConfig) {
+		config.order = OrderByID
+	}
+}
+
+// DumpOrder возвращает порядок записей дампов каталога dir, записанный в манифест.
+// Для дампов без манифеста или без порядка в нем возвращается OrderInsertion
+func DumpOrder(dir string) (ExportOrder, error) {
+	_, order, err := readManifest(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	return order, nil
+}
+
+// exportRecords - записи сервиса в порядке экспорта
+type exportRecords struct {
+	accounts   []*types.Account
+	payments   []*types.Payment
+	favorites  []*types.Favorite
+	scheduled  []*types.ScheduledPayment
+	operations []*types.Operation
+	merchants  []*types.Merchant
+}
+
+// records возвращает записи сервиса в порядке order, вызывается под блокировкой сервиса
+func (s *Service) records(order ExportOrder) exportRecords {
+	if order != OrderByID {
+		return exportRecords{s.accounts, s.payments, s.favorites, s.scheduled, s.operations, s.merchants}
+	}
+	r := exportRecords{
+		accounts:   append([]*types.Account(nil), s.accounts...),
+		payments:   append([]*types.Payment(nil), s.payments...),
+		favorites:  append([]*types.Favorite(nil), s.favorites...),
+		scheduled:  append([]*types.ScheduledPayment(nil), s.scheduled...),
+		operations: append([]*types.Operation(nil), s.operations...),
+		merchants:  append([]*types.Merchant(nil), s.merchants...),
+	}
+	sort.SliceStable(r.accounts, func(i, j int) bool {
+		return r.accounts[i].ID < r.accounts[j].ID
+	})
+	sort.SliceStable(r.payments, func(i, j int) bool {
+		return r.payments[i].ID < r.payments[j].ID
+	})
+	sort.SliceStable(r.favorites, func(i, j int) bool {
+		return r.favorites[i].ID < r.favorites[j].ID
+	})
+	sort.SliceStable(r.scheduled, func(i, j int) bool {
+		return r.scheduled[i].ID < r.scheduled[j].ID
+	})
+	sort.SliceStable(r.operations, func(i, j int) bool {
+		return r.operations[i].ID < r.operations[j].ID
+	})
+	sort.SliceStable(r.merchants, func(i, j int) bool {
+		return r.merchants[i].ID < r.merchants[j].ID
+	})
+	return r
+}
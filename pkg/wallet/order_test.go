@@ -0,0 +1,72 @@
+package wallet
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestService_Export_canonicalOrder(t *testing.T) {
+	a := newTestService()
+	err := a.ImportAccounts(strings.NewReader("1;+992000000001;100\n2;+992000000002;200\n"))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	b := newTestService()
+	err = b.ImportAccounts(strings.NewReader("2;+992000000002;200\n1;+992000000001;100\n"))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	dirA, dirB := t.TempDir(), t.TempDir()
+	err = a.Export(dirA, WithCanonicalOrder())
+	if err != nil {
+		t.Errorf("Export(): error = %v", err)
+		return
+	}
+	err = b.Export(dirB, WithCanonicalOrder())
+	if err != nil {
+		t.Errorf("Export(): error = %v", err)
+		return
+	}
+	for _, file := range []string{"accounts.dump", manifestName} {
+		dataA, errA := ioutil.ReadFile(dirA + "/" + file)
+		dataB, errB := ioutil.ReadFile(dirB + "/" + file)
+		if errA != nil || errB != nil || !bytes.Equal(dataA, dataB) {
+			t.Errorf("Export(): %s differs, a = %q, b = %q", file, dataA, dataB)
+			return
+		}
+	}
+	order, err := DumpOrder(dirA)
+	if err != nil || order != OrderByID {
+		t.Errorf("DumpOrder(): order = %v, error = %v", order, err)
+		return
+	}
+
+	streamA, streamB := &bytes.Buffer{}, &bytes.Buffer{}
+	_ = a.ExportAccountsTo(streamA, WithCanonicalOrder())
+	_ = b.ExportAccountsTo(streamB, WithCanonicalOrder())
+	if streamA.String() != streamB.String() {
+		t.Errorf("ExportAccountsTo(): a = %q, b = %q", streamA, streamB)
+		return
+	}
+
+	err = b.Export(dirB)
+	if err != nil {
+		t.Errorf("Export(): error = %v", err)
+		return
+	}
+	order, err = DumpOrder(dirB)
+	if err != nil || order != OrderInsertion {
+		t.Errorf("DumpOrder(): order = %v, error = %v", order, err)
+		return
+	}
+	data, _ := ioutil.ReadFile(dirB + "/accounts.dump")
+	if !strings.HasPrefix(string(data), addDumpHeader("2;")) {
+		t.Errorf("Export(): accounts must keep insertion order, dump = %q", data)
+		return
+	}
+}
@@ -0,0 +1,40 @@
+package wallet
+
+import (
+	"time"
+
+	"github.com/sidalsoft/wallet/pkg/types"
+)
+
+// SetCreditLimit разрешает балансу счета уходить в минус не больше чем на limit
+func (s *Service) SetCreditLimit(accountID int64, limit types.Money) (err error) {
+	defer s.audit("SetCreditLimit", &err, "accountID", accountID, "limit", limit)
+	if limit < 0 {
+		return ErrInvalidCreditLimit
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	account, err := s.findAccountByID(accountID)
+	if err != nil {
+		return err
+	}
+	account.CreditLimit = limit
+	s.markAccount(account.ID)
+	return nil
+}
+
+// changeBalance изменяет баланс счета и отмечает время ухода в минус
+func (s *Service) changeBalance(account *types.Account, delta types.Money) {
+	if delta == 0 {
+		return
+	}
+	account.Balance += delta
+	if account.Balance >= 0 {
+		account.OverdrawnSince = time.Time{}
+	} else if account.OverdrawnSince.IsZero() {
+		account.OverdrawnSince = s.clock()
+	}
+	s.markAccount(account.ID)
+	s.recordBalance(account)
+	s.emit(EventBalanceChanged, account, nil, delta)
+}
@@ -0,0 +1,70 @@
+package wallet
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestService_SetCreditLimit_success(t *testing.T) {
+	s := newTestService()
+	now := time.Date(2021, 3, 31, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	account, err := s.addAccountWithBalance("+992928885522", 100_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = s.SetCreditLimit(account.ID, 50_00)
+	if err != nil {
+		t.Errorf("SetCreditLimit(): error = %v", err)
+		return
+	}
+	payment, err := s.Pay(account.ID, 130_00, "auto")
+	if err != nil {
+		t.Errorf("Pay(): error = %v", err)
+		return
+	}
+	account, _ = s.FindAccountByID(account.ID)
+	if account.Balance != -30_00 || !account.OverdrawnSince.Equal(now) {
+		t.Errorf("Pay(): account must be overdrawn, account = %v", account)
+		return
+	}
+	_, err = s.Pay(account.ID, 30_00, "auto")
+	if !errors.Is(err, ErrNotEnoughBalance) {
+		t.Errorf("Pay(): must return ErrNotEnoughBalance, returned = %v", err)
+		return
+	}
+
+	_ = s.Reject(payment.ID)
+	account, _ = s.FindAccountByID(account.ID)
+	if account.Balance != 100_00 || !account.OverdrawnSince.IsZero() {
+		t.Errorf("Reject(): account must not be overdrawn, account = %v", account)
+		return
+	}
+
+	dir := t.TempDir()
+	_, _ = s.Pay(account.ID, 110_00, "auto")
+	_ = s.Export(dir)
+	imported := &Service{}
+	_ = imported.Import(dir)
+	got, err := imported.FindAccountByID(account.ID)
+	if err != nil || got.CreditLimit != 50_00 || !got.OverdrawnSince.Equal(now) {
+		t.Errorf("Import(): credit limit not imported, account = %v, error = %v", got, err)
+		return
+	}
+}
+
+func TestService_SetCreditLimit_fail(t *testing.T) {
+	s := newTestService()
+	account, err := s.addAccountWithBalance("+992928885522", 100_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = s.SetCreditLimit(account.ID, -1)
+	if !errors.Is(err, ErrInvalidCreditLimit) {
+		t.Errorf("SetCreditLimit(): must return ErrInvalidCreditLimit, returned = %v", err)
+		return
+	}
+}
@@ -0,0 +1,151 @@
+package wallet
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sidalsoft/wallet/pkg/types"
+)
+
+// MoveOperation - вид операций, которыми MoveBetweenWallets записывает перевод:
+// списание с одного кошелька и зачисление на другой. Payload операций - WalletMove в JSON
+const MoveOperation = "move"
+
+// WalletMove - перевод Amount между кошельками одного владельца
+type WalletMove struct {
+	FromAccountID int64       `json:"fromAccountId"`
+	ToAccountID   int64       `json:"toAccountId"`
+	Amount        types.Money `json:"amount"`
+}
+
+// OpenWallet открывает владельцу телефона phone дополнительный кошелек name,
+// например "savings", в валюте основного кошелька
+func (s *Service) OpenWallet(phone types.Phone, name string) (_ *types.Account, err error) {
+	defer s.audit("OpenWallet", &err, "phone", phone, "wallet", name)
+	defer s.observe("RegisterAccount", time.Now())
+	if strings.TrimSpace(name) == "" {
+		return nil, ErrInvalidWallet
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	main, err := s.findAccountByPhone(phone)
+	if err != nil {
+		return nil, err
+	}
+	for _, wallet := range s.ownerWallets(phone) {
+		if wallet.Wallet == name {
+			return nil, ErrWalletRegistered
+		}
+	}
+	return copyAccount(s.newAccount(phone, main.Currency, name), nil)
+}
+
+// FindOwnerByPhone возвращает владельца телефона phone с его открытыми кошельками
+func (s *Service) FindOwnerByPhone(phone types.Phone) (*types.Owner, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, err := s.findAccountByPhone(phone)
+	if err != nil {
+		return nil, err
+	}
+	owner := &types.Owner{Phone: phone}
+	for _, wallet := range s.ownerWallets(phone) {
+		owner.Wallets = append(owner.Wallets, *wallet)
+	}
+	return owner, nil
+}
+
+// ownerWallets возвращает открытые кошельки телефона phone, основной - первым
+func (s *Service) ownerWallets(phone types.Phone) []*types.Account {
+	var wallets []*types.Account
+	for _, account := range s.accounts {
+		if account.Phone != phone || account.Status == types.AccountStatusClosed {
+			continue
+		}
+		if account.Wallet == "" {
+			wallets = append([]*types.Account{account}, wallets...)
+		} else {
+			wallets = append(wallets, account)
+		}
+	}
+	return wallets
+}
+
+// MoveBetweenWallets переводит amount между кошельками одного владельца без комиссий
+// и лимитов. Перевод записывается двумя операциями вида MoveOperation: на счет
+// списания и на счет зачисления
+func (s *Service) MoveBetweenWallets(fromAccountID, toAccountID int64, amount types.Money) (err error) {
+	defer s.audit("MoveBetweenWallets", &err, "accountID", fromAccountID, "toAccountID", toAccountID, "amount", amount)
+	defer s.observe("MoveBetweenWallets", time.Now())
+	if amount <= 0 {
+		return ErrAmountMustBePositive
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	from, err := s.findAccountByID(fromAccountID)
+	if err != nil {
+		return err
+	}
+	to, err := s.findAccountByID(toAccountID)
+	if err != nil {
+		return err
+	}
+	if to.ID == from.ID {
+		return ErrSelfTransfer
+	}
+	if to.Phone != from.Phone {
+		return ErrNotSameOwner
+	}
+	if to.Currency != from.Currency {
+		return ErrCurrencyMismatch
+	}
+	if from.Status == types.AccountStatusFrozen {
+		return ErrAccountFrozen
+	}
+	if from.Status == types.AccountStatusClosed || to.Status == types.AccountStatusClosed {
+		return ErrAccountClosed
+	}
+	if !s.canDebit(from, amount) {
+		return ErrNotEnoughBalance
+	}
+	payload, err := json.Marshal(WalletMove{FromAccountID: from.ID, ToAccountID: to.ID, Amount: amount})
+	if err != nil {
+		return err
+	}
+	s.changeBalance(from, -amount)
+	s.changeBalance(to, amount)
+	for _, account := range []*types.Account{from, to} {
+		operation := &types.Operation{
+			ID:        uuid.New().String(),
+			Kind:      MoveOperation,
+			AccountID: account.ID,
+			Payload:   payload,
+		}
+		s.operations = append(s.operations, operation)
+		s.markOperation(operation.ID)
+	}
+	return nil
+}
+
+// WalletMoves возвращает переводы MoveBetweenWallets кошелька accountID в порядке проведения
+func (s *Service) WalletMoves(accountID int64) ([]WalletMove, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, err := s.findAccountByID(accountID)
+	if err != nil {
+		return nil, err
+	}
+	var moves []WalletMove
+	for _, operation := range s.operations {
+		if operation.Kind != MoveOperation || operation.AccountID != accountID {
+			continue
+		}
+		var move WalletMove
+		if json.Unmarshal(operation.Payload, &move) == nil {
+			moves = append(moves, move)
+		}
+	}
+	return moves, nil
+}
@@ -0,0 +1,110 @@
+package wallet
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/sidalsoft/wallet/pkg/types"
+)
+
+func TestService_MoveBetweenWallets_success(t *testing.T) {
+	s := newTestService()
+	main, err := s.addAccountWithBalance("+992928885522", 1_000_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	savings, err := s.OpenWallet(main.Phone, "savings")
+	if err != nil {
+		t.Errorf("OpenWallet(): error = %v", err)
+		return
+	}
+	if savings.Phone != main.Phone || savings.Wallet != "savings" || savings.ID == main.ID {
+		t.Errorf("OpenWallet(): wallet = %v", savings)
+		return
+	}
+	found, err := s.FindAccountByPhone(main.Phone)
+	if err != nil || found.ID != main.ID {
+		t.Errorf("FindAccountByPhone(): must return main wallet, account = %v, error = %v", found, err)
+		return
+	}
+
+	err = s.MoveBetweenWallets(main.ID, savings.ID, 300_00)
+	if err != nil {
+		t.Errorf("MoveBetweenWallets(): error = %v", err)
+		return
+	}
+	owner, err := s.FindOwnerByPhone(main.Phone)
+	if err != nil || len(owner.Wallets) != 2 {
+		t.Errorf("FindOwnerByPhone(): owner = %v, error = %v", owner, err)
+		return
+	}
+	if owner.Wallets[0].Balance != 700_00 || owner.Wallets[1].Balance != 300_00 {
+		t.Errorf("MoveBetweenWallets(): wallets = %v", owner.Wallets)
+		return
+	}
+	moves, err := s.WalletMoves(savings.ID)
+	want := WalletMove{FromAccountID: main.ID, ToAccountID: savings.ID, Amount: 300_00}
+	if err != nil || len(moves) != 1 || moves[0] != want {
+		t.Errorf("WalletMoves(): moves = %v, error = %v", moves, err)
+		return
+	}
+
+	err = s.ChangePhone(main.ID, "+992000000001")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	got, err := s.FindAccountByID(savings.ID)
+	if err != nil || got.Phone != "+992000000001" {
+		t.Errorf("ChangePhone(): wallets must follow owner, wallet = %v, error = %v", got, err)
+		return
+	}
+}
+
+func TestService_MoveBetweenWallets_fail(t *testing.T) {
+	s := newTestService()
+	main, err := s.addAccountWithBalance("+992928885522", 100_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	other, err := s.RegisterAccount("+992000000001")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	savings, err := s.OpenWallet(main.Phone, "savings")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, err = s.OpenWallet(main.Phone, "savings")
+	if !errors.Is(err, ErrWalletRegistered) {
+		t.Errorf("OpenWallet(): error = %v, want %v", err, ErrWalletRegistered)
+		return
+	}
+	_, err = s.OpenWallet(main.Phone, " ")
+	if !errors.Is(err, ErrInvalidWallet) {
+		t.Errorf("OpenWallet(): error = %v, want %v", err, ErrInvalidWallet)
+		return
+	}
+
+	tests := []struct {
+		from, to int64
+		amount   int64
+		want     error
+	}{
+		{main.ID, other.ID, 10_00, ErrNotSameOwner},
+		{main.ID, main.ID, 10_00, ErrSelfTransfer},
+		{main.ID, savings.ID, 200_00, ErrNotEnoughBalance},
+		{main.ID, savings.ID, 0, ErrAmountMustBePositive},
+	}
+	for _, tt := range tests {
+		err = s.MoveBetweenWallets(tt.from, tt.to, types.Money(tt.amount))
+		if !errors.Is(err, tt.want) {
+			t.Errorf("MoveBetweenWallets(%d, %d, %d): error = %v, want %v", tt.from, tt.to, tt.amount, err, tt.want)
+			return
+		}
+	}
+}
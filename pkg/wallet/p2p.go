@@ -0,0 +1,63 @@
+package wallet
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sidalsoft/wallet/pkg/types"
+)
+
+// P2POperation - вид операции, которой PayToPhone записывает зачисление получателю
+const P2POperation = "p2p"
+
+// PayToPhone переводит amount со счета fromAccountID на счет с номером phone.
+// Списание записывается платежом отправителя, сразу завершенным, поэтому его нельзя
+// отклонить. Зачисление записывается операцией вида P2POperation на счет получателя
+// со ссылкой на платеж. Если номер не зарегистрирован, возвращается ErrAccountNotFound
+func (s *Service) PayToPhone(fromAccountID int64, phone types.Phone, amount types.Money, category types.PaymentCategory) (_ *types.Payment, err error) {
+	defer s.audit("PayToPhone", &err, "accountID", fromAccountID, "phone", phone, "amount", amount, "category", category)
+	defer s.observe("Pay", time.Now())
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	from, err := s.findAccountByID(fromAccountID)
+	if err != nil {
+		return nil, err
+	}
+	to, err := s.findAccountByPhone(phone)
+	if err != nil {
+		return nil, err
+	}
+	if to.ID == from.ID {
+		return nil, ErrSelfTransfer
+	}
+	if to.Currency != from.Currency {
+		return nil, ErrCurrencyMismatch
+	}
+	err = s.checkDuplicate(fromAccountID, amount, category)
+	if err != nil {
+		return nil, err
+	}
+	return copyPayment(s.transfer(from, to, amount, category))
+}
+
+// transfer проводит перевод, как PayToPhone, без проверки повторных платежей.
+// Счета from и to уже проверены вызывающим
+func (s *Service) transfer(from, to *types.Account, amount types.Money, category types.PaymentCategory, options ...PayOption) (*types.Payment, error) {
+	payment, err := s.pay(from.ID, amount, category, options...)
+	if err != nil {
+		return nil, err
+	}
+	payment.Status = types.PaymentStatusOk
+	s.changeBalance(to, payment.Amount)
+	operation := &types.Operation{
+		ID:        uuid.New().String(),
+		Kind:      P2POperation,
+		AccountID: to.ID,
+		PaymentID: payment.ID,
+	}
+	s.operations = append(s.operations, operation)
+	s.markOperation(operation.ID)
+	s.creditCashback(from, payment)
+	s.earnPoints(from, payment)
+	return payment, nil
+}
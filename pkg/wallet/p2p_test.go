@@ -0,0 +1,78 @@
+package wallet
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestService_PayToPhone_success(t *testing.T) {
+	s := newTestService()
+	from, err := s.addAccountWithBalance("+992900000001", 1_000_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	to, err := s.RegisterAccount("+992900000002")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	payment, err := s.PayToPhone(from.ID, to.Phone, 300_00, "p2p")
+	if err != nil {
+		t.Errorf("PayToPhone(): error = %v", err)
+		return
+	}
+	from, _ = s.FindAccountByID(from.ID)
+	to, _ = s.FindAccountByID(to.ID)
+	if from.Balance != 700_00 || to.Balance != 300_00 {
+		t.Errorf("PayToPhone(): from = %v, to = %v", from, to)
+		return
+	}
+	var linked bool
+	for _, operation := range s.operations {
+		linked = linked || operation.Kind == P2POperation && operation.AccountID == to.ID && operation.PaymentID == payment.ID
+	}
+	if !linked {
+		t.Errorf("PayToPhone(): credit must be linked to payment %v", payment.ID)
+		return
+	}
+	err = s.Reject(payment.ID)
+	if !errors.Is(err, ErrInvalidPaymentStatus) {
+		t.Errorf("Reject(): must return ErrInvalidPaymentStatus, returned = %v", err)
+		return
+	}
+}
+
+func TestService_PayToPhone_fail(t *testing.T) {
+	s := newTestService()
+	from, err := s.addAccountWithBalance("+992900000001", 1_000_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, err = s.PayToPhone(from.ID, "+992900000404", 100_00, "p2p")
+	if !errors.Is(err, ErrAccountNotFound) {
+		t.Errorf("PayToPhone(): must return ErrAccountNotFound, returned = %v", err)
+		return
+	}
+	_, err = s.PayToPhone(from.ID, from.Phone, 100_00, "p2p")
+	if !errors.Is(err, ErrSelfTransfer) {
+		t.Errorf("PayToPhone(): must return ErrSelfTransfer, returned = %v", err)
+		return
+	}
+	to, err := s.RegisterAccount("+992900000002")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, err = s.PayToPhone(from.ID, to.Phone, 2_000_00, "p2p")
+	if !errors.Is(err, ErrNotEnoughBalance) {
+		t.Errorf("PayToPhone(): must return ErrNotEnoughBalance, returned = %v", err)
+		return
+	}
+	to, _ = s.FindAccountByID(to.ID)
+	if to.Balance != 0 {
+		t.Errorf("PayToPhone(): failed payment must not credit, to = %v", to)
+		return
+	}
+}
@@ -0,0 +1,78 @@
+package wallet
+
+import "github.com/sidalsoft/wallet/pkg/types"
+
+// PaymentProcessor обрабатывает платежи своей категории перед списанием средств:
+// может проверить платеж, вернув ошибку, изменить его сумму или категорию.
+// Вызывается под блокировкой сервиса, поэтому обращается к данным только через lookup
+type PaymentProcessor interface {
+	Process(lookup AccountLookup, payment *types.Payment) error
+}
+
+// PaymentProcessorFunc позволяет использовать функцию как PaymentProcessor
+type PaymentProcessorFunc func(lookup AccountLookup, payment *types.Payment) error
+
+func (f PaymentProcessorFunc) Process(lookup AccountLookup, payment *types.Payment) error {
+	return f(lookup, payment)
+}
+
+// AccountLookup дает обработчикам доступ на чтение к счетам сервиса
+type AccountLookup interface {
+	FindAccountByID(accountID int64) (types.Account, error)
+	FindAccountByPhone(phone types.Phone) (types.Account, error)
+}
+
+type serviceLookup struct {
+	s *Service
+}
+
+func (l serviceLookup) FindAccountByID(accountID int64) (types.Account, error) {
+	account, err := l.s.findAccountByID(accountID)
+	if err != nil {
+		return types.Account{}, err
+	}
+	return *account, nil
+}
+
+func (l serviceLookup) FindAccountByPhone(phone types.Phone) (types.Account, error) {
+	account, err := l.s.findAccountByPhone(phone)
+	if err != nil {
+		return types.Account{}, err
+	}
+	return *account, nil
+}
+
+// RegisterProcessor назначает обработчик платежам категории category,
+// заменяя ранее назначенный. nil снимает обработчик
+func (s *Service) RegisterProcessor(category types.PaymentCategory, processor PaymentProcessor) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if processor == nil {
+		delete(s.processors, category)
+		return
+	}
+	if s.processors == nil {
+		s.processors = make(map[types.PaymentCategory]PaymentProcessor)
+	}
+	s.processors[category] = processor
+}
+
+// process пропускает черновик платежа через обработчик его категории.
+// Идентификаторы, счет и статус платежа обработчик изменить не может
+func (s *Service) process(payment *types.Payment) error {
+	processor, ok := s.processors[payment.Category]
+	if !ok {
+		return nil
+	}
+	draft := *payment
+	err := processor.Process(serviceLookup{s: s}, &draft)
+	if err != nil {
+		return err
+	}
+	if draft.Amount <= 0 {
+		return ErrAmountMustBePositive
+	}
+	payment.Amount = draft.Amount
+	payment.Category = draft.Category
+	return nil
+}
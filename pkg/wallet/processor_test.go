@@ -0,0 +1,76 @@
+package wallet
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/sidalsoft/wallet/pkg/types"
+)
+
+var errUnknownBill = errors.New("unknown bill")
+
+func TestService_RegisterProcessor_success(t *testing.T) {
+	s := newTestService()
+	account, err := s.addAccountWithBalance("+992928885522", 100_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, err = s.RegisterAccount("+992928000000")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	// переводы уходят в категорию "transfer:<id получателя>" и стоят на 1 сомони дороже
+	s.RegisterProcessor("transfer", PaymentProcessorFunc(func(lookup AccountLookup, payment *types.Payment) error {
+		payee, err := lookup.FindAccountByPhone("+992928000000")
+		if err != nil {
+			return err
+		}
+		payment.Category = types.PaymentCategory("transfer:" + payee.Phone)
+		payment.Amount += 1_00
+		payment.AccountID = payee.ID
+		return nil
+	}))
+	payment, err := s.Pay(account.ID, 10_00, "transfer")
+	if err != nil {
+		t.Errorf("Pay(): error = %v", err)
+		return
+	}
+	if payment.Category != "transfer:+992928000000" || payment.Amount != 11_00 || payment.AccountID != account.ID {
+		t.Errorf("Pay(): payment not processed, payment = %v", payment)
+		return
+	}
+	account, _ = s.FindAccountByID(account.ID)
+	if account.Balance != 89_00 {
+		t.Errorf("Pay(): wrong balance, account = %v", account)
+		return
+	}
+}
+
+func TestService_RegisterProcessor_fail(t *testing.T) {
+	s := newTestService()
+	account, err := s.addAccountWithBalance("+992928885522", 100_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	s.RegisterProcessor("utilities", PaymentProcessorFunc(func(lookup AccountLookup, payment *types.Payment) error {
+		return errUnknownBill
+	}))
+	_, err = s.Pay(account.ID, 10_00, "utilities")
+	if !errors.Is(err, errUnknownBill) {
+		t.Errorf("Pay(): must return processor error, returned = %v", err)
+		return
+	}
+	if account.Balance != 100_00 || len(s.payments) != 0 {
+		t.Errorf("Pay(): state changed, account = %v", account)
+		return
+	}
+	s.RegisterProcessor("utilities", nil)
+	_, err = s.Pay(account.ID, 10_00, "utilities")
+	if err != nil {
+		t.Errorf("Pay(): error = %v", err)
+		return
+	}
+}
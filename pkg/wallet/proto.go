@@ -0,0 +1,386 @@
+package wallet
+
+import (
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"sort"
+	"time"
+
+	"github.com/sidalsoft/wallet/pkg/types"
+)
+
+// Типы полей protobuf, используемые в wallet.proto
+const (
+	protoVarint = 0
+	protoBytes  = 2
+)
+
+// ExportProto записывает счета, платежи и избранное в w одним сообщением State
+// из wallet.proto, чтобы состояние можно было передать сервисам не на Go
+func (s *Service) ExportProto(w io.Writer, options ...ExportOption) error {
+	defer s.observe("ExportProto", time.Now())
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	records := s.records(newExportConfig(options).order)
+	var state protoBuffer
+	for _, account := range records.accounts {
+		var message protoBuffer
+		message.int64(1, account.ID)
+		message.string(2, string(account.Phone))
+		message.int64(3, int64(account.Balance))
+		message.string(4, string(account.Currency))
+		message.string(5, string(account.Status))
+		message.int64(6, int64(account.CreditLimit))
+		message.time(7, account.OverdrawnSince)
+		message.time(8, account.Registered)
+		message.int64(9, account.Points)
+		message.string(10, string(account.Type))
+		message.string(11, account.Wallet)
+		state.bytes(1, message)
+	}
+	for _, payment := range records.payments {
+		var message protoBuffer
+		message.string(1, payment.ID)
+		message.int64(2, payment.AccountID)
+		message.int64(3, int64(payment.Amount))
+		message.string(4, string(payment.Category))
+		message.string(5, string(payment.Status))
+		message.string(6, string(payment.Currency))
+		message.int64(7, int64(payment.Fee))
+		message.time(8, payment.Created)
+		message.string(9, string(payment.InferredCategory))
+		message.string(10, string(payment.OriginalCategory))
+		message.string(11, payment.Comment)
+		keys := make([]string, 0, len(payment.Metadata))
+		for key := range payment.Metadata {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			var entry protoBuffer
+			entry.string(1, key)
+			entry.string(2, payment.Metadata[key])
+			message.bytes(12, entry)
+		}
+		for _, tag := range payment.Tags {
+			message.string(13, tag)
+		}
+		message.string(14, payment.MerchantID)
+		state.bytes(2, message)
+	}
+	for _, favorite := range records.favorites {
+		var message protoBuffer
+		message.string(1, favorite.ID)
+		message.int64(2, favorite.AccountID)
+		message.string(3, favorite.Name)
+		message.int64(4, int64(favorite.Amount))
+		message.string(5, string(favorite.Category))
+		message.time(6, favorite.Deleted)
+		message.string(7, string(favorite.Group))
+		state.bytes(3, message)
+	}
+	_, err := w.Write(state)
+	return err
+}
+
+// ImportProto читает сообщение State, записанное ExportProto, и применяет его
+// так же, как Import применяет дампы. Неизвестные поля пропускаются
+func (s *Service) ImportProto(r io.Reader, options ...ImportOption) (err error) {
+	config := importConfig{}
+	for _, option := range options {
+		option(&config)
+	}
+	defer s.audit("ImportProto", &err, "merge", config.merge)
+	defer s.observe("ImportProto", time.Now())
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	parsed, err := parseProto(data)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if config.merge == MergeFailOnConflict {
+		err = s.checkImportConflicts(parsed, s.newImportIndex())
+		if err != nil {
+			return err
+		}
+	}
+	s.markFull()
+	s.importDumps(parsed, config.merge)
+	return nil
+}
+
+func parseProto(data []byte) (*parsedDumps, error) {
+	parsed := &parsedDumps{}
+	state := protoReader{data: data}
+	for state.next() {
+		if state.wire != protoBytes {
+			state.skip()
+			continue
+		}
+		message := protoReader{data: state.bytes()}
+		switch state.field {
+		case 1:
+			account := &types.Account{}
+			for message.next() {
+				switch message.field {
+				case 1:
+					account.ID = message.int64()
+				case 2:
+					account.Phone = types.Phone(message.string())
+				case 3:
+					account.Balance = types.Money(message.int64())
+				case 4:
+					account.Currency = types.Currency(message.string())
+				case 5:
+					account.Status = types.AccountStatus(message.string())
+				case 6:
+					account.CreditLimit = types.Money(message.int64())
+				case 7:
+					account.OverdrawnSince = message.time()
+				case 8:
+					account.Registered = message.time()
+				case 9:
+					account.Points = message.int64()
+				case 10:
+					account.Type = types.AccountType(message.string())
+				case 11:
+					account.Wallet = message.string()
+				default:
+					message.skip()
+				}
+			}
+			if account.ID <= 0 || account.Phone == "" {
+				message.fail()
+			}
+			// в сообщениях до появления типов счетов тип не записан
+			if account.Type == "" {
+				account.Type = types.AccountTypePersonal
+			}
+			parsed.accounts = append(parsed.accounts, account)
+		case 2:
+			payment := &types.Payment{}
+			for message.next() {
+				switch message.field {
+				case 1:
+					payment.ID = message.string()
+				case 2:
+					payment.AccountID = message.int64()
+				case 3:
+					payment.Amount = types.Money(message.int64())
+				case 4:
+					payment.Category = types.PaymentCategory(message.string())
+				case 5:
+					payment.Status = types.PaymentStatus(message.string())
+				case 6:
+					payment.Currency = types.Currency(message.string())
+				case 7:
+					payment.Fee = types.Money(message.int64())
+				case 8:
+					payment.Created = message.time()
+				case 9:
+					payment.InferredCategory = types.PaymentCategory(message.string())
+				case 10:
+					payment.OriginalCategory = types.PaymentCategory(message.string())
+				case 11:
+					payment.Comment = message.string()
+				case 12:
+					entry := protoReader{data: message.bytes()}
+					var key, value string
+					for entry.next() {
+						switch entry.field {
+						case 1:
+							key = entry.string()
+						case 2:
+							value = entry.string()
+						default:
+							entry.skip()
+						}
+					}
+					if entry.err != nil {
+						message.fail()
+					}
+					if payment.Metadata == nil {
+						payment.Metadata = make(map[string]string)
+					}
+					payment.Metadata[key] = value
+				case 13:
+					payment.Tags = append(payment.Tags, message.string())
+				case 14:
+					payment.MerchantID = message.string()
+				default:
+					message.skip()
+				}
+			}
+			if payment.ID == "" {
+				message.fail()
+			}
+			parsed.payments = append(parsed.payments, payment)
+		case 3:
+			favorite := &types.Favorite{}
+			for message.next() {
+				switch message.field {
+				case 1:
+					favorite.ID = message.string()
+				case 2:
+					favorite.AccountID = message.int64()
+				case 3:
+					favorite.Name = message.string()
+				case 4:
+					favorite.Amount = types.Money(message.int64())
+				case 5:
+					favorite.Category = types.PaymentCategory(message.string())
+				case 6:
+					favorite.Deleted = message.time()
+				case 7:
+					favorite.Group = types.FavoriteGroup(message.string())
+				default:
+					message.skip()
+				}
+			}
+			if favorite.ID == "" {
+				message.fail()
+			}
+			parsed.favorites = append(parsed.favorites, favorite)
+		}
+		if message.err != nil {
+			return nil, message.err
+		}
+	}
+	if state.err != nil {
+		return nil, state.err
+	}
+	return parsed, nil
+}
+
+// protoBuffer кодирует поля сообщения protobuf. Нулевые значения, как в proto3, не пишутся
+type protoBuffer []byte
+
+func (b *protoBuffer) uvarint(value uint64) {
+	var buf [binary.MaxVarintLen64]byte
+	*b = append(*b, buf[:binary.PutUvarint(buf[:], value)]...)
+}
+
+func (b *protoBuffer) key(field int, wire int) {
+	b.uvarint(uint64(field<<3 | wire))
+}
+
+func (b *protoBuffer) int64(field int, value int64) {
+	if value == 0 {
+		return
+	}
+	b.key(field, protoVarint)
+	b.uvarint(uint64(value))
+}
+
+func (b *protoBuffer) time(field int, value time.Time) {
+	if value.IsZero() {
+		return
+	}
+	b.int64(field, value.Unix())
+}
+
+func (b *protoBuffer) string(field int, value string) {
+	if value == "" {
+		return
+	}
+	b.bytes(field, []byte(value))
+}
+
+func (b *protoBuffer) bytes(field int, value []byte) {
+	b.key(field, protoBytes)
+	b.uvarint(uint64(len(value)))
+	*b = append(*b, value...)
+}
+
+// protoReader читает поля сообщения protobuf и запоминает первую ошибку
+type protoReader struct {
+	data  []byte
+	field int
+	wire  int
+	err   error
+}
+
+func (r *protoReader) fail() {
+	if r.err == nil {
+		r.err = ErrMalformedProto
+	}
+	r.data = nil
+}
+
+func (r *protoReader) varint() uint64 {
+	value, n := binary.Uvarint(r.data)
+	if n <= 0 {
+		r.fail()
+		return 0
+	}
+	r.data = r.data[n:]
+	return value
+}
+
+// next читает ключ следующего поля, false - сообщение закончилось или испорчено
+func (r *protoReader) next() bool {
+	if r.err != nil || len(r.data) == 0 {
+		return false
+	}
+	key := r.varint()
+	r.field, r.wire = int(key>>3), int(key&7)
+	return r.err == nil
+}
+
+func (r *protoReader) int64() int64 {
+	if r.wire != protoVarint {
+		r.fail()
+		return 0
+	}
+	return int64(r.varint())
+}
+
+func (r *protoReader) time() time.Time {
+	return time.Unix(r.int64(), 0).UTC()
+}
+
+func (r *protoReader) bytes() []byte {
+	if r.wire != protoBytes {
+		r.fail()
+		return nil
+	}
+	n := r.varint()
+	if n > uint64(len(r.data)) {
+		r.fail()
+		return nil
+	}
+	value := r.data[:n]
+	r.data = r.data[n:]
+	return value
+}
+
+func (r *protoReader) string() string {
+	return string(r.bytes())
+}
+
+// skip пропускает значение неизвестного поля
+func (r *protoReader) skip() {
+	switch r.wire {
+	case protoVarint:
+		r.varint()
+	case protoBytes:
+		r.bytes()
+	case 1, 5:
+		size := 8
+		if r.wire == 5 {
+			size = 4
+		}
+		if len(r.data) < size {
+			r.fail()
+			return
+		}
+		r.data = r.data[size:]
+	default:
+		r.fail()
+	}
+}
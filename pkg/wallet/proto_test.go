@@ -0,0 +1,86 @@
+package wallet
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestService_ExportProto_success(t *testing.T) {
+	s := newTestService()
+	account, err := s.addAccountWithBalance("+992928885522", 1_000_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = s.SetCreditLimit(account.ID, 100_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	payment, err := s.PayWithOptions(account.ID, 100_00, "auto;car", WithComment("бензин"), WithMetadata(map[string]string{"order": "42", "empty": ""}))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	favorite, err := s.FavoritePayment(payment.ID, "машина")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	buf := &bytes.Buffer{}
+	err = s.ExportProto(buf)
+	if err != nil {
+		t.Errorf("ExportProto(): error = %v", err)
+		return
+	}
+	imported := newTestService()
+	err = imported.ImportProto(buf)
+	if err != nil {
+		t.Errorf("ImportProto(): error = %v", err)
+		return
+	}
+
+	// время передается с точностью до секунды
+	wantAccount, _ := s.FindAccountByID(account.ID)
+	wantAccount.Registered = wantAccount.Registered.Truncate(time.Second)
+	payment.Created = payment.Created.Truncate(time.Second)
+	gotAccount, err := imported.FindAccountByID(account.ID)
+	if err != nil || !reflect.DeepEqual(gotAccount, wantAccount) {
+		t.Errorf("ImportProto(): account = %v, want %v, error = %v", gotAccount, wantAccount, err)
+		return
+	}
+	gotPayment, err := imported.FindPaymentByID(payment.ID)
+	if err != nil || !reflect.DeepEqual(gotPayment, payment) {
+		t.Errorf("ImportProto(): payment = %v, want %v, error = %v", gotPayment, payment, err)
+		return
+	}
+	gotFavorite, err := imported.FindFavoriteByID(favorite.ID)
+	if err != nil || !reflect.DeepEqual(gotFavorite, favorite) {
+		t.Errorf("ImportProto(): favorite = %v, want %v, error = %v", gotFavorite, favorite, err)
+		return
+	}
+}
+
+func TestService_ImportProto_fail(t *testing.T) {
+	s := newTestService()
+	for _, data := range [][]byte{
+		{0x0a, 0x05, 0x08},       // длина сообщения больше данных
+		{0x0a, 0x02, 0x12, 0x00}, // счет без ID
+		{0x12, 0x02, 0x10, 0x01}, // платеж без ID
+		{0x0a, 0x02, 0x0f, 0x00}, // неизвестный тип поля
+	} {
+		err := s.ImportProto(bytes.NewReader(data))
+		if !errors.Is(err, ErrMalformedProto) {
+			t.Errorf("ImportProto(%x): error = %v, want %v", data, err, ErrMalformedProto)
+			return
+		}
+	}
+	if len(s.accounts) != 0 || len(s.payments) != 0 {
+		t.Errorf("ImportProto(): state must not change, accounts = %v, payments = %v", s.accounts, s.payments)
+		return
+	}
+}
@@ -0,0 +1,89 @@
+package wallet
+
+// Quota ограничивает количество объектов одного счета. 0 означает отсутствие ограничения
+type Quota struct {
+	Favorites int
+	Scheduled int
+}
+
+// QuotaUsage описывает использование квоты счетом
+type QuotaUsage struct {
+	Quota     Quota
+	Favorites int
+	Scheduled int
+}
+
+// SetDefaultQuota задает квоту для счетов без собственной квоты
+func (s *Service) SetDefaultQuota(quota Quota) (err error) {
+	defer s.audit("SetDefaultQuota", &err, "favorites", quota.Favorites, "scheduled", quota.Scheduled)
+	if quota.Favorites < 0 || quota.Scheduled < 0 {
+		return ErrInvalidQuota
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.defaultQuota = quota
+	return nil
+}
+
+// SetAccountQuota задает квоту счета вместо квоты по умолчанию
+func (s *Service) SetAccountQuota(accountID int64, quota Quota) (err error) {
+	defer s.audit("SetAccountQuota", &err, "accountID", accountID, "favorites", quota.Favorites, "scheduled", quota.Scheduled)
+	if quota.Favorites < 0 || quota.Scheduled < 0 {
+		return ErrInvalidQuota
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.findAccountByID(accountID)
+	if err != nil {
+		return err
+	}
+	if s.quotas == nil {
+		s.quotas = make(map[int64]Quota)
+	}
+	s.quotas[accountID] = quota
+	return nil
+}
+
+func (s *Service) QuotaUsage(accountID int64) (QuotaUsage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, err := s.findAccountByID(accountID)
+	if err != nil {
+		return QuotaUsage{}, err
+	}
+	return s.quotaUsage(accountID), nil
+}
+
+func (s *Service) quotaUsage(accountID int64) QuotaUsage {
+	usage := QuotaUsage{Quota: s.defaultQuota}
+	if quota, ok := s.quotas[accountID]; ok {
+		usage.Quota = quota
+	}
+	for _, favorite := range s.favorites {
+		if favorite.AccountID == accountID && favorite.Deleted.IsZero() {
+			usage.Favorites++
+		}
+	}
+	for _, scheduled := range s.scheduled {
+		if scheduled.AccountID == accountID && scheduled.Deleted.IsZero() {
+			usage.Scheduled++
+		}
+	}
+	return usage
+}
+
+func (s *Service) checkFavoritesQuota(accountID int64) error {
+	usage := s.quotaUsage(accountID)
+	if usage.Quota.Favorites > 0 && usage.Favorites >= usage.Quota.Favorites {
+		return ErrQuotaExceeded
+	}
+	return nil
+}
+
+func (s *Service) checkScheduledQuota(accountID int64) error {
+	usage := s.quotaUsage(accountID)
+	if usage.Quota.Scheduled > 0 && usage.Scheduled >= usage.Quota.Scheduled {
+		return ErrQuotaExceeded
+	}
+	return nil
+}
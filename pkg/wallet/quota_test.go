@@ -0,0 +1,71 @@
+package wallet
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestService_SetAccountQuota_success(t *testing.T) {
+	s := newTestService()
+	account, payments, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = s.SetDefaultQuota(Quota{Favorites: 1, Scheduled: 1})
+	if err != nil {
+		t.Errorf("SetDefaultQuota(): error = %v", err)
+		return
+	}
+	_, err = s.FavoritePayment(payments[0].ID, "first")
+	if err != nil {
+		t.Errorf("FavoritePayment(): error = %v", err)
+		return
+	}
+	_, err = s.FavoritePayment(payments[0].ID, "second")
+	if !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("FavoritePayment(): must return ErrQuotaExceeded, returned = %v", err)
+		return
+	}
+
+	err = s.SetAccountQuota(account.ID, Quota{Favorites: 2})
+	if err != nil {
+		t.Errorf("SetAccountQuota(): error = %v", err)
+		return
+	}
+	_, err = s.FavoritePayment(payments[0].ID, "second")
+	if err != nil {
+		t.Errorf("FavoritePayment(): error = %v", err)
+		return
+	}
+	usage, err := s.QuotaUsage(account.ID)
+	if err != nil {
+		t.Errorf("QuotaUsage(): error = %v", err)
+		return
+	}
+	if usage.Favorites != 2 || usage.Quota.Favorites != 2 || usage.Quota.Scheduled != 0 {
+		t.Errorf("QuotaUsage(): wrong usage = %+v", usage)
+		return
+	}
+}
+
+func TestService_SetAccountQuota_fail(t *testing.T) {
+	s := newTestService()
+	account, err := s.addAccountWithBalance("+992928885522", 100_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = s.SetAccountQuota(account.ID, Quota{Scheduled: -1})
+	if !errors.Is(err, ErrInvalidQuota) {
+		t.Errorf("SetAccountQuota(): must return ErrInvalidQuota, returned = %v", err)
+		return
+	}
+	_ = s.SetAccountQuota(account.ID, Quota{Scheduled: 1})
+	_, _ = s.SchedulePayment(account.ID, 1_00, "internet", "@daily")
+	_, err = s.SchedulePayment(account.ID, 1_00, "internet", "@daily")
+	if !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("SchedulePayment(): must return ErrQuotaExceeded, returned = %v", err)
+		return
+	}
+}
@@ -0,0 +1,65 @@
+package wallet
+
+import (
+	"time"
+
+	"github.com/sidalsoft/wallet/pkg/types"
+)
+
+// PaymentQuery отбирает платежи. Пустые поля не ограничивают выборку,
+// From включается в промежуток, To - нет
+type PaymentQuery struct {
+	AccountID int64
+	Category  types.PaymentCategory
+	Status    types.PaymentStatus
+	From      time.Time
+	To        time.Time
+}
+
+func (q PaymentQuery) matches(payment *types.Payment) bool {
+	if q.AccountID != 0 && payment.AccountID != q.AccountID {
+		return false
+	}
+	if q.Category != "" && payment.Category != q.Category {
+		return false
+	}
+	if q.Status != "" && payment.Status != q.Status {
+		return false
+	}
+	if !q.From.IsZero() && payment.Created.Before(q.From) {
+		return false
+	}
+	if !q.To.IsZero() && !payment.Created.Before(q.To) {
+		return false
+	}
+	return true
+}
+
+// RecategorizePayments переносит отобранные платежи в категорию category и возвращает
+// их число. Категория до первого переноса сохраняется в OriginalCategory, балансы
+// и комиссии не меняются. С dryRun платежи только подсчитываются
+func (s *Service) RecategorizePayments(query PaymentQuery, category types.PaymentCategory, dryRun bool) (_ int, err error) {
+	defer s.audit("RecategorizePayments", &err, "accountID", query.AccountID, "fromCategory", query.Category, "category", category, "dryRun", dryRun)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if category == "" {
+		return 0, ErrInvalidCategory
+	}
+
+	count := 0
+	for _, payment := range s.payments {
+		if !query.matches(payment) || payment.Category == category {
+			continue
+		}
+		count++
+		if dryRun {
+			continue
+		}
+		if payment.OriginalCategory == "" {
+			payment.OriginalCategory = payment.Category
+		}
+		payment.Category = category
+		s.markPayment(payment.ID)
+	}
+	return count, nil
+}
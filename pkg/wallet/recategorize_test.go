@@ -0,0 +1,85 @@
+package wallet
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/sidalsoft/wallet/pkg/types"
+)
+
+func TestService_RecategorizePayments_success(t *testing.T) {
+	s := newTestService()
+	account, payments, err := s.addAccount(testAccount{
+		phone:   "+992000000001",
+		balance: 10_000_00,
+		payments: []struct {
+			amount   types.Money
+			category types.PaymentCategory
+		}{
+			{amount: 1_000_00, category: "auto"},
+			{amount: 2_000_00, category: "auto"},
+			{amount: 500_00, category: "food"},
+		},
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	query := PaymentQuery{AccountID: account.ID, Category: "auto"}
+
+	count, err := s.RecategorizePayments(query, "transport", true)
+	if err != nil || count != 2 {
+		t.Errorf("RecategorizePayments(): dry run count = %v, error = %v", count, err)
+		return
+	}
+	payment, err := s.FindPaymentByID(payments[0].ID)
+	if err != nil || payment.Category != "auto" {
+		t.Errorf("RecategorizePayments(): dry run must not change payments, payment = %v, error = %v", payment, err)
+		return
+	}
+
+	count, err = s.RecategorizePayments(query, "transport", false)
+	if err != nil || count != 2 {
+		t.Errorf("RecategorizePayments(): count = %v, error = %v", count, err)
+		return
+	}
+	payment, err = s.FindPaymentByID(payments[1].ID)
+	if err != nil || payment.Category != "transport" || payment.OriginalCategory != "auto" {
+		t.Errorf("RecategorizePayments(): payment = %v, error = %v", payment, err)
+		return
+	}
+	payment, err = s.FindPaymentByID(payments[2].ID)
+	if err != nil || payment.Category != "food" || payment.OriginalCategory != "" {
+		t.Errorf("RecategorizePayments(): payment must not match, payment = %v, error = %v", payment, err)
+		return
+	}
+
+	_, err = s.RecategorizePayments(PaymentQuery{Category: "transport"}, "travel", false)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	payment, err = s.FindPaymentByID(payments[0].ID)
+	if err != nil || payment.OriginalCategory != "auto" {
+		t.Errorf("RecategorizePayments(): original category must be kept, payment = %v, error = %v", payment, err)
+		return
+	}
+	got, err := s.FindAccountByID(account.ID)
+	if err != nil || got.Balance != 6_500_00 {
+		t.Errorf("RecategorizePayments(): balance must not change, account = %v, error = %v", got, err)
+		return
+	}
+	if entries := s.AuditLog(AuditQuery{Op: "RecategorizePayments"}); len(entries) != 3 {
+		t.Errorf("RecategorizePayments(): audit entries = %v", entries)
+		return
+	}
+}
+
+func TestService_RecategorizePayments_fail(t *testing.T) {
+	s := newTestService()
+	_, err := s.RecategorizePayments(PaymentQuery{}, "", false)
+	if !errors.Is(err, ErrInvalidCategory) {
+		t.Errorf("RecategorizePayments(): error = %v, want %v", err, ErrInvalidCategory)
+		return
+	}
+}
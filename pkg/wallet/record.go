@@ -0,0 +1,203 @@
+package wallet
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sidalsoft/wallet/pkg/types"
+)
+
+// MalformedDumpError возвращается Import, если строку дампа не удалось разобрать.
+// Line - номер строки после заголовка версии. errors.Is(err, ErrMalformedDump) истинно
+type MalformedDumpError struct {
+	Dump  string
+	Line  int
+	Field string
+	Value string
+}
+
+func (e *MalformedDumpError) Error() string {
+	return fmt.Sprintf("%s: %s line %d: invalid %s %q", ErrMalformedDump, e.Dump, e.Line, e.Field, e.Value)
+}
+
+func (e *MalformedDumpError) Unwrap() error {
+	return ErrMalformedDump
+}
+
+// parsedDumps - записи дампов, разобранные до применения к сервису
+type parsedDumps struct {
+	accounts   []*types.Account
+	payments   []*types.Payment
+	favorites  []*types.Favorite
+	scheduled  []*types.ScheduledPayment
+	operations []*types.Operation
+	merchants  []*types.Merchant
+}
+
+// dumpRecord разбирает поля одной строки дампа и запоминает первую ошибку
+type dumpRecord struct {
+	dump   string
+	line   int
+	fields []string
+	err    error
+}
+
+func (r *dumpRecord) fail(field string, value string) {
+	if r.err == nil {
+		r.err = &MalformedDumpError{Dump: r.dump, Line: r.line, Field: field, Value: value}
+	}
+}
+
+func (r *dumpRecord) int64(i int, field string) int64 {
+	value, err := strconv.ParseInt(r.fields[i], 10, 64)
+	if err != nil {
+		r.fail(field, r.fields[i])
+	}
+	return value
+}
+
+func (r *dumpRecord) money(i int, field string) types.Money {
+	return types.Money(r.int64(i, field))
+}
+
+func (r *dumpRecord) time(i int, field string) time.Time {
+	return time.Unix(r.int64(i, field), 0).UTC()
+}
+
+func (r *dumpRecord) metadata(i int, field string) map[string]string {
+	metadata, err := types.SplitMetadata(r.fields[i])
+	if err != nil {
+		r.fail(field, r.fields[i])
+	}
+	return metadata
+}
+
+func (r *dumpRecord) tags(i int) []string {
+	if r.fields[i] == "" {
+		return nil
+	}
+	return strings.Split(r.fields[i], ",")
+}
+
+func (r *dumpRecord) id(i int, field string) string {
+	if r.fields[i] == "" {
+		r.fail(field, "")
+	}
+	return r.fields[i]
+}
+
+// parseDumps разбирает дампы текущей версии. Пустые строки пропускаются,
+// на первой ошибочной строке возвращается *MalformedDumpError
+func parseDumps(dumps map[string]string) (*parsedDumps, error) {
+	parsed := &parsedDumps{}
+	for _, name := range dumpNames {
+		for i, line := range strings.Split(dumps[name], "\n") {
+			if line == "" {
+				continue
+			}
+			err := parsed.parseLine(name, i+1, line)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return parsed, nil
+}
+
+// parseLine разбирает строку line дампа name текущей версии и добавляет запись
+func (p *parsedDumps) parseLine(name string, line int, text string) error {
+	r := &dumpRecord{dump: name, line: line, fields: types.SplitFields(text)}
+	if len(r.fields) != len(dumpSchemas[name]) {
+		return &MalformedDumpError{Dump: name, Line: line, Field: "field count", Value: strconv.Itoa(len(r.fields))}
+	}
+	p.add(r)
+	return r.err
+}
+
+// append добавляет записи other
+func (p *parsedDumps) append(other *parsedDumps) {
+	p.accounts = append(p.accounts, other.accounts...)
+	p.payments = append(p.payments, other.payments...)
+	p.favorites = append(p.favorites, other.favorites...)
+	p.scheduled = append(p.scheduled, other.scheduled...)
+	p.operations = append(p.operations, other.operations...)
+	p.merchants = append(p.merchants, other.merchants...)
+}
+
+func (p *parsedDumps) add(r *dumpRecord) {
+	switch r.dump {
+	case "accounts":
+		p.accounts = append(p.accounts, &types.Account{
+			ID:             r.int64(0, "id"),
+			Phone:          types.Phone(r.id(1, "phone")),
+			Balance:        r.money(2, "balance"),
+			Currency:       types.Currency(r.id(3, "currency")),
+			Status:         types.AccountStatus(r.id(4, "status")),
+			CreditLimit:    r.money(5, "credit limit"),
+			OverdrawnSince: r.time(6, "overdrawn since"),
+			Registered:     r.time(7, "registered"),
+			Points:         r.int64(8, "points"),
+			Type:           types.AccountType(r.id(9, "type")),
+			Wallet:         r.fields[10],
+		})
+	case "payments":
+		p.payments = append(p.payments, &types.Payment{
+			ID:               r.id(0, "id"),
+			AccountID:        r.int64(1, "account id"),
+			Amount:           r.money(2, "amount"),
+			Category:         types.PaymentCategory(r.fields[3]),
+			Status:           types.PaymentStatus(r.id(4, "status")),
+			Currency:         types.Currency(r.id(5, "currency")),
+			Fee:              r.money(6, "fee"),
+			Created:          r.time(7, "created"),
+			InferredCategory: types.PaymentCategory(r.fields[8]),
+			OriginalCategory: types.PaymentCategory(r.fields[9]),
+			Comment:          r.fields[10],
+			Metadata:         r.metadata(11, "metadata"),
+			Tags:             r.tags(12),
+			MerchantID:       r.fields[13],
+		})
+	case "favorites":
+		p.favorites = append(p.favorites, &types.Favorite{
+			ID:        r.id(0, "id"),
+			AccountID: r.int64(1, "account id"),
+			Name:      r.fields[2],
+			Amount:    r.money(3, "amount"),
+			Category:  types.PaymentCategory(r.fields[4]),
+			Deleted:   r.time(5, "deleted"),
+			Group:     types.FavoriteGroup(r.fields[6]),
+		})
+	case "scheduled":
+		p.scheduled = append(p.scheduled, &types.ScheduledPayment{
+			ID:        r.id(0, "id"),
+			AccountID: r.int64(1, "account id"),
+			Amount:    r.money(2, "amount"),
+			Category:  types.PaymentCategory(r.fields[3]),
+			Schedule:  types.Schedule(r.id(4, "schedule")),
+			NextRun:   r.time(5, "next run"),
+			Deleted:   r.time(6, "deleted"),
+		})
+	case "operations":
+		payload, err := base64.StdEncoding.DecodeString(r.fields[4])
+		if err != nil {
+			r.fail("payload", r.fields[4])
+		}
+		p.operations = append(p.operations, &types.Operation{
+			ID:        r.id(0, "id"),
+			Kind:      r.id(1, "kind"),
+			AccountID: r.int64(2, "account id"),
+			PaymentID: r.fields[3],
+			Payload:   payload,
+		})
+	case "merchants":
+		p.merchants = append(p.merchants, &types.Merchant{
+			ID:        r.id(0, "id"),
+			Name:      r.fields[1],
+			Category:  types.PaymentCategory(r.fields[2]),
+			AccountID: r.int64(3, "account id"),
+		})
+	}
+}
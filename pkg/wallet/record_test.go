@@ -0,0 +1,91 @@
+package wallet
+
+import (
+	"errors"
+	"io/ioutil"
+	"testing"
+
+	"github.com/sidalsoft/wallet/pkg/types"
+)
+
+func TestService_Import_escaped(t *testing.T) {
+	s := newTestService()
+	account, err := s.addAccountWithBalance("+992000000001", 100_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	payment, err := s.Pay(account.ID, 10_00, "food;drinks|bar\nnight")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	favorite, err := s.FavoritePayment(payment.ID, `bar\cafe`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	dir := t.TempDir()
+	err = s.Export(dir)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	imported := newTestService()
+	err = imported.Import(dir)
+	if err != nil {
+		t.Errorf("Import(): error = %v", err)
+		return
+	}
+	got, err := imported.FindPaymentByID(payment.ID)
+	if err != nil || got.Category != payment.Category {
+		t.Errorf("Import(): payment = %v, error = %v", got, err)
+		return
+	}
+	gotFavorite, err := imported.FindFavoriteByID(favorite.ID)
+	if err != nil || gotFavorite.Name != favorite.Name || gotFavorite.Category != payment.Category {
+		t.Errorf("Import(): favorite = %v, error = %v", gotFavorite, err)
+		return
+	}
+}
+
+func TestService_Import_malformed(t *testing.T) {
+	dir := t.TempDir()
+	data := "#version 4\n1;+992000000001;100;TJS;ACTIVE;0;0;0\n2;+992000000002;lots;TJS;ACTIVE;0;0;0\n"
+	err := ioutil.WriteFile(dir+"/accounts.dump", []byte(data), 0666)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	s := newTestService()
+	err = s.Import(dir)
+	var malformed *MalformedDumpError
+	if !errors.As(err, &malformed) || !errors.Is(err, ErrMalformedDump) {
+		t.Errorf("Import(): error = %v, want *MalformedDumpError", err)
+		return
+	}
+	want := MalformedDumpError{Dump: "accounts", Line: 2, Field: "balance", Value: "lots"}
+	if *malformed != want {
+		t.Errorf("Import(): error = %+v, want %+v", *malformed, want)
+		return
+	}
+	if len(s.accounts) != 0 {
+		t.Errorf("Import(): malformed dump must not be applied, accounts = %v", s.accounts)
+		return
+	}
+}
+
+func TestMigrateDump_v3(t *testing.T) {
+	got, err := migrateDump("favorites", "#version 3\nf1;1;bar\\cafe;100;auto;0\n")
+	if err != nil {
+		t.Errorf("migrateDump(): error = %v", err)
+		return
+	}
+	fields := types.SplitFields(got[:len(got)-1])
+	if len(fields) != 7 || fields[2] != `bar\cafe` || fields[6] != "" {
+		t.Errorf("migrateDump(): got = %q", got)
+		return
+	}
+}
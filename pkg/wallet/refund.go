@@ -0,0 +1,112 @@
+package wallet
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sidalsoft/wallet/pkg/types"
+)
+
+// RefundOperation - вид операции, которой Refund записывает возврат.
+// Payload операции - сумма возврата в минимальных единицах
+const RefundOperation = "refund"
+
+// Refund возвращает на счет часть amount завершенного платежа и записывает возврат
+// операцией вида RefundOperation со ссылкой на платеж. Сумма всех возвратов платежа
+// не может превысить его сумму, комиссия не возвращается. Кешбэк и баллы за платеж
+// списываются в доле возврата от суммы платежа, баллы - не больше остатка на счете.
+// Возврат платежа PayToMerchant списывается со счета получателя. Переводы PayToPhone
+// не возвращаются
+func (s *Service) Refund(paymentID string, amount types.Money) (_ *types.Operation, err error) {
+	defer s.audit("Refund", &err, "paymentID", paymentID, "amount", amount)
+	defer s.observe("Refund", time.Now())
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if amount <= 0 {
+		return nil, ErrAmountMustBePositive
+	}
+	payment, err := s.findPaymentByID(paymentID)
+	if err != nil {
+		return nil, err
+	}
+	if payment.Status != types.PaymentStatusOk {
+		return nil, ErrInvalidPaymentStatus
+	}
+	refunded := types.Money(0)
+	cashback, points := int64(0), int64(0)
+	for _, operation := range s.operations {
+		if operation.PaymentID != payment.ID {
+			continue
+		}
+		switch operation.Kind {
+		case P2POperation:
+			return nil, ErrInvalidPaymentStatus
+		case RefundOperation:
+			refunded += types.Money(payloadInt(operation))
+		case CashbackOperation:
+			cashback += payloadInt(operation)
+		case PointsOperation:
+			points += payloadInt(operation)
+		}
+	}
+	if refunded+amount > payment.Amount {
+		return nil, ErrRefundExceeded
+	}
+	account, err := s.findAccountByID(payment.AccountID)
+	if err != nil {
+		return nil, err
+	}
+	if account.Status == types.AccountStatusClosed {
+		return nil, ErrAccountClosed
+	}
+	if payment.MerchantID != "" {
+		merchant, err := s.findMerchantByID(payment.MerchantID)
+		if err != nil {
+			return nil, err
+		}
+		merchantAccount, err := s.findAccountByID(merchant.AccountID)
+		if err != nil {
+			return nil, err
+		}
+		if !s.canDebit(merchantAccount, amount) {
+			return nil, ErrNotEnoughBalance
+		}
+		s.changeBalance(merchantAccount, -amount)
+	}
+	s.changeBalance(account, amount)
+	// доля считается нарастающим итогом, чтобы полный возврат частями списал все без остатка
+	reversedCashback := refundShare(cashback, refunded+amount, payment.Amount) - refundShare(cashback, refunded, payment.Amount)
+	if reversedCashback > 0 {
+		s.changeBalance(account, -types.Money(reversedCashback))
+	}
+	reversedPoints := refundShare(points, refunded+amount, payment.Amount) - refundShare(points, refunded, payment.Amount)
+	if reversedPoints > account.Points {
+		reversedPoints = account.Points
+	}
+	if reversedPoints > 0 {
+		account.Points -= reversedPoints
+		s.markAccount(account.ID)
+	}
+	operation := &types.Operation{
+		ID:        uuid.New().String(),
+		Kind:      RefundOperation,
+		AccountID: account.ID,
+		PaymentID: payment.ID,
+		Payload:   []byte(strconv.FormatInt(int64(amount), 10)),
+	}
+	s.operations = append(s.operations, operation)
+	s.markOperation(operation.ID)
+	return copyOperation(operation, nil)
+}
+
+// refundShare возвращает долю total, приходящуюся на возвращенные refunded из amount
+func refundShare(total int64, refunded, amount types.Money) int64 {
+	return total * int64(refunded) / int64(amount)
+}
+
+// payloadInt возвращает число из Payload операций возврата, кешбэка и баллов
+func payloadInt(operation *types.Operation) int64 {
+	n, _ := strconv.ParseInt(string(operation.Payload), 10, 64)
+	return n
+}
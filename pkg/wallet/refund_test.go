@@ -0,0 +1,153 @@
+package wallet
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/sidalsoft/wallet/pkg/types"
+)
+
+func TestService_Refund_success(t *testing.T) {
+	s := newTestService()
+	account, payments, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	payment := payments[0]
+	err = s.Confirm(payment.ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	for _, amount := range []types.Money{400_00, 600_00} {
+		refund, err := s.Refund(payment.ID, amount)
+		if err != nil || refund.PaymentID != payment.ID {
+			t.Errorf("Refund(): refund = %v, error = %v", refund, err)
+			return
+		}
+	}
+	got, err := s.FindAccountByID(account.ID)
+	if err != nil || got.Balance != defaultTestAccount.balance {
+		t.Errorf("Refund(): account = %v, error = %v", got, err)
+		return
+	}
+	_, err = s.Refund(payment.ID, 1)
+	if !errors.Is(err, ErrRefundExceeded) {
+		t.Errorf("Refund(): must return ErrRefundExceeded, returned = %v", err)
+		return
+	}
+}
+
+func TestService_Refund_fail(t *testing.T) {
+	s := newTestService()
+	_, payments, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, err = s.Refund(payments[0].ID, 100_00)
+	if !errors.Is(err, ErrInvalidPaymentStatus) {
+		t.Errorf("Refund(): must return ErrInvalidPaymentStatus, returned = %v", err)
+		return
+	}
+	_, err = s.Refund("unknown", 100_00)
+	if !errors.Is(err, ErrPaymentNotFound) {
+		t.Errorf("Refund(): must return ErrPaymentNotFound, returned = %v", err)
+		return
+	}
+}
+
+func TestService_Refund_rewards(t *testing.T) {
+	s := newTestService()
+	account, err := s.addAccountWithBalance("+992928885522", 10_000_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = s.SetCashback("food", Cashback{BasisPoints: 100})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = s.SetPointsRule("food", PointsRule{Points: 1, Per: 10_00})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	payment, err := s.Pay(account.ID, 1_000_00, "food")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = s.Confirm(payment.ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	tests := []struct {
+		refund  types.Money
+		balance types.Money
+		points  int64
+	}{
+		{refund: 300_00, balance: 9_307_00, points: 70},
+		{refund: 700_00, balance: 10_000_00, points: 0},
+	}
+	for _, tt := range tests {
+		_, err = s.Refund(payment.ID, tt.refund)
+		if err != nil {
+			t.Errorf("Refund(): error = %v", err)
+			return
+		}
+		got, _ := s.FindAccountByID(account.ID)
+		if got.Balance != tt.balance || got.Points != tt.points {
+			t.Errorf("Refund(): account = %v, want balance %v and %v points", got, tt.balance, tt.points)
+			return
+		}
+	}
+}
+
+func TestService_Refund_redeemedPoints(t *testing.T) {
+	s := newTestService()
+	account, err := s.addAccountWithBalance("+992928885522", 10_000_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = s.SetPointsRule("food", PointsRule{Points: 1, Per: 10_00})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = s.SetPointValue(1)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	payment, err := s.Pay(account.ID, 1_000_00, "food")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = s.Confirm(payment.ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, err = s.RedeemPoints(account.ID, 80)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, err = s.Refund(payment.ID, 500_00)
+	if err != nil {
+		t.Errorf("Refund(): error = %v", err)
+		return
+	}
+	got, _ := s.FindAccountByID(account.ID)
+	if got.Points != 0 {
+		t.Errorf("Refund(): account = %v, want 0 points", got)
+		return
+	}
+}
@@ -0,0 +1,196 @@
+package wallet
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/sidalsoft/wallet/pkg/types"
+)
+
+// RetentionRule задает срок хранения данных одной категории. Данные старше MaxAge
+// записываются в Archive, если он задан, и удаляются. Нулевой MaxAge отключает правило
+type RetentionRule struct {
+	MaxAge  time.Duration
+	Archive io.Writer
+}
+
+// RetentionPolicy задает правила хранения по категориям данных.
+// Audit - записи журнала аудита в формате JSON Lines,
+// Rejected - отклоненные платежи в формате дампа payments,
+// Deleted - удаленное избранное и отмененные запланированные платежи
+// в форматах их дампов, срок считается от удаления
+type RetentionPolicy struct {
+	Audit    RetentionRule
+	Rejected RetentionRule
+	Deleted  RetentionRule
+}
+
+// RetentionReport сообщает, сколько записей удалено или, при DryRun, было бы удалено
+type RetentionReport struct {
+	DryRun           bool
+	AuditEntries     int
+	RejectedPayments int
+	DeletedItems     int
+}
+
+// ApplyRetention применяет политику хранения на момент now. Платежи без времени
+// создания, импортированные из старых дампов, не удаляются
+func (s *Service) ApplyRetention(policy RetentionPolicy, now time.Time, dryRun bool) (RetentionReport, error) {
+	report := RetentionReport{DryRun: dryRun}
+	if policy.Audit.MaxAge > 0 {
+		count, err := s.retainAudit(policy.Audit, now.Add(-policy.Audit.MaxAge), dryRun)
+		if err != nil {
+			return report, err
+		}
+		report.AuditEntries = count
+	}
+	if policy.Rejected.MaxAge > 0 {
+		count, err := s.retainRejected(policy.Rejected, now.Add(-policy.Rejected.MaxAge), dryRun)
+		if err != nil {
+			return report, err
+		}
+		report.RejectedPayments = count
+	}
+	if policy.Deleted.MaxAge > 0 {
+		count, err := s.retainDeleted(policy.Deleted, now.Add(-policy.Deleted.MaxAge), dryRun)
+		if err != nil {
+			return report, err
+		}
+		report.DeletedItems = count
+	}
+	return report, nil
+}
+
+func (s *Service) retainAudit(rule RetentionRule, before time.Time, dryRun bool) (int, error) {
+	s.auditLog.mu.Lock()
+	defer s.auditLog.mu.Unlock()
+
+	// записи журнала упорядочены по времени
+	count := 0
+	for count < len(s.auditLog.entries) && s.auditLog.entries[count].Time.Before(before) {
+		count++
+	}
+	if dryRun || count == 0 {
+		return count, nil
+	}
+	if rule.Archive != nil {
+		encoder := json.NewEncoder(rule.Archive)
+		for _, entry := range s.auditLog.entries[:count] {
+			err := encoder.Encode(entry)
+			if err != nil {
+				return 0, err
+			}
+		}
+	}
+	s.auditLog.entries = append([]types.AuditEntry(nil), s.auditLog.entries[count:]...)
+	return count, nil
+}
+
+func (s *Service) retainRejected(rule RetentionRule, before time.Time, dryRun bool) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var expired []*types.Payment
+	kept := make([]*types.Payment, 0, len(s.payments))
+	for _, payment := range s.payments {
+		if payment.Status == types.PaymentStatusFail && !payment.Created.IsZero() && payment.Created.Before(before) {
+			expired = append(expired, payment)
+			continue
+		}
+		kept = append(kept, payment)
+	}
+	if dryRun || len(expired) == 0 {
+		return len(expired), nil
+	}
+	if rule.Archive != nil {
+		for _, payment := range expired {
+			_, err := io.WriteString(rule.Archive, payment.ToString()+"\n")
+			if err != nil {
+				return 0, err
+			}
+		}
+	}
+	s.payments = kept
+	s.paymentTimes.reset()
+	s.markFull()
+	return len(expired), nil
+}
+
+func (s *Service) retainDeleted(rule RetentionRule, before time.Time, dryRun bool) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expired := func(deleted time.Time) bool {
+		return !deleted.IsZero() && deleted.Before(before)
+	}
+	var lines []string
+	favorites := make([]*types.Favorite, 0, len(s.favorites))
+	for _, favorite := range s.favorites {
+		if expired(favorite.Deleted) {
+			lines = append(lines, favorite.ToString())
+			continue
+		}
+		favorites = append(favorites, favorite)
+	}
+	scheduled := make([]*types.ScheduledPayment, 0, len(s.scheduled))
+	for _, sp := range s.scheduled {
+		if expired(sp.Deleted) {
+			lines = append(lines, sp.ToString())
+			continue
+		}
+		scheduled = append(scheduled, sp)
+	}
+	if dryRun || len(lines) == 0 {
+		return len(lines), nil
+	}
+	if rule.Archive != nil {
+		for _, line := range lines {
+			_, err := io.WriteString(rule.Archive, line+"\n")
+			if err != nil {
+				return 0, err
+			}
+		}
+	}
+	s.favorites = favorites
+	s.scheduled = scheduled
+	s.markFull()
+	return len(lines), nil
+}
+
+// RetentionJob периодически применяет политику хранения в фоне
+type RetentionJob struct {
+	svc    *Service
+	policy RetentionPolicy
+	loop   *backgroundLoop
+	// OnError, если задан, получает ошибки каждого запуска
+	OnError func(error)
+}
+
+// NewRetentionJob возвращает ErrInvalidInterval, если interval не положителен.
+// Сроки хранения отсчитываются по часам сервиса
+func NewRetentionJob(svc *Service, policy RetentionPolicy, interval time.Duration) (*RetentionJob, error) {
+	j := &RetentionJob{svc: svc, policy: policy}
+	loop, err := newBackgroundLoop(interval, func(time.Time) {
+		_, err := j.svc.ApplyRetention(j.policy, j.svc.clock(), false)
+		if err != nil && j.OnError != nil {
+			j.OnError(err)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	j.loop = loop
+	return j, nil
+}
+
+// Start запускает задание. Повторный вызов ничего не делает
+func (j *RetentionJob) Start() {
+	j.loop.start()
+}
+
+// Stop останавливает запущенное Start задание и ждет завершения текущего запуска.
+// Без Start возвращается сразу
+func (j *RetentionJob) Stop() {
+	j.loop.halt()
+}
@@ -0,0 +1,81 @@
+package wallet
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestService_ApplyRetention_success(t *testing.T) {
+	s := newTestService()
+	now := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+
+	account, err := s.addAccountWithBalance("+992928885522", 100_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	old, err := s.Pay(account.ID, 10_00, "auto")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = s.Reject(old.ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	old, _ = s.FindPaymentByID(old.ID)
+	now = now.AddDate(1, 0, 0)
+	recent, err := s.Pay(account.ID, 10_00, "auto")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = s.Reject(recent.ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	var audit, rejected bytes.Buffer
+	policy := RetentionPolicy{
+		Audit:    RetentionRule{MaxAge: 30 * 24 * time.Hour, Archive: &audit},
+		Rejected: RetentionRule{MaxAge: 30 * 24 * time.Hour, Archive: &rejected},
+	}
+	report, err := s.ApplyRetention(policy, now, true)
+	if err != nil {
+		t.Errorf("ApplyRetention(): error = %v", err)
+		return
+	}
+	if report.AuditEntries != 4 || report.RejectedPayments != 1 || audit.Len() != 0 || len(s.payments) != 2 {
+		t.Errorf("ApplyRetention(): dry run must not change data, report = %+v", report)
+		return
+	}
+
+	report, err = s.ApplyRetention(policy, now, false)
+	if err != nil {
+		t.Errorf("ApplyRetention(): error = %v", err)
+		return
+	}
+	if report.AuditEntries != 4 || report.RejectedPayments != 1 {
+		t.Errorf("ApplyRetention(): wrong report = %+v", report)
+		return
+	}
+	if strings.Count(audit.String(), "\n") != 4 || rejected.String() != old.ToString()+"\n" {
+		t.Errorf("ApplyRetention(): wrong archives, audit = %q, rejected = %q", audit.String(), rejected.String())
+		return
+	}
+	_, err = s.FindPaymentByID(old.ID)
+	if !errors.Is(err, ErrPaymentNotFound) {
+		t.Errorf("ApplyRetention(): old rejected payment must be purged, error = %v", err)
+		return
+	}
+	if entries := s.AuditLog(AuditQuery{}); len(entries) != 2 {
+		t.Errorf("ApplyRetention(): recent audit entries must be kept, entries = %+v", entries)
+		return
+	}
+}
@@ -0,0 +1,75 @@
+package wallet
+
+import (
+	"github.com/sidalsoft/wallet/pkg/rule"
+	"github.com/sidalsoft/wallet/pkg/types"
+)
+
+type feeRule struct {
+	rule *rule.Rule
+	fee  Fee
+}
+
+// AddFeeRule добавляет правило комиссии на языке pkg/rule, например
+// "amount > 1000_00 && category == 'transfer'". Комиссия первого совпавшего правила
+// заменяет комиссию категории, заданную SetFee
+func (s *Service) AddFeeRule(condition string, fee Fee) (err error) {
+	defer s.audit("AddFeeRule", &err, "condition", condition, "flat", fee.Flat, "basisPoints", fee.BasisPoints)
+	err = validateFee(fee)
+	if err != nil {
+		return err
+	}
+	r, err := rule.Compile(condition)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.feeRules = append(s.feeRules, feeRule{rule: r, fee: fee})
+	return nil
+}
+
+// AddBlockRule добавляет правило риска: платежи, для которых оно истинно,
+// отклоняются с ErrPaymentBlocked до списания средств
+func (s *Service) AddBlockRule(condition string) (err error) {
+	defer s.audit("AddBlockRule", &err, "condition", condition)
+	r, err := rule.Compile(condition)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.blockRules = append(s.blockRules, r)
+	return nil
+}
+
+// ResetRules удаляет все правила комиссий и риска
+func (s *Service) ResetRules() (err error) {
+	defer s.audit("ResetRules", &err)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.feeRules = nil
+	s.blockRules = nil
+	return nil
+}
+
+// ruleEnv возвращает переменные правил для платежа со счета до списания
+func ruleEnv(account *types.Account, payment *types.Payment) rule.Env {
+	return rule.Env{
+		Amount:    payment.Amount,
+		Balance:   account.Balance,
+		AccountID: account.ID,
+		Hour:      payment.Created.Hour(),
+		Category:  payment.Category,
+		Currency:  payment.Currency,
+	}
+}
+
+func (s *Service) blocked(env *rule.Env) bool {
+	for _, r := range s.blockRules {
+		if r.Match(env) {
+			return true
+		}
+	}
+	return false
+}
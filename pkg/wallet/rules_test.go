@@ -0,0 +1,107 @@
+package wallet
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/sidalsoft/wallet/pkg/rule"
+)
+
+func TestService_AddFeeRule_success(t *testing.T) {
+	s := newTestService()
+	account, err := s.addAccountWithBalance("+992928885522", 10_000_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = s.SetFee("transfer", Fee{Flat: 1_00})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = s.AddFeeRule("amount > 1000_00 && category == 'transfer'", Fee{BasisPoints: 100})
+	if err != nil {
+		t.Errorf("AddFeeRule(): error = %v", err)
+		return
+	}
+
+	payment, err := s.Pay(account.ID, 2_000_00, "transfer")
+	if err != nil || payment.Fee != 20_00 {
+		t.Errorf("Pay(): rule fee not applied, payment = %v, error = %v", payment, err)
+		return
+	}
+	payment, err = s.Pay(account.ID, 100_00, "transfer")
+	if err != nil || payment.Fee != 1_00 {
+		t.Errorf("Pay(): category fee not applied, payment = %v, error = %v", payment, err)
+		return
+	}
+
+	err = s.ResetRules()
+	if err != nil {
+		t.Errorf("ResetRules(): error = %v", err)
+		return
+	}
+	payment, err = s.Pay(account.ID, 2_000_00, "transfer")
+	if err != nil || payment.Fee != 1_00 {
+		t.Errorf("Pay(): rule fee applied after reset, payment = %v, error = %v", payment, err)
+		return
+	}
+}
+
+func TestService_AddFeeRule_fail(t *testing.T) {
+	s := newTestService()
+	err := s.AddFeeRule("amount > 'big'", Fee{Flat: 1_00})
+	if !errors.As(err, new(*rule.SyntaxError)) {
+		t.Errorf("AddFeeRule(): error = %v, want *rule.SyntaxError", err)
+		return
+	}
+	err = s.AddFeeRule("amount > 0", Fee{Flat: -1})
+	if !errors.Is(err, ErrInvalidFee) {
+		t.Errorf("AddFeeRule(): error = %v, want %v", err, ErrInvalidFee)
+		return
+	}
+}
+
+func TestService_AddBlockRule_success(t *testing.T) {
+	s := newTestService()
+	account, err := s.addAccountWithBalance("+992928885522", 10_000_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = s.AddBlockRule("category == 'casino' || amount * 2 > balance")
+	if err != nil {
+		t.Errorf("AddBlockRule(): error = %v", err)
+		return
+	}
+
+	_, err = s.Pay(account.ID, 100_00, "casino")
+	if !errors.Is(err, ErrPaymentBlocked) {
+		t.Errorf("Pay(): error = %v, want %v", err, ErrPaymentBlocked)
+		return
+	}
+	_, err = s.Pay(account.ID, 6_000_00, "auto")
+	if !errors.Is(err, ErrPaymentBlocked) {
+		t.Errorf("Pay(): error = %v, want %v", err, ErrPaymentBlocked)
+		return
+	}
+	_, err = s.Pay(account.ID, 4_000_00, "auto")
+	if err != nil {
+		t.Errorf("Pay(): error = %v", err)
+		return
+	}
+	account, _ = s.FindAccountByID(account.ID)
+	if account.Balance != 6_000_00 {
+		t.Errorf("Pay(): blocked payments must not change balance, account = %v", account)
+		return
+	}
+}
+
+func TestService_AddBlockRule_fail(t *testing.T) {
+	s := newTestService()
+	err := s.AddBlockRule("amount")
+	if !errors.As(err, new(*rule.SyntaxError)) {
+		t.Errorf("AddBlockRule(): error = %v, want *rule.SyntaxError", err)
+		return
+	}
+}
@@ -0,0 +1,176 @@
+package wallet
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sidalsoft/wallet/pkg/types"
+)
+
+// ScheduleError возвращается RunScheduled, если часть запланированных платежей не прошла
+type ScheduleError struct {
+	Failed map[string]error
+}
+
+func (e *ScheduleError) Error() string {
+	return fmt.Sprintf("%d scheduled payments failed", len(e.Failed))
+}
+
+func nextRun(schedule types.Schedule, from time.Time) (time.Time, error) {
+	switch schedule {
+	case "@hourly":
+		return from.Add(time.Hour), nil
+	case "@daily":
+		return from.AddDate(0, 0, 1), nil
+	case "@weekly":
+		return from.AddDate(0, 0, 7), nil
+	case "@monthly":
+		return from.AddDate(0, 1, 0), nil
+	}
+	if !strings.HasPrefix(string(schedule), "@every ") {
+		return time.Time{}, ErrInvalidSchedule
+	}
+	interval, err := time.ParseDuration(strings.TrimPrefix(string(schedule), "@every "))
+	if err != nil || interval <= 0 {
+		return time.Time{}, ErrInvalidSchedule
+	}
+	return from.Add(interval), nil
+}
+
+func (s *Service) SchedulePayment(accountID int64, amount types.Money, category types.PaymentCategory, schedule types.Schedule) (_ *types.ScheduledPayment, err error) {
+	defer s.audit("SchedulePayment", &err, "accountID", accountID, "amount", amount, "category", category, "schedule", schedule)
+	if amount <= 0 {
+		return nil, ErrAmountMustBePositive
+	}
+	next, err := nextRun(schedule, s.clock())
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.findAccountByID(accountID)
+	if err != nil {
+		return nil, err
+	}
+	err = s.checkScheduledQuota(accountID)
+	if err != nil {
+		return nil, err
+	}
+	scheduled := &types.ScheduledPayment{
+		ID:        uuid.New().String(),
+		AccountID: accountID,
+		Amount:    amount,
+		Category:  category,
+		Schedule:  schedule,
+		NextRun:   next,
+	}
+	s.scheduled = append(s.scheduled, scheduled)
+	s.markScheduled(scheduled.ID)
+	return copyScheduledPayment(scheduled, nil)
+}
+
+func (s *Service) FindScheduledPaymentByID(scheduledID string) (*types.ScheduledPayment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return copyScheduledPayment(s.findScheduledPaymentByID(scheduledID))
+}
+
+func (s *Service) findScheduledPaymentByID(scheduledID string) (*types.ScheduledPayment, error) {
+	for _, sp := range s.scheduled {
+		if sp.ID == scheduledID && sp.Deleted.IsZero() {
+			return sp, nil
+		}
+	}
+	return nil, ErrScheduledPaymentNotFound
+}
+
+// CancelScheduledPayment отменяет платеж. Отмененный платеж можно вернуть
+// через RestoreScheduledPayment, пока его не удалит политика хранения
+func (s *Service) CancelScheduledPayment(scheduledID string) (err error) {
+	defer s.audit("CancelScheduledPayment", &err, "scheduledID", scheduledID)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sp, err := s.findScheduledPaymentByID(scheduledID)
+	if err != nil {
+		return err
+	}
+	sp.Deleted = s.clock()
+	s.markScheduled(sp.ID)
+	return nil
+}
+
+// RunScheduled проводит все платежи, время которых наступило к моменту now.
+// Пропущенные запуски не догоняются: платеж проводится один раз,
+// а следующий запуск назначается от now. Затем исполняются постоянные поручения
+// CreateStandingOrder, их переводы тоже входят в результат.
+func (s *Service) RunScheduled(now time.Time) (_ []*types.Payment, err error) {
+	defer s.audit("RunScheduled", &err, "now", now.Unix())
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var payments []*types.Payment
+	failed := make(map[string]error)
+	for _, sp := range s.scheduled {
+		if !sp.Deleted.IsZero() || sp.NextRun.After(now) {
+			continue
+		}
+		next, err := nextRun(sp.Schedule, now)
+		if err != nil {
+			failed[sp.ID] = err
+			continue
+		}
+		sp.NextRun = next
+		s.markScheduled(sp.ID)
+		payment, err := s.pay(sp.AccountID, sp.Amount, sp.Category)
+		if err != nil {
+			failed[sp.ID] = err
+			continue
+		}
+		payment, _ = copyPayment(payment, nil)
+		payments = append(payments, payment)
+	}
+	payments = append(payments, s.runStandingOrders(now)...)
+	if len(failed) > 0 {
+		return payments, &ScheduleError{Failed: failed}
+	}
+	return payments, nil
+}
+
+// Scheduler периодически вызывает RunScheduled в фоне
+type Scheduler struct {
+	svc  *Service
+	loop *backgroundLoop
+	// OnError, если задан, получает ошибки каждого запуска
+	OnError func(error)
+}
+
+// NewScheduler возвращает ErrInvalidInterval, если interval не положителен. Время
+// запуска берется из часов сервиса, как и расписание платежей
+func NewScheduler(svc *Service, interval time.Duration) (*Scheduler, error) {
+	sc := &Scheduler{svc: svc}
+	loop, err := newBackgroundLoop(interval, func(time.Time) {
+		_, err := sc.svc.RunScheduled(sc.svc.clock())
+		if err != nil && sc.OnError != nil {
+			sc.OnError(err)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	sc.loop = loop
+	return sc, nil
+}
+
+// Start запускает планировщик. Повторный вызов ничего не делает
+func (sc *Scheduler) Start() {
+	sc.loop.start()
+}
+
+// Stop останавливает запущенный Start планировщик и ждет завершения текущего запуска.
+// Без Start возвращается сразу
+func (sc *Scheduler) Stop() {
+	sc.loop.halt()
+}
@@ -0,0 +1,215 @@
+package wallet
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestService_SchedulePayment_fail(t *testing.T) {
+	s := newTestService()
+	account, err := s.addAccountWithBalance("+992928885522", 1_000_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, err = s.SchedulePayment(account.ID, 100_00, "internet", "every day")
+	if !errors.Is(err, ErrInvalidSchedule) {
+		t.Errorf("SchedulePayment(): must return ErrInvalidSchedule, returned = %v", err)
+		return
+	}
+	_, err = s.SchedulePayment(account.ID+1, 100_00, "internet", "@daily")
+	if !errors.Is(err, ErrAccountNotFound) {
+		t.Errorf("SchedulePayment(): must return ErrAccountNotFound, returned = %v", err)
+		return
+	}
+}
+
+func TestService_RunScheduled_success(t *testing.T) {
+	s := newTestService()
+	account, err := s.addAccountWithBalance("+992928885522", 1_000_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	scheduled, err := s.SchedulePayment(account.ID, 100_00, "internet", "@every 1h")
+	if err != nil {
+		t.Errorf("SchedulePayment(): error = %v", err)
+		return
+	}
+
+	payments, err := s.RunScheduled(time.Now())
+	if err != nil || len(payments) != 0 {
+		t.Errorf("RunScheduled(): nothing must be due, payments = %v, error = %v", payments, err)
+		return
+	}
+
+	now := scheduled.NextRun
+	payments, err = s.RunScheduled(now)
+	if err != nil {
+		t.Errorf("RunScheduled(): error = %v", err)
+		return
+	}
+	if len(payments) != 1 || payments[0].Amount != 100_00 {
+		t.Errorf("RunScheduled(): wrong payments = %v", payments)
+		return
+	}
+	account, _ = s.FindAccountByID(account.ID)
+	if account.Balance != 900_00 {
+		t.Errorf("RunScheduled(): balance didn't changed, account = %v", account)
+		return
+	}
+	scheduled, _ = s.FindScheduledPaymentByID(scheduled.ID)
+	if !scheduled.NextRun.Equal(now.Add(time.Hour)) {
+		t.Errorf("RunScheduled(): next run not moved, scheduled = %v", scheduled)
+		return
+	}
+}
+
+func TestService_RunScheduled_fail(t *testing.T) {
+	s := newTestService()
+	account, err := s.addAccountWithBalance("+992928885522", 50_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	scheduled, err := s.SchedulePayment(account.ID, 100_00, "internet", "@daily")
+	if err != nil {
+		t.Errorf("SchedulePayment(): error = %v", err)
+		return
+	}
+	_, err = s.RunScheduled(scheduled.NextRun)
+	var scheduleErr *ScheduleError
+	if !errors.As(err, &scheduleErr) {
+		t.Errorf("RunScheduled(): must return *ScheduleError, returned = %v", err)
+		return
+	}
+	if scheduleErr.Failed[scheduled.ID] != ErrNotEnoughBalance {
+		t.Errorf("RunScheduled(): must fail with ErrNotEnoughBalance, returned = %v", scheduleErr.Failed)
+		return
+	}
+}
+
+func TestService_Export_scheduled(t *testing.T) {
+	s := newTestService()
+	account, err := s.addAccountWithBalance("+992928885522", 1_000_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	scheduled, err := s.SchedulePayment(account.ID, 100_00, "internet", "@monthly")
+	if err != nil {
+		t.Errorf("SchedulePayment(): error = %v", err)
+		return
+	}
+	dir := t.TempDir()
+	err = s.Export(dir)
+	if err != nil {
+		t.Errorf("Export(): error = %v", err)
+		return
+	}
+
+	imported := &Service{}
+	err = imported.Import(dir)
+	if err != nil {
+		t.Errorf("Import(): error = %v", err)
+		return
+	}
+	got, err := imported.FindScheduledPaymentByID(scheduled.ID)
+	if err != nil {
+		t.Errorf("Import(): scheduled payment not imported, error = %v", err)
+		return
+	}
+	if got.Schedule != scheduled.Schedule || got.NextRun.Unix() != scheduled.NextRun.Unix() {
+		t.Errorf("Import(): wrong scheduled payment = %v, want = %v", got, scheduled)
+		return
+	}
+}
+
+func TestService_SchedulePayment_clock(t *testing.T) {
+	s := newTestService()
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.FixedZone("UTC+5", 5*60*60))
+	s.now = func() time.Time { return now }
+	account, err := s.addAccountWithBalance("+992928885522", 1_000_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	scheduled, err := s.SchedulePayment(account.ID, 100_00, "internet", "@daily")
+	if err != nil || !scheduled.NextRun.Equal(now.AddDate(0, 0, 1)) || scheduled.NextRun.Location() != time.UTC {
+		t.Errorf("SchedulePayment(): scheduled = %v, error = %v", scheduled, err)
+		return
+	}
+}
+
+func TestScheduler_lifecycle(t *testing.T) {
+	_, err := NewScheduler(newTestService().Service, 0)
+	if !errors.Is(err, ErrInvalidInterval) {
+		t.Errorf("NewScheduler(): error = %v, want %v", err, ErrInvalidInterval)
+		return
+	}
+	sc, err := NewScheduler(newTestService().Service, time.Hour)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	// Stop без Start не должен зависать
+	sc.Stop()
+
+	sc, err = NewScheduler(newTestService().Service, time.Hour)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	sc.Start()
+	sc.Start()
+	sc.Stop()
+	sc.Stop()
+}
+
+func TestScheduler_clock(t *testing.T) {
+	s := newTestService()
+	now := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	account, err := s.addAccountWithBalance("+992928885522", 1_000_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, err = s.SchedulePayment(account.ID, 100_00, "internet", "@daily")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	// по часам сервиса платеж еще не наступил, хотя по настоящему времени давно пора
+	sc, err := NewScheduler(s.Service, time.Millisecond)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	sc.Start()
+	time.Sleep(20 * time.Millisecond)
+	sc.Stop()
+	got, _ := s.FindAccountByID(account.ID)
+	if got.Balance != 1_000_00 {
+		t.Errorf("Scheduler: paid before the service clock reached the schedule, account = %v", got)
+		return
+	}
+
+	s.now = func() time.Time { return now.AddDate(0, 0, 2) }
+	sc, err = NewScheduler(s.Service, time.Millisecond)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	sc.Start()
+	defer sc.Stop()
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		got, _ = s.FindAccountByID(account.ID)
+		if got.Balance == 900_00 {
+			return
+		}
+	}
+	t.Errorf("Scheduler: scheduled payment not made by the service clock, account = %v", got)
+}
@@ -0,0 +1,195 @@
+package wallet
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/sidalsoft/wallet/pkg/types"
+)
+
+// searchLimit - наибольшее число счетов, которое возвращает SearchAccounts
+const searchLimit = 100
+
+// fuzzySearchDigits - наименьшая длина запроса для поиска с ошибкой. Одна ошибка
+// меняет не больше трех триграмм, с 6 цифр хотя бы одна триграмма запроса уцелеет
+const fuzzySearchDigits = 6
+
+// phoneIndex - индекс триграмм цифр телефонов. Списки не очищаются при смене
+// телефона, поэтому найденные кандидаты всегда сверяются с текущим телефоном счета
+type phoneIndex struct {
+	trigrams map[string][]*types.Account
+}
+
+// add индексирует текущий телефон счета, вызывается при регистрации и смене телефона
+func (i *phoneIndex) add(account *types.Account) {
+	if i.trigrams == nil {
+		i.trigrams = make(map[string][]*types.Account)
+	}
+	for _, trigram := range trigrams(phoneDigits(string(account.Phone))) {
+		accounts := i.trigrams[trigram]
+		if len(accounts) == 0 || accounts[len(accounts)-1] != account {
+			i.trigrams[trigram] = append(accounts, account)
+		}
+	}
+}
+
+func (i *phoneIndex) reset() {
+	i.trigrams = nil
+}
+
+// candidates возвращает счета, у которых с query совпадает не меньше min триграмм
+func (i *phoneIndex) candidates(query string, min int) []*types.Account {
+	counts := make(map[*types.Account]int)
+	for _, trigram := range uniqueStrings(trigrams(query)) {
+		seen := make(map[*types.Account]bool)
+		for _, account := range i.trigrams[trigram] {
+			if !seen[account] {
+				seen[account] = true
+				counts[account]++
+			}
+		}
+	}
+	var accounts []*types.Account
+	for account, count := range counts {
+		if count >= min {
+			accounts = append(accounts, account)
+		}
+	}
+	return accounts
+}
+
+// SearchAccounts ищет счета по части номера телефона. Учитываются только цифры,
+// поэтому "+992 93" и "99293" равнозначны. Сначала идут счета, телефон которых
+// оканчивается на query, затем содержащие query, затем для запросов от 6 цифр -
+// отличающиеся от query одной цифрой (замена, пропуск или лишняя цифра).
+// Внутри группы счета упорядочены по ID, возвращается не больше 100 счетов
+func (s *Service) SearchAccounts(query string) ([]types.Account, error) {
+	digits := phoneDigits(query)
+	if digits == "" {
+		return nil, ErrInvalidSearch
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var candidates []*types.Account
+	if len(digits) < 3 {
+		candidates = s.accounts
+	} else {
+		min := len(uniqueStrings(trigrams(digits)))
+		if len(digits) >= fuzzySearchDigits && min > 3 {
+			min -= 3
+		} else if len(digits) >= fuzzySearchDigits {
+			min = 1
+		}
+		candidates = s.phones.candidates(digits, min)
+	}
+
+	const (
+		suffix = iota
+		partial
+		fuzzy
+		none
+	)
+	type match struct {
+		account *types.Account
+		rank    int
+	}
+	var matches []match
+	for _, account := range candidates {
+		phone := phoneDigits(string(account.Phone))
+		rank := none
+		switch {
+		case strings.HasSuffix(phone, digits):
+			rank = suffix
+		case strings.Contains(phone, digits):
+			rank = partial
+		case len(digits) >= fuzzySearchDigits && containsWithinOneEdit(phone, digits):
+			rank = fuzzy
+		}
+		if rank != none {
+			matches = append(matches, match{account: account, rank: rank})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].rank != matches[j].rank {
+			return matches[i].rank < matches[j].rank
+		}
+		return matches[i].account.ID < matches[j].account.ID
+	})
+	if len(matches) > searchLimit {
+		matches = matches[:searchLimit]
+	}
+	accounts := make([]types.Account, len(matches))
+	for i, m := range matches {
+		accounts[i] = *m.account
+	}
+	return accounts, nil
+}
+
+func phoneDigits(phone string) string {
+	digits := strings.Builder{}
+	for _, c := range phone {
+		if c >= '0' && c <= '9' {
+			digits.WriteRune(c)
+		}
+	}
+	return digits.String()
+}
+
+func trigrams(s string) []string {
+	var result []string
+	for i := 0; i+3 <= len(s); i++ {
+		result = append(result, s[i:i+3])
+	}
+	return result
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool)
+	var result []string
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+// containsWithinOneEdit сообщает, что в text есть подстрока, отличающаяся от pattern
+// не больше чем на одну замену, вставку или удаление (алгоритм Селлерса)
+func containsWithinOneEdit(text string, pattern string) bool {
+	prev := make([]int, len(pattern)+1)
+	cur := make([]int, len(pattern)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	if prev[len(pattern)] <= 1 {
+		return true
+	}
+	for i := 1; i <= len(text); i++ {
+		cur[0] = 0
+		for j := 1; j <= len(pattern); j++ {
+			cost := 1
+			if text[i-1] == pattern[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j-1]+cost, prev[j]+1, cur[j-1]+1)
+		}
+		if cur[len(pattern)] <= 1 {
+			return true
+		}
+		prev, cur = cur, prev
+	}
+	return false
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
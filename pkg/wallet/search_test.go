@@ -0,0 +1,78 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/sidalsoft/wallet/pkg/types"
+)
+
+func TestService_SearchAccounts_success(t *testing.T) {
+	s := newTestService()
+	for _, phone := range []types.Phone{"+992935551234", "+992901234777", "+992935551235", "+992111111111"} {
+		_, err := s.RegisterAccount(phone)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+	}
+	err := s.ChangePhone(4, "+992935559999")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	tests := []struct {
+		query string
+		want  []int64
+	}{
+		{"1234", []int64{1, 2}},
+		{"99", []int64{4, 1, 2, 3}},
+		{"+992 93555 1234", []int64{1, 3}},
+		{"555123", []int64{1, 3}},
+		{"5551239", []int64{1, 3}},
+		{"1111", nil},
+		{"9999", []int64{4}},
+	}
+	for _, tt := range tests {
+		accounts, err := s.SearchAccounts(tt.query)
+		if err != nil {
+			t.Errorf("SearchAccounts(%q): error = %v", tt.query, err)
+			return
+		}
+		var got []int64
+		for _, account := range accounts {
+			got = append(got, account.ID)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("SearchAccounts(%q) = %v, want %v", tt.query, got, tt.want)
+			return
+		}
+	}
+}
+
+func TestService_SearchAccounts_fail(t *testing.T) {
+	s := newTestService()
+	_, err := s.SearchAccounts("+ -")
+	if !errors.Is(err, ErrInvalidSearch) {
+		t.Errorf("SearchAccounts(): error = %v, want %v", err, ErrInvalidSearch)
+		return
+	}
+}
+
+func BenchmarkSearchAccounts(b *testing.B) {
+	s := &Service{}
+	for i := 0; i < 1_000_000; i++ {
+		account := &types.Account{ID: int64(i + 1), Phone: types.Phone(fmt.Sprintf("+99290%07d", i))}
+		s.accounts = append(s.accounts, account)
+		s.phones.add(account)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := s.SearchAccounts("9034567")
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -1,16 +1,21 @@
 package wallet
 
 import (
+	"crypto/ed25519"
 	"errors"
 	"fmt"
 	"github.com/google/uuid"
+	"github.com/sidalsoft/wallet/pkg/rule"
 	"github.com/sidalsoft/wallet/pkg/types"
 	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 )
 
 var (
@@ -21,32 +26,213 @@ var (
 	ErrNotEnoughBalance     = errors.New("not enough balance")
 	ErrPaymentNotFound      = errors.New("payment not found")
 	ErrFavoriteNotFound     = errors.New("favorite not found")
+
+	ErrScheduledPaymentNotFound = errors.New("scheduled payment not found")
+	ErrInvalidSchedule          = errors.New("invalid schedule")
+	ErrInvalidInterval          = errors.New("invalid interval")
+	ErrCurrencyMismatch         = errors.New("currency doesn't match account currency")
+	ErrUnknownCurrency          = errors.New("unknown currency")
+	ErrSelfTransfer             = errors.New("can't pay to own account")
+	ErrRefundExceeded           = errors.New("refund exceeds payment amount")
+	ErrInvalidTag               = errors.New("invalid tag")
+	ErrInvalidMerchant          = errors.New("invalid merchant")
+	ErrMerchantNotFound         = errors.New("merchant not found")
+	ErrInvalidSLO               = errors.New("invalid slo")
+	ErrSLONotFound              = errors.New("slo not found")
+	ErrAccountFrozen            = errors.New("account frozen")
+	ErrAccountClosed            = errors.New("account closed")
+	ErrBalanceNotEmpty          = errors.New("balance not empty")
+	ErrInvalidPaymentStatus     = errors.New("invalid payment status")
+	ErrInvalidPage              = errors.New("invalid page")
+	ErrInvalidOperation         = errors.New("invalid operation kind")
+	ErrOperationRegistered      = errors.New("operation already registered")
+	ErrUnknownOperation         = errors.New("unknown operation")
+	ErrOperationNotFound        = errors.New("operation not found")
+	ErrInvalidFee               = errors.New("invalid fee")
+	ErrInvalidInterestRate      = errors.New("invalid interest rate")
+	ErrInvalidCashback          = errors.New("invalid cashback")
+	ErrInvalidPoints            = errors.New("invalid points")
+	ErrNotEnoughPoints          = errors.New("not enough points")
+	ErrPointsDisabled           = errors.New("points redemption disabled")
+	ErrInvalidAccountType       = errors.New("invalid account type")
+	ErrAccountTypeRestricted    = errors.New("operation not allowed for account type")
+	ErrInvalidWallet            = errors.New("invalid wallet name")
+	ErrWalletRegistered         = errors.New("wallet already registered")
+	ErrNotSameOwner             = errors.New("wallets belong to different owners")
+	ErrInvalidBudget            = errors.New("invalid budget")
+	ErrBudgetExceeded           = errors.New("budget exceeded")
+	ErrHoldNotFound             = errors.New("hold not found")
+	ErrHoldNotActive            = errors.New("hold already captured or released")
+	ErrHoldExceeded             = errors.New("capture exceeds held amount")
+	ErrInvalidStandingOrder     = errors.New("invalid standing order")
+	ErrStandingOrderNotFound    = errors.New("standing order not found")
+	ErrInvalidQuota             = errors.New("invalid quota")
+	ErrQuotaExceeded            = errors.New("quota exceeded")
+	ErrInvalidLimit             = errors.New("invalid limit")
+	ErrLimitExceeded            = errors.New("limit exceeded")
+	ErrInvalidCreditLimit       = errors.New("invalid credit limit")
+	ErrInvalidCorrection        = errors.New("invalid correction")
+	ErrCorrectionNotFound       = errors.New("correction not found")
+	ErrCorrectionReviewed       = errors.New("correction already reviewed")
+	ErrSameReviewer             = errors.New("correction must be reviewed by another person")
+	ErrCategoryNotInferred      = errors.New("category not inferred")
+	ErrInvalidPeriod            = errors.New("invalid period")
+	ErrDumpEncrypted            = errors.New("dump is encrypted")
+	ErrDumpNotEncrypted         = errors.New("dump is not encrypted")
+	ErrDumpDecrypt              = errors.New("dump can't be decrypted")
+	ErrCorruptedDump            = errors.New("corrupted dump")
+	ErrBalanceUnknown           = errors.New("balance at this time is unknown")
+	ErrNoSigningKey             = errors.New("signing key not set")
+	ErrInvalidSignature         = errors.New("invalid signature")
+	ErrUnsupportedDumpVersion   = errors.New("unsupported dump version")
+	ErrWALDisabled              = errors.New("write-ahead log disabled")
+	ErrInvalidCategory          = errors.New("invalid category")
+	ErrInvalidMetadata          = errors.New("invalid metadata")
+	ErrImportConflict           = errors.New("import conflicts with existing records")
+	ErrMalformedDump            = errors.New("malformed dump")
+	ErrMalformedProto           = errors.New("malformed protobuf state")
+	ErrPaymentBlocked           = errors.New("payment blocked by rule")
+	ErrUnknownMessage           = errors.New("unknown message kind")
+	ErrTemplateVariable         = errors.New("invalid template variable")
+	ErrTemplateNotFound         = errors.New("template not found")
+	ErrBackupNotFound           = errors.New("backup not found")
+	ErrInvalidSearch            = errors.New("invalid search query")
+	ErrPossibleDuplicate        = errors.New("possible duplicate payment")
+	ErrBatchCommitted           = errors.New("batch already committed")
 )
 
 type Service struct {
+	mu            sync.RWMutex
 	nextAccountID int64
 	accounts      []*types.Account
+	phones        phoneIndex
+	paymentTimes  paymentTimeIndex
 	payments      []*types.Payment
 	favorites     []*types.Favorite
+	scheduled     []*types.ScheduledPayment
+	corrections   []*types.Correction
+	operations    []*types.Operation
+	merchants     []*types.Merchant
+	handlers      map[string]OperationHandler
+	processors    map[types.PaymentCategory]PaymentProcessor
+	categorizer   Categorizer
+	eventHandlers []func(Event)
+	batchEvents   []Event
+	hooks         AccountHooks
+	fees          map[types.PaymentCategory]Fee
+	cashback      map[types.PaymentCategory]Cashback
+	pointsRules   map[types.PaymentCategory]PointsRule
+	pointValue    types.Money
+	feeRules      []feeRule
+	blockRules    []*rule.Rule
+	rounding      RoundingMode
+	dedupWindow   time.Duration
+	feeAccountID  int64
+	interestRates map[types.AccountType]int64
+	defaultQuota  Quota
+	quotas        map[int64]Quota
+	limits        map[int64]map[LimitPeriod]types.Money
+	budgets       map[int64]map[types.PaymentCategory]Budget
+	budgetMode    BudgetMode
+	paymentTTL    time.Duration
+	heldFunds     holdIndex
+	now           func() time.Time
+	slo           sloTracker
+	auditLog      auditLog
+	logger        Logger
+	dumpKey       []byte
+	signingKey    ed25519.PrivateKey
+	balances      map[int64][]balanceEntry
+	balanceLimit  int
+	dirty         dirtySet
+	wal           *writeAheadLog
+	autoSave      *autoSave
 }
 
-func (s *Service) RegisterAccount(phone types.Phone) (*types.Account, error) {
-	for _, account := range s.accounts {
-		if account.Phone == phone {
-			return nil, ErrPhoneRegistered
+func (s *Service) RegisterAccount(phone types.Phone) (_ *types.Account, err error) {
+	defer s.audit("RegisterAccount", &err, "phone", phone)
+	defer s.observe("RegisterAccount", time.Now())
+	account, after, err := s.registerAccountHooked(phone, types.DefaultCurrency)
+	if err != nil {
+		return nil, err
+	}
+	after()
+	return copyAccount(account, nil)
+}
+
+func (s *Service) RegisterAccountWithCurrency(phone types.Phone, currency types.Currency) (_ *types.Account, err error) {
+	defer s.audit("RegisterAccountWithCurrency", &err, "phone", phone, "currency", currency)
+	defer s.observe("RegisterAccount", time.Now())
+	if !currency.Known() {
+		return nil, ErrUnknownCurrency
+	}
+	account, after, err := s.registerAccountHooked(phone, currency)
+	if err != nil {
+		return nil, err
+	}
+	after()
+	return copyAccount(account, nil)
+}
+
+func (s *Service) registerAccountHooked(phone types.Phone, currency types.Currency) (*types.Account, func(), error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	hooks := s.hooks
+	if hooks.BeforeRegister != nil {
+		err := hooks.BeforeRegister(phone)
+		if err != nil {
+			return nil, nil, &HookError{Op: "RegisterAccount", Err: err}
 		}
 	}
+	account, err := s.registerAccount(phone, currency)
+	if err != nil {
+		return nil, nil, err
+	}
+	snapshot := *account
+	return account, func() {
+		if hooks.AfterRegister != nil {
+			hooks.AfterRegister(snapshot)
+		}
+	}, nil
+}
+
+func (s *Service) registerAccount(phone types.Phone, currency types.Currency) (*types.Account, error) {
+	if _, err := s.findAccountByPhone(phone); err == nil {
+		return nil, ErrPhoneRegistered
+	}
+	return s.newAccount(phone, currency, ""), nil
+}
+
+// newAccount добавляет счет, для основного кошелька телефона wallet пуст
+func (s *Service) newAccount(phone types.Phone, currency types.Currency, wallet string) *types.Account {
 	s.nextAccountID++
 	account := &types.Account{
-		ID:      s.nextAccountID,
-		Phone:   phone,
-		Balance: 0,
+		ID:         s.nextAccountID,
+		Phone:      phone,
+		Balance:    0,
+		Currency:   currency,
+		Status:     types.AccountStatusActive,
+		Registered: s.clock(),
+		Type:       types.AccountTypePersonal,
+		Wallet:     wallet,
 	}
 	s.accounts = append(s.accounts, account)
-	return account, nil
+	s.phones.add(account)
+	s.markAccount(account.ID)
+	s.recordBalance(account)
+	s.emit(EventAccountRegistered, account, nil, 0)
+	return account
+}
+
+func (s *Service) Deposit(accountID int64, amount types.Money) (err error) {
+	defer s.audit("Deposit", &err, "accountID", accountID, "amount", amount)
+	defer s.observe("Deposit", time.Now())
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.deposit(accountID, amount)
 }
 
-func (s *Service) Deposit(accountID int64, amount types.Money) error {
+func (s *Service) deposit(accountID int64, amount types.Money) error {
 	if amount <= 0 {
 		return ErrAmountMustBePositive
 	}
@@ -61,11 +247,112 @@ func (s *Service) Deposit(accountID int64, amount types.Money) error {
 	if account == nil {
 		return ErrAccountNotFound
 	}
-	account.Balance += amount
+	return s.depositTo(account, amount)
+}
+
+func (s *Service) depositTo(account *types.Account, amount types.Money) error {
+	if account.Status == types.AccountStatusClosed {
+		return ErrAccountClosed
+	}
+	s.changeBalance(account, amount)
+	return nil
+}
+
+func (s *Service) Pay(accountID int64, amount types.Money, category types.PaymentCategory) (_ *types.Payment, err error) {
+	defer s.audit("Pay", &err, "accountID", accountID, "amount", amount, "category", category)
+	defer s.observe("Pay", time.Now())
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err = s.checkDuplicate(accountID, amount, category)
+	if err != nil {
+		return nil, err
+	}
+	return copyPayment(s.pay(accountID, amount, category))
+}
+
+// PayOption задает дополнительные данные платежа PayWithOptions
+type PayOption func(payment *types.Payment)
+
+// WithComment добавляет платежу комментарий
+func WithComment(comment string) PayOption {
+	return func(payment *types.Payment) {
+		payment.Comment = comment
+	}
+}
+
+// WithMetadata добавляет платежу пары ключ-значение, например номер заказа
+func WithMetadata(metadata map[string]string) PayOption {
+	return func(payment *types.Payment) {
+		if payment.Metadata == nil {
+			payment.Metadata = make(map[string]string, len(metadata))
+		}
+		for key, value := range metadata {
+			payment.Metadata[key] = value
+		}
+	}
+}
+
+// reservedMetadata - ключи метаданных, которые записывает только сервис
+var reservedMetadata = map[string]bool{
+	CorrectionMetadata: true,
+	InterestMetadata:   true,
+}
+
+// checkMetadata возвращает ErrInvalidMetadata для пустых и зарезервированных ключей
+func checkMetadata(metadata map[string]string) error {
+	for key := range metadata {
+		if key == "" || reservedMetadata[key] {
+			return ErrInvalidMetadata
+		}
+	}
 	return nil
 }
 
-func (s *Service) Pay(accountID int64, amount types.Money, category types.PaymentCategory) (*types.Payment, error) {
+// PayWithOptions проводит платеж как Pay и сохраняет в нем данные options.
+// Метаданные с ключами, которые записывает сам сервис, отклоняются с ErrInvalidMetadata
+func (s *Service) PayWithOptions(accountID int64, amount types.Money, category types.PaymentCategory, options ...PayOption) (_ *types.Payment, err error) {
+	defer s.audit("PayWithOptions", &err, "accountID", accountID, "amount", amount, "category", category)
+	defer s.observe("Pay", time.Now())
+	draft := &types.Payment{}
+	for _, option := range options {
+		option(draft)
+	}
+	err = checkMetadata(draft.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err = s.checkDuplicate(accountID, amount, category)
+	if err != nil {
+		return nil, err
+	}
+	return copyPayment(s.pay(accountID, amount, category, options...))
+}
+
+func (s *Service) PayInCurrency(accountID int64, amount types.Money, currency types.Currency, category types.PaymentCategory) (_ *types.Payment, err error) {
+	defer s.audit("PayInCurrency", &err, "accountID", accountID, "amount", amount, "currency", currency, "category", category)
+	defer s.observe("Pay", time.Now())
+	if !currency.Known() {
+		return nil, ErrUnknownCurrency
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	account, err := s.findAccountByID(accountID)
+	if err != nil {
+		return nil, err
+	}
+	if account.Currency != currency {
+		return nil, ErrCurrencyMismatch
+	}
+	err = s.checkDuplicate(accountID, amount, category)
+	if err != nil {
+		return nil, err
+	}
+	return copyPayment(s.pay(accountID, amount, category))
+}
+
+func (s *Service) pay(accountID int64, amount types.Money, category types.PaymentCategory, options ...PayOption) (*types.Payment, error) {
 	if amount <= 0 {
 		return nil, ErrAmountMustBePositive
 	}
@@ -79,55 +366,147 @@ func (s *Service) Pay(accountID int64, amount types.Money, category types.Paymen
 	if account == nil {
 		return nil, ErrAccountNotFound
 	}
-	if account.Balance < amount {
-		return nil, ErrNotEnoughBalance
+	if account.Status == types.AccountStatusFrozen {
+		return nil, ErrAccountFrozen
+	}
+	if account.Status == types.AccountStatusClosed {
+		return nil, ErrAccountClosed
 	}
-	account.Balance -= amount
 	paymentID := uuid.New().String()
 	payment := &types.Payment{
 		ID:        paymentID,
 		AccountID: accountID,
 		Amount:    amount,
+		Currency:  account.Currency,
 		Category:  category,
 		Status:    types.PaymentStatusInProgress,
+		Created:   s.clock(),
 	}
+	for _, option := range options {
+		option(payment)
+	}
+	s.categorize(payment)
+	env := ruleEnv(account, payment)
+	if s.blocked(&env) {
+		return nil, ErrPaymentBlocked
+	}
+	err := s.process(payment)
+	if err != nil {
+		return nil, err
+	}
+	env = ruleEnv(account, payment)
+	payment.Fee = s.fee(&env)
+	if !s.canDebit(account, payment.Amount+payment.Fee) {
+		return nil, ErrNotEnoughBalance
+	}
+	err = s.checkLimits(accountID, payment.Amount, payment.Created)
+	if err != nil {
+		return nil, err
+	}
+	overBudget, err := s.checkBudgets(payment)
+	if err != nil {
+		return nil, err
+	}
+	s.changeBalance(account, -(payment.Amount + payment.Fee))
+	s.creditFee(payment.Fee)
 	s.payments = append(s.payments, payment)
+	s.paymentTimes.add(payment)
+	s.markPayment(payment.ID)
+	s.emit(EventPaymentCreated, account, payment, 0)
+	if overBudget {
+		s.emit(EventBudgetExceeded, account, payment, 0)
+	}
 	return payment, nil
 }
 
-func (s *Service) Reject(paymentID string) error {
-	payment, err := s.FindPaymentByID(paymentID)
+func (s *Service) Reject(paymentID string) (err error) {
+	defer s.audit("Reject", &err, "paymentID", paymentID)
+	defer s.observe("Reject", time.Now())
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	payment, err := s.findPaymentByID(paymentID)
 	if err != nil {
 		return err
 	}
-	account, err := s.FindAccountByID(payment.AccountID)
+	if payment.Status != types.PaymentStatusInProgress {
+		return ErrInvalidPaymentStatus
+	}
+	return s.reject(payment)
+}
+
+// reject отклоняет платеж в статусе INPROGRESS и возвращает плательщику сумму с комиссией
+func (s *Service) reject(payment *types.Payment) error {
+	account, err := s.findAccountByID(payment.AccountID)
 	if err != nil {
 		return err
 	}
 	payment.Status = types.PaymentStatusFail
-	account.Balance += payment.Amount
+	s.markPayment(payment.ID)
+	s.changeBalance(account, payment.Amount+payment.Fee)
+	s.creditFee(-payment.Fee)
+	s.emit(EventPaymentRejected, account, payment, 0)
 	return nil
 }
 
-func (s *Service) Repeat(paymentID string) (*types.Payment, error) {
-	p, err := s.FindPaymentByID(paymentID)
+func (s *Service) Confirm(paymentID string) (err error) {
+	defer s.audit("Confirm", &err, "paymentID", paymentID)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	payment, err := s.findPaymentByID(paymentID)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	if payment.Status != types.PaymentStatusInProgress {
+		return ErrInvalidPaymentStatus
+	}
+	account, err := s.findAccountByID(payment.AccountID)
+	if err != nil {
+		return err
 	}
-	pp, err := s.Pay(p.AccountID, p.Amount, p.Category)
+	payment.Status = types.PaymentStatusOk
+	s.markPayment(payment.ID)
+	s.creditCashback(account, payment)
+	s.earnPoints(account, payment)
+	s.emit(EventPaymentConfirmed, account, payment, 0)
+	return nil
+}
+
+func (s *Service) Repeat(paymentID string) (_ *types.Payment, err error) {
+	defer s.audit("Repeat", &err, "paymentID", paymentID)
+	defer s.observe("Repeat", time.Now())
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, err := s.findPaymentByID(paymentID)
 	if err != nil {
 		return nil, err
 	}
-	return pp, nil
+	return copyPayment(s.pay(p.AccountID, p.Amount, p.Category))
 }
 
-func (s *Service) FavoritePayment(paymentID string, name string) (*types.Favorite, error) {
-	for _, favorite := range s.favorites {
-		if favorite.Name == name {
-			return nil, ErrFavoriteRegistered
-		}
+func (s *Service) FavoritePayment(paymentID string, name string) (_ *types.Favorite, err error) {
+	defer s.audit("FavoritePayment", &err, "paymentID", paymentID, "name", name)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return copyFavorite(s.favoritePayment(paymentID, name))
+}
+
+func (s *Service) favoritePayment(paymentID string, name string) (*types.Favorite, error) {
+	payment, err := s.findPaymentByID(paymentID)
+	if err != nil {
+		return nil, err
 	}
-	payment, err := s.FindPaymentByID(paymentID)
+	account, err := s.findAccountByID(payment.AccountID)
+	if err != nil {
+		return nil, err
+	}
+	err = checkAccountType(account, "FavoritePayment")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.findFavoriteByName(payment.AccountID, name); err == nil {
+		return nil, ErrFavoriteRegistered
+	}
+	err = s.checkFavoritesQuota(payment.AccountID)
 	if err != nil {
 		return nil, err
 	}
@@ -139,18 +518,210 @@ func (s *Service) FavoritePayment(paymentID string, name string) (*types.Favorit
 		Category:  payment.Category,
 	}
 	s.favorites = append(s.favorites, favorite)
+	s.markFavorite(favorite.ID)
 	return favorite, nil
 }
 
-func (s *Service) PayFromFavorite(favoriteID string) (*types.Payment, error) {
-	fw, err := s.FindFavoriteByID(favoriteID)
+// EditFavorite меняет название, сумму и категорию избранного. Платежи, уже
+// совершенные из избранного, не меняются
+func (s *Service) EditFavorite(favoriteID string, name string, amount types.Money, category types.PaymentCategory) (_ *types.Favorite, err error) {
+	defer s.audit("EditFavorite", &err, "favoriteID", favoriteID, "name", name, "amount", amount, "category", category)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if amount <= 0 {
+		return nil, ErrAmountMustBePositive
+	}
+	if category == "" {
+		return nil, ErrInvalidCategory
+	}
+	favorite, err := s.findActiveFavoriteByID(favoriteID)
+	if err != nil {
+		return nil, err
+	}
+	if f, err := s.findFavoriteByName(favorite.AccountID, name); err == nil && f != favorite {
+		return nil, ErrFavoriteRegistered
+	}
+	favorite.Name = name
+	favorite.Amount = amount
+	favorite.Category = category
+	s.markFavorite(favorite.ID)
+	return copyFavorite(favorite, nil)
+}
+
+func (s *Service) PayFromFavorite(favoriteID string) (_ *types.Payment, err error) {
+	defer s.audit("PayFromFavorite", &err, "favoriteID", favoriteID)
+	defer s.observe("PayFromFavorite", time.Now())
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fw, err := s.findActiveFavoriteByID(favoriteID)
+	if err != nil {
+		return nil, err
+	}
+	return copyPayment(s.payFromFavorite(fw, fw.Amount))
+}
+
+// PayFromFavoriteAmount проводит платеж по счету и категории избранного
+// на сумму amount вместо сохраненной в избранном
+func (s *Service) PayFromFavoriteAmount(favoriteID string, amount types.Money) (_ *types.Payment, err error) {
+	defer s.audit("PayFromFavoriteAmount", &err, "favoriteID", favoriteID, "amount", amount)
+	defer s.observe("PayFromFavorite", time.Now())
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fw, err := s.findActiveFavoriteByID(favoriteID)
+	if err != nil {
+		return nil, err
+	}
+	return copyPayment(s.payFromFavorite(fw, amount))
+}
+
+func (s *Service) payFromFavorite(fw *types.Favorite, amount types.Money) (*types.Payment, error) {
+	account, err := s.findAccountByID(fw.AccountID)
+	if err != nil {
+		return nil, err
+	}
+	err = checkAccountType(account, "PayFromFavorite")
+	if err != nil {
+		return nil, err
+	}
+	err = s.checkDuplicate(fw.AccountID, amount, fw.Category)
+	if err != nil {
+		return nil, err
+	}
+	return s.pay(fw.AccountID, amount, fw.Category)
+}
+
+func (s *Service) FreezeAccount(accountID int64) (err error) {
+	defer s.audit("FreezeAccount", &err, "accountID", accountID)
+	return s.setAccountStatus(accountID, types.AccountStatusFrozen)
+}
+
+func (s *Service) UnfreezeAccount(accountID int64) (err error) {
+	defer s.audit("UnfreezeAccount", &err, "accountID", accountID)
+	return s.setAccountStatus(accountID, types.AccountStatusActive)
+}
+
+func (s *Service) setAccountStatus(accountID int64, status types.AccountStatus) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	account, err := s.findAccountByID(accountID)
+	if err != nil {
+		return err
+	}
+	if account.Status == types.AccountStatusClosed {
+		return ErrAccountClosed
+	}
+	account.Status = status
+	s.markAccount(account.ID)
+	return nil
+}
+
+func (s *Service) CloseAccount(accountID int64) (err error) {
+	defer s.audit("CloseAccount", &err, "accountID", accountID)
+	after, err := s.closeAccountHooked(accountID)
+	if err != nil {
+		return err
+	}
+	after()
+	return nil
+}
+
+func (s *Service) closeAccountHooked(accountID int64) (func(), error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	account, err := s.findAccountByID(accountID)
 	if err != nil {
 		return nil, err
 	}
-	return s.Pay(fw.AccountID, fw.Amount, fw.Category)
+	if account.Status == types.AccountStatusClosed {
+		return nil, ErrAccountClosed
+	}
+	if account.Balance != 0 {
+		return nil, ErrBalanceNotEmpty
+	}
+	hooks := s.hooks
+	if hooks.BeforeClose != nil {
+		err = hooks.BeforeClose(*account)
+		if err != nil {
+			return nil, &HookError{Op: "CloseAccount", Err: err}
+		}
+	}
+	account.Status = types.AccountStatusClosed
+	s.markAccount(account.ID)
+	snapshot := *account
+	return func() {
+		if hooks.AfterClose != nil {
+			hooks.AfterClose(snapshot)
+		}
+	}, nil
+}
+
+func (s *Service) ChangePhone(accountID int64, phone types.Phone) (err error) {
+	defer s.audit("ChangePhone", &err, "accountID", accountID, "phone", phone)
+	after, err := s.changePhoneHooked(accountID, phone)
+	if err != nil {
+		return err
+	}
+	after()
+	return nil
+}
+
+func (s *Service) changePhoneHooked(accountID int64, phone types.Phone) (func(), error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	account, err := s.findAccountByID(accountID)
+	if err != nil {
+		return nil, err
+	}
+	if account.Status == types.AccountStatusClosed {
+		return nil, ErrAccountClosed
+	}
+	return s.changePhoneWithHooks(account, phone)
+}
+
+// changePhoneWithHooks меняет телефон с хуком BeforeChangePhone и возвращает вызов
+// AfterChangePhone, который выполняется после снятия блокировки
+func (s *Service) changePhoneWithHooks(account *types.Account, phone types.Phone) (func(), error) {
+	hooks := s.hooks
+	if hooks.BeforeChangePhone != nil {
+		err := hooks.BeforeChangePhone(*account, phone)
+		if err != nil {
+			return nil, &HookError{Op: "ChangePhone", Err: err}
+		}
+	}
+	oldPhone := account.Phone
+	err := s.changePhone(account, phone)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := *account
+	return func() {
+		if hooks.AfterChangePhone != nil {
+			hooks.AfterChangePhone(snapshot, oldPhone)
+		}
+	}, nil
+}
+
+// changePhone меняет телефон владельца account, вместе с ним меняется телефон
+// всех открытых кошельков владельца
+func (s *Service) changePhone(account *types.Account, phone types.Phone) error {
+	if acc, err := s.findAccountByPhone(phone); err == nil && acc.Phone != account.Phone {
+		return ErrPhoneRegistered
+	}
+	for _, wallet := range s.ownerWallets(account.Phone) {
+		wallet.Phone = phone
+		s.phones.add(wallet)
+		s.markAccount(wallet.ID)
+	}
+	return nil
 }
 
 func (s *Service) FindAccountByID(accountID int64) (*types.Account, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return copyAccount(s.findAccountByID(accountID))
+}
+
+func (s *Service) findAccountByID(accountID int64) (*types.Account, error) {
 	for _, acc := range s.accounts {
 		if acc.ID == accountID {
 			return acc, nil
@@ -159,7 +730,28 @@ func (s *Service) FindAccountByID(accountID int64) (*types.Account, error) {
 	return nil, ErrAccountNotFound
 }
 
+func (s *Service) FindAccountByPhone(phone types.Phone) (*types.Account, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return copyAccount(s.findAccountByPhone(phone))
+}
+
+func (s *Service) findAccountByPhone(phone types.Phone) (*types.Account, error) {
+	for _, acc := range s.accounts {
+		if acc.Phone == phone && acc.Wallet == "" && acc.Status != types.AccountStatusClosed {
+			return acc, nil
+		}
+	}
+	return nil, ErrAccountNotFound
+}
+
 func (s *Service) FindPaymentByID(paymentID string) (*types.Payment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return copyPayment(s.findPaymentByID(paymentID))
+}
+
+func (s *Service) findPaymentByID(paymentID string) (*types.Payment, error) {
 	for _, py := range s.payments {
 		if py.ID == paymentID {
 			return py, nil
@@ -169,6 +761,29 @@ func (s *Service) FindPaymentByID(paymentID string) (*types.Payment, error) {
 }
 
 func (s *Service) FindFavoriteByID(favoriteID string) (*types.Favorite, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return copyFavorite(s.findActiveFavoriteByID(favoriteID))
+}
+
+// FindFavoriteByName возвращает действующее избранное счета с названием name.
+// Названия избранного уникальны в пределах счета
+func (s *Service) FindFavoriteByName(accountID int64, name string) (*types.Favorite, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return copyFavorite(s.findFavoriteByName(accountID, name))
+}
+
+func (s *Service) findFavoriteByName(accountID int64, name string) (*types.Favorite, error) {
+	for _, favorite := range s.favorites {
+		if favorite.AccountID == accountID && favorite.Name == name && favorite.Deleted.IsZero() {
+			return favorite, nil
+		}
+	}
+	return nil, ErrFavoriteNotFound
+}
+
+func (s *Service) findFavoriteByID(favoriteID string) (*types.Favorite, error) {
 	for _, py := range s.favorites {
 		if py.ID == favoriteID {
 			return py, nil
@@ -177,39 +792,47 @@ func (s *Service) FindFavoriteByID(favoriteID string) (*types.Favorite, error) {
 	return nil, ErrFavoriteNotFound
 }
 
-func (s *Service) ExportToFile(path string) error {
-	file, err := os.Create(path)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-	for _, account := range s.accounts {
-		_, err = file.Write([]byte(account.ToString() + "|"))
-		if err != nil {
-			return err
-		}
+func (s *Service) ExportToFile(path string, options ...ExportOption) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	data := strings.Builder{}
+	for _, account := range s.records(newExportConfig(options).order).accounts {
+		data.WriteString(account.ToString() + "|")
 	}
-	return nil
+	return writeFileAtomic(path, data.String())
 }
 
-func (s *Service) ImportFromFile(path string) error {
+func (s *Service) ImportFromFile(path string) (err error) {
+	defer s.audit("ImportFromFile", &err, "path", path)
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	file, err := os.Open(path)
 	if err != nil {
 		return err
 	}
-	str, _ := io.ReadAll(file)
-	arr := strings.Split(string(str), "|")
-	for _, ac := range arr {
-		accountStr := strings.Split(ac, ";")
-		if len(accountStr) < 2 {
+	defer file.Close()
+	str, err := io.ReadAll(file)
+	if err != nil {
+		return err
+	}
+	for i, ac := range types.SplitRecords(string(str), '|') {
+		accountStr := types.SplitFields(ac)
+		if len(accountStr) < 3 {
 			continue
 		}
-		account, err := s.RegisterAccount(types.Phone(accountStr[1]))
+		m, err := strconv.ParseInt(accountStr[2], 10, 64)
+		if err != nil {
+			return &MalformedDumpError{Dump: filepath.Base(path), Line: i + 1, Field: "balance", Value: accountStr[2]}
+		}
+		currency := types.DefaultCurrency
+		if len(accountStr) > 3 {
+			currency = types.Currency(accountStr[3])
+		}
+		account, err := s.registerAccount(types.Phone(accountStr[1]), currency)
 		if err != nil {
 			return err
 		}
-		m, _ := strconv.Atoi(accountStr[2])
-		err = s.Deposit(account.ID, types.Money(m))
+		err = s.deposit(account.ID, types.Money(m))
 		if err != nil {
 			return err
 		}
@@ -217,153 +840,308 @@ func (s *Service) ImportFromFile(path string) error {
 	return nil
 }
 
-func (s *Service) Export(dir string) error {
-	save := func(data string, name string) error {
-		_ = os.Mkdir(dir, 0777)
-		f, err := os.Create(dir + "/" + name + ".dump")
-		if err != nil {
-			return err
-		}
-		defer f.Close()
+// ExportError возвращается Export, если часть дампов не удалось записать.
+// Остальные дампы при этом записаны
+type ExportError struct {
+	Failed map[string]error
+}
+
+func (e *ExportError) Error() string {
+	names := make([]string, 0, len(e.Failed))
+	for name := range e.Failed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	messages := make([]string, len(names))
+	for i, name := range names {
+		messages[i] = name + ": " + e.Failed[name].Error()
+	}
+	return "export failed: " + strings.Join(messages, "; ")
+}
+
+// writeFileAtomic записывает данные во временный файл рядом с path и переименовывает его,
+// чтобы при сбое на диске остался либо старый, либо новый файл целиком
+func writeFileAtomic(path string, data string) error {
+	dir, name := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+	f, err := ioutil.TempFile(dir, "."+name+".tmp*")
+	if err != nil {
+		return err
+	}
+	// TempFile создает файл с правами 0600, а дамп должен остаться доступным на чтение,
+	// как после ioutil.WriteFile, или сохранить права заменяемого файла
+	mode := os.FileMode(0644)
+	if info, statErr := os.Stat(path); statErr == nil {
+		mode = info.Mode().Perm()
+	}
+	err = f.Chmod(mode)
+	if err == nil {
 		_, err = f.WriteString(data)
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+		return err
+	}
+	// синхронизация каталога закрепляет переименование, но поддерживается не везде
+	if d, err := os.Open(dir); err == nil {
+		_ = d.Sync()
+		_ = d.Close()
+	}
+	return nil
+}
+
+func (s *Service) Export(dir string, options ...ExportOption) error {
+	defer s.observe("Export", time.Now())
+	return s.export(dir, false, newExportConfig(options))
+}
+
+func (s *Service) export(dir string, compressed bool, config exportConfig) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	s.dirty.mu.Lock()
+	defer s.dirty.mu.Unlock()
+	return s.exportLocked(dir, compressed, config)
+}
+
+// exportLocked записывает все дампы, вызывается под блокировкой сервиса и s.dirty.mu
+func (s *Service) exportLocked(dir string, compressed bool, config exportConfig) error {
+	err := os.MkdirAll(dir, 0777)
+	if err != nil {
+		return err
+	}
+	exportErr := &ExportError{Failed: make(map[string]error)}
+	manifest := make(map[string]string)
+	save := func(data string, name string) {
+		file, sum, err := s.writeDump(dir, name, data, compressed)
 		if err != nil {
-			return err
+			exportErr.Failed[name] = err
+			return
 		}
-		return nil
+		manifest[file] = sum
 	}
+	records := s.records(config.order)
 
-	if len(s.accounts) > 0 {
+	if len(records.accounts) > 0 {
 		data := strings.Builder{}
-		for _, account := range s.accounts {
+		for _, account := range records.accounts {
 			data.WriteString(account.ToString() + "\n")
 		}
-		err := save(data.String(), "accounts")
-		if err != nil {
-			return err
-		}
+		save(data.String(), "accounts")
 	}
 
-	if len(s.favorites) > 0 {
+	if len(records.favorites) > 0 {
 		data := strings.Builder{}
-		for _, favorite := range s.favorites {
+		for _, favorite := range records.favorites {
 			data.WriteString(favorite.ToString() + "\n")
 		}
-		err := save(data.String(), "favorites")
-		if err != nil {
-			return err
-		}
+		save(data.String(), "favorites")
 	}
 
-	if len(s.payments) > 0 {
+	if len(records.payments) > 0 {
 		data := strings.Builder{}
-		for _, payment := range s.payments {
+		for _, payment := range records.payments {
 			data.WriteString(payment.ToString() + "\n")
 		}
-		err := save(data.String(), "payments")
-		if err != nil {
-			return err
+		save(data.String(), "payments")
+	}
+
+	if len(records.scheduled) > 0 {
+		data := strings.Builder{}
+		for _, scheduled := range records.scheduled {
+			data.WriteString(scheduled.ToString() + "\n")
 		}
+		save(data.String(), "scheduled")
 	}
+
+	if len(records.operations) > 0 {
+		data := strings.Builder{}
+		for _, operation := range records.operations {
+			data.WriteString(operationToString(operation) + "\n")
+		}
+		save(data.String(), "operations")
+	}
+
+	if len(records.merchants) > 0 {
+		data := strings.Builder{}
+		for _, merchant := range records.merchants {
+			data.WriteString(merchant.ToString() + "\n")
+		}
+		save(data.String(), "merchants")
+	}
+	// дампы переписаны целиком, состояния хешей для дописывания устарели
+	s.dirty.hashes = nil
+	err = removeStaleDumps(dir, manifest, exportErr.Failed)
+	if err != nil {
+		exportErr.Failed["stale"] = err
+	}
+	err = writeManifest(dir, manifest, config.order)
+	if err != nil {
+		exportErr.Failed[manifestName] = err
+	}
+	if len(exportErr.Failed) > 0 {
+		return exportErr
+	}
+	s.dirty.reset(dir, compressed)
 	return nil
 }
 
-func (s *Service) Import(dir string) error {
-	read := func(name string) string {
-		data, err := ioutil.ReadFile(dir + "/" + name + ".dump")
+// Import загружает дампы каталога dir. Записи с ID, которые уже есть в сервисе,
+// обрабатываются по стратегии WithMergeStrategy, по умолчанию MergeOverwrite
+func (s *Service) Import(dir string, options ...ImportOption) (err error) {
+	config := importConfig{}
+	for _, option := range options {
+		option(&config)
+	}
+	defer s.audit("Import", &err, "dir", dir, "merge", config.merge)
+	defer s.observe("Import", time.Now())
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err = verifyManifest(dir)
+	if err != nil {
+		return err
+	}
+	dumps := make(map[string]string)
+	for _, name := range dumpNames {
+		dumps[name], err = s.readDump(dir, name)
+		if err != nil {
+			return err
+		}
+	}
+	parsed, err := parseDumps(dumps)
+	if err != nil {
+		return err
+	}
+	if config.merge == MergeFailOnConflict {
+		err = s.checkImportConflicts(parsed, s.newImportIndex())
 		if err != nil {
-			return ""
+			return err
 		}
-		return string(data)
 	}
+	s.markFull()
+	s.importDumps(parsed, config.merge)
+	return nil
+}
+
+// importDumps применяет разобранные дампы. Записи с уже известным ID заменяются,
+// поэтому из нескольких строк одной записи действует последняя.
+// С MergeSkipExisting записи, которые уже есть в сервисе, не меняются
+func (s *Service) importDumps(parsed *parsedDumps, merge MergeStrategy) {
+	s.mergeDumps(parsed, merge, s.newImportIndex())
+	s.finishImport()
+}
 
-	data := read("accounts")
-	arr := strings.Split(data, "\n")
-	for _, ac := range arr {
-		accountStr := strings.Split(ac, ";")
-		if len(accountStr) < 2 {
+// mergeDumps применяет записи дампов, находя существующие по index и добавляя в него
+// новые. Индексы платежей по времени и удержаний обновляет finishImport
+func (s *Service) mergeDumps(parsed *parsedDumps, merge MergeStrategy, index *importIndex) {
+	skip := merge == MergeSkipExisting
+
+	for _, account := range parsed.accounts {
+		i, ok := index.accounts[account.ID]
+		if ok && skip {
 			continue
 		}
-		ID, _ := strconv.Atoi(accountStr[0])
-		Phone := types.Phone(accountStr[1])
-		Balance, _ := strconv.Atoi(accountStr[2])
-		fw, err := s.FindAccountByID(int64(ID))
-		if err != nil {
-			fw = &types.Account{
-				ID:      int64(ID),
-				Phone:   Phone,
-				Balance: types.Money(Balance),
+		if ok {
+			*s.accounts[i] = *account
+			account = s.accounts[i]
+		} else {
+			index.accounts[account.ID] = len(s.accounts)
+			s.accounts = append(s.accounts, account)
+			if account.ID > s.nextAccountID {
+				s.nextAccountID = account.ID
 			}
-			s.accounts = append(s.accounts, fw)
-			s.nextAccountID = int64(ID)
 		}
-		fw.Phone = Phone
-		fw.Balance = types.Money(Balance)
+		s.phones.add(account)
+		s.recordBalance(account)
 	}
 
-	data = read("payments")
-	payments := strings.Split(data, "\n")
-	for _, ac := range payments {
-		paymentStr := strings.Split(ac, ";")
-		if len(paymentStr) < 2 {
+	for _, payment := range parsed.payments {
+		i, ok := index.payments[payment.ID]
+		if ok && skip {
 			continue
 		}
-		ID := paymentStr[0]
-		AccountID, _ := strconv.Atoi(paymentStr[1])
-		Amount, _ := strconv.Atoi(paymentStr[2])
-		Category := paymentStr[3]
-		Status := paymentStr[4]
-		py, err := s.FindPaymentByID(ID)
-		if err == nil {
-			py.AccountID = int64(AccountID)
-			py.Amount = types.Money(Amount)
-			py.Category = types.PaymentCategory(Category)
-			py.Status = types.PaymentStatus(Status)
+		if ok {
+			*s.payments[i] = *payment
 			continue
 		}
-		s.payments = append(s.payments, &types.Payment{
-			ID:        ID,
-			AccountID: int64(AccountID),
-			Amount:    types.Money(Amount),
-			Category:  types.PaymentCategory(Category),
-			Status:    types.PaymentStatus(Status),
-		})
+		index.payments[payment.ID] = len(s.payments)
+		s.payments = append(s.payments, payment)
 	}
 
-	data = read("favorites")
-	favorites := strings.Split(data, "\n")
-	for _, ac := range favorites {
-		favoriteStr := strings.Split(ac, ";")
-		if len(favoriteStr) < 2 {
+	for _, favorite := range parsed.favorites {
+		i, ok := index.favorites[favorite.ID]
+		if ok && skip {
 			continue
 		}
-		ID := favoriteStr[0]
-		AccountID, _ := strconv.Atoi(favoriteStr[1])
-		Name := favoriteStr[2]
-		Amount, _ := strconv.Atoi(favoriteStr[3])
-		Category := favoriteStr[4]
-		fw, err := s.FindFavoriteByID(ID)
-		if err == nil {
-			fw.AccountID = int64(AccountID)
-			fw.Amount = types.Money(Amount)
-			fw.Name = Name
-			fw.Category = types.PaymentCategory(Category)
+		if ok {
+			*s.favorites[i] = *favorite
 			continue
 		}
-		favorite := &types.Favorite{
-			ID:        uuid.New().String(),
-			AccountID: int64(AccountID),
-			Amount:    types.Money(Amount),
-			Name:      Name,
-			Category:  types.PaymentCategory(Category),
-		}
+		index.favorites[favorite.ID] = len(s.favorites)
 		s.favorites = append(s.favorites, favorite)
 	}
-	return nil
+
+	for _, sp := range parsed.scheduled {
+		i, ok := index.scheduled[sp.ID]
+		if ok && skip {
+			continue
+		}
+		if ok {
+			s.scheduled[i] = sp
+			continue
+		}
+		index.scheduled[sp.ID] = len(s.scheduled)
+		s.scheduled = append(s.scheduled, sp)
+	}
+
+	for _, operation := range parsed.operations {
+		i, ok := index.operations[operation.ID]
+		if ok && skip {
+			continue
+		}
+		if ok {
+			s.operations[i] = operation
+			continue
+		}
+		index.operations[operation.ID] = len(s.operations)
+		s.operations = append(s.operations, operation)
+	}
+
+	for _, merchant := range parsed.merchants {
+		i, ok := index.merchants[merchant.ID]
+		if ok && skip {
+			continue
+		}
+		if ok {
+			*s.merchants[i] = *merchant
+			continue
+		}
+		index.merchants[merchant.ID] = len(s.merchants)
+		s.merchants = append(s.merchants, merchant)
+	}
+}
+
+// finishImport перестраивает индексы, которые зависят от всех импортированных записей
+func (s *Service) finishImport() {
+	s.paymentTimes.reset()
+	s.rebuildHolds()
 }
 
 func (s *Service) ExportAccountHistory(accountID int64) ([]types.Payment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 
-	account, err := s.FindAccountByID(accountID)
+	account, err := s.findAccountByID(accountID)
 
 	if err != nil {
 		return nil, err
@@ -372,14 +1150,7 @@ func (s *Service) ExportAccountHistory(accountID int64) ([]types.Payment, error)
 	var payments []types.Payment
 	for _, v := range s.payments {
 		if v.AccountID == account.ID {
-			data := types.Payment{
-				ID:        v.ID,
-				AccountID: v.AccountID,
-				Amount:    v.Amount,
-				Category:  v.Category,
-				Status:    v.Status,
-			}
-			payments = append(payments, data)
+			payments = append(payments, *v)
 		}
 	}
 	return payments, nil
@@ -425,6 +1196,8 @@ func (s *Service) HistoryToFiles(payments []types.Payment, dir string, records i
 }
 
 func (s *Service) SumPayments(goroutines int) types.Money {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 	mu := sync.Mutex{}
 	sum := types.Money(0)
 	m1 := len(s.payments) % goroutines
@@ -441,7 +1214,7 @@ func (s *Service) SumPayments(goroutines int) types.Money {
 
 			payments := s.payments[sss : sss+m]
 			for _, p := range payments {
-				if p == nil {
+				if p == nil || !isSpending(p) {
 					continue
 				}
 				val += p.Amount
@@ -452,41 +1225,58 @@ func (s *Service) SumPayments(goroutines int) types.Money {
 		}(sss)
 		ss += m
 	}
-	go func() {
-		if m1 > 0 {
-			for _, p := range s.payments[ss : ss+m1] {
-				sum += p.Amount
-			}
-		}
-	}()
 	wg.Wait()
+	for _, p := range s.payments[ss : ss+m1] {
+		if isSpending(p) {
+			sum += p.Amount
+		}
+	}
 	return sum
 }
 
+// progressChunkSize количество платежей, суммируемых одной горутиной SumPaymentsWithProgress
+var progressChunkSize = 100_000
+
+// SumPaymentsWithProgress суммирует платежи частями по progressChunkSize и отправляет
+// сумму каждой части в канал, закрывая его в конце. Без платежей отправляется одна
+// нулевая Progress
 func (s *Service) SumPaymentsWithProgress() <-chan types.Progress {
-	gorotunCountSize := 100_0000
+	s.mu.RLock()
+	payments := s.payments
+	s.mu.RUnlock()
 
 	wg := sync.WaitGroup{}
-	goroutines := len(s.payments) / gorotunCountSize
-	if goroutines <= 1 {
-		goroutines = 1
+	ch := make(chan types.Progress)
 
+	if len(payments) == 0 {
+		go func() {
+			defer close(ch)
+			ch <- types.Progress{Result: 0}
+		}()
+		return ch
 	}
-	ch := make(chan types.Progress)
 
-	for i := 0; i < goroutines; i++ {
+	for from := 0; from < len(payments); from += progressChunkSize {
+		to := from + progressChunkSize
+		if to > len(payments) {
+			to = len(payments)
+		}
 		wg.Add(1)
 		go func(ch chan<- types.Progress, payments []*types.Payment) {
-			var sum types.Money = 0
 			defer wg.Done()
+			var sum types.Money = 0
+			s.mu.RLock()
 			for _, pay := range payments {
-				sum += pay.Amount
+				if isSpending(pay) {
+					sum += pay.Amount
+				}
 			}
+			s.mu.RUnlock()
 			ch <- types.Progress{
 				Part:   len(payments),
 				Result: sum,
 			}
-		}(ch, s.payments)
+		}(ch, payments[from:to])
 	}
 
 	go func() {
@@ -496,3 +1286,124 @@ func (s *Service) SumPaymentsWithProgress() <-chan types.Progress {
 
 	return ch
 }
+
+func (s *Service) FilterPayments(accountID int64, goroutines int) ([]types.Payment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, err := s.findAccountByID(accountID)
+	if err != nil {
+		return nil, err
+	}
+	return s.filterPayments(func(payment types.Payment) bool {
+		return payment.AccountID == accountID
+	}, goroutines), nil
+}
+
+func (s *Service) FilterPaymentsByFn(filter func(payment types.Payment) bool, goroutines int) ([]types.Payment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.filterPayments(filter, goroutines), nil
+}
+
+func (s *Service) filterPayments(filter func(payment types.Payment) bool, goroutines int) []types.Payment {
+	if goroutines < 1 {
+		goroutines = 1
+	}
+
+	parts := make([][]types.Payment, goroutines)
+	m := len(s.payments) / goroutines
+	wg := sync.WaitGroup{}
+	for i := 0; i < goroutines; i++ {
+		from := i * m
+		to := from + m
+		if i == goroutines-1 {
+			to = len(s.payments)
+		}
+		wg.Add(1)
+		go func(i int, payments []*types.Payment) {
+			defer wg.Done()
+			for _, p := range payments {
+				if filter(*p) {
+					parts[i] = append(parts[i], *p)
+				}
+			}
+		}(i, s.payments[from:to])
+	}
+	wg.Wait()
+
+	var payments []types.Payment
+	for _, part := range parts {
+		payments = append(payments, part...)
+	}
+	return payments
+}
+
+// SumPaymentsByCategory суммирует платежи категории category и ее подкатегорий,
+// разделяя платежи между goroutines горутинами
+func (s *Service) SumPaymentsByCategory(category types.PaymentCategory, goroutines int) types.Money {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.aggregatePayments(func(payment types.Payment) bool {
+		return payment.Category.Within(category)
+	}, goroutines).sum
+}
+
+// SumPaymentsByStatus суммирует платежи со статусом status
+func (s *Service) SumPaymentsByStatus(status types.PaymentStatus, goroutines int) types.Money {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.aggregatePayments(func(payment types.Payment) bool {
+		return payment.Status == status
+	}, goroutines).sum
+}
+
+// CountPayments считает платежи, для которых filter возвращает true.
+// filter вызывается одновременно из нескольких горутин
+func (s *Service) CountPayments(filter func(payment types.Payment) bool, goroutines int) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.aggregatePayments(filter, goroutines).count
+}
+
+type paymentAggregate struct {
+	sum   types.Money
+	count int
+}
+
+// aggregatePayments считает сумму и число платежей, прошедших filter, так же как
+// filterPayments делит платежи на goroutines частей. Исправительные транзакции не учитываются
+func (s *Service) aggregatePayments(filter func(payment types.Payment) bool, goroutines int) paymentAggregate {
+	if goroutines < 1 {
+		goroutines = 1
+	}
+
+	parts := make([]paymentAggregate, goroutines)
+	m := len(s.payments) / goroutines
+	wg := sync.WaitGroup{}
+	for i := 0; i < goroutines; i++ {
+		from := i * m
+		to := from + m
+		if i == goroutines-1 {
+			to = len(s.payments)
+		}
+		wg.Add(1)
+		go func(i int, payments []*types.Payment) {
+			defer wg.Done()
+			for _, p := range payments {
+				if isSpending(p) && filter(*p) {
+					parts[i].sum += p.Amount
+					parts[i].count++
+				}
+			}
+		}(i, s.payments[from:to])
+	}
+	wg.Wait()
+
+	result := paymentAggregate{}
+	for _, part := range parts {
+		result.sum += part.sum
+		result.count += part.count
+	}
+	return result
+}
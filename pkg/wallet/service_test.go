@@ -1,9 +1,12 @@
 package wallet
 
 import (
+	"errors"
 	"fmt"
 	"github.com/google/uuid"
 	"github.com/sidalsoft/wallet/pkg/types"
+	"io/ioutil"
+	"os"
 	"reflect"
 	"testing"
 )
@@ -25,7 +28,7 @@ func (s *testService) addAccountWithBalance(phone types.Phone, balance types.Mon
 	if err != nil {
 		return nil, fmt.Errorf("can't deposit account, error = %v", err)
 	}
-	return account, nil
+	return s.FindAccountByID(account.ID)
 }
 
 type testAccount struct {
@@ -53,6 +56,10 @@ func (s *testService) addAccount(data testAccount) (*types.Account, []*types.Pay
 			return nil, nil, fmt.Errorf("can't make payment, error = %v", err)
 		}
 	}
+	account, err = s.FindAccountByID(account.ID)
+	if err != nil {
+		return nil, nil, err
+	}
 	return account, payments, nil
 }
 
@@ -138,6 +145,66 @@ func TestService_Reject_success(t *testing.T) {
 
 }
 
+func TestService_Reject_fail(t *testing.T) {
+	s := newTestService()
+	_, payments, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	payment := payments[0]
+	_ = s.Reject(payment.ID)
+	err = s.Reject(payment.ID)
+	if !errors.Is(err, ErrInvalidPaymentStatus) {
+		t.Errorf("Reject(): must return ErrInvalidPaymentStatus, returned = %v", err)
+		return
+	}
+}
+
+func TestService_Confirm_success(t *testing.T) {
+	s := newTestService()
+	account, payments, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	payment := payments[0]
+	err = s.Confirm(payment.ID)
+	if err != nil {
+		t.Errorf("Confirm(): error = %v", err)
+		return
+	}
+	payment, _ = s.FindPaymentByID(payment.ID)
+	if payment.Status != types.PaymentStatusOk {
+		t.Errorf("Confirm(): status didn't changed, payment = %v", payment)
+		return
+	}
+	err = s.Reject(payment.ID)
+	if !errors.Is(err, ErrInvalidPaymentStatus) {
+		t.Errorf("Reject(): must return ErrInvalidPaymentStatus, returned = %v", err)
+		return
+	}
+	if account.Balance != defaultTestAccount.balance-payment.Amount {
+		t.Errorf("Reject(): balance changed, account = %v", account)
+		return
+	}
+}
+
+func TestService_Confirm_fail(t *testing.T) {
+	s := newTestService()
+	_, payments, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_ = s.Reject(payments[0].ID)
+	err = s.Confirm(payments[0].ID)
+	if !errors.Is(err, ErrInvalidPaymentStatus) {
+		t.Errorf("Confirm(): must return ErrInvalidPaymentStatus, returned = %v", err)
+		return
+	}
+}
+
 func TestService_Repeat_success(t *testing.T) {
 	srv := &Service{
 		accounts: make([]*types.Account, 0),
@@ -150,6 +217,7 @@ func TestService_Repeat_success(t *testing.T) {
 
 	p, _ := srv.Repeat(pp.ID)
 	p.ID = pp.ID
+	p.Created = pp.Created
 	if !reflect.DeepEqual(p, pp) {
 		t.Errorf("Repeat(): expected %v returned = %v", pp, p)
 	}
@@ -241,6 +309,35 @@ func TestService_PayFromFavorite_fail(t *testing.T) {
 	}
 }
 
+func TestService_PayFromFavoriteAmount_success(t *testing.T) {
+	s := newTestService()
+	_, payments, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	favorite, err := s.FavoritePayment(payments[0].ID, "car")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	payment, err := s.PayFromFavoriteAmount(favorite.ID, 250_00)
+	if err != nil || payment.Amount != 250_00 || payment.Category != favorite.Category {
+		t.Errorf("PayFromFavoriteAmount(): payment = %v, error = %v", payment, err)
+		return
+	}
+	favorite, err = s.FindFavoriteByID(favorite.ID)
+	if err != nil || favorite.Amount != payments[0].Amount {
+		t.Errorf("PayFromFavoriteAmount(): favorite must not change, favorite = %v, error = %v", favorite, err)
+		return
+	}
+	_, err = s.PayFromFavoriteAmount(favorite.ID, 0)
+	if !errors.Is(err, ErrAmountMustBePositive) {
+		t.Errorf("PayFromFavoriteAmount(): must return ErrAmountMustBePositive, returned = %v", err)
+		return
+	}
+}
+
 func TestService_FindFavoriteByID_success(t *testing.T) {
 	srv := &Service{
 		accounts:  make([]*types.Account, 0),
@@ -273,6 +370,119 @@ func TestService_FindFavoriteByID_fail(t *testing.T) {
 	}
 }
 
+func TestService_FindFavoriteByName_success(t *testing.T) {
+	s := newTestService()
+	var favorites []*types.Favorite
+	for _, phone := range []types.Phone{"+992900000001", "+992900000002"} {
+		account, err := s.addAccountWithBalance(phone, 1_000_00)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		payment, err := s.Pay(account.ID, 100_00, "internet")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		favorite, err := s.FavoritePayment(payment.ID, "Internet")
+		if err != nil {
+			t.Errorf("FavoritePayment(): names must be unique per account, error = %v", err)
+			return
+		}
+		favorites = append(favorites, favorite)
+	}
+	for _, want := range favorites {
+		got, err := s.FindFavoriteByName(want.AccountID, "Internet")
+		if err != nil || got.ID != want.ID {
+			t.Errorf("FindFavoriteByName(): favorite = %v, error = %v, want %v", got, err, want)
+			return
+		}
+	}
+}
+
+func TestService_FindFavoriteByName_fail(t *testing.T) {
+	s := newTestService()
+	account, payments, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, err = s.FavoritePayment(payments[0].ID, "Internet")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, err = s.FavoritePayment(payments[0].ID, "Internet")
+	if !errors.Is(err, ErrFavoriteRegistered) {
+		t.Errorf("FavoritePayment(): must return ErrFavoriteRegistered, returned = %v", err)
+		return
+	}
+	_, err = s.FindFavoriteByName(account.ID, "Phone")
+	if !errors.Is(err, ErrFavoriteNotFound) {
+		t.Errorf("FindFavoriteByName(): must return ErrFavoriteNotFound, returned = %v", err)
+		return
+	}
+}
+
+func TestService_EditFavorite_success(t *testing.T) {
+	s := newTestService()
+	_, payments, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	favorite, err := s.FavoritePayment(payments[0].ID, "car")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	edited, err := s.EditFavorite(favorite.ID, "fuel", 50_00, "fuel")
+	if err != nil || edited.Name != "fuel" || edited.Amount != 50_00 || edited.Category != "fuel" {
+		t.Errorf("EditFavorite(): favorite = %v, error = %v", edited, err)
+		return
+	}
+	payment, err := s.PayFromFavorite(favorite.ID)
+	if err != nil || payment.Amount != 50_00 || payment.Category != "fuel" {
+		t.Errorf("PayFromFavorite(): payment = %v, error = %v", payment, err)
+		return
+	}
+}
+
+func TestService_EditFavorite_fail(t *testing.T) {
+	s := newTestService()
+	_, payments, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	favorite, err := s.FavoritePayment(payments[0].ID, "car")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, err = s.FavoritePayment(payments[0].ID, "home")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, err = s.EditFavorite(favorite.ID, "home", 50_00, "auto")
+	if !errors.Is(err, ErrFavoriteRegistered) {
+		t.Errorf("EditFavorite(): must return ErrFavoriteRegistered, returned = %v", err)
+		return
+	}
+	_, err = s.EditFavorite(favorite.ID, "car", 0, "auto")
+	if !errors.Is(err, ErrAmountMustBePositive) {
+		t.Errorf("EditFavorite(): must return ErrAmountMustBePositive, returned = %v", err)
+		return
+	}
+	_ = s.DeleteFavorite(favorite.ID)
+	_, err = s.EditFavorite(favorite.ID, "car", 50_00, "auto")
+	if !errors.Is(err, ErrFavoriteNotFound) {
+		t.Errorf("EditFavorite(): must return ErrFavoriteNotFound, returned = %v", err)
+		return
+	}
+}
+
 func TestService_ExportToFile(t *testing.T) {
 	srv := &Service{
 		accounts:  make([]*types.Account, 0),
@@ -321,6 +531,261 @@ func TestService_Export(t *testing.T) {
 	println(err)
 }
 
+func TestService_PayWithOptions_reservedMetadata(t *testing.T) {
+	s := newTestService()
+	account, err := s.addAccountWithBalance("+992928885522", 1_000_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, err = s.PayWithOptions(account.ID, 100_00, "shop", WithMetadata(map[string]string{CorrectionMetadata: "forged"}))
+	if !errors.Is(err, ErrInvalidMetadata) {
+		t.Errorf("PayWithOptions(): error = %v, want %v", err, ErrInvalidMetadata)
+		return
+	}
+}
+
+func TestService_PayWithOptions_success(t *testing.T) {
+	s := newTestService()
+	account, err := s.addAccountWithBalance("+992928885522", 1_000_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	metadata := map[string]string{"order": "A-1;2|3", "note": "a=b&c"}
+	payment, err := s.PayWithOptions(account.ID, 100_00, "shop", WithComment("заказ\n№1; срочно"), WithMetadata(metadata))
+	if err != nil || payment.Comment != "заказ\n№1; срочно" || !reflect.DeepEqual(payment.Metadata, metadata) {
+		t.Errorf("PayWithOptions(): payment = %v, error = %v", payment, err)
+		return
+	}
+	payment.Metadata["order"] = "changed"
+
+	dir := t.TempDir()
+	err = s.Export(dir)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	imported := newTestService()
+	err = imported.Import(dir)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	got, err := imported.FindPaymentByID(payment.ID)
+	if err != nil || got.Comment != "заказ\n№1; срочно" || !reflect.DeepEqual(got.Metadata, metadata) {
+		t.Errorf("Import(): payment = %v, error = %v", got, err)
+		return
+	}
+}
+
+func TestService_PayInCurrency_success(t *testing.T) {
+	s := newTestService()
+	account, err := s.RegisterAccountWithCurrency("+992928885522", "USD")
+	if err != nil {
+		t.Errorf("RegisterAccountWithCurrency(): error = %v", err)
+		return
+	}
+	_ = s.Deposit(account.ID, 100_00)
+	payment, err := s.PayInCurrency(account.ID, 10_00, "USD", "auto")
+	if err != nil {
+		t.Errorf("PayInCurrency(): error = %v", err)
+		return
+	}
+	if payment.Currency != "USD" {
+		t.Errorf("PayInCurrency(): wrong currency, payment = %v", payment)
+		return
+	}
+}
+
+func TestService_PayInCurrency_fail(t *testing.T) {
+	s := newTestService()
+	account, err := s.addAccountWithBalance("+992928885522", 100_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, err = s.PayInCurrency(account.ID, 10_00, "USD", "auto")
+	if !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("PayInCurrency(): must return ErrCurrencyMismatch, returned = %v", err)
+		return
+	}
+	_, err = s.PayInCurrency(account.ID, 10_00, "XYZ", "auto")
+	if !errors.Is(err, ErrUnknownCurrency) {
+		t.Errorf("PayInCurrency(): must return ErrUnknownCurrency, returned = %v", err)
+		return
+	}
+	_, err = s.RegisterAccountWithCurrency("+992928885523", "XYZ")
+	if !errors.Is(err, ErrUnknownCurrency) {
+		t.Errorf("RegisterAccountWithCurrency(): must return ErrUnknownCurrency, returned = %v", err)
+		return
+	}
+	if account.Balance != 100_00 {
+		t.Errorf("PayInCurrency(): balance changed, account = %v", account)
+		return
+	}
+}
+
+func TestService_Import_currency(t *testing.T) {
+	s := newTestService()
+	account, err := s.RegisterAccountWithCurrency("+992928885522", "EUR")
+	if err != nil {
+		t.Errorf("RegisterAccountWithCurrency(): error = %v", err)
+		return
+	}
+	_ = s.Deposit(account.ID, 100_00)
+	payment, _ := s.Pay(account.ID, 10_00, "auto")
+	dir := t.TempDir()
+	err = s.Export(dir)
+	if err != nil {
+		t.Errorf("Export(): error = %v", err)
+		return
+	}
+
+	imported := &Service{}
+	err = imported.Import(dir)
+	if err != nil {
+		t.Errorf("Import(): error = %v", err)
+		return
+	}
+	gotAccount, _ := imported.FindAccountByID(account.ID)
+	gotPayment, _ := imported.FindPaymentByID(payment.ID)
+	if gotAccount == nil || gotAccount.Currency != "EUR" || gotPayment == nil || gotPayment.Currency != "EUR" {
+		t.Errorf("Import(): currency not imported, account = %v, payment = %v", gotAccount, gotPayment)
+		return
+	}
+}
+
+func TestService_Export_atomic(t *testing.T) {
+	s := newTestService()
+	_, _, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	dir := t.TempDir()
+	err = os.Mkdir(dir+"/payments.dump", 0777)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = s.Export(dir)
+	exportErr, ok := err.(*ExportError)
+	if !ok || len(exportErr.Failed) != 1 || exportErr.Failed["payments"] == nil {
+		t.Errorf("Export(): must return ExportError for payments, returned = %v", err)
+		return
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	var names []string
+	for _, file := range files {
+		names = append(names, file.Name())
+	}
+	if !reflect.DeepEqual(names, []string{"accounts.dump", manifestName, "payments.dump"}) {
+		t.Errorf("Export(): must write other dumps without temporary files, files = %v", names)
+		return
+	}
+}
+
+func TestService_Export_fail(t *testing.T) {
+	s := newTestService()
+	path := t.TempDir() + "/file"
+	err := ioutil.WriteFile(path, nil, 0666)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = s.Export(path)
+	if err == nil {
+		t.Errorf("Export(): must return error when dir can't be created")
+		return
+	}
+}
+
+func TestService_FreezeAccount_success(t *testing.T) {
+	s := newTestService()
+	account, err := s.addAccountWithBalance("+992928885522", 100_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = s.FreezeAccount(account.ID)
+	if err != nil {
+		t.Errorf("FreezeAccount(): error = %v", err)
+		return
+	}
+	_, err = s.Pay(account.ID, 10_00, "auto")
+	if !errors.Is(err, ErrAccountFrozen) {
+		t.Errorf("Pay(): must return ErrAccountFrozen, returned = %v", err)
+		return
+	}
+	err = s.UnfreezeAccount(account.ID)
+	if err != nil {
+		t.Errorf("UnfreezeAccount(): error = %v", err)
+		return
+	}
+	_, err = s.Pay(account.ID, 10_00, "auto")
+	if err != nil {
+		t.Errorf("Pay(): error = %v", err)
+		return
+	}
+}
+
+func TestService_FreezeAccount_fail(t *testing.T) {
+	s := newTestService()
+	err := s.FreezeAccount(1)
+	if !errors.Is(err, ErrAccountNotFound) {
+		t.Errorf("FreezeAccount(): must return ErrAccountNotFound, returned = %v", err)
+		return
+	}
+}
+
+func TestService_CloseAccount_success(t *testing.T) {
+	s := newTestService()
+	account, err := s.RegisterAccount("+992928885522")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = s.CloseAccount(account.ID)
+	if err != nil {
+		t.Errorf("CloseAccount(): error = %v", err)
+		return
+	}
+	err = s.Deposit(account.ID, 10_00)
+	if !errors.Is(err, ErrAccountClosed) {
+		t.Errorf("Deposit(): must return ErrAccountClosed, returned = %v", err)
+		return
+	}
+	_, err = s.RegisterAccount("+992928885522")
+	if err != nil {
+		t.Errorf("RegisterAccount(): phone of closed account must be free, error = %v", err)
+		return
+	}
+}
+
+func TestService_CloseAccount_fail(t *testing.T) {
+	s := newTestService()
+	account, err := s.addAccountWithBalance("+992928885522", 10_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = s.CloseAccount(account.ID)
+	if !errors.Is(err, ErrBalanceNotEmpty) {
+		t.Errorf("CloseAccount(): must return ErrBalanceNotEmpty, returned = %v", err)
+		return
+	}
+	if account.Status != types.AccountStatusActive {
+		t.Errorf("CloseAccount(): status changed, account = %v", account)
+		return
+	}
+}
+
 func BenchmarkSumPayments(b *testing.B) {
 	srv := &Service{
 		accounts:  make([]*types.Account, 0),
@@ -347,3 +812,264 @@ func BenchmarkSumPayments(b *testing.B) {
 		b.Errorf("want => %v got => %v", want, got)
 	}
 }
+
+func TestService_FilterPayments_success(t *testing.T) {
+	s := newTestService()
+	first, err := s.addAccountWithBalance("+992928885522", 100_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	second, err := s.addAccountWithBalance("+992928000000", 100_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	for i := 0; i < 7; i++ {
+		_, _ = s.Pay(first.ID, 1_00, "auto")
+		_, _ = s.Pay(second.ID, 2_00, "food")
+	}
+	want, _ := s.ExportAccountHistory(second.ID)
+	for _, goroutines := range []int{0, 1, 3, 20} {
+		got, err := s.FilterPayments(second.ID, goroutines)
+		if err != nil {
+			t.Errorf("FilterPayments(%v): error = %v", goroutines, err)
+			return
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("FilterPayments(%v): got = %v, want = %v", goroutines, got, want)
+			return
+		}
+	}
+}
+
+func TestService_FilterPayments_fail(t *testing.T) {
+	s := newTestService()
+	_, err := s.FilterPayments(1, 2)
+	if !errors.Is(err, ErrAccountNotFound) {
+		t.Errorf("FilterPayments(): must return ErrAccountNotFound, returned = %v", err)
+		return
+	}
+}
+
+func TestService_SumPaymentsWithProgress(t *testing.T) {
+	s := newTestService()
+	account, err := s.addAccountWithBalance("+992928885522", 1_000_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	for i := types.Money(1); i <= 25; i++ {
+		_, _ = s.Pay(account.ID, i, "auto")
+	}
+
+	defer func(size int) { progressChunkSize = size }(progressChunkSize)
+	progressChunkSize = 10
+
+	parts, count, sum := 0, 0, types.Money(0)
+	for progress := range s.SumPaymentsWithProgress() {
+		parts++
+		count += progress.Part
+		sum += progress.Result
+	}
+	if parts != 3 || count != 25 || sum != 325 {
+		t.Errorf("SumPaymentsWithProgress(): parts = %v, count = %v, sum = %v", parts, count, sum)
+		return
+	}
+}
+
+func TestService_SumPaymentsWithProgress_empty(t *testing.T) {
+	s := newTestService()
+	var got []types.Progress
+	for progress := range s.SumPaymentsWithProgress() {
+		got = append(got, progress)
+	}
+	if len(got) != 1 || got[0] != (types.Progress{}) {
+		t.Errorf("SumPaymentsWithProgress(): progress = %v, want one zero Progress", got)
+		return
+	}
+}
+
+func TestService_FilterPaymentsByFn(t *testing.T) {
+	s := newTestService()
+	account, err := s.addAccountWithBalance("+992928885522", 100_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	for i := types.Money(1); i <= 10; i++ {
+		category := types.PaymentCategory("auto")
+		if i%2 == 0 {
+			category = "food"
+		}
+		_, _ = s.Pay(account.ID, i, category)
+	}
+	got, err := s.FilterPaymentsByFn(func(payment types.Payment) bool {
+		return payment.Category == "food" && payment.Amount > 4
+	}, 3)
+	if err != nil {
+		t.Errorf("FilterPaymentsByFn(): error = %v", err)
+		return
+	}
+	if len(got) != 3 || got[0].Amount != 6 || got[2].Amount != 10 {
+		t.Errorf("FilterPaymentsByFn(): wrong payments = %v", got)
+		return
+	}
+}
+
+func newBenchmarkService(b *testing.B, accounts int, paymentsPerAccount int) *Service {
+	srv := &Service{}
+	for i := 0; i < accounts; i++ {
+		account, err := srv.RegisterAccount(types.Phone(fmt.Sprint("+992", i)))
+		if err != nil {
+			b.Fatal(err)
+		}
+		err = srv.Deposit(account.ID, types.Money(paymentsPerAccount))
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+	for j := 0; j < paymentsPerAccount; j++ {
+		for i := 1; i <= accounts; i++ {
+			_, err := srv.Pay(int64(i), 1, "auto")
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+	return srv
+}
+
+func benchmarkFilterPayments(b *testing.B, goroutines int) {
+	srv := newBenchmarkService(b, 100, 10_000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := srv.FilterPayments(5, goroutines)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkFilterPayments_1(b *testing.B) {
+	benchmarkFilterPayments(b, 1)
+}
+
+func BenchmarkFilterPayments_4(b *testing.B) {
+	benchmarkFilterPayments(b, 4)
+}
+
+func BenchmarkFilterPayments_16(b *testing.B) {
+	benchmarkFilterPayments(b, 16)
+}
+
+func TestService_SumPaymentsByCategory(t *testing.T) {
+	s := newTestService()
+	account, err := s.addAccountWithBalance("+992928885522", 10_000_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	payments := []struct {
+		amount   types.Money
+		category types.PaymentCategory
+	}{
+		{100_00, "food"}, {200_00, "food/cafe"}, {300_00, "auto"}, {400_00, "fun"}, {500_00, "foodstuff"},
+	}
+	for _, p := range payments {
+		_, err = s.Pay(account.ID, p.amount, p.category)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+	}
+	rejected, err := s.Pay(account.ID, 50_00, "food")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = s.Reject(rejected.ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	for _, goroutines := range []int{0, 1, 4, 10} {
+		sum := s.SumPaymentsByCategory("food", goroutines)
+		if sum != 350_00 {
+			t.Errorf("SumPaymentsByCategory(%d): sum = %v, want %v", goroutines, sum, types.Money(350_00))
+			return
+		}
+		sum = s.SumPaymentsByStatus(types.PaymentStatusFail, goroutines)
+		if sum != 50_00 {
+			t.Errorf("SumPaymentsByStatus(%d): sum = %v, want %v", goroutines, sum, types.Money(50_00))
+			return
+		}
+		count := s.CountPayments(func(payment types.Payment) bool {
+			return payment.Amount >= 300_00
+		}, goroutines)
+		if count != 3 {
+			t.Errorf("CountPayments(%d): count = %v, want 3", goroutines, count)
+			return
+		}
+	}
+}
+
+func benchmarkSumPaymentsByCategory(b *testing.B, goroutines int) {
+	srv := newBenchmarkService(b, 100, 10_000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		srv.SumPaymentsByCategory("auto", goroutines)
+	}
+}
+
+func BenchmarkSumPaymentsByCategory_1(b *testing.B) {
+	benchmarkSumPaymentsByCategory(b, 1)
+}
+
+func BenchmarkSumPaymentsByCategory_4(b *testing.B) {
+	benchmarkSumPaymentsByCategory(b, 4)
+}
+
+func BenchmarkSumPaymentsByCategory_16(b *testing.B) {
+	benchmarkSumPaymentsByCategory(b, 16)
+}
+
+func benchmarkCountPayments(b *testing.B, goroutines int) {
+	srv := newBenchmarkService(b, 100, 10_000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		srv.CountPayments(func(payment types.Payment) bool {
+			return payment.Status == types.PaymentStatusInProgress
+		}, goroutines)
+	}
+}
+
+func BenchmarkCountPayments_1(b *testing.B) {
+	benchmarkCountPayments(b, 1)
+}
+
+func BenchmarkCountPayments_16(b *testing.B) {
+	benchmarkCountPayments(b, 16)
+}
+
+func TestService_FindAccountByID_copy(t *testing.T) {
+	s := newTestService()
+	account, payments, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	account.Balance = 1_000_000_00
+	payments[0].Status = types.PaymentStatusOk
+	got, err := s.FindAccountByID(account.ID)
+	if err != nil || got.Balance != 9_000_00 {
+		t.Errorf("FindAccountByID(): returned account must be a copy, account = %v, error = %v", got, err)
+		return
+	}
+	payment, err := s.FindPaymentByID(payments[0].ID)
+	if err != nil || payment.Status != types.PaymentStatusInProgress {
+		t.Errorf("FindPaymentByID(): returned payment must be a copy, payment = %v, error = %v", payment, err)
+		return
+	}
+}
@@ -0,0 +1,120 @@
+package wallet
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// SLO задает цель по задержке операции: доля Objective вызовов
+// в скользящем окне Window должна укладываться в Threshold
+type SLO struct {
+	Objective float64
+	Threshold time.Duration
+	Window    time.Duration
+}
+
+// SLOStatus описывает состояние SLO операции в текущем окне.
+// BurnRate равен 1, когда бюджет ошибок расходуется ровно с допустимой скоростью
+type SLOStatus struct {
+	Operation string
+	Total     int
+	Slow      int
+	Latency   time.Duration
+	BurnRate  float64
+}
+
+type sloSample struct {
+	at       time.Time
+	duration time.Duration
+}
+
+type sloTracker struct {
+	mu      sync.Mutex
+	targets map[string]SLO
+	samples map[string][]sloSample
+}
+
+// SetSLO включает отслеживание задержки операции: "RegisterAccount", "Deposit",
+// "Pay", "Reject", "Repeat", "PayFromFavorite", "Export", "Import"
+func (s *Service) SetSLO(operation string, slo SLO) error {
+	if slo.Objective <= 0 || slo.Objective >= 1 || slo.Threshold <= 0 || slo.Window <= 0 {
+		return ErrInvalidSLO
+	}
+	s.slo.mu.Lock()
+	defer s.slo.mu.Unlock()
+	if s.slo.targets == nil {
+		s.slo.targets = make(map[string]SLO)
+		s.slo.samples = make(map[string][]sloSample)
+	}
+	s.slo.targets[operation] = slo
+	return nil
+}
+
+func (s *Service) SLOStatus(operation string) (SLOStatus, error) {
+	s.slo.mu.Lock()
+	defer s.slo.mu.Unlock()
+	slo, ok := s.slo.targets[operation]
+	if !ok {
+		return SLOStatus{}, ErrSLONotFound
+	}
+	samples := s.slo.prune(operation, slo, time.Now())
+
+	status := SLOStatus{Operation: operation, Total: len(samples)}
+	if len(samples) == 0 {
+		return status, nil
+	}
+	for _, sample := range samples {
+		if sample.duration > slo.Threshold {
+			status.Slow++
+		}
+	}
+	status.Latency = percentile(samples, slo.Objective)
+	status.BurnRate = float64(status.Slow) / float64(status.Total) / (1 - slo.Objective)
+	return status, nil
+}
+
+// observe вызывается через defer в начале операции
+func (s *Service) observe(operation string, started time.Time) {
+	now := time.Now()
+	s.slo.mu.Lock()
+	defer s.slo.mu.Unlock()
+	slo, ok := s.slo.targets[operation]
+	if !ok {
+		return
+	}
+	samples := s.slo.prune(operation, slo, now)
+	// операции берут блокировку не в порядке завершения, а prune ищет по at двоичным
+	// поиском, поэтому выборка вставкой держится упорядоченной по времени завершения
+	i := sort.Search(len(samples), func(i int) bool {
+		return samples[i].at.After(now)
+	})
+	samples = append(samples, sloSample{})
+	copy(samples[i+1:], samples[i:])
+	samples[i] = sloSample{at: now, duration: now.Sub(started)}
+	s.slo.samples[operation] = samples
+}
+
+// percentile возвращает задержку, в которую укладывается доля objective выборки.
+// Выборка упорядочена по времени завершения, поэтому сортируется ее копия
+func percentile(samples []sloSample, objective float64) time.Duration {
+	durations := make([]time.Duration, len(samples))
+	for i, sample := range samples {
+		durations[i] = sample.duration
+	}
+	sort.Slice(durations, func(i, j int) bool {
+		return durations[i] < durations[j]
+	})
+	return durations[int(float64(len(durations)-1)*objective)]
+}
+
+func (t *sloTracker) prune(operation string, slo SLO, now time.Time) []sloSample {
+	samples := t.samples[operation]
+	from := now.Add(-slo.Window)
+	i := sort.Search(len(samples), func(i int) bool {
+		return samples[i].at.After(from)
+	})
+	samples = samples[i:]
+	t.samples[operation] = samples
+	return samples
+}
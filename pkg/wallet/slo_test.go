@@ -0,0 +1,89 @@
+package wallet
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestService_SLOStatus_success(t *testing.T) {
+	s := newTestService()
+	err := s.SetSLO("Deposit", SLO{Objective: 0.99, Threshold: time.Nanosecond, Window: time.Minute})
+	if err != nil {
+		t.Errorf("SetSLO(): error = %v", err)
+		return
+	}
+	account, err := s.addAccountWithBalance("+992928885522", 100_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_ = s.Deposit(account.ID, 100_00)
+
+	status, err := s.SLOStatus("Deposit")
+	if err != nil {
+		t.Errorf("SLOStatus(): error = %v", err)
+		return
+	}
+	if status.Total != 2 || status.Slow != 2 {
+		t.Errorf("SLOStatus(): wrong counts, status = %+v", status)
+		return
+	}
+	if status.BurnRate < 99 || status.BurnRate > 101 {
+		t.Errorf("SLOStatus(): burn rate must be 100, status = %+v", status)
+		return
+	}
+}
+
+func TestService_SLOStatus_fail(t *testing.T) {
+	s := newTestService()
+	err := s.SetSLO("Pay", SLO{Objective: 1, Threshold: time.Millisecond, Window: time.Minute})
+	if !errors.Is(err, ErrInvalidSLO) {
+		t.Errorf("SetSLO(): must return ErrInvalidSLO, returned = %v", err)
+		return
+	}
+	_, err = s.SLOStatus("Pay")
+	if !errors.Is(err, ErrSLONotFound) {
+		t.Errorf("SLOStatus(): must return ErrSLONotFound, returned = %v", err)
+		return
+	}
+}
+
+func TestService_SLOStatus_latency(t *testing.T) {
+	s := newTestService()
+	err := s.SetSLO("Pay", SLO{Objective: 0.5, Threshold: time.Hour, Window: time.Hour})
+	if err != nil {
+		t.Errorf("SetSLO(): error = %v", err)
+		return
+	}
+	now := time.Now()
+	for _, duration := range []time.Duration{30 * time.Minute, 10 * time.Minute, 20 * time.Minute} {
+		s.observe("Pay", now.Add(-duration))
+	}
+	status, err := s.SLOStatus("Pay")
+	if err != nil {
+		t.Errorf("SLOStatus(): error = %v", err)
+		return
+	}
+	if status.Latency < 20*time.Minute || status.Latency >= 30*time.Minute {
+		t.Errorf("SLOStatus(): latency = %v, want median 20m", status.Latency)
+		return
+	}
+}
+
+func TestService_observe_order(t *testing.T) {
+	s := newTestService()
+	err := s.SetSLO("Pay", SLO{Objective: 0.5, Threshold: time.Hour, Window: time.Hour})
+	if err != nil {
+		t.Errorf("SetSLO(): error = %v", err)
+		return
+	}
+	late := time.Now().Add(time.Minute)
+	s.slo.samples["Pay"] = []sloSample{{at: late, duration: time.Second}}
+	s.observe("Pay", time.Now())
+	samples := s.slo.samples["Pay"]
+	if len(samples) != 2 || !samples[0].at.Before(samples[1].at) {
+		t.Errorf("observe(): samples = %v, want ordered by completion", samples)
+		return
+	}
+}
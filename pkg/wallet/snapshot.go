@@ -0,0 +1,157 @@
+package wallet
+
+import (
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/sidalsoft/wallet/pkg/types"
+)
+
+// Snapshot хранит копию счетов и платежей на момент снятия
+type Snapshot struct {
+	Accounts []types.Account
+	Payments []types.Payment
+}
+
+// AccountChange описывает счет, который есть в обоих снимках, но отличается
+type AccountChange struct {
+	Before types.Account
+	After  types.Account
+}
+
+// PaymentChange описывает платеж, который есть в обоих снимках, но отличается
+type PaymentChange struct {
+	Before types.Payment
+	After  types.Payment
+}
+
+// SnapshotDiff перечисляет изменения при переходе от одного снимка к другому.
+// Счета упорядочены по ID, платежи - в порядке следования в снимке
+type SnapshotDiff struct {
+	AddedAccounts    []types.Account
+	RemovedAccounts  []types.Account
+	ModifiedAccounts []AccountChange
+	AddedPayments    []types.Payment
+	RemovedPayments  []types.Payment
+	ModifiedPayments []PaymentChange
+}
+
+// Empty сообщает, что снимки совпадают
+func (d *SnapshotDiff) Empty() bool {
+	return len(d.AddedAccounts) == 0 && len(d.RemovedAccounts) == 0 && len(d.ModifiedAccounts) == 0 &&
+		len(d.AddedPayments) == 0 && len(d.RemovedPayments) == 0 && len(d.ModifiedPayments) == 0
+}
+
+func (s *Service) Snapshot() Snapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	snapshot := Snapshot{
+		Accounts: make([]types.Account, 0, len(s.accounts)),
+		Payments: make([]types.Payment, 0, len(s.payments)),
+	}
+	for _, account := range s.accounts {
+		snapshot.Accounts = append(snapshot.Accounts, *account)
+	}
+	for _, payment := range s.payments {
+		snapshot.Payments = append(snapshot.Payments, *payment)
+	}
+	return snapshot
+}
+
+// LoadSnapshot читает снимок из каталога, созданного Export
+func LoadSnapshot(dir string) (Snapshot, error) {
+	_, err := os.Stat(dir)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	s := &Service{}
+	err = s.Import(dir)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	return s.Snapshot(), nil
+}
+
+// DiffSnapshots сравнивает снимки a и b. Время сравнивается с точностью до секунды,
+// как оно сохраняется Export, поэтому снимок из каталога можно сравнивать с текущим состоянием
+func DiffSnapshots(a, b Snapshot) *SnapshotDiff {
+	diff := &SnapshotDiff{}
+
+	accounts := make(map[int64]types.Account, len(a.Accounts))
+	for _, account := range a.Accounts {
+		accounts[account.ID] = account
+	}
+	for _, after := range b.Accounts {
+		before, ok := accounts[after.ID]
+		if !ok {
+			diff.AddedAccounts = append(diff.AddedAccounts, after)
+			continue
+		}
+		delete(accounts, after.ID)
+		if !accountsEqual(before, after) {
+			diff.ModifiedAccounts = append(diff.ModifiedAccounts, AccountChange{Before: before, After: after})
+		}
+	}
+	for _, account := range a.Accounts {
+		if _, ok := accounts[account.ID]; ok {
+			diff.RemovedAccounts = append(diff.RemovedAccounts, account)
+		}
+	}
+
+	payments := make(map[string]types.Payment, len(a.Payments))
+	for _, payment := range a.Payments {
+		payments[payment.ID] = payment
+	}
+	for _, after := range b.Payments {
+		before, ok := payments[after.ID]
+		if !ok {
+			diff.AddedPayments = append(diff.AddedPayments, after)
+			continue
+		}
+		delete(payments, after.ID)
+		if !paymentsEqual(before, after) {
+			diff.ModifiedPayments = append(diff.ModifiedPayments, PaymentChange{Before: before, After: after})
+		}
+	}
+	for _, payment := range a.Payments {
+		if _, ok := payments[payment.ID]; ok {
+			diff.RemovedPayments = append(diff.RemovedPayments, payment)
+		}
+	}
+
+	sort.Slice(diff.AddedAccounts, func(i, j int) bool {
+		return diff.AddedAccounts[i].ID < diff.AddedAccounts[j].ID
+	})
+	sort.Slice(diff.RemovedAccounts, func(i, j int) bool {
+		return diff.RemovedAccounts[i].ID < diff.RemovedAccounts[j].ID
+	})
+	sort.Slice(diff.ModifiedAccounts, func(i, j int) bool {
+		return diff.ModifiedAccounts[i].After.ID < diff.ModifiedAccounts[j].After.ID
+	})
+	return diff
+}
+
+func accountsEqual(a, b types.Account) bool {
+	if a.OverdrawnSince.Unix() != b.OverdrawnSince.Unix() || a.Registered.Unix() != b.Registered.Unix() {
+		return false
+	}
+	a.OverdrawnSince = b.OverdrawnSince
+	a.Registered = b.Registered
+	return a == b
+}
+
+func paymentsEqual(a, b types.Payment) bool {
+	if a.Created.Unix() != b.Created.Unix() || types.JoinMetadata(a.Metadata) != types.JoinMetadata(b.Metadata) {
+		return false
+	}
+	a.Created = b.Created
+	if strings.Join(a.Tags, ",") != strings.Join(b.Tags, ",") {
+		return false
+	}
+	a.Metadata, b.Metadata = nil, nil
+	a.Tags, b.Tags = nil, nil
+	return reflect.DeepEqual(a, b)
+}
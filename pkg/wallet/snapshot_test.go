@@ -0,0 +1,75 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/sidalsoft/wallet/pkg/types"
+)
+
+func TestDiffSnapshots_success(t *testing.T) {
+	s := newTestService()
+	account, payments, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	removed, err := s.RegisterAccount("+992928885500")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	dir := t.TempDir()
+	err = s.Export(dir)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	before, err := LoadSnapshot(dir)
+	if err != nil {
+		t.Errorf("LoadSnapshot(): error = %v", err)
+		return
+	}
+	if diff := DiffSnapshots(before, s.Snapshot()); !diff.Empty() {
+		t.Errorf("DiffSnapshots(): exported state must match live state, diff = %+v", diff)
+		return
+	}
+
+	err = s.Reject(payments[0].ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	added, err := s.Pay(account.ID, 1_00, "auto")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	after := s.Snapshot()
+	after.Accounts = after.Accounts[:1]
+
+	diff := DiffSnapshots(before, after)
+	if len(diff.AddedAccounts) != 0 || len(diff.RemovedAccounts) != 1 || diff.RemovedAccounts[0].ID != removed.ID {
+		t.Errorf("DiffSnapshots(): wrong added/removed accounts, diff = %+v", diff)
+		return
+	}
+	if len(diff.ModifiedAccounts) != 1 || diff.ModifiedAccounts[0].Before.Balance == diff.ModifiedAccounts[0].After.Balance {
+		t.Errorf("DiffSnapshots(): wrong modified accounts, diff = %+v", diff)
+		return
+	}
+	if len(diff.AddedPayments) != 1 || diff.AddedPayments[0].ID != added.ID {
+		t.Errorf("DiffSnapshots(): wrong added payments, diff = %+v", diff)
+		return
+	}
+	if len(diff.ModifiedPayments) != 1 || diff.ModifiedPayments[0].After.Status != types.PaymentStatusFail {
+		t.Errorf("DiffSnapshots(): wrong modified payments, diff = %+v", diff)
+		return
+	}
+}
+
+func TestLoadSnapshot_fail(t *testing.T) {
+	_, err := LoadSnapshot(t.TempDir() + "/missing")
+	if err == nil {
+		t.Errorf("LoadSnapshot(): must return error for missing dir")
+		return
+	}
+}
@@ -0,0 +1,96 @@
+package wallet
+
+import (
+	"time"
+
+	"github.com/sidalsoft/wallet/pkg/types"
+)
+
+// DeletedItems перечисляет удаленное избранное и отмененные запланированные платежи счета
+type DeletedItems struct {
+	Favorites []types.Favorite
+	Scheduled []types.ScheduledPayment
+}
+
+// DeleteFavorite помечает избранное удаленным. Удаленное избранное можно вернуть
+// через RestoreFavorite, пока его не удалит политика хранения
+func (s *Service) DeleteFavorite(favoriteID string) (err error) {
+	defer s.audit("DeleteFavorite", &err, "favoriteID", favoriteID)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	favorite, err := s.findActiveFavoriteByID(favoriteID)
+	if err != nil {
+		return err
+	}
+	favorite.Deleted = s.clock()
+	s.markFavorite(favorite.ID)
+	return nil
+}
+
+func (s *Service) RestoreFavorite(favoriteID string) (err error) {
+	defer s.audit("RestoreFavorite", &err, "favoriteID", favoriteID)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	favorite, err := s.findFavoriteByID(favoriteID)
+	if err != nil || favorite.Deleted.IsZero() {
+		return ErrFavoriteNotFound
+	}
+	if _, err := s.findFavoriteByName(favorite.AccountID, favorite.Name); err == nil {
+		return ErrFavoriteRegistered
+	}
+	err = s.checkFavoritesQuota(favorite.AccountID)
+	if err != nil {
+		return err
+	}
+	favorite.Deleted = time.Time{}
+	s.markFavorite(favorite.ID)
+	return nil
+}
+
+func (s *Service) RestoreScheduledPayment(scheduledID string) (err error) {
+	defer s.audit("RestoreScheduledPayment", &err, "scheduledID", scheduledID)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, sp := range s.scheduled {
+		if sp.ID != scheduledID || sp.Deleted.IsZero() {
+			continue
+		}
+		err = s.checkScheduledQuota(sp.AccountID)
+		if err != nil {
+			return err
+		}
+		sp.Deleted = time.Time{}
+		s.markScheduled(sp.ID)
+		return nil
+	}
+	return ErrScheduledPaymentNotFound
+}
+
+func (s *Service) ListDeleted(accountID int64) (DeletedItems, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, err := s.findAccountByID(accountID)
+	if err != nil {
+		return DeletedItems{}, err
+	}
+	var items DeletedItems
+	for _, favorite := range s.favorites {
+		if favorite.AccountID == accountID && !favorite.Deleted.IsZero() {
+			items.Favorites = append(items.Favorites, *favorite)
+		}
+	}
+	for _, sp := range s.scheduled {
+		if sp.AccountID == accountID && !sp.Deleted.IsZero() {
+			items.Scheduled = append(items.Scheduled, *sp)
+		}
+	}
+	return items, nil
+}
+
+func (s *Service) findActiveFavoriteByID(favoriteID string) (*types.Favorite, error) {
+	favorite, err := s.findFavoriteByID(favoriteID)
+	if err != nil || !favorite.Deleted.IsZero() {
+		return nil, ErrFavoriteNotFound
+	}
+	return favorite, nil
+}
@@ -0,0 +1,102 @@
+package wallet
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestService_DeleteFavorite_success(t *testing.T) {
+	s := newTestService()
+	_, payments, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	favorite, err := s.FavoritePayment(payments[0].ID, "car")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = s.DeleteFavorite(favorite.ID)
+	if err != nil {
+		t.Errorf("DeleteFavorite(): error = %v", err)
+		return
+	}
+	_, err = s.PayFromFavorite(favorite.ID)
+	if !errors.Is(err, ErrFavoriteNotFound) {
+		t.Errorf("PayFromFavorite(): must return ErrFavoriteNotFound, returned = %v", err)
+		return
+	}
+	deleted, err := s.ListDeleted(favorite.AccountID)
+	if err != nil || len(deleted.Favorites) != 1 || deleted.Favorites[0].ID != favorite.ID {
+		t.Errorf("ListDeleted(): items = %+v, error = %v", deleted, err)
+		return
+	}
+
+	err = s.RestoreFavorite(favorite.ID)
+	if err != nil {
+		t.Errorf("RestoreFavorite(): error = %v", err)
+		return
+	}
+	_, err = s.PayFromFavorite(favorite.ID)
+	if err != nil {
+		t.Errorf("PayFromFavorite(): error = %v", err)
+		return
+	}
+	err = s.RestoreFavorite(favorite.ID)
+	if !errors.Is(err, ErrFavoriteNotFound) {
+		t.Errorf("RestoreFavorite(): must return ErrFavoriteNotFound, returned = %v", err)
+		return
+	}
+}
+
+func TestService_RestoreScheduledPayment_success(t *testing.T) {
+	s := newTestService()
+	now := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	account, err := s.addAccountWithBalance("+992928885522", 100_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	sp, err := s.SchedulePayment(account.ID, 10_00, "auto", "@daily")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = s.CancelScheduledPayment(sp.ID)
+	if err != nil {
+		t.Errorf("CancelScheduledPayment(): error = %v", err)
+		return
+	}
+	err = s.RestoreScheduledPayment(sp.ID)
+	if err != nil {
+		t.Errorf("RestoreScheduledPayment(): error = %v", err)
+		return
+	}
+	_, err = s.FindScheduledPaymentByID(sp.ID)
+	if err != nil {
+		t.Errorf("FindScheduledPaymentByID(): error = %v", err)
+		return
+	}
+
+	err = s.CancelScheduledPayment(sp.ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	var archive bytes.Buffer
+	policy := RetentionPolicy{Deleted: RetentionRule{MaxAge: 24 * time.Hour, Archive: &archive}}
+	report, err := s.ApplyRetention(policy, now.AddDate(0, 0, 2), false)
+	if err != nil || report.DeletedItems != 1 || archive.Len() == 0 {
+		t.Errorf("ApplyRetention(): report = %+v, error = %v", report, err)
+		return
+	}
+	err = s.RestoreScheduledPayment(sp.ID)
+	if !errors.Is(err, ErrScheduledPaymentNotFound) {
+		t.Errorf("RestoreScheduledPayment(): must return ErrScheduledPaymentNotFound after purge, returned = %v", err)
+		return
+	}
+}
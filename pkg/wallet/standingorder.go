@@ -0,0 +1,225 @@
+package wallet
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sidalsoft/wallet/pkg/types"
+)
+
+// StandingOrderOperation - вид операции, которой записывается постоянное поручение.
+// ID операции - идентификатор поручения, AccountID - счет списания, Payload - StandingOrder в JSON
+const StandingOrderOperation = "standing"
+
+// StandingOrder - постоянное поручение перевести Amount со счета FromAccountID на счет
+// ToAccountID по расписанию Schedule до Until включительно. Нулевой Until - без срока
+type StandingOrder struct {
+	ID            string         `json:"-"`
+	FromAccountID int64          `json:"fromAccountId"`
+	ToAccountID   int64          `json:"toAccountId"`
+	Amount        types.Money    `json:"amount"`
+	Schedule      types.Schedule `json:"schedule"`
+	Until         time.Time      `json:"until"`
+	NextRun       time.Time      `json:"nextRun"`
+	Canceled      bool           `json:"canceled,omitempty"`
+}
+
+// StandingOrderError - причина неудачного исполнения поручения OrderID,
+// передается в Event.Err события EventStandingOrderFailed
+type StandingOrderError struct {
+	OrderID string
+	Err     error
+}
+
+func (e *StandingOrderError) Error() string {
+	return fmt.Sprintf("standing order %s: %v", e.OrderID, e.Err)
+}
+
+func (e *StandingOrderError) Unwrap() error {
+	return e.Err
+}
+
+// CreateStandingOrder создает постоянное поручение. Его исполняет RunScheduled: каждое
+// исполнение - перевод, как PayToPhone, с метаданными платежа "standingOrder" = ID поручения
+func (s *Service) CreateStandingOrder(fromAccountID, toAccountID int64, amount types.Money, schedule types.Schedule, until time.Time) (_ *StandingOrder, err error) {
+	defer s.audit("CreateStandingOrder", &err, "accountID", fromAccountID, "toAccountID", toAccountID, "amount", amount, "schedule", schedule)
+	if amount <= 0 {
+		return nil, ErrAmountMustBePositive
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.clock()
+	next, err := nextRun(schedule, now)
+	if err != nil {
+		return nil, err
+	}
+	if !until.IsZero() && until.Before(next) {
+		return nil, ErrInvalidStandingOrder
+	}
+	from, err := s.findAccountByID(fromAccountID)
+	if err != nil {
+		return nil, err
+	}
+	to, err := s.findAccountByID(toAccountID)
+	if err != nil {
+		return nil, err
+	}
+	if to.ID == from.ID {
+		return nil, ErrSelfTransfer
+	}
+	if to.Currency != from.Currency {
+		return nil, ErrCurrencyMismatch
+	}
+	order := StandingOrder{
+		ID:            uuid.New().String(),
+		FromAccountID: from.ID,
+		ToAccountID:   to.ID,
+		Amount:        amount,
+		Schedule:      schedule,
+		Until:         until,
+		NextRun:       next,
+	}
+	payload, err := json.Marshal(order)
+	if err != nil {
+		return nil, err
+	}
+	operation := &types.Operation{
+		ID:        order.ID,
+		Kind:      StandingOrderOperation,
+		AccountID: from.ID,
+		Payload:   payload,
+	}
+	s.operations = append(s.operations, operation)
+	s.markOperation(operation.ID)
+	return &order, nil
+}
+
+// FindStandingOrderByID возвращает действующее поручение
+func (s *Service) FindStandingOrderByID(orderID string) (*StandingOrder, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, order, err := s.findStandingOrder(orderID)
+	if err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// SkipStandingOrder пропускает ближайшее исполнение поручения
+func (s *Service) SkipStandingOrder(orderID string) (err error) {
+	defer s.audit("SkipStandingOrder", &err, "orderID", orderID)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	operation, order, err := s.findStandingOrder(orderID)
+	if err != nil {
+		return err
+	}
+	order.NextRun, err = nextRun(order.Schedule, order.NextRun)
+	if err != nil {
+		return err
+	}
+	return s.saveStandingOrder(operation, order)
+}
+
+// CancelStandingOrder отменяет поручение, проведенные переводы остаются
+func (s *Service) CancelStandingOrder(orderID string) (err error) {
+	defer s.audit("CancelStandingOrder", &err, "orderID", orderID)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	operation, order, err := s.findStandingOrder(orderID)
+	if err != nil {
+		return err
+	}
+	order.Canceled = true
+	return s.saveStandingOrder(operation, order)
+}
+
+// runStandingOrders исполняет поручения, время которых наступило к моменту now.
+// Как и у запланированных платежей, пропущенные запуски не догоняются. Неудачные
+// исполнения сообщаются событием EventStandingOrderFailed
+func (s *Service) runStandingOrders(now time.Time) []*types.Payment {
+	var payments []*types.Payment
+	for _, operation := range s.operations {
+		if operation.Kind != StandingOrderOperation {
+			continue
+		}
+		var order StandingOrder
+		if json.Unmarshal(operation.Payload, &order) != nil || order.Canceled || order.NextRun.After(now) {
+			continue
+		}
+		order.ID = operation.ID
+		if !order.Until.IsZero() && order.NextRun.After(order.Until) {
+			continue
+		}
+		payment, err := s.executeStandingOrder(operation, order, now)
+		if err != nil {
+			if from, findErr := s.findAccountByID(order.FromAccountID); findErr == nil {
+				s.dispatch(Event{
+					Kind:    EventStandingOrderFailed,
+					Time:    s.clock(),
+					Account: *from,
+					Err:     &StandingOrderError{OrderID: order.ID, Err: err},
+				})
+			}
+			continue
+		}
+		payment, _ = copyPayment(payment, nil)
+		payments = append(payments, payment)
+	}
+	return payments
+}
+
+func (s *Service) executeStandingOrder(operation *types.Operation, order StandingOrder, now time.Time) (*types.Payment, error) {
+	next, err := nextRun(order.Schedule, now)
+	if err != nil {
+		return nil, err
+	}
+	order.NextRun = next
+	err = s.saveStandingOrder(operation, order)
+	if err != nil {
+		return nil, err
+	}
+	from, err := s.findAccountByID(order.FromAccountID)
+	if err != nil {
+		return nil, err
+	}
+	to, err := s.findAccountByID(order.ToAccountID)
+	if err != nil {
+		return nil, err
+	}
+	if to.Status == types.AccountStatusClosed {
+		return nil, ErrAccountClosed
+	}
+	return s.transfer(from, to, order.Amount, "", WithMetadata(map[string]string{"standingOrder": order.ID}))
+}
+
+func (s *Service) findStandingOrder(orderID string) (*types.Operation, StandingOrder, error) {
+	for _, operation := range s.operations {
+		if operation.ID != orderID || operation.Kind != StandingOrderOperation {
+			continue
+		}
+		var order StandingOrder
+		err := json.Unmarshal(operation.Payload, &order)
+		if err != nil {
+			return nil, StandingOrder{}, err
+		}
+		if order.Canceled {
+			break
+		}
+		order.ID = operation.ID
+		return operation, order, nil
+	}
+	return nil, StandingOrder{}, ErrStandingOrderNotFound
+}
+
+func (s *Service) saveStandingOrder(operation *types.Operation, order StandingOrder) error {
+	payload, err := json.Marshal(order)
+	if err != nil {
+		return err
+	}
+	operation.Payload = payload
+	s.markOperation(operation.ID)
+	return nil
+}
@@ -0,0 +1,121 @@
+package wallet
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestService_CreateStandingOrder_success(t *testing.T) {
+	s := newTestService()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	from, err := s.addAccountWithBalance("+992928885522", 1_000_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	to, err := s.RegisterAccount("+992000000001")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	order, err := s.CreateStandingOrder(from.ID, to.ID, 100_00, "@daily", now.AddDate(0, 0, 3))
+	if err != nil {
+		t.Errorf("CreateStandingOrder(): error = %v", err)
+		return
+	}
+
+	now = now.AddDate(0, 0, 1)
+	payments, err := s.RunScheduled(now)
+	if err != nil || len(payments) != 1 || payments[0].Metadata["standingOrder"] != order.ID {
+		t.Errorf("RunScheduled(): payments = %v, error = %v", payments, err)
+		return
+	}
+	got, _ := s.FindAccountByID(to.ID)
+	if got.Balance != 100_00 {
+		t.Errorf("RunScheduled(): recipient balance = %v, want %v", got.Balance, 100_00)
+		return
+	}
+
+	err = s.SkipStandingOrder(order.ID)
+	if err != nil {
+		t.Errorf("SkipStandingOrder(): error = %v", err)
+		return
+	}
+	now = now.AddDate(0, 0, 1)
+	payments, err = s.RunScheduled(now)
+	if err != nil || len(payments) != 0 {
+		t.Errorf("RunScheduled(): skipped order executed, payments = %v, error = %v", payments, err)
+		return
+	}
+	now = now.AddDate(0, 0, 1)
+	payments, err = s.RunScheduled(now)
+	if err != nil || len(payments) != 1 {
+		t.Errorf("RunScheduled(): payments = %v, error = %v", payments, err)
+		return
+	}
+	// следующий запуск позже Until
+	now = now.AddDate(0, 0, 1)
+	payments, err = s.RunScheduled(now)
+	if err != nil || len(payments) != 0 {
+		t.Errorf("RunScheduled(): expired order executed, payments = %v, error = %v", payments, err)
+		return
+	}
+}
+
+func TestService_CreateStandingOrder_fail(t *testing.T) {
+	s := newTestService()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	from, err := s.addAccountWithBalance("+992928885522", 50_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	to, err := s.RegisterAccount("+992000000001")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, err = s.CreateStandingOrder(from.ID, from.ID, 100_00, "@daily", time.Time{})
+	if !errors.Is(err, ErrSelfTransfer) {
+		t.Errorf("CreateStandingOrder(): error = %v, want %v", err, ErrSelfTransfer)
+		return
+	}
+	_, err = s.CreateStandingOrder(from.ID, to.ID, 100_00, "@daily", now)
+	if !errors.Is(err, ErrInvalidStandingOrder) {
+		t.Errorf("CreateStandingOrder(): error = %v, want %v", err, ErrInvalidStandingOrder)
+		return
+	}
+	order, err := s.CreateStandingOrder(from.ID, to.ID, 100_00, "@daily", time.Time{})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	var failed []Event
+	s.OnEvent(func(event Event) {
+		if event.Kind == EventStandingOrderFailed {
+			failed = append(failed, event)
+		}
+	})
+	now = now.AddDate(0, 0, 1)
+	payments, err := s.RunScheduled(now)
+	var orderErr *StandingOrderError
+	if err != nil || len(payments) != 0 || len(failed) != 1 ||
+		!errors.As(failed[0].Err, &orderErr) || orderErr.OrderID != order.ID || !errors.Is(failed[0].Err, ErrNotEnoughBalance) {
+		t.Errorf("RunScheduled(): payments = %v, events = %v, error = %v", payments, failed, err)
+		return
+	}
+
+	err = s.CancelStandingOrder(order.ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, err = s.FindStandingOrderByID(order.ID)
+	if !errors.Is(err, ErrStandingOrderNotFound) {
+		t.Errorf("FindStandingOrderByID(): error = %v, want %v", err, ErrStandingOrderNotFound)
+		return
+	}
+}
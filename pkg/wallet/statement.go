@@ -0,0 +1,220 @@
+package wallet
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sidalsoft/wallet/pkg/types"
+)
+
+// Period - промежуток времени [From, To)
+type Period struct {
+	From time.Time
+	To   time.Time
+}
+
+// Contains сообщает, попадает ли t в промежуток
+func (p Period) Contains(t time.Time) bool {
+	return !t.Before(p.From) && t.Before(p.To)
+}
+
+// StatementLine - операция из выписки пользователя. Line - номер строки в CSV
+type StatementLine struct {
+	Line      int
+	Date      time.Time
+	Amount    types.Money
+	Category  types.PaymentCategory
+	PaymentID string
+}
+
+// StatementMatch связывает строку выписки с платежом сервиса
+type StatementMatch struct {
+	Statement StatementLine
+	Payment   types.Payment
+}
+
+// StatementReport - результат сверки выписки пользователя с платежами счета.
+// Mismatched - строки, указавшие ID платежа с другой суммой
+type StatementReport struct {
+	AccountID       int64
+	Period          Period
+	Matched         []StatementMatch
+	Mismatched      []StatementMatch
+	OnlyInStatement []StatementLine
+	OnlyInRecords   []types.Payment
+}
+
+// CompareStatements сверяет выписку пользователя с неотклоненными платежами счета за период.
+// Выписка - CSV со столбцами date,amount[,category[,paymentID]], дата в виде 2006-01-02
+// или RFC3339, сумма в валюте счета, знак суммы не учитывается. Строка заголовка
+// допускается. Строка без ID платежа сопоставляется с платежом на ту же сумму в тот же день
+func (s *Service) CompareStatements(accountID int64, period Period, externalStatement io.Reader) (*StatementReport, error) {
+	s.buildPaymentTimes()
+	s.mu.RLock()
+	account, err := s.findAccountByID(accountID)
+	if err != nil {
+		s.mu.RUnlock()
+		return nil, err
+	}
+	currency := account.Currency
+	var records []types.Payment
+	for _, payment := range s.paymentsBetween(accountID, period.From, period.To) {
+		if payment.Status != types.PaymentStatusFail {
+			records = append(records, *payment)
+		}
+	}
+	s.mu.RUnlock()
+
+	lines, err := readStatement(externalStatement, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &StatementReport{AccountID: accountID, Period: period}
+	matched := make([]bool, len(records))
+	var unmatched []StatementLine
+	for _, line := range lines {
+		if !period.Contains(line.Date) {
+			continue
+		}
+		if line.PaymentID == "" {
+			unmatched = append(unmatched, line)
+			continue
+		}
+		found := false
+		for i, payment := range records {
+			if !matched[i] && payment.ID == line.PaymentID {
+				matched[i], found = true, true
+				match := StatementMatch{Statement: line, Payment: payment}
+				if payment.Amount == line.Amount {
+					report.Matched = append(report.Matched, match)
+				} else {
+					report.Mismatched = append(report.Mismatched, match)
+				}
+				break
+			}
+		}
+		if !found {
+			report.OnlyInStatement = append(report.OnlyInStatement, line)
+		}
+	}
+	for _, line := range unmatched {
+		found := false
+		for i, payment := range records {
+			if !matched[i] && payment.Amount == line.Amount && sameDay(payment.Created, line.Date) {
+				matched[i], found = true, true
+				report.Matched = append(report.Matched, StatementMatch{Statement: line, Payment: payment})
+				break
+			}
+		}
+		if !found {
+			report.OnlyInStatement = append(report.OnlyInStatement, line)
+		}
+	}
+	for i, payment := range records {
+		if !matched[i] {
+			report.OnlyInRecords = append(report.OnlyInRecords, payment)
+		}
+	}
+	return report, nil
+}
+
+// WriteCSV записывает расхождения отчета для передачи в разбор спора:
+// вид расхождения, дату, сумму по выписке, сумму по данным сервиса, категорию и ID платежа
+func (r *StatementReport) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	_ = writer.Write([]string{"issue", "date", "statement_amount", "recorded_amount", "category", "payment_id"})
+	for _, match := range r.Mismatched {
+		_ = writer.Write([]string{
+			"amount_mismatch",
+			match.Payment.Created.Format(time.RFC3339),
+			strconv.FormatInt(int64(match.Statement.Amount), 10),
+			strconv.FormatInt(int64(match.Payment.Amount), 10),
+			string(match.Payment.Category),
+			match.Payment.ID,
+		})
+	}
+	for _, line := range r.OnlyInStatement {
+		_ = writer.Write([]string{
+			"only_in_statement",
+			line.Date.Format(time.RFC3339),
+			strconv.FormatInt(int64(line.Amount), 10),
+			"",
+			string(line.Category),
+			line.PaymentID,
+		})
+	}
+	for _, payment := range r.OnlyInRecords {
+		_ = writer.Write([]string{
+			"only_in_records",
+			payment.Created.Format(time.RFC3339),
+			"",
+			strconv.FormatInt(int64(payment.Amount), 10),
+			string(payment.Category),
+			payment.ID,
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func readStatement(r io.Reader, currency types.Currency) ([]StatementLine, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	var lines []StatementLine
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(record) < 2 {
+			return nil, fmt.Errorf("statement line %d: expected date and amount", line)
+		}
+		field := func(i int) string {
+			if i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+		date, err := parseStatementDate(field(0))
+		if err != nil {
+			if line == 1 {
+				continue
+			}
+			return nil, fmt.Errorf("statement line %d: %w", line, err)
+		}
+		amount, err := currency.Parse(strings.TrimPrefix(field(1), "-"))
+		if err != nil {
+			return nil, fmt.Errorf("statement line %d: %w", line, err)
+		}
+		lines = append(lines, StatementLine{
+			Line:      line,
+			Date:      date,
+			Amount:    amount,
+			Category:  types.PaymentCategory(field(2)),
+			PaymentID: field(3),
+		})
+	}
+	return lines, nil
+}
+
+func parseStatementDate(s string) (time.Time, error) {
+	date, err := time.Parse(time.RFC3339, s)
+	if err == nil {
+		return date.UTC(), nil
+	}
+	return time.Parse("2006-01-02", s)
+}
+
+func sameDay(a, b time.Time) bool {
+	ay, am, ad := a.UTC().Date()
+	by, bm, bd := b.UTC().Date()
+	return ay == by && am == bm && ad == bd
+}
@@ -0,0 +1,91 @@
+package wallet
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestService_CompareStatements_success(t *testing.T) {
+	s := newTestService()
+	now := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+
+	account, err := s.addAccountWithBalance("+992928885522", 1000_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	byID, err := s.Pay(account.ID, 10_00, "auto")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	byAmount, err := s.Pay(account.ID, 20_00, "food")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	now = now.AddDate(0, 0, 1)
+	unlisted, err := s.Pay(account.ID, 30_00, "auto")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	statement := "date,amount,category,id\n" +
+		"2021-03-01,-10.00,auto," + byID.ID + "\n" +
+		"2021-03-01,-20.00,food,\n" +
+		"2021-03-02,-45.00,auto,\n" +
+		"2021-04-01,-99.00,auto,\n"
+	period := Period{From: time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC), To: time.Date(2021, 4, 1, 0, 0, 0, 0, time.UTC)}
+	report, err := s.CompareStatements(account.ID, period, strings.NewReader(statement))
+	if err != nil {
+		t.Errorf("CompareStatements(): error = %v", err)
+		return
+	}
+	if len(report.Matched) != 2 || report.Matched[0].Payment.ID != byID.ID || report.Matched[1].Payment.ID != byAmount.ID {
+		t.Errorf("CompareStatements(): wrong matched = %+v", report.Matched)
+		return
+	}
+	if len(report.OnlyInStatement) != 1 || report.OnlyInStatement[0].Amount != 45_00 {
+		t.Errorf("CompareStatements(): wrong only in statement = %+v", report.OnlyInStatement)
+		return
+	}
+	if len(report.OnlyInRecords) != 1 || report.OnlyInRecords[0].ID != unlisted.ID {
+		t.Errorf("CompareStatements(): wrong only in records = %+v", report.OnlyInRecords)
+		return
+	}
+
+	var buf bytes.Buffer
+	err = report.WriteCSV(&buf)
+	if err != nil {
+		t.Errorf("WriteCSV(): error = %v", err)
+		return
+	}
+	if strings.Count(buf.String(), "\n") != 3 {
+		t.Errorf("WriteCSV(): wrong report = %q", buf.String())
+		return
+	}
+}
+
+func TestService_CompareStatements_fail(t *testing.T) {
+	s := newTestService()
+	account, err := s.addAccountWithBalance("+992928885522", 1000_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, err = s.CompareStatements(account.ID, Period{}, strings.NewReader("date,amount\n2021-03-01,abc\n"))
+	if err == nil {
+		t.Errorf("CompareStatements(): must return error for invalid amount")
+		return
+	}
+	_, err = s.CompareStatements(account.ID+1, Period{}, strings.NewReader(""))
+	if !errors.Is(err, ErrAccountNotFound) {
+		t.Errorf("CompareStatements(): must return ErrAccountNotFound, returned = %v", err)
+		return
+	}
+}
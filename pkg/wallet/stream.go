@@ -0,0 +1,189 @@
+package wallet
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"time"
+)
+
+// maxDumpLine ограничивает длину строки дампа при потоковом импорте
+const maxDumpLine = 16 << 20
+
+// importBatchSize - число записей, которые потоковый импорт применяет за раз
+var importBatchSize = 10_000
+
+// ImportAccounts построчно читает из r незашифрованный и несжатый дамп счетов
+// в формате Export, не загружая его в память целиком. Дамп может начинаться с
+// заголовка версии, старые версии переводятся в текущую.
+// В отличие от Import, записи применяются пачками по мере чтения: при ошибочной строке
+// или конфликте с MergeFailOnConflict уже прочитанные записи остаются в сервисе
+func (s *Service) ImportAccounts(r io.Reader, options ...ImportOption) error {
+	return s.importStream("ImportAccounts", "accounts", r, options)
+}
+
+// ImportPayments как ImportAccounts, но для дампа платежей
+func (s *Service) ImportPayments(r io.Reader, options ...ImportOption) error {
+	return s.importStream("ImportPayments", "payments", r, options)
+}
+
+// ImportFavorites как ImportAccounts, но для дампа избранного
+func (s *Service) ImportFavorites(r io.Reader, options ...ImportOption) error {
+	return s.importStream("ImportFavorites", "favorites", r, options)
+}
+
+// ImportScheduled как ImportAccounts, но для дампа запланированных платежей
+func (s *Service) ImportScheduled(r io.Reader, options ...ImportOption) error {
+	return s.importStream("ImportScheduled", "scheduled", r, options)
+}
+
+// ImportOperations как ImportAccounts, но для дампа операций
+func (s *Service) ImportOperations(r io.Reader, options ...ImportOption) error {
+	return s.importStream("ImportOperations", "operations", r, options)
+}
+
+// ImportMerchants как ImportAccounts, но для дампа получателей платежей
+func (s *Service) ImportMerchants(r io.Reader, options ...ImportOption) error {
+	return s.importStream("ImportMerchants", "merchants", r, options)
+}
+
+func (s *Service) importStream(operation string, name string, r io.Reader, options []ImportOption) (err error) {
+	config := importConfig{}
+	for _, option := range options {
+		option(&config)
+	}
+	defer s.audit(operation, &err, "merge", config.merge)
+	defer s.observe(operation, time.Now())
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.markFull()
+	index := s.newImportIndex()
+	defer s.finishImport()
+
+	// с MergeFailOnConflict строка конфликтует и с прочитанными до нее, поэтому
+	// пачка применяется перед проверкой каждой строки
+	batchSize := importBatchSize
+	if config.merge == MergeFailOnConflict {
+		batchSize = 1
+	}
+	batch := &parsedDumps{}
+	size := 0
+	apply := func() {
+		s.mergeDumps(batch, config.merge, index)
+		batch = &parsedDumps{}
+		size = 0
+	}
+	defer apply()
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, maxDumpLine)
+	version := 0
+	line := 0
+	for first := true; scanner.Scan(); first = false {
+		text := scanner.Text()
+		if first && strings.HasPrefix(text, dumpHeader) {
+			version, err = parseDumpHeader(text)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		line++
+		if text == "" {
+			continue
+		}
+		for _, migrated := range migrateLines(name, version, []string{text}) {
+			record := &parsedDumps{}
+			err = record.parseLine(name, line, migrated)
+			if err != nil {
+				return err
+			}
+			if config.merge == MergeFailOnConflict {
+				err = s.checkImportConflicts(record, index)
+				if err != nil {
+					return err
+				}
+			}
+			batch.append(record)
+			size++
+			if size >= batchSize {
+				apply()
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+// ExportAccountsTo записывает в w незашифрованный и несжатый дамп счетов в формате
+// Export с заголовком версии, который читает ImportAccounts. Дамп пишется по строкам
+// под блокировкой чтения, поэтому медленный w задерживает изменения сервиса
+func (s *Service) ExportAccountsTo(w io.Writer, options ...ExportOption) error {
+	return s.exportStream("ExportAccountsTo", w, options, func(records exportRecords, write func(string)) {
+		for _, account := range records.accounts {
+			write(account.ToString())
+		}
+	})
+}
+
+// ExportPaymentsTo как ExportAccountsTo, но для дампа платежей
+func (s *Service) ExportPaymentsTo(w io.Writer, options ...ExportOption) error {
+	return s.exportStream("ExportPaymentsTo", w, options, func(records exportRecords, write func(string)) {
+		for _, payment := range records.payments {
+			write(payment.ToString())
+		}
+	})
+}
+
+// ExportFavoritesTo как ExportAccountsTo, но для дампа избранного
+func (s *Service) ExportFavoritesTo(w io.Writer, options ...ExportOption) error {
+	return s.exportStream("ExportFavoritesTo", w, options, func(records exportRecords, write func(string)) {
+		for _, favorite := range records.favorites {
+			write(favorite.ToString())
+		}
+	})
+}
+
+// ExportScheduledTo как ExportAccountsTo, но для дампа запланированных платежей
+func (s *Service) ExportScheduledTo(w io.Writer, options ...ExportOption) error {
+	return s.exportStream("ExportScheduledTo", w, options, func(records exportRecords, write func(string)) {
+		for _, scheduled := range records.scheduled {
+			write(scheduled.ToString())
+		}
+	})
+}
+
+// ExportOperationsTo как ExportAccountsTo, но для дампа операций
+func (s *Service) ExportOperationsTo(w io.Writer, options ...ExportOption) error {
+	return s.exportStream("ExportOperationsTo", w, options, func(records exportRecords, write func(string)) {
+		for _, operation := range records.operations {
+			write(operationToString(operation))
+		}
+	})
+}
+
+// ExportMerchantsTo как ExportAccountsTo, но для дампа получателей платежей
+func (s *Service) ExportMerchantsTo(w io.Writer, options ...ExportOption) error {
+	return s.exportStream("ExportMerchantsTo", w, options, func(records exportRecords, write func(string)) {
+		for _, merchant := range records.merchants {
+			write(merchant.ToString())
+		}
+	})
+}
+
+// exportStream пишет заголовок версии и строки, переданные lines, до первой ошибки w
+func (s *Service) exportStream(operation string, w io.Writer, options []ExportOption, lines func(records exportRecords, write func(string))) error {
+	defer s.observe(operation, time.Now())
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	buf := bufio.NewWriter(w)
+	_, err := buf.WriteString(addDumpHeader(""))
+	lines(s.records(newExportConfig(options).order), func(line string) {
+		if err == nil {
+			_, err = buf.WriteString(line + "\n")
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return buf.Flush()
+}
@@ -0,0 +1,242 @@
+package wallet
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/sidalsoft/wallet/pkg/types"
+)
+
+func TestService_ImportAccounts_success(t *testing.T) {
+	s := newTestService()
+	account, err := s.addAccountWithBalance("+992928885522", 1_000_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	payment, err := s.Pay(account.ID, 100_00, "auto")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	dir := t.TempDir()
+	err = s.Export(dir)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	imported := newTestService()
+	for name, importStream := range map[string]func(io.Reader, ...ImportOption) error{
+		"accounts": imported.ImportAccounts,
+		"payments": imported.ImportPayments,
+	} {
+		file, err := os.Open(dir + "/" + name + ".dump")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		// дамп читается через канал, как из сети
+		reader, writer := io.Pipe()
+		go func() {
+			_, err := io.Copy(writer, file)
+			writer.CloseWithError(err)
+		}()
+		err = importStream(reader)
+		file.Close()
+		if err != nil {
+			t.Errorf("Import %s: error = %v", name, err)
+			return
+		}
+	}
+	got, err := imported.FindAccountByID(account.ID)
+	if err != nil || got.Balance != 900_00 {
+		t.Errorf("ImportAccounts(): account = %v, error = %v", got, err)
+		return
+	}
+	_, err = imported.FindPaymentByID(payment.ID)
+	if err != nil {
+		t.Errorf("ImportPayments(): error = %v", err)
+		return
+	}
+}
+
+func TestService_ImportAccounts_batches(t *testing.T) {
+	defer func(size int) { importBatchSize = size }(importBatchSize)
+	importBatchSize = 2
+
+	s := newTestService()
+	var account *types.Account
+	for i := 0; i < 5; i++ {
+		var err error
+		account, err = s.addAccountWithBalance(types.Phone("+99292888552"+strconv.Itoa(i)), 1_000_00)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+	}
+	_, err := s.Hold(account.ID, 300_00, "auto")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	accounts, operations := bytes.Buffer{}, bytes.Buffer{}
+	err = s.ExportAccountsTo(&accounts)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = s.ExportOperationsTo(&operations)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	imported := newTestService()
+	// повторный импорт заменяет записи, в том числе из прошлых пачек
+	for _, dump := range []string{accounts.String(), accounts.String()} {
+		err = imported.ImportAccounts(strings.NewReader(dump))
+		if err != nil {
+			t.Errorf("ImportAccounts(): error = %v", err)
+			return
+		}
+	}
+	err = imported.ImportOperations(&operations)
+	if err != nil {
+		t.Errorf("ImportOperations(): error = %v", err)
+		return
+	}
+	if len(imported.accounts) != 5 {
+		t.Errorf("ImportAccounts(): accounts = %v, want 5", len(imported.accounts))
+		return
+	}
+	available, err := imported.AvailableBalance(account.ID)
+	if err != nil || available != 700_00 {
+		t.Errorf("ImportOperations(): available = %v, error = %v, want %v", available, err, 700_00)
+		return
+	}
+}
+
+func TestService_ImportAccounts_migrate(t *testing.T) {
+	s := newTestService()
+	err := s.ImportAccounts(strings.NewReader("1;+992000000001;100\n\n2;+992000000002;200\n"))
+	if err != nil {
+		t.Errorf("ImportAccounts(): error = %v", err)
+		return
+	}
+	account, err := s.FindAccountByID(2)
+	if err != nil || account.Balance != 200 || account.Currency != "TJS" {
+		t.Errorf("ImportAccounts(): account = %v, error = %v", account, err)
+		return
+	}
+}
+
+func TestService_ImportAccounts_fail(t *testing.T) {
+	s := newTestService()
+	err := s.ImportAccounts(strings.NewReader("#version 99\n"))
+	if !errors.Is(err, ErrUnsupportedDumpVersion) {
+		t.Errorf("ImportAccounts(): error = %v, want %v", err, ErrUnsupportedDumpVersion)
+		return
+	}
+
+	err = s.ImportAccounts(strings.NewReader("#version 4\n1;+992000000001;100;TJS;ACTIVE;0;0;0\n2;+992000000002;x;TJS;ACTIVE;0;0;0\n"))
+	var malformed *MalformedDumpError
+	if !errors.As(err, &malformed) || malformed.Line != 2 || malformed.Field != "balance" {
+		t.Errorf("ImportAccounts(): error = %v, want *MalformedDumpError on line 2", err)
+		return
+	}
+	_, err = s.FindAccountByID(1)
+	if err != nil {
+		t.Errorf("ImportAccounts(): records before the malformed line must be imported, error = %v", err)
+		return
+	}
+
+	err = s.ImportAccounts(strings.NewReader("1;+992000000001;100\n"), WithMergeStrategy(MergeFailOnConflict))
+	if !errors.Is(err, ErrImportConflict) {
+		t.Errorf("ImportAccounts(): error = %v, want %v", err, ErrImportConflict)
+		return
+	}
+}
+
+func TestService_ExportAccountsTo_success(t *testing.T) {
+	s := newTestService()
+	account, err := s.addAccountWithBalance("+992928885522", 1_000_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	payment, err := s.Pay(account.ID, 100_00, "auto;car")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	favorite, err := s.FavoritePayment(payment.ID, "car")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	imported := newTestService()
+	for name, pair := range map[string]struct {
+		export     func(io.Writer, ...ExportOption) error
+		importFrom func(io.Reader, ...ImportOption) error
+	}{
+		"accounts":  {s.ExportAccountsTo, imported.ImportAccounts},
+		"payments":  {s.ExportPaymentsTo, imported.ImportPayments},
+		"favorites": {s.ExportFavoritesTo, imported.ImportFavorites},
+	} {
+		buf := &bytes.Buffer{}
+		writer := gzip.NewWriter(buf)
+		err = pair.export(writer)
+		if err == nil {
+			err = writer.Close()
+		}
+		if err != nil {
+			t.Errorf("Export %s: error = %v", name, err)
+			return
+		}
+		reader, err := gzip.NewReader(buf)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		err = pair.importFrom(reader)
+		if err != nil {
+			t.Errorf("Import %s: error = %v", name, err)
+			return
+		}
+	}
+	got, err := imported.FindPaymentByID(payment.ID)
+	if err != nil || got.Category != "auto;car" {
+		t.Errorf("ExportPaymentsTo(): payment = %v, error = %v", got, err)
+		return
+	}
+	_, err = imported.FindFavoriteByID(favorite.ID)
+	if err != nil {
+		t.Errorf("ExportFavoritesTo(): error = %v", err)
+		return
+	}
+}
+
+func TestService_ExportAccountsTo_fail(t *testing.T) {
+	s := newTestService()
+	_, err := s.RegisterAccount("+992928885522")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	want := errors.New("closed")
+	reader, writer := io.Pipe()
+	reader.CloseWithError(want)
+	err = s.ExportAccountsTo(writer)
+	if err != want {
+		t.Errorf("ExportAccountsTo(): error = %v, want %v", err, want)
+		return
+	}
+}
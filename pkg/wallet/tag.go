@@ -0,0 +1,58 @@
+package wallet
+
+import (
+	"strings"
+
+	"github.com/sidalsoft/wallet/pkg/types"
+)
+
+// TagPayment добавляет платежу метки tags, например "vacation" или "business".
+// Метки, которые у платежа уже есть, пропускаются. Метка не может быть пустой
+// и содержать запятую
+func (s *Service) TagPayment(paymentID string, tags ...string) (err error) {
+	defer s.audit("TagPayment", &err, "paymentID", paymentID, "tags", strings.Join(tags, ","))
+	for _, tag := range tags {
+		if tag == "" || strings.Contains(tag, ",") {
+			return ErrInvalidTag
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	payment, err := s.findPaymentByID(paymentID)
+	if err != nil {
+		return err
+	}
+	for _, tag := range tags {
+		if !hasTag(payment, tag) {
+			payment.Tags = append(payment.Tags, tag)
+		}
+	}
+	s.markPayment(payment.ID)
+	return nil
+}
+
+// FindPaymentsByTag возвращает платежи всех счетов с меткой tag в порядке создания
+func (s *Service) FindPaymentsByTag(tag string) ([]types.Payment, error) {
+	if tag == "" {
+		return nil, ErrInvalidTag
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var payments []types.Payment
+	for _, payment := range s.payments {
+		if hasTag(payment, tag) {
+			copied, _ := copyPayment(payment, nil)
+			payments = append(payments, *copied)
+		}
+	}
+	return payments, nil
+}
+
+func hasTag(payment *types.Payment, tag string) bool {
+	for _, t := range payment.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+package wallet
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestService_TagPayment_success(t *testing.T) {
+	s := newTestService()
+	_, payments, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	payment := payments[0]
+	err = s.TagPayment(payment.ID, "vacation", "business")
+	if err != nil {
+		t.Errorf("TagPayment(): error = %v", err)
+		return
+	}
+	err = s.TagPayment(payment.ID, "vacation")
+	if err != nil {
+		t.Errorf("TagPayment(): error = %v", err)
+		return
+	}
+
+	dir := t.TempDir()
+	err = s.Export(dir)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	imported := newTestService()
+	err = imported.Import(dir)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	found, err := imported.FindPaymentsByTag("business")
+	if err != nil || len(found) != 1 || found[0].ID != payment.ID || len(found[0].Tags) != 2 {
+		t.Errorf("FindPaymentsByTag(): payments = %v, error = %v", found, err)
+		return
+	}
+	found, err = imported.FindPaymentsByTag("food")
+	if err != nil || len(found) != 0 {
+		t.Errorf("FindPaymentsByTag(): payments = %v, error = %v", found, err)
+		return
+	}
+}
+
+func TestService_TagPayment_fail(t *testing.T) {
+	s := newTestService()
+	_, payments, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = s.TagPayment(payments[0].ID, "a,b")
+	if !errors.Is(err, ErrInvalidTag) {
+		t.Errorf("TagPayment(): must return ErrInvalidTag, returned = %v", err)
+		return
+	}
+	err = s.TagPayment("unknown", "vacation")
+	if !errors.Is(err, ErrPaymentNotFound) {
+		t.Errorf("TagPayment(): must return ErrPaymentNotFound, returned = %v", err)
+		return
+	}
+}
@@ -0,0 +1,170 @@
+package wallet
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"text/template/parse"
+)
+
+// MessageKind вид исходящего сообщения клиенту
+type MessageKind string
+
+// Виды сообщений, для которых регистрируются шаблоны
+const (
+	MessagePaymentConfirmation MessageKind = "PaymentConfirmation"
+	MessageOTP                 MessageKind = "OTP"
+	MessageLowBalance          MessageKind = "LowBalance"
+)
+
+// DefaultLanguage - язык, шаблон которого используется, если для запрошенного языка шаблона нет
+const DefaultLanguage = "ru"
+
+// messageVariable описывает переменную шаблона: required - шаблон обязан ее использовать
+type messageVariable struct {
+	name     string
+	required bool
+}
+
+var messageVariables = map[MessageKind][]messageVariable{
+	MessagePaymentConfirmation: {{"Phone", false}, {"Amount", true}, {"Category", false}, {"PaymentID", false}},
+	MessageOTP:                 {{"Phone", false}, {"Code", true}},
+	MessageLowBalance:          {{"Phone", false}, {"Balance", true}, {"Currency", false}},
+}
+
+// TemplateError возвращается RegisterTemplate, если шаблон не разбирается,
+// использует неизвестную переменную или не использует обязательную
+type TemplateError struct {
+	Kind     MessageKind
+	Language string
+	Err      error
+}
+
+func (e *TemplateError) Error() string {
+	return fmt.Sprintf("template %s/%s: %v", e.Kind, e.Language, e.Err)
+}
+
+func (e *TemplateError) Unwrap() error {
+	return e.Err
+}
+
+// MessageTemplates - реестр шаблонов сообщений по видам и языкам. Шаблоны используют
+// синтаксис text/template с переменными вида {{.Amount}} и проверяются при регистрации,
+// поэтому ошибка в настройках обнаруживается при загрузке, а не при отправке
+type MessageTemplates struct {
+	mu        sync.RWMutex
+	templates map[MessageKind]map[string]*template.Template
+}
+
+func NewMessageTemplates() *MessageTemplates {
+	return &MessageTemplates{templates: make(map[MessageKind]map[string]*template.Template)}
+}
+
+// RegisterTemplate разбирает и проверяет шаблон вида kind для языка language,
+// заменяя ранее зарегистрированный
+func (m *MessageTemplates) RegisterTemplate(kind MessageKind, language string, text string) error {
+	variables, ok := messageVariables[kind]
+	if !ok {
+		return ErrUnknownMessage
+	}
+	fail := func(err error) error {
+		return &TemplateError{Kind: kind, Language: language, Err: err}
+	}
+	t, err := template.New(string(kind) + "/" + language).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return fail(err)
+	}
+	used := make(map[string]bool)
+	for _, t := range t.Templates() {
+		if t.Tree != nil {
+			collectFields(t.Tree.Root, used)
+		}
+	}
+	known := make(map[string]bool)
+	for _, variable := range variables {
+		known[variable.name] = true
+		if variable.required && !used[variable.name] {
+			return fail(fmt.Errorf("%w: required variable %s not used", ErrTemplateVariable, variable.name))
+		}
+	}
+	var unknown []string
+	for name := range used {
+		if !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fail(fmt.Errorf("%w: unknown variables %s", ErrTemplateVariable, strings.Join(unknown, ", ")))
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.templates[kind] == nil {
+		m.templates[kind] = make(map[string]*template.Template)
+	}
+	m.templates[kind][language] = t
+	return nil
+}
+
+// Render подставляет vars в шаблон вида kind для языка language или DefaultLanguage.
+// Переменные, которых нет в vars, возвращают ошибку
+func (m *MessageTemplates) Render(kind MessageKind, language string, vars map[string]interface{}) (string, error) {
+	m.mu.RLock()
+	t, ok := m.templates[kind][language]
+	if !ok {
+		t, ok = m.templates[kind][DefaultLanguage]
+	}
+	m.mu.RUnlock()
+	if !ok {
+		return "", ErrTemplateNotFound
+	}
+	text := strings.Builder{}
+	err := t.Execute(&text, vars)
+	if err != nil {
+		return "", err
+	}
+	return text.String(), nil
+}
+
+// collectFields собирает имена полей верхнего уровня ({{.Name}}), используемых в шаблоне
+func collectFields(node parse.Node, used map[string]bool) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			collectFields(child, used)
+		}
+	case *parse.ActionNode:
+		collectFields(n.Pipe, used)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			collectFields(cmd, used)
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			collectFields(arg, used)
+		}
+	case *parse.FieldNode:
+		used[n.Ident[0]] = true
+	case *parse.IfNode:
+		collectFields(&n.BranchNode, used)
+	case *parse.RangeNode:
+		collectFields(&n.BranchNode, used)
+	case *parse.WithNode:
+		collectFields(&n.BranchNode, used)
+	case *parse.BranchNode:
+		collectFields(n.Pipe, used)
+		collectFields(n.List, used)
+		collectFields(n.ElseList, used)
+	case *parse.TemplateNode:
+		collectFields(n.Pipe, used)
+	}
+}
@@ -0,0 +1,69 @@
+package wallet
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMessageTemplates_Render_success(t *testing.T) {
+	m := NewMessageTemplates()
+	err := m.RegisterTemplate(MessageLowBalance, "ru", "На счете {{.Phone}} осталось {{.Balance}} {{.Currency}}")
+	if err != nil {
+		t.Errorf("RegisterTemplate(): error = %v", err)
+		return
+	}
+	err = m.RegisterTemplate(MessageLowBalance, "en", "{{if .Phone}}{{.Phone}}: {{end}}low balance {{.Balance}}")
+	if err != nil {
+		t.Errorf("RegisterTemplate(): error = %v", err)
+		return
+	}
+
+	vars := map[string]interface{}{"Phone": "+992000000001", "Balance": 10, "Currency": "TJS"}
+	text, err := m.Render(MessageLowBalance, "en", vars)
+	if err != nil || text != "+992000000001: low balance 10" {
+		t.Errorf("Render(): text = %q, error = %v", text, err)
+		return
+	}
+	text, err = m.Render(MessageLowBalance, "tg", vars)
+	if err != nil || text != "На счете +992000000001 осталось 10 TJS" {
+		t.Errorf("Render(): must fall back to %s, text = %q, error = %v", DefaultLanguage, text, err)
+		return
+	}
+}
+
+func TestMessageTemplates_RegisterTemplate_fail(t *testing.T) {
+	m := NewMessageTemplates()
+	tests := []struct {
+		kind MessageKind
+		text string
+		want error
+	}{
+		{MessageOTP, "Код: {{.Cod}}", ErrTemplateVariable},
+		{MessageOTP, "Код: {{.Code}}, сумма {{.Amount}}", ErrTemplateVariable},
+		{MessagePaymentConfirmation, "Платеж {{.Amount", nil},
+		{"Promo", "Скидка", ErrUnknownMessage},
+	}
+	for _, tt := range tests {
+		err := m.RegisterTemplate(tt.kind, "ru", tt.text)
+		if err == nil || tt.want != nil && !errors.Is(err, tt.want) {
+			t.Errorf("RegisterTemplate(%q): error = %v, want %v", tt.text, err, tt.want)
+			return
+		}
+	}
+	_, err := m.Render(MessageOTP, "ru", nil)
+	if !errors.Is(err, ErrTemplateNotFound) {
+		t.Errorf("Render(): error = %v, want %v", err, ErrTemplateNotFound)
+		return
+	}
+
+	err = m.RegisterTemplate(MessageOTP, "ru", "Код: {{.Code}}")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, err = m.Render(MessageOTP, "ru", map[string]interface{}{"Phone": "+992000000001"})
+	if err == nil {
+		t.Errorf("Render(): missing variable must fail")
+		return
+	}
+}
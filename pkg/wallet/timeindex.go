@@ -0,0 +1,119 @@
+package wallet
+
+import (
+	"sort"
+	"time"
+
+	"github.com/sidalsoft/wallet/pkg/types"
+)
+
+// paymentTimeIndex - платежи каждого счета, упорядоченные по времени создания.
+// Новые платежи добавляются в индекс, а массовые изменения платежей (импорт,
+// восстановление, очистка, откат пакета) сбрасывают его, и он строится заново
+// при следующем запросе
+type paymentTimeIndex struct {
+	built    bool
+	accounts map[int64][]*types.Payment
+}
+
+// add добавляет новый платеж, вызывается после добавления в s.payments
+func (i *paymentTimeIndex) add(payment *types.Payment) {
+	if !i.built {
+		return
+	}
+	payments := i.accounts[payment.AccountID]
+	n := sort.Search(len(payments), func(j int) bool {
+		return payments[j].Created.After(payment.Created)
+	})
+	payments = append(payments, nil)
+	copy(payments[n+1:], payments[n:])
+	payments[n] = payment
+	i.accounts[payment.AccountID] = payments
+}
+
+func (i *paymentTimeIndex) reset() {
+	i.built = false
+	i.accounts = nil
+}
+
+func (i *paymentTimeIndex) build(payments []*types.Payment) {
+	i.accounts = make(map[int64][]*types.Payment)
+	for _, payment := range payments {
+		i.accounts[payment.AccountID] = append(i.accounts[payment.AccountID], payment)
+	}
+	for _, list := range i.accounts {
+		sort.SliceStable(list, func(a, b int) bool {
+			return list[a].Created.Before(list[b].Created)
+		})
+	}
+	i.built = true
+}
+
+// between возвращает платежи счета, созданные в [from, to), в порядке создания
+func (i *paymentTimeIndex) between(accountID int64, from, to time.Time) []*types.Payment {
+	payments := i.accounts[accountID]
+	start := sort.Search(len(payments), func(j int) bool {
+		return !payments[j].Created.Before(from)
+	})
+	end := sort.Search(len(payments), func(j int) bool {
+		return !payments[j].Created.Before(to)
+	})
+	if end < start {
+		end = start
+	}
+	return payments[start:end]
+}
+
+// PaymentsBetween возвращает платежи счета, созданные в [from, to), в порядке создания.
+// Платежи выбираются по индексу времени без просмотра остальных платежей
+func (s *Service) PaymentsBetween(accountID int64, from, to time.Time) ([]types.Payment, error) {
+	if to.Before(from) {
+		return nil, ErrInvalidPeriod
+	}
+	s.buildPaymentTimes()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, err := s.findAccountByID(accountID)
+	if err != nil {
+		return nil, err
+	}
+	var payments []types.Payment
+	for _, payment := range s.paymentsBetween(accountID, from, to) {
+		payments = append(payments, *payment)
+	}
+	return payments, nil
+}
+
+// buildPaymentTimes строит индекс времени, если он сброшен. Вызывается без блокировки
+// перед запросом под блокировкой на чтение
+func (s *Service) buildPaymentTimes() {
+	s.mu.RLock()
+	built := s.paymentTimes.built
+	s.mu.RUnlock()
+	if built {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.paymentTimes.built {
+		s.paymentTimes.build(s.payments)
+	}
+}
+
+// paymentsBetween выбирает платежи счета за [from, to) по индексу времени. Если индекс
+// успели сбросить после buildPaymentTimes, платежи выбираются просмотром всех платежей
+func (s *Service) paymentsBetween(accountID int64, from, to time.Time) []*types.Payment {
+	if s.paymentTimes.built {
+		return s.paymentTimes.between(accountID, from, to)
+	}
+	var payments []*types.Payment
+	for _, payment := range s.payments {
+		if payment.AccountID == accountID && (Period{From: from, To: to}).Contains(payment.Created) {
+			payments = append(payments, payment)
+		}
+	}
+	sort.SliceStable(payments, func(i, j int) bool {
+		return payments[i].Created.Before(payments[j].Created)
+	})
+	return payments
+}
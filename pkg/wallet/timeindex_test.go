@@ -0,0 +1,70 @@
+package wallet
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestService_PaymentsBetween_success(t *testing.T) {
+	s := newTestService()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	account, err := s.addAccountWithBalance("+992928885522", 10_000_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	other, err := s.addAccountWithBalance("+992000000001", 10_000_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	for i := 0; i < 3; i++ {
+		_, err = s.Pay(account.ID, 100_00, "auto")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		_, err = s.Pay(other.ID, 100_00, "auto")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		now = now.Add(time.Hour)
+	}
+
+	payments, err := s.PaymentsBetween(account.ID, now.Add(-2*time.Hour), now)
+	if err != nil || len(payments) != 2 || payments[0].AccountID != account.ID ||
+		!payments[0].Created.Equal(now.Add(-2*time.Hour)) {
+		t.Errorf("PaymentsBetween(): payments = %v, error = %v", payments, err)
+		return
+	}
+
+	// платеж после построения индекса попадает в него
+	created, err := s.Pay(account.ID, 100_00, "auto")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	payments, err = s.PaymentsBetween(account.ID, now, now.Add(time.Hour))
+	if err != nil || len(payments) != 1 || payments[0].ID != created.ID {
+		t.Errorf("PaymentsBetween(): payments = %v, error = %v", payments, err)
+		return
+	}
+}
+
+func TestService_PaymentsBetween_fail(t *testing.T) {
+	s := newTestService()
+	now := time.Now()
+	_, err := s.PaymentsBetween(1, now, now.Add(-time.Hour))
+	if !errors.Is(err, ErrInvalidPeriod) {
+		t.Errorf("PaymentsBetween(): error = %v, want %v", err, ErrInvalidPeriod)
+		return
+	}
+	_, err = s.PaymentsBetween(1, now.Add(-time.Hour), now)
+	if !errors.Is(err, ErrAccountNotFound) {
+		t.Errorf("PaymentsBetween(): error = %v, want %v", err, ErrAccountNotFound)
+		return
+	}
+}
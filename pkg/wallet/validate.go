@@ -0,0 +1,253 @@
+package wallet
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sidalsoft/wallet/pkg/types"
+)
+
+// DumpProblemKind представляет собой вид ошибки в дампе
+type DumpProblemKind string
+
+// Виды ошибок в дампе
+const (
+	DumpProblemMalformed DumpProblemKind = "MALFORMED"
+	DumpProblemDuplicate DumpProblemKind = "DUPLICATE"
+	DumpProblemReference DumpProblemKind = "REFERENCE"
+)
+
+// DumpProblem описывает ошибку в строке дампа. Line - номер строки после заголовка версии
+type DumpProblem struct {
+	Dump    string
+	Line    int
+	Kind    DumpProblemKind
+	Message string
+}
+
+// DumpReport - результат ValidateDump. Records - число записей в каждом дампе
+type DumpReport struct {
+	Records  map[string]int
+	Problems []DumpProblem
+}
+
+// Valid сообщает, что в дампе нет ошибок
+func (r DumpReport) Valid() bool {
+	return len(r.Problems) == 0
+}
+
+type dumpField struct {
+	name  string
+	valid func(value string) bool
+}
+
+var dumpSchemas = map[string][]dumpField{
+	"accounts": {
+		{"id", isPositiveInt}, {"phone", isNotEmpty}, {"balance", isInt},
+		{"currency", isCurrency}, {"status", isOneOf(types.AccountStatusActive, types.AccountStatusFrozen, types.AccountStatusClosed)},
+		{"creditLimit", isInt}, {"overdrawnSince", isInt}, {"registered", isInt}, {"points", isInt},
+		{"type", isOneOf(types.AccountTypePersonal, types.AccountTypeMerchant, types.AccountTypeSystem)}, {"wallet", isAny},
+	},
+	"payments": {
+		{"id", isNotEmpty}, {"accountID", isPositiveInt}, {"amount", isInt},
+		{"category", isAny}, {"status", isOneOf(types.PaymentStatusOk, types.PaymentStatusFail, types.PaymentStatusInProgress)},
+		{"currency", isCurrency}, {"fee", isInt}, {"created", isInt},
+		{"inferredCategory", isAny}, {"originalCategory", isAny},
+		{"comment", isAny}, {"metadata", isMetadata}, {"tags", isAny}, {"merchantID", isAny},
+	},
+	"favorites": {
+		{"id", isNotEmpty}, {"accountID", isPositiveInt}, {"name", isAny},
+		{"amount", isInt}, {"category", isAny}, {"deleted", isInt}, {"group", isAny},
+	},
+	"scheduled": {
+		{"id", isNotEmpty}, {"accountID", isPositiveInt}, {"amount", isInt},
+		{"category", isAny}, {"schedule", isSchedule}, {"nextRun", isInt}, {"deleted", isInt},
+	},
+	"operations": {
+		{"id", isNotEmpty}, {"kind", isNotEmpty}, {"accountID", isPositiveInt},
+		{"paymentID", isAny}, {"payload", isBase64},
+	},
+	"merchants": {
+		{"id", isNotEmpty}, {"name", isNotEmpty}, {"category", isNotEmpty}, {"accountID", isPositiveInt},
+	},
+}
+
+// ValidateDump разбирает дампы каталога dir так же, как Import, но не меняет состояние
+// сервиса и сообщает обо всех ошибочных строках, повторяющихся ID и ссылках на
+// отсутствующие счета и платежи. Ссылки на счета и платежи сервиса считаются верными.
+// Дампы ExportIncremental содержат повторяющиеся ID по построению: Import применяет
+// последнюю строку. Ошибка возвращается, только если дамп нельзя прочитать
+func (s *Service) ValidateDump(dir string) (DumpReport, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	report := DumpReport{Records: make(map[string]int)}
+	err := verifyManifest(dir)
+	if err != nil {
+		return report, err
+	}
+
+	ids := make(map[string]map[string]int)
+	var records [][]string
+	var lines []int
+	var names []string
+	for _, name := range dumpNames {
+		data, err := s.readDump(dir, name)
+		if err != nil {
+			return report, err
+		}
+		ids[name] = make(map[string]int)
+		phones := make(map[string]string)
+		schema := dumpSchemas[name]
+		for i, line := range strings.Split(data, "\n") {
+			if line == "" {
+				continue
+			}
+			problem := func(kind DumpProblemKind, format string, args ...interface{}) {
+				report.Problems = append(report.Problems, DumpProblem{
+					Dump:    name,
+					Line:    i + 1,
+					Kind:    kind,
+					Message: fmt.Sprintf(format, args...),
+				})
+			}
+			report.Records[name]++
+			fields := types.SplitFields(line)
+			if len(fields) != len(schema) {
+				problem(DumpProblemMalformed, "%d fields, want %d", len(fields), len(schema))
+				continue
+			}
+			malformed := false
+			for j, field := range schema {
+				if !field.valid(fields[j]) {
+					problem(DumpProblemMalformed, "invalid %s %q", field.name, fields[j])
+					malformed = true
+				}
+			}
+			if malformed {
+				continue
+			}
+			if first, ok := ids[name][fields[0]]; ok {
+				problem(DumpProblemDuplicate, "duplicate id %s, first on line %d", fields[0], first)
+			} else {
+				ids[name][fields[0]] = i + 1
+			}
+			if name == "accounts" {
+				// у одного телефона может быть несколько кошельков с разными именами
+				key := fields[1] + ";" + fields[10]
+				if other, ok := phones[key]; ok && other != fields[0] {
+					problem(DumpProblemDuplicate, "phone %s already used by account %s", fields[1], other)
+				}
+				phones[key] = fields[0]
+			}
+			records = append(records, fields)
+			lines = append(lines, i+1)
+			names = append(names, name)
+		}
+	}
+
+	accountExists := func(id string) bool {
+		if _, ok := ids["accounts"][id]; ok {
+			return true
+		}
+		accountID, _ := strconv.ParseInt(id, 10, 64)
+		_, err := s.findAccountByID(accountID)
+		return err == nil
+	}
+	paymentExists := func(id string) bool {
+		if _, ok := ids["payments"][id]; ok {
+			return true
+		}
+		_, err := s.findPaymentByID(id)
+		return err == nil
+	}
+	merchantExists := func(id string) bool {
+		if _, ok := ids["merchants"][id]; ok {
+			return true
+		}
+		_, err := s.findMerchantByID(id)
+		return err == nil
+	}
+	for i, fields := range records {
+		name := names[i]
+		problem := func(format string, args ...interface{}) {
+			report.Problems = append(report.Problems, DumpProblem{
+				Dump:    name,
+				Line:    lines[i],
+				Kind:    DumpProblemReference,
+				Message: fmt.Sprintf(format, args...),
+			})
+		}
+		switch name {
+		case "payments", "favorites", "scheduled":
+			if !accountExists(fields[1]) {
+				problem("unknown account %s", fields[1])
+			}
+			if name == "payments" && fields[13] != "" && !merchantExists(fields[13]) {
+				problem("unknown merchant %s", fields[13])
+			}
+		case "merchants":
+			if !accountExists(fields[3]) {
+				problem("unknown account %s", fields[3])
+			}
+		case "operations":
+			if !accountExists(fields[2]) {
+				problem("unknown account %s", fields[2])
+			}
+			if fields[3] != "" && !paymentExists(fields[3]) {
+				problem("unknown payment %s", fields[3])
+			}
+		}
+	}
+	return report, nil
+}
+
+func isAny(string) bool {
+	return true
+}
+
+func isNotEmpty(value string) bool {
+	return value != ""
+}
+
+func isInt(value string) bool {
+	_, err := strconv.ParseInt(value, 10, 64)
+	return err == nil
+}
+
+func isPositiveInt(value string) bool {
+	n, err := strconv.ParseInt(value, 10, 64)
+	return err == nil && n > 0
+}
+
+func isBase64(value string) bool {
+	_, err := base64.StdEncoding.DecodeString(value)
+	return err == nil
+}
+
+func isMetadata(value string) bool {
+	_, err := types.SplitMetadata(value)
+	return err == nil
+}
+
+func isCurrency(value string) bool {
+	return types.Currency(value).Known()
+}
+
+func isSchedule(value string) bool {
+	_, err := nextRun(types.Schedule(value), time.Time{})
+	return err == nil
+}
+
+func isOneOf(values ...interface{}) func(string) bool {
+	return func(value string) bool {
+		for _, v := range values {
+			if fmt.Sprint(v) == value {
+				return true
+			}
+		}
+		return false
+	}
+}
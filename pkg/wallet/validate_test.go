@@ -0,0 +1,82 @@
+package wallet
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestService_ValidateDump_success(t *testing.T) {
+	dirs, err := ioutil.ReadDir("testdata/compat")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	for _, dir := range dirs {
+		report, err := newTestService().ValidateDump(filepath.Join("testdata/compat", dir.Name()))
+		if err != nil || !report.Valid() {
+			t.Errorf("ValidateDump(%s): problems = %v, error = %v", dir.Name(), report.Problems, err)
+		}
+	}
+
+	s := newTestService()
+	_, _, err = s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	dir := t.TempDir()
+	err = s.Export(dir)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	report, err := newTestService().ValidateDump(dir)
+	if err != nil || !report.Valid() || report.Records["accounts"] != 1 || report.Records["payments"] != 1 {
+		t.Errorf("ValidateDump(): report = %v, error = %v", report, err)
+		return
+	}
+}
+
+func TestService_ValidateDump_problems(t *testing.T) {
+	dir := t.TempDir()
+	dumps := map[string]string{
+		"accounts": "1;+992000000001;100;TJS;ACTIVE;0;0;0\n" +
+			"1;+992000000002;100;TJS;ACTIVE;0;0;0\n" +
+			"2;+992000000001;100;TJS;ACTIVE;0;0;0\n" +
+			"3;+992000000003;100;TJS;ACTIVE;0;0;0;1\n",
+		"payments": "p1;1;100;auto;OK;TJS;0;0;;\n" +
+			"p2;7;100;auto;DONE;TJS;0;0;;\n" +
+			"p3;7;100;auto;OK;TJS;0;0;;\n",
+	}
+	for name, data := range dumps {
+		err := ioutil.WriteFile(dir+"/"+name+".dump", []byte(data), 0666)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	s := newTestService()
+	report, err := s.ValidateDump(dir)
+	if err != nil {
+		t.Errorf("ValidateDump(): error = %v", err)
+		return
+	}
+	want := []DumpProblem{
+		{Dump: "accounts", Line: 2, Kind: DumpProblemDuplicate, Message: "duplicate id 1, first on line 1"},
+		{Dump: "accounts", Line: 3, Kind: DumpProblemDuplicate, Message: "phone +992000000001 already used by account 1"},
+		{Dump: "accounts", Line: 4, Kind: DumpProblemMalformed, Message: "12 fields, want 11"},
+		{Dump: "payments", Line: 2, Kind: DumpProblemMalformed, Message: `invalid status "DONE"`},
+		{Dump: "payments", Line: 3, Kind: DumpProblemReference, Message: "unknown account 7"},
+	}
+	if !reflect.DeepEqual(report.Problems, want) {
+		t.Errorf("ValidateDump(): problems = %v, want %v", report.Problems, want)
+		return
+	}
+	if len(s.accounts) != 0 {
+		t.Errorf("ValidateDump(): must not change service, accounts = %v", s.accounts)
+		return
+	}
+}
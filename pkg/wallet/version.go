@@ -0,0 +1,218 @@
+package wallet
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/sidalsoft/wallet/pkg/types"
+)
+
+// dumpVersion - версия формата дампов, которые пишет Export.
+// Дампы без заголовка записаны до появления версий и имеют версию 0
+const dumpVersion = 11
+
+const dumpHeader = "#version "
+
+// dumpMigration переводит строки дампа name из своей версии в следующую
+type dumpMigration func(name string, lines []string) []string
+
+// dumpMigrations[v] переводит дамп версии v в версию v+1. При изменении формата
+// увеличивается dumpVersion и добавляется миграция с предыдущей версии
+var dumpMigrations = map[int]dumpMigration{
+	0:  migrateDumpV0,
+	1:  migrateDumpV1,
+	2:  migrateDumpV2,
+	3:  migrateDumpV3,
+	4:  migrateDumpV4,
+	5:  migrateDumpV5,
+	6:  migrateDumpV6,
+	7:  migrateDumpV7,
+	8:  migrateDumpV8,
+	9:  migrateDumpV9,
+	10: migrateDumpV10,
+}
+
+func addDumpHeader(data string) string {
+	return dumpHeader + strconv.Itoa(dumpVersion) + "\n" + data
+}
+
+// migrateDump снимает заголовок версии и переводит дамп в текущую версию
+func migrateDump(name string, data string) (string, error) {
+	version := 0
+	if strings.HasPrefix(data, dumpHeader) {
+		header := data
+		if i := strings.IndexByte(data, '\n'); i >= 0 {
+			header, data = data[:i], data[i+1:]
+		} else {
+			data = ""
+		}
+		v, err := parseDumpHeader(header)
+		if err != nil {
+			return "", err
+		}
+		version = v
+	}
+	if version == dumpVersion {
+		return data, nil
+	}
+
+	var lines []string
+	for _, line := range strings.Split(data, "\n") {
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	lines = migrateLines(name, version, lines)
+	if len(lines) == 0 {
+		return "", nil
+	}
+	return strings.Join(lines, "\n") + "\n", nil
+}
+
+// parseDumpHeader возвращает версию из заголовка дампа
+func parseDumpHeader(header string) (int, error) {
+	version, err := strconv.Atoi(strings.TrimPrefix(header, dumpHeader))
+	if err != nil || version < 0 || version > dumpVersion {
+		return 0, ErrUnsupportedDumpVersion
+	}
+	return version, nil
+}
+
+// migrateLines переводит строки дампа name из версии version в текущую
+func migrateLines(name string, version int, lines []string) []string {
+	for ; version < dumpVersion; version++ {
+		lines = dumpMigrations[version](name, lines)
+	}
+	return lines
+}
+
+// migrateDumpV0 дополняет строки дампов без версии полями, которые добавлялись
+// в конец строки, значениями по умолчанию
+func migrateDumpV0(name string, lines []string) []string {
+	switch name {
+	case "accounts":
+		return padFields(lines, []string{"", "", "0", "TJS", "ACTIVE", "0", "-62135596800", "-62135596800"})
+	case "payments":
+		return padFields(lines, []string{"", "", "0", "", "", "TJS", "0", "-62135596800", ""})
+	}
+	return lines
+}
+
+// migrateDumpV1 добавляет избранному и запланированным платежам время удаления
+func migrateDumpV1(name string, lines []string) []string {
+	switch name {
+	case "favorites":
+		return padFields(lines, []string{"", "", "", "0", "", "-62135596800"})
+	case "scheduled":
+		return padFields(lines, []string{"", "", "0", "", "", "-62135596800", "-62135596800"})
+	}
+	return lines
+}
+
+// migrateDumpV2 добавляет платежам исходную категорию
+func migrateDumpV2(name string, lines []string) []string {
+	if name == "payments" {
+		return padFields(lines, []string{"", "", "0", "", "", "TJS", "0", "-62135596800", "", ""})
+	}
+	return lines
+}
+
+// migrateDumpV3 экранирует поля: до версии 4 разделители внутри полей не экранировались
+func migrateDumpV3(name string, lines []string) []string {
+	for i, line := range lines {
+		fields := strings.Split(line, ";")
+		values := make([]interface{}, len(fields))
+		for j, field := range fields {
+			values[j] = field
+		}
+		lines[i] = types.JoinFields(values...)
+	}
+	return lines
+}
+
+// migrateDumpV4 добавляет избранному папку
+func migrateDumpV4(name string, lines []string) []string {
+	if name == "favorites" {
+		for i := range lines {
+			lines[i] += ";"
+		}
+	}
+	return lines
+}
+
+// migrateDumpV5 добавляет платежам комментарий и метаданные
+func migrateDumpV5(name string, lines []string) []string {
+	if name == "payments" {
+		for i := range lines {
+			lines[i] += ";;"
+		}
+	}
+	return lines
+}
+
+// migrateDumpV6 добавляет платежам метки
+func migrateDumpV6(name string, lines []string) []string {
+	if name == "payments" {
+		for i := range lines {
+			lines[i] += ";"
+		}
+	}
+	return lines
+}
+
+// migrateDumpV7 добавляет платежам получателя. Дамп получателей появился
+// в версии 8, в старых версиях его нет
+func migrateDumpV7(name string, lines []string) []string {
+	if name == "payments" {
+		for i := range lines {
+			lines[i] += ";"
+		}
+	}
+	return lines
+}
+
+// migrateDumpV8 добавляет счетам баллы лояльности
+func migrateDumpV8(name string, lines []string) []string {
+	if name == "accounts" {
+		for i := range lines {
+			lines[i] += ";0"
+		}
+	}
+	return lines
+}
+
+// migrateDumpV9 добавляет счетам тип: до версии 10 все счета были счетами пользователей
+func migrateDumpV9(name string, lines []string) []string {
+	if name == "accounts" {
+		for i := range lines {
+			lines[i] += ";" + string(types.AccountTypePersonal)
+		}
+	}
+	return lines
+}
+
+// migrateDumpV10 добавляет счетам имя кошелька: до версии 11 у владельца был один,
+// основной кошелек
+func migrateDumpV10(name string, lines []string) []string {
+	if name == "accounts" {
+		for i := range lines {
+			lines[i] += ";"
+		}
+	}
+	return lines
+}
+
+// padFields дополняет строки версий до 4, в которых поля не экранировались
+func padFields(lines []string, defaults []string) []string {
+	for i, line := range lines {
+		fields := strings.Split(line, ";")
+		if len(fields) < 2 {
+			continue
+		}
+		if len(fields) < len(defaults) {
+			fields = append(fields, defaults[len(fields):]...)
+		}
+		lines[i] = strings.Join(fields, ";")
+	}
+	return lines
+}
@@ -0,0 +1,34 @@
+package wallet
+
+import (
+	"errors"
+	"io/ioutil"
+	"testing"
+)
+
+func TestMigrateDump_v0(t *testing.T) {
+	got, err := migrateDump("accounts", "1;+992900000001;90000\n")
+	if err != nil {
+		t.Errorf("migrateDump(): error = %v", err)
+		return
+	}
+	want := "1;+992900000001;90000;TJS;ACTIVE;0;-62135596800;-62135596800;0;PERSONAL;\n"
+	if got != want {
+		t.Errorf("migrateDump(): got = %q, want = %q", got, want)
+		return
+	}
+}
+
+func TestService_Import_unsupportedVersion(t *testing.T) {
+	dir := t.TempDir()
+	err := ioutil.WriteFile(dir+"/accounts.dump", []byte("#version 999\n1;+992900000001;90000\n"), 0666)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = (&Service{}).Import(dir)
+	if !errors.Is(err, ErrUnsupportedDumpVersion) {
+		t.Errorf("Import(): must return ErrUnsupportedDumpVersion, returned = %v", err)
+		return
+	}
+}
@@ -0,0 +1,270 @@
+package wallet
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"hash/crc32"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+)
+
+// walName - файл журнала упреждающей записи в каталоге снимка
+const walName = "wal.log"
+
+// writeAheadLog дописывает измененные записи в журнал после каждого изменяющего вызова.
+// Строка журнала - "<crc32>;<дамп>;<запись>", при заданном ключе шифрования
+// "<дамп>;<запись>" шифруется и кодируется base64
+type writeAheadLog struct {
+	dir          string
+	compactAfter int
+	mu           sync.Mutex
+	file         *os.File
+	records      int
+	pending      dirtySet
+}
+
+// WithWAL включает журнал упреждающей записи в каталоге dir. Журнал ведется после Recover:
+// каждое изменение записывается в журнал до возврата из метода, а после compactAfter
+// записей (0 - только при импорте и удалении данных) сжимается в снимок - полный Export в dir.
+// Если записать журнал не удалось, метод возвращает ошибку записи, хотя изменение уже
+// применено в памяти: его сохранит снимок при следующей успешной синхронизации
+func WithWAL(dir string, compactAfter int) Option {
+	return func(s *Service) {
+		s.wal = &writeAheadLog{dir: dir, compactAfter: compactAfter}
+	}
+}
+
+// Recover восстанавливает состояние из снимка и журнала в каталоге WithWAL,
+// сжимает их в новый снимок и начинает вести журнал. Недописанная последняя
+// строка журнала, оставшаяся после сбоя, отбрасывается
+func (s *Service) Recover() (err error) {
+	if s.wal == nil {
+		return ErrWALDisabled
+	}
+	w := s.wal
+	defer s.audit("Recover", &err, "dir", w.dir)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file != nil {
+		return nil
+	}
+
+	err = os.MkdirAll(w.dir, 0777)
+	if err != nil {
+		return err
+	}
+	err = verifyManifest(w.dir)
+	if err != nil {
+		return err
+	}
+	dumps := make(map[string]string)
+	for _, name := range dumpNames {
+		dumps[name], err = s.readDump(w.dir, name)
+		if err != nil {
+			return err
+		}
+	}
+	parsed, err := parseDumps(dumps)
+	if err != nil {
+		return err
+	}
+	logged, err := s.readWAL()
+	if err != nil {
+		return err
+	}
+	replayed, err := parseDumps(logged)
+	if err != nil {
+		return err
+	}
+	s.importDumps(parsed, MergeOverwrite)
+	s.importDumps(replayed, MergeOverwrite)
+
+	file, err := os.OpenFile(w.dir+"/"+walName, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0666)
+	if err != nil {
+		return err
+	}
+	w.file = file
+	return s.compactWAL()
+}
+
+// Compact немедленно сжимает журнал в снимок
+func (s *Service) Compact() error {
+	if s.wal == nil {
+		return ErrWALDisabled
+	}
+	s.wal.pending.markFull()
+	return s.syncWAL()
+}
+
+// readWAL разбирает журнал в дампы по именам
+func (s *Service) readWAL() (map[string]string, error) {
+	content, err := ioutil.ReadFile(s.wal.dir + "/" + walName)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	dumps := make(map[string]string)
+	lines := strings.SplitAfter(string(content), "\n")
+	for i, line := range lines {
+		if !strings.HasSuffix(line, "\n") {
+			// запись оборвалась при сбое и не была подтверждена вызывающему
+			break
+		}
+		name, record, err := s.decodeWALRecord(strings.TrimSuffix(line, "\n"))
+		if err != nil {
+			return nil, &CorruptedDumpError{Failed: map[string]string{
+				walName: fmt.Sprintf("line %d: %v", i+1, err),
+			}}
+		}
+		dumps[name] += record + "\n"
+	}
+	return dumps, nil
+}
+
+func (s *Service) encodeWALRecord(name string, record string) (string, error) {
+	payload := name + ";" + record
+	if s.dumpKey != nil {
+		sealed, err := s.seal(walName, payload)
+		if err != nil {
+			return "", err
+		}
+		payload = base64.StdEncoding.EncodeToString([]byte(sealed))
+	}
+	return fmt.Sprintf("%08x;%s\n", crc32.ChecksumIEEE([]byte(payload)), payload), nil
+}
+
+func (s *Service) decodeWALRecord(line string) (string, string, error) {
+	parts := strings.SplitN(line, ";", 2)
+	if len(parts) < 2 || parts[0] != fmt.Sprintf("%08x", crc32.ChecksumIEEE([]byte(parts[1]))) {
+		return "", "", ErrCorruptedDump
+	}
+	payload := parts[1]
+	if s.dumpKey != nil {
+		sealed, err := base64.StdEncoding.DecodeString(payload)
+		if err != nil {
+			return "", "", ErrDumpDecrypt
+		}
+		payload, err = s.open(walName, sealed)
+		if err != nil {
+			return "", "", err
+		}
+	}
+	parts = strings.SplitN(payload, ";", 2)
+	if len(parts) < 2 {
+		return "", "", ErrCorruptedDump
+	}
+	return parts[0], parts[1], nil
+}
+
+// syncWAL дописывает в журнал записи, измененные после предыдущего вызова,
+// и при необходимости сжимает журнал. Вызывается без блокировки сервиса
+func (s *Service) syncWAL() error {
+	w := s.wal
+	if w == nil {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return nil
+	}
+
+	w.pending.mu.Lock()
+	var records []string
+	add := func(name string, record string) {
+		records = append(records, name, record)
+	}
+	for _, account := range s.accounts {
+		if w.pending.accounts[account.ID] {
+			add("accounts", account.ToString())
+		}
+	}
+	for _, payment := range s.payments {
+		if w.pending.payments[payment.ID] {
+			add("payments", payment.ToString())
+		}
+	}
+	for _, favorite := range s.favorites {
+		if w.pending.favorites[favorite.ID] {
+			add("favorites", favorite.ToString())
+		}
+	}
+	for _, scheduled := range s.scheduled {
+		if w.pending.scheduled[scheduled.ID] {
+			add("scheduled", scheduled.ToString())
+		}
+	}
+	for _, operation := range s.operations {
+		if w.pending.operations[operation.ID] {
+			add("operations", operationToString(operation))
+		}
+	}
+	for _, merchant := range s.merchants {
+		if w.pending.merchants[merchant.ID] {
+			add("merchants", merchant.ToString())
+		}
+	}
+	full := w.pending.full
+	w.pending.reset("", false)
+	w.pending.mu.Unlock()
+
+	data := bytes.Buffer{}
+	for i := 0; i < len(records); i += 2 {
+		line, err := s.encodeWALRecord(records[i], records[i+1])
+		if err != nil {
+			w.pending.markFull()
+			return err
+		}
+		data.WriteString(line)
+	}
+	if data.Len() > 0 {
+		_, err := w.file.Write(data.Bytes())
+		if err == nil {
+			err = w.file.Sync()
+		}
+		if err != nil {
+			// строки могли записаться частично, снимок сохранит состояние целиком
+			w.pending.markFull()
+			return err
+		}
+		w.records += len(records) / 2
+	}
+	if full || (w.compactAfter > 0 && w.records >= w.compactAfter) {
+		return s.compactWAL()
+	}
+	return nil
+}
+
+// compactWAL записывает снимок и очищает журнал. Вызывается под блокировкой сервиса
+// и w.mu после записи в журнал всех изменений, поэтому при сбое до очистки журнал
+// повторно применяет к снимку то же состояние. Удаленные записи при этом могут вернуться
+func (s *Service) compactWAL() error {
+	w := s.wal
+	s.dirty.mu.Lock()
+	err := s.exportLocked(w.dir, false, exportConfig{order: OrderInsertion})
+	s.dirty.mu.Unlock()
+	if err != nil {
+		w.pending.markFull()
+		return err
+	}
+	err = w.file.Truncate(0)
+	if err == nil {
+		err = w.file.Sync()
+	}
+	if err != nil {
+		w.pending.markFull()
+		return err
+	}
+	w.records = 0
+	return nil
+}
@@ -0,0 +1,213 @@
+package wallet
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/sidalsoft/wallet/pkg/types"
+)
+
+func TestService_Recover_success(t *testing.T) {
+	dir := t.TempDir()
+	s := NewService(WithWAL(dir, 0))
+	err := s.Recover()
+	if err != nil {
+		t.Errorf("Recover(): error = %v", err)
+		return
+	}
+	account, err := s.RegisterAccount("+992000000001")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = s.Deposit(account.ID, 10_000_00)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	payment, err := s.Pay(account.ID, 1_000_00, "auto")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	favorite, err := s.FavoritePayment(payment.ID, "car")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	logged, err := ioutil.ReadFile(dir + "/" + walName)
+	if err != nil || !strings.Contains(string(logged), payment.ID) {
+		t.Errorf("Recover(): wal must contain the payment, wal = %q, error = %v", logged, err)
+		return
+	}
+
+	// сервис завершился без экспорта, состояние восстанавливается из журнала
+	recovered := NewService(WithWAL(dir, 0))
+	err = recovered.Recover()
+	if err != nil {
+		t.Errorf("Recover(): error = %v", err)
+		return
+	}
+	got, err := recovered.FindAccountByID(account.ID)
+	if err != nil || got.Balance != 9_000_00 {
+		t.Errorf("Recover(): account = %v, error = %v", got, err)
+		return
+	}
+	_, err = recovered.FindFavoriteByID(favorite.ID)
+	if err != nil {
+		t.Errorf("Recover(): favorite must keep its ID, error = %v", err)
+		return
+	}
+	logged, err = ioutil.ReadFile(dir + "/" + walName)
+	if err != nil || len(logged) != 0 {
+		t.Errorf("Recover(): wal must be compacted, wal = %q, error = %v", logged, err)
+		return
+	}
+}
+
+func TestService_Recover_compact(t *testing.T) {
+	dir := t.TempDir()
+	s := NewService(WithWAL(dir, 3))
+	err := s.Recover()
+	if err != nil {
+		t.Errorf("Recover(): error = %v", err)
+		return
+	}
+	account, err := s.RegisterAccount("+992000000001")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	for i := 0; i < 3; i++ {
+		err = s.Deposit(account.ID, 100)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+	}
+	logged, err := ioutil.ReadFile(dir + "/" + walName)
+	if err != nil || strings.Count(string(logged), "\n") != 1 {
+		t.Errorf("Recover(): wal must be compacted after 3 records, wal = %q, error = %v", logged, err)
+		return
+	}
+
+	imported := &Service{}
+	err = imported.Import(dir)
+	if err != nil {
+		t.Errorf("Import(): error = %v", err)
+		return
+	}
+	got, err := imported.FindAccountByID(account.ID)
+	if err != nil || got.Balance != 200 {
+		t.Errorf("Import(): snapshot account = %v, error = %v", got, err)
+		return
+	}
+}
+
+func TestService_Recover_tornRecord(t *testing.T) {
+	dir := t.TempDir()
+	s := NewService(WithWAL(dir, 0), WithEncryptionKey(make([]byte, 32)))
+	err := s.Recover()
+	if err != nil {
+		t.Errorf("Recover(): error = %v", err)
+		return
+	}
+	account, err := s.RegisterAccount("+992000000001")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = s.Deposit(account.ID, 100)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	logged, err := ioutil.ReadFile(dir + "/" + walName)
+	if err != nil || strings.Contains(string(logged), "+992000000001") {
+		t.Errorf("Recover(): wal must be encrypted, wal = %q, error = %v", logged, err)
+		return
+	}
+	err = ioutil.WriteFile(dir+"/"+walName, append(logged, "0000"...), 0666)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	recovered := NewService(WithWAL(dir, 0), WithEncryptionKey(make([]byte, 32)))
+	err = recovered.Recover()
+	if err != nil {
+		t.Errorf("Recover(): error = %v", err)
+		return
+	}
+	got, err := recovered.FindAccountByID(account.ID)
+	if err != nil || got.Balance != 100 {
+		t.Errorf("Recover(): account = %v, error = %v", got, err)
+		return
+	}
+}
+
+func TestService_Recover_fail(t *testing.T) {
+	err := newTestService().Recover()
+	if !errors.Is(err, ErrWALDisabled) {
+		t.Errorf("Recover(): error = %v, want %v", err, ErrWALDisabled)
+		return
+	}
+
+	dir := t.TempDir()
+	err = ioutil.WriteFile(dir+"/"+walName, []byte("00000000;accounts;1;+992000000001;100\n"), 0666)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	s := NewService(WithWAL(dir, 0))
+	err = s.Recover()
+	var corrupted *CorruptedDumpError
+	if !errors.As(err, &corrupted) {
+		t.Errorf("Recover(): error = %v, want *CorruptedDumpError", err)
+		return
+	}
+	_, err = os.Stat(dir + "/accounts.dump")
+	if !os.IsNotExist(err) {
+		t.Errorf("Recover(): snapshot must not be written, error = %v", err)
+		return
+	}
+	_, err = s.FindAccountByPhone(types.Phone("+992000000001"))
+	if !errors.Is(err, ErrAccountNotFound) {
+		t.Errorf("FindAccountByPhone(): error = %v", err)
+		return
+	}
+}
+
+func TestService_WAL_writeFail(t *testing.T) {
+	dir := t.TempDir()
+	s := NewService(WithWAL(dir, 0))
+	err := s.Recover()
+	if err != nil {
+		t.Errorf("Recover(): error = %v", err)
+		return
+	}
+	account, err := s.RegisterAccount("+992000000001")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	// журнал открыт только на чтение, запись в него не удается
+	file, err := os.Open(dir + "/" + walName)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_ = s.wal.file.Close()
+	s.wal.file = file
+	defer file.Close()
+
+	err = s.Deposit(account.ID, 100_00)
+	if err == nil {
+		t.Errorf("Deposit(): must return the WAL error")
+		return
+	}
+}
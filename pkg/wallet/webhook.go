@@ -0,0 +1,152 @@
+package wallet
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sidalsoft/wallet/pkg/types"
+)
+
+// WebhookDispatcher отправляет события сервиса POST-запросами с JSON на заданные адреса.
+// События копятся в очереди и доставляются в фоне после Start
+type WebhookDispatcher struct {
+	urls []string
+	// Client выполняет запросы, по умолчанию http.DefaultClient
+	Client *http.Client
+	// Retries - число повторов после неудачной попытки доставки
+	Retries int
+	// Backoff - пауза перед первым повтором, каждая следующая пауза вдвое длиннее
+	Backoff time.Duration
+	// OnError, если задан, получает ошибку доставки после всех повторов
+	OnError func(url string, err error)
+
+	mu      sync.Mutex
+	queue   []Event
+	pending chan struct{}
+
+	once sync.Once
+	stop chan struct{}
+	done chan struct{}
+}
+
+type webhookPayload struct {
+	Kind    EventKind      `json:"kind"`
+	Time    time.Time      `json:"time"`
+	Account types.Account  `json:"account"`
+	Payment *types.Payment `json:"payment,omitempty"`
+	Delta   types.Money    `json:"delta,omitempty"`
+}
+
+func NewWebhookDispatcher(svc *Service, urls ...string) *WebhookDispatcher {
+	d := &WebhookDispatcher{
+		urls:    urls,
+		Retries: 3,
+		Backoff: time.Second,
+		pending: make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	svc.OnEvent(d.enqueue)
+	return d
+}
+
+// enqueue вызывается под блокировкой сервиса, поэтому только ставит событие в очередь
+func (d *WebhookDispatcher) enqueue(event Event) {
+	d.mu.Lock()
+	d.queue = append(d.queue, event)
+	d.mu.Unlock()
+	select {
+	case d.pending <- struct{}{}:
+	default:
+	}
+}
+
+func (d *WebhookDispatcher) Start() {
+	go func() {
+		defer close(d.done)
+		for {
+			select {
+			case <-d.stop:
+				return
+			case <-d.pending:
+			}
+			d.mu.Lock()
+			events := d.queue
+			d.queue = nil
+			d.mu.Unlock()
+			for _, event := range events {
+				for _, url := range d.urls {
+					if !d.deliver(url, event) {
+						return
+					}
+				}
+			}
+		}
+	}()
+}
+
+// Stop останавливает доставку и ждет завершения текущего запроса.
+// Недоставленные события отбрасываются
+func (d *WebhookDispatcher) Stop() {
+	d.once.Do(func() {
+		close(d.stop)
+	})
+	<-d.done
+}
+
+// deliver отправляет событие с повторами и возвращает false, если доставку остановили
+func (d *WebhookDispatcher) deliver(url string, event Event) bool {
+	payload := webhookPayload{Kind: event.Kind, Time: event.Time, Account: event.Account, Delta: event.Delta}
+	if event.Payment.ID != "" {
+		payload.Payment = &event.Payment
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		d.fail(url, err)
+		return true
+	}
+
+	backoff := d.Backoff
+	for attempt := 0; ; attempt++ {
+		err = d.post(url, body)
+		if err == nil {
+			return true
+		}
+		if attempt == d.Retries {
+			d.fail(url, err)
+			return true
+		}
+		select {
+		case <-d.stop:
+			return false
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func (d *WebhookDispatcher) post(url string, body []byte) error {
+	client := d.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (d *WebhookDispatcher) fail(url string, err error) {
+	if d.OnError != nil {
+		d.OnError(url, err)
+	}
+}
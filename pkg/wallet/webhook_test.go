@@ -0,0 +1,88 @@
+package wallet
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWebhookDispatcher_success(t *testing.T) {
+	var mu sync.Mutex
+	attempts := 0
+	received := make(chan webhookPayload, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		attempts++
+		first := attempts == 1
+		mu.Unlock()
+		if first {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var payload webhookPayload
+		err := json.NewDecoder(r.Body).Decode(&payload)
+		if err != nil {
+			t.Error(err)
+		}
+		received <- payload
+	}))
+	defer server.Close()
+
+	s := newTestService()
+	d := NewWebhookDispatcher(s.Service, server.URL)
+	d.Backoff = time.Millisecond
+	d.OnError = func(url string, err error) {
+		t.Errorf("OnError(): url = %v, error = %v", url, err)
+	}
+	d.Start()
+	defer d.Stop()
+
+	account, err := s.RegisterAccount("+992928885522")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	select {
+	case payload := <-received:
+		if payload.Kind != EventAccountRegistered || payload.Account.ID != account.ID || payload.Payment != nil {
+			t.Errorf("WebhookDispatcher: wrong payload = %+v", payload)
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("WebhookDispatcher: event was not delivered")
+	}
+}
+
+func TestWebhookDispatcher_fail(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	s := newTestService()
+	d := NewWebhookDispatcher(s.Service, server.URL)
+	d.Retries = 2
+	d.Backoff = time.Millisecond
+	failed := make(chan error, 1)
+	d.OnError = func(url string, err error) {
+		failed <- err
+	}
+	d.Start()
+	defer d.Stop()
+
+	_, err := s.RegisterAccount("+992928885522")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	select {
+	case err := <-failed:
+		if err == nil {
+			t.Errorf("OnError(): must receive error")
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("WebhookDispatcher: delivery must fail after retries")
+	}
+}